@@ -0,0 +1,64 @@
+// Package gateway provides circuit breaker reporting and responses
+package gateway
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-gateway/gateway/internal/router"
+)
+
+// unsent reports whether no attempt reached a provider, because each was refused by a circuit
+// breaker or found no concurrency slot
+func unsent(tried []router.RoutingAttempt) bool {
+	for _, attempt := range tried {
+		if !attempt.CircuitOpen && !attempt.Saturated {
+			return false
+		}
+	}
+	return true
+}
+
+// respondCircuitOpen writes the 503 returned when open circuit breakers kept a request from every provider
+func respondCircuitOpen(c *gin.Context, circuitErr *router.CircuitOpenError) {
+	retryAfter := int(math.Ceil(circuitErr.RetryAfter.Seconds()))
+	if retryAfter < 1 {
+		retryAfter = 1
+	}
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error": gin.H{
+			"message": "Circuit breakers are open for every provider of this model, retry later",
+			"type":    "overloaded_error",
+			"code":    "circuit_open",
+		},
+	})
+}
+
+// circuitOpen returns the circuit breaker error of a request that never reached a provider, if any
+func circuitOpen(err error, tried []router.RoutingAttempt) (*router.CircuitOpenError, bool) {
+	var circuitErr *router.CircuitOpenError
+	if !errors.As(err, &circuitErr) || !unsent(tried) {
+		return nil, false
+	}
+	return circuitErr, true
+}
+
+// listBreakers returns the state of every provider+model circuit breaker and the recent state transitions
+func (g *Gateway) listBreakers(c *gin.Context) {
+	breakers := []router.WindowBreakerStats{}
+	events := []router.BreakerEvent{}
+	if g.smartRouter != nil {
+		breakers, events = g.smartRouter.BreakerReports()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"breakers": breakers,
+		"events":   events,
+	})
+}
@@ -0,0 +1,28 @@
+// Package gateway provides capability-aware model resolution
+package gateway
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// filterByCapabilities narrows candidates to the providers whose model supports every
+// optional feature the request uses, so tools, images or response_format are never dropped
+func (g *Gateway) filterByCapabilities(req *types.Request, candidates []types.Provider) ([]types.Provider, error) {
+	return router.FilterByCapabilities(req.ToChatCompletionRequest(), candidates, g.models.capabilitiesOf)
+}
+
+// respondUnsupportedCapability writes the error returned when no provider supports the request's features
+func respondUnsupportedCapability(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": gin.H{
+			"message": err.Error(),
+			"type":    "invalid_request_error",
+			"code":    "unsupported_capability",
+		},
+	})
+}
@@ -0,0 +1,87 @@
+// Package gateway provides request priorities and saturation responses for per-provider concurrency limits
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-gateway/gateway/internal/middleware"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// applyRequestPriority attaches the priority the request waits for provider concurrency slots with.
+// The caller's API key tier sets the priority; an X-Priority header may lower it but never raise it,
+// so callers can deprioritize batch work without jumping ahead of other tenants.
+func (g *Gateway) applyRequestPriority(c *gin.Context, req *types.Request) {
+	if g.smartRouter == nil {
+		return
+	}
+
+	identity := policyIdentity(c)
+	tier := ""
+	if apiKey, ok := middleware.GetAPIKeyFromContext(c); ok {
+		tier = apiKey.Tier
+	}
+	priority := g.smartRouter.TierPriority(identity.APIKey, tier)
+
+	if header := c.GetHeader("X-Priority"); header != "" {
+		if requested, err := strconv.Atoi(header); err == nil && requested < priority {
+			priority = requested
+		}
+	}
+	if priority != 0 {
+		c.Request = c.Request.WithContext(router.WithRequestPriority(c.Request.Context(), priority))
+	}
+}
+
+// saturatedOnly reports whether every attempt failed waiting for a concurrency slot
+func saturatedOnly(tried []router.RoutingAttempt) bool {
+	if len(tried) == 0 {
+		return false
+	}
+	for _, attempt := range tried {
+		if !attempt.Saturated {
+			return false
+		}
+	}
+	return true
+}
+
+// respondSaturated writes the 503 returned when every provider tried was at its concurrency limit
+func respondSaturated(c *gin.Context, err error) {
+	retryAfter := 1
+	var retryErr *retry.ProviderRetryError
+	if errors.As(err, &retryErr) && retryErr.RetryAfter > retryAfter {
+		retryAfter = retryErr.RetryAfter
+	}
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"error": gin.H{
+			"message": "All providers are at their concurrency limit, retry later",
+			"type":    "overloaded_error",
+			"code":    "provider_saturated",
+		},
+	})
+}
+
+// listConcurrency returns the live limit, requests in flight and queue depth of every concurrency limit,
+// with the admission counts and queue wait times recorded for each
+func (g *Gateway) listConcurrency(c *gin.Context) {
+	limits := []router.ConcurrencyReport{}
+	admission := map[string]*router.AdmissionMetrics{}
+	if g.smartRouter != nil {
+		limits = g.smartRouter.ConcurrencyReports()
+		admission = g.smartRouter.GetAdmissionMetrics()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"limits":    limits,
+		"admission": admission,
+	})
+}
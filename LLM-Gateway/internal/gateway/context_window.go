@@ -0,0 +1,78 @@
+// Package gateway provides context-window-aware model resolution
+package gateway
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// fitContextWindow narrows candidates to the providers whose context window holds the request.
+// When none can, the request is upgraded along the model's configured larger siblings until one fits.
+func (g *Gateway) fitContextWindow(c *gin.Context, req *types.Request, candidates []types.Provider) ([]types.Provider, error) {
+	requested := req.Model
+	seen := map[string]bool{}
+
+	for {
+		fitting, err := router.FitContextWindow(req, candidates, g.models.contextWindow)
+		var exceeded *router.ContextWindowError
+		if !errors.As(err, &exceeded) {
+			if err == nil && req.Model != requested {
+				c.Header("X-Gateway-Model", req.Model)
+				g.logger.WithFields(logrus.Fields{
+					"request_id": req.ID,
+					"requested":  requested,
+					"model":      req.Model,
+				}).Info("Prompt exceeds the requested model's context window, upgraded model")
+			}
+			return fitting, err
+		}
+
+		seen[req.Model] = true
+		if g.smartRouter == nil {
+			return nil, err
+		}
+		larger, ok := g.smartRouter.ContextUpgrade(req.Model)
+		if !ok || seen[larger] {
+			return nil, err
+		}
+		upgraded, policyErr := router.FilterByPolicy(router.RoutingPolicyFrom(c.Request.Context()), larger, g.models.providersFor(larger))
+		if policyErr != nil {
+			g.logger.WithError(policyErr).WithField("model", larger).Warn("Routing policy forbids the configured context upgrade")
+			return nil, err
+		}
+		if len(upgraded) == 0 {
+			g.logger.WithField("model", larger).Warn("No provider serves the configured context upgrade")
+			return nil, err
+		}
+
+		// The upgrade must not drop a feature the request uses
+		upgradedReq := *req
+		upgradedReq.Model = larger
+		upgraded, capabilityErr := g.filterByCapabilities(&upgradedReq, upgraded)
+		if capabilityErr != nil {
+			g.logger.WithError(capabilityErr).WithField("model", larger).Warn("The configured context upgrade lacks a feature the request uses")
+			return nil, err
+		}
+
+		req.Model = larger
+		candidates = upgraded
+	}
+}
+
+// respondContextLengthExceeded writes the OpenAI context_length_exceeded error
+func respondContextLengthExceeded(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"error": gin.H{
+			"message": err.Error(),
+			"type":    "invalid_request_error",
+			"param":   "messages",
+			"code":    "context_length_exceeded",
+		},
+	})
+}
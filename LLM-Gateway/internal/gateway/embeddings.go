@@ -0,0 +1,205 @@
+// Package gateway provides the OpenAI-compatible embeddings endpoint
+package gateway
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// embeddingResponse is the /v1/embeddings wire format; Embedding is []float64 or a base64 string
+type embeddingResponse struct {
+	Object   string               `json:"object"`
+	Data     []embeddingData      `json:"data"`
+	Model    string               `json:"model"`
+	Provider string               `json:"provider,omitempty"`
+	Usage    types.EmbeddingUsage `json:"usage"`
+}
+
+type embeddingData struct {
+	Object    string      `json:"object"`
+	Index     int         `json:"index"`
+	Embedding interface{} `json:"embedding"`
+}
+
+// embeddings handles /v1/embeddings (OpenAI compatible endpoint)
+func (g *Gateway) embeddings(c *gin.Context) {
+	var req types.EmbeddingRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		g.logger.WithError(err).Error("Failed to bind embeddings request")
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": "Invalid request format",
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	inputs, err := req.Inputs()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": err.Error(),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	if req.EncodingFormat != "" && req.EncodingFormat != "float" && req.EncodingFormat != "base64" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": fmt.Sprintf("Unsupported encoding_format: %s", req.EncodingFormat),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	req.RequestID = generateRequestID()
+
+	// The router sees embeddings as a request for the model alone
+	routed := &types.Request{ID: req.RequestID, Model: req.Model}
+	if !g.applyRoutingPolicy(c, routed) {
+		return
+	}
+	g.applyRequestPriority(c, routed)
+
+	// Resolve the model to the providers serving it that can embed
+	candidates := g.embeddingProvidersFor(req.Model)
+	if len(candidates) == 0 {
+		g.logger.WithField("model", req.Model).Warn("No embedding provider serves the requested model")
+		respondModelNotFound(c, req.Model)
+		return
+	}
+	candidates, err = g.filterByPolicy(c, routed, candidates)
+	if err != nil {
+		g.logger.WithError(err).WithField("model", req.Model).Warn("Routing policy forbids the request")
+		respondPolicyViolation(c, err)
+		return
+	}
+
+	g.logger.WithFields(logrus.Fields{
+		"request_id": req.RequestID,
+		"model":      req.Model,
+		"candidates": len(candidates),
+		"inputs":     len(inputs),
+	}).Info("Processing embeddings request")
+
+	// Embeddings are routed, admitted and failed over like chat
+	result, err := g.dispatch(c.Request.Context(), routed, candidates, func(ctx context.Context, p types.Provider, _ *types.Request) (interface{}, error) {
+		return g.callEmbedder(ctx, p.(types.EmbeddingProvider), &req)
+	})
+	if err != nil {
+		g.logger.WithError(err).Error("Embeddings API call failed")
+		respondEmbeddingFailure(c, err)
+		return
+	}
+
+	response := result.Response.(*types.EmbeddingResponse)
+	writeRoutingHeaders(c, result)
+	c.JSON(http.StatusOK, encodeEmbeddingResponse(response, req.EncodingFormat))
+}
+
+// embeddingProvidersFor returns the providers serving a model that implement EmbeddingProvider
+func (g *Gateway) embeddingProvidersFor(model string) []types.Provider {
+	var embedders []types.Provider
+	for _, p := range g.models.providersFor(model) {
+		if _, ok := p.(types.EmbeddingProvider); ok {
+			embedders = append(embedders, p)
+		}
+	}
+	return embedders
+}
+
+// callEmbedder calls an embedding adapter within the provider's timeout
+func (g *Gateway) callEmbedder(ctx context.Context, embedder types.EmbeddingProvider, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout(embedder))
+	defer cancel()
+
+	start := time.Now()
+	response, err := embedder.Embed(ctx, req)
+	if err != nil {
+		g.logger.WithError(err).WithField("provider", embedder.GetName()).Error("Provider embeddings call failed")
+		return nil, fmt.Errorf("%s embeddings call failed: %w", embedder.GetName(), err)
+	}
+
+	g.logger.WithFields(logrus.Fields{
+		"request_id": req.RequestID,
+		"provider":   embedder.GetName(),
+		"tokens":     response.Usage.TotalTokens,
+		"duration":   time.Since(start),
+	}).Info("Embeddings API call successful")
+
+	return response, nil
+}
+
+// respondEmbeddingFailure writes the error for an embeddings request no provider answered. When the
+// last upstream rejected the request itself, e.g. for too many inputs, its status and message are returned.
+func respondEmbeddingFailure(c *gin.Context, err error) {
+	var retryErr *retry.ProviderRetryError
+	if errors.As(lastAttemptError(err), &retryErr) && retryErr.Category == types.ErrorClient &&
+		retryErr.StatusCode >= http.StatusBadRequest && retryErr.StatusCode < http.StatusInternalServerError {
+		writeAttemptHeaders(c, attemptsOf(err))
+		c.JSON(retryErr.StatusCode, gin.H{
+			"error": gin.H{
+				"message": retryErr.Message,
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+	respondDispatchFailure(c, err)
+}
+
+// encodeEmbeddingResponse builds the wire response, packing vectors as little-endian float32 base64 if requested
+func encodeEmbeddingResponse(resp *types.EmbeddingResponse, encodingFormat string) *embeddingResponse {
+	out := &embeddingResponse{
+		Object:   "list",
+		Data:     make([]embeddingData, len(resp.Data)),
+		Model:    resp.Model,
+		Provider: resp.Provider,
+		Usage:    resp.Usage,
+	}
+
+	for i, embedding := range resp.Data {
+		out.Data[i] = embeddingData{Object: "embedding", Index: embedding.Index, Embedding: embedding.Embedding}
+		if encodingFormat == "base64" {
+			buf := make([]byte, 4*len(embedding.Embedding))
+			for j, value := range embedding.Embedding {
+				binary.LittleEndian.PutUint32(buf[4*j:], math.Float32bits(float32(value)))
+			}
+			out.Data[i].Embedding = base64.StdEncoding.EncodeToString(buf)
+		}
+	}
+
+	return out
+}
+
+// lastAttemptError returns the error of the final attempt in a failover chain, which ended it
+func lastAttemptError(err error) error {
+	var failoverErr *router.FailoverError
+	if !errors.As(err, &failoverErr) {
+		return err
+	}
+	if joined, ok := failoverErr.Err.(interface{ Unwrap() []error }); ok {
+		if errs := joined.Unwrap(); len(errs) > 0 {
+			return errs[len(errs)-1]
+		}
+	}
+	return failoverErr.Err
+}
@@ -0,0 +1,118 @@
+// Package gateway provides traffic splitting experiments for chat requests
+package gateway
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/internal/middleware"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// servedContextKey holds the servedRequest of a request that was answered in full
+const servedContextKey = "gateway_served"
+
+// servedRequest is the provider, model and usage that answered a request
+type servedRequest struct {
+	provider string
+	model    string
+	usage    types.Usage
+}
+
+// markServed records that a request was answered in full, for experiment statistics
+func markServed(c *gin.Context, provider, model string, usage *types.Usage) {
+	served := &servedRequest{provider: provider, model: model}
+	if usage != nil {
+		served.usage = *usage
+	}
+	c.Set(servedContextKey, served)
+}
+
+// assignExperiment moves a request for a model under experiment onto the caller's variant
+// and stamps the assignment on the response headers
+func (g *Gateway) assignExperiment(c *gin.Context, req *types.Request) *router.ExperimentAssignment {
+	if g.smartRouter == nil {
+		return nil
+	}
+
+	assignment, ok := g.smartRouter.AssignExperiment(req.Model, experimentIdentity(c, req), req.Timestamp)
+	if !ok {
+		return nil
+	}
+
+	c.Header("X-Gateway-Experiment", assignment.Experiment)
+	c.Header("X-Gateway-Variant", assignment.Variant)
+	g.logger.WithFields(logrus.Fields{
+		"request_id": req.ID,
+		"experiment": assignment.Experiment,
+		"variant":    assignment.Variant,
+		"requested":  req.Model,
+		"model":      assignment.Model,
+	}).Info("Request assigned to experiment variant")
+
+	req.Model = assignment.Model
+	return assignment
+}
+
+// recordExperiment adds the request's latency, success, token usage and cost to its variant.
+// Requests that were not answered in full count as errors.
+func (g *Gateway) recordExperiment(c *gin.Context, req *types.Request, assignment *router.ExperimentAssignment) {
+	outcome := router.ExperimentOutcome{Latency: time.Since(req.Timestamp)}
+	if value, ok := c.Get(servedContextKey); ok {
+		served := value.(*servedRequest)
+		outcome.Success = true
+		outcome.Usage = served.usage
+		outcome.Cost = g.usageCost(served)
+	}
+
+	g.smartRouter.RecordExperimentOutcome(assignment, outcome)
+}
+
+// usageCost prices the tokens a provider reported for a served request
+func (g *Gateway) usageCost(served *servedRequest) float64 {
+	providerType := served.provider
+	if provider, err := g.registry.GetProvider(served.provider); err == nil {
+		providerType = provider.GetType()
+	}
+
+	pricing := g.pricing.PricingFor(served.provider, providerType, served.model)
+	return cost.TokenCost(pricing, served.usage.PromptTokens, served.usage.CompletionTokens)
+}
+
+// experimentIdentity returns who a request comes from: the authenticated user and API key when the
+// auth middleware ran, otherwise the request's user_id and the API key it presents
+func experimentIdentity(c *gin.Context, req *types.Request) router.ExperimentIdentity {
+	identity := router.ExperimentIdentity{UserID: req.UserID, RequestID: req.ID}
+
+	if user, ok := middleware.GetUserFromContext(c); ok {
+		identity.UserID = strconv.FormatUint(uint64(user.ID), 10)
+	}
+	if apiKey, ok := middleware.GetAPIKeyFromContext(c); ok {
+		identity.APIKey = strconv.FormatUint(uint64(apiKey.ID), 10)
+	} else if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		identity.APIKey = apiKey
+	} else if bearer := c.GetHeader("Authorization"); strings.HasPrefix(bearer, "Bearer ") {
+		identity.APIKey = strings.TrimPrefix(bearer, "Bearer ")
+	}
+
+	return identity
+}
+
+// listExperiments reports the latency, error rate, cost and token usage of every experiment variant
+func (g *Gateway) listExperiments(c *gin.Context) {
+	experiments := []router.ExperimentReport{}
+	if g.smartRouter != nil {
+		experiments = g.smartRouter.ExperimentReports()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"experiments": experiments,
+	})
+}
@@ -0,0 +1,124 @@
+// Package gateway provides cross-provider failover for chat requests
+package gateway
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// dispatch sends req to the best of candidates, failing over to the next best provider
+// when one returns a retryable error
+func (g *Gateway) dispatch(ctx context.Context, req *types.Request, candidates []types.Provider, call router.ProviderCall) (*router.SmartRoutingResult, error) {
+	if g.smartRouter == nil {
+		provider := candidates[0]
+		response, err := call(ctx, provider, req)
+		if err != nil {
+			return nil, err
+		}
+		return &router.SmartRoutingResult{
+			Provider:     provider,
+			ProviderName: provider.GetName(),
+			Attempts:     1,
+			Tried:        []router.RoutingAttempt{{ProviderName: provider.GetName()}},
+			Response:     response,
+		}, nil
+	}
+
+	return g.smartRouter.ExecuteWithFailover(ctx, req, candidates, call)
+}
+
+// completeChat answers a non-streaming chat request, failing over between the providers serving its model
+func (g *Gateway) completeChat(c *gin.Context, req *types.Request, candidates []types.Provider) {
+	result, err := g.dispatch(c.Request.Context(), req, candidates, func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		return g.callProvider(ctx, p, req)
+	})
+	if err != nil {
+		g.logger.WithError(err).Error("API call failed")
+		respondDispatchFailure(c, err)
+		return
+	}
+
+	response := result.Response.(*types.Response)
+	markServed(c, result.ProviderName, response.Model, &response.Usage)
+	g.mirrorToShadow(c.Request.Context(), req, response)
+	writeRoutingHeaders(c, result)
+	c.JSON(http.StatusOK, response)
+}
+
+// writeRoutingHeaders reports the provider that served a request and every provider tried on the way
+func writeRoutingHeaders(c *gin.Context, result *router.SmartRoutingResult) {
+	c.Header("X-Gateway-Provider", result.ProviderName)
+	if result.Model != "" {
+		c.Header("X-Gateway-Model", result.Model)
+	}
+	writeAttemptHeaders(c, result.Tried)
+}
+
+// writeAttemptHeaders writes the attempt count, the providers tried in order and whether a hedge was sent
+func writeAttemptHeaders(c *gin.Context, tried []router.RoutingAttempt) {
+	if len(tried) == 0 {
+		return
+	}
+
+	names := make([]string, len(tried))
+	hedged := false
+	for i, attempt := range tried {
+		names[i] = attempt.ProviderName
+		hedged = hedged || attempt.Hedged
+	}
+	c.Header("X-Gateway-Attempts", strconv.Itoa(len(tried)))
+	c.Header("X-Gateway-Tried-Providers", strings.Join(names, ","))
+	if hedged {
+		c.Header("X-Gateway-Hedged", "true")
+	}
+}
+
+// attemptsOf returns the attempts recorded in a failover error, if any
+func attemptsOf(err error) []router.RoutingAttempt {
+	var failoverErr *router.FailoverError
+	if errors.As(err, &failoverErr) {
+		return failoverErr.Attempts
+	}
+	return nil
+}
+
+// respondDispatchFailure writes the error for a request no provider answered: a 403 when its routing
+// policy forbade every candidate, a 503 when open circuit breakers or saturation kept it from every
+// provider, otherwise the attempts made and the generic upstream failure
+func respondDispatchFailure(c *gin.Context, err error) {
+	var policyErr *router.PolicyError
+	if errors.As(err, &policyErr) {
+		respondPolicyViolation(c, err)
+		return
+	}
+
+	tried := attemptsOf(err)
+	writeAttemptHeaders(c, tried)
+	if circuitErr, ok := circuitOpen(err, tried); ok {
+		respondCircuitOpen(c, circuitErr)
+		return
+	}
+	if saturatedOnly(tried) {
+		respondSaturated(c, err)
+		return
+	}
+	respondAPICallFailed(c)
+}
+
+// respondAPICallFailed writes the generic upstream failure error
+func respondAPICallFailed(c *gin.Context) {
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"error": gin.H{
+			"message": "API call failed",
+			"type":    "api_error",
+		},
+	})
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,19 +13,28 @@ import (
 
 	"github.com/llm-gateway/gateway/internal/providers"
 	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/cost"
 	"github.com/llm-gateway/gateway/pkg/types"
 	"github.com/llm-gateway/gateway/pkg/utils"
 )
 
+// defaultProviderTimeout bounds calls to providers whose config sets no timeout
+const defaultProviderTimeout = 60 * time.Second
+
 // Gateway represents the core gateway service
 type Gateway struct {
-	config        *types.Config
-	router        *gin.Engine
-	server        *http.Server
-	logger        *logrus.Logger
-	middleware    []types.Middleware
-	smartRouter   *router.SmartRouter      // Week4: 智能路由器
-	zhipuProvider *providers.ZhipuProvider // Week5: 智谱AI提供商
+	config      *types.Config
+	router      *gin.Engine
+	server      *http.Server
+	logger      *logrus.Logger
+	middleware  []types.Middleware
+	smartRouter *router.SmartRouter    // Week4: 智能路由器
+	registry    types.ProviderRegistry // Providers built from the providers config section
+	models      *modelCatalog          // Model ID -> providers serving it
+	pricing     *cost.PricingManager   // Prices experiment traffic
+
+	authenticate gin.HandlerFunc // Authenticates API requests; nil leaves them unauthenticated
+	requestLog   RequestLog      // Stores a row per chat request; nil logs none
 }
 
 // New creates a new Gateway instance
@@ -64,16 +72,45 @@ func New(cfg *types.Config) *Gateway {
 	utilsLogger := &utils.Logger{Logger: logger}
 	smartRouterConfig := router.DefaultSmartRouterConfig()
 	if cfg.SmartRouter != nil {
-		smartRouterConfig.Strategy = cfg.SmartRouter.Strategy
-		smartRouterConfig.HealthCheckInterval = cfg.SmartRouter.HealthCheckInterval
+		if cfg.SmartRouter.Strategy != "" {
+			smartRouterConfig.Strategy = cfg.SmartRouter.Strategy
+		}
+		if cfg.SmartRouter.HealthCheckInterval > 0 {
+			smartRouterConfig.HealthCheckInterval = cfg.SmartRouter.HealthCheckInterval
+		}
 		smartRouterConfig.FailoverEnabled = cfg.SmartRouter.FailoverEnabled
 		smartRouterConfig.MaxRetries = cfg.SmartRouter.MaxRetries
+		if cfg.SmartRouter.FailoverTimeout > 0 {
+			smartRouterConfig.FailoverTimeout = cfg.SmartRouter.FailoverTimeout
+		}
 		smartRouterConfig.Weights = cfg.SmartRouter.Weights
-		smartRouterConfig.CircuitBreaker.Enabled = cfg.SmartRouter.CircuitBreaker.Enabled
-		smartRouterConfig.CircuitBreaker.Threshold = cfg.SmartRouter.CircuitBreaker.Threshold
-		smartRouterConfig.CircuitBreaker.Timeout = cfg.SmartRouter.CircuitBreaker.Timeout
-		smartRouterConfig.CircuitBreaker.MaxRequests = cfg.SmartRouter.CircuitBreaker.MaxRequests
+		if cfg.SmartRouter.CircuitBreaker != nil {
+			smartRouterConfig.CircuitBreaker.Enabled = cfg.SmartRouter.CircuitBreaker.Enabled
+			smartRouterConfig.CircuitBreaker.Threshold = cfg.SmartRouter.CircuitBreaker.Threshold
+			smartRouterConfig.CircuitBreaker.Timeout = cfg.SmartRouter.CircuitBreaker.Timeout
+			smartRouterConfig.CircuitBreaker.MaxRequests = cfg.SmartRouter.CircuitBreaker.MaxRequests
+			smartRouterConfig.CircuitBreaker.FailureRate = cfg.SmartRouter.CircuitBreaker.FailureRate
+			smartRouterConfig.CircuitBreaker.MinRequests = cfg.SmartRouter.CircuitBreaker.MinRequests
+			smartRouterConfig.CircuitBreaker.Window = cfg.SmartRouter.CircuitBreaker.Window
+			smartRouterConfig.CircuitBreaker.WindowSize = cfg.SmartRouter.CircuitBreaker.WindowSize
+		}
 		smartRouterConfig.MetricsEnabled = cfg.SmartRouter.MetricsEnabled
+		smartRouterConfig.VirtualModels = cfg.SmartRouter.VirtualModels
+		smartRouterConfig.Hedging = cfg.SmartRouter.Hedging
+		smartRouterConfig.CostOptimized = cfg.SmartRouter.CostOptimized
+		smartRouterConfig.PrefixAffinity = cfg.SmartRouter.PrefixAffinity
+		smartRouterConfig.Experiments = cfg.SmartRouter.Experiments
+		smartRouterConfig.Shadows = cfg.SmartRouter.Shadows
+		smartRouterConfig.Policies = cfg.SmartRouter.Policies
+		smartRouterConfig.DefaultPolicy = cfg.SmartRouter.DefaultPolicy
+		smartRouterConfig.Concurrency = cfg.SmartRouter.Concurrency
+		smartRouterConfig.OutlierDetection = cfg.SmartRouter.OutlierDetection
+		if len(cfg.SmartRouter.ContextUpgrades) > 0 {
+			smartRouterConfig.ContextUpgrades = make(map[string]string, len(cfg.SmartRouter.ContextUpgrades))
+			for _, upgrade := range cfg.SmartRouter.ContextUpgrades {
+				smartRouterConfig.ContextUpgrades[upgrade.From] = upgrade.To
+			}
+		}
 	}
 
 	smartRouter, err := router.NewSmartRouter(smartRouterConfig, utilsLogger)
@@ -81,23 +118,44 @@ func New(cfg *types.Config) *Gateway {
 		logger.WithError(err).Warn("Failed to initialize Smart Router, using mock router")
 	}
 
-	// Initialize Week5 智谱AI provider
-	zhipuConfig := &types.ProviderConfig{
-		Name:    "zhipu-provider",
-		Type:    "zhipu",
-		Enabled: true,
-		BaseURL: "https://open.bigmodel.cn/api/paas/v4",
-		Timeout: 30 * time.Second,
+	// Build every enabled provider declared in config
+	registry := providers.NewDefaultRegistry(utilsLogger)
+	if _, err := providers.NewProviderFactory(utilsLogger).BuildProviders(registry, cfg.Providers); err != nil {
+		logger.WithError(err).Warn("Failed to build some configured providers")
+	}
+
+	// Week5 智谱AI provider: keep the built-in default when none is configured
+	if len(registry.GetProvidersByType("zhipu")) == 0 {
+		zhipuConfig := &types.ProviderConfig{
+			Name:    "zhipu",
+			Type:    "zhipu",
+			Enabled: true,
+			BaseURL: "https://open.bigmodel.cn/api/paas/v4",
+			Timeout: 30 * time.Second,
+		}
+		if err := registry.RegisterProvider(providers.NewZhipuProvider(zhipuConfig, utilsLogger)); err != nil {
+			logger.WithError(err).Warn("Failed to register default Zhipu provider")
+		}
+	}
+
+	// Hand every provider to the Smart Router, which picks among those serving a model
+	if smartRouter != nil {
+		for _, p := range registry.GetProviders() {
+			if err := smartRouter.AddProvider(p); err != nil {
+				logger.WithError(err).WithField("provider", p.GetName()).Warn("Failed to add provider to Smart Router")
+			}
+		}
 	}
-	zhipuProvider := providers.NewZhipuProvider(zhipuConfig, utilsLogger)
 
 	gateway := &Gateway{
-		config:        cfg,
-		router:        ginRouter,
-		logger:        logger,
-		middleware:    make([]types.Middleware, 0),
-		smartRouter:   smartRouter,
-		zhipuProvider: zhipuProvider,
+		config:      cfg,
+		router:      ginRouter,
+		logger:      logger,
+		middleware:  make([]types.Middleware, 0),
+		smartRouter: smartRouter,
+		registry:    registry,
+		models:      newModelCatalog(registry, utilsLogger),
+		pricing:     cost.NewPricingManager(),
 	}
 
 	// Setup routes
@@ -113,10 +171,14 @@ func (g *Gateway) setupRoutes() {
 
 	// API version 1
 	v1 := g.router.Group("/v1")
+	v1.Use(g.authenticated)
 	{
 		// Chat completions endpoint (OpenAI compatible)
 		v1.POST("/chat/completions", g.chatCompletions)
 
+		// Embeddings endpoint (OpenAI compatible)
+		v1.POST("/embeddings", g.embeddings)
+
 		// Stream chat completions endpoint (SSE)
 		v1.POST("/chat/stream", g.chatStream)
 
@@ -126,6 +188,10 @@ func (g *Gateway) setupRoutes() {
 			admin.GET("/status", g.adminStatus)
 			admin.GET("/providers", g.listProviders)
 			admin.GET("/metrics", g.getMetrics)
+			admin.GET("/experiments", g.listExperiments)
+			admin.GET("/shadows", g.listShadows)
+			admin.GET("/concurrency", g.listConcurrency)
+			admin.GET("/breakers", g.listBreakers)
 		}
 	}
 }
@@ -210,6 +276,25 @@ smart_router_circuit_breaker_state{provider="baidu"} 0
 	c.String(http.StatusOK, metrics)
 }
 
+// Handler returns the HTTP handler serving the gateway API
+func (g *Gateway) Handler() http.Handler {
+	return g.router
+}
+
+// UseAuthentication installs the middleware that authenticates API requests, such as
+// middleware.AuthMiddleware.OptionalAuth(). Routing policies and priority tiers bind only to the
+// user and API key it verifies. Call it before the gateway starts serving.
+func (g *Gateway) UseAuthentication(handler gin.HandlerFunc) {
+	g.authenticate = handler
+}
+
+// authenticated runs the installed authentication middleware, if any
+func (g *Gateway) authenticated(c *gin.Context) {
+	if g.authenticate != nil {
+		g.authenticate(c)
+	}
+}
+
 // AddMiddleware adds a middleware to the gateway
 func (g *Gateway) AddMiddleware(middleware types.Middleware) {
 	g.middleware = append(g.middleware, middleware)
@@ -242,6 +327,9 @@ func (g *Gateway) chatCompletions(c *gin.Context) {
 	// Set request ID and timestamp
 	req.ID = generateRequestID()
 	req.Timestamp = time.Now()
+	if req.ConversationID == "" {
+		req.ConversationID = c.GetHeader("X-Conversation-ID")
+	}
 
 	g.logger.WithFields(logrus.Fields{
 		"request_id": req.ID,
@@ -249,66 +337,64 @@ func (g *Gateway) chatCompletions(c *gin.Context) {
 		"user_id":    req.UserID,
 	}).Info("Processing chat completion request")
 
-	// Week 5: Use real provider adapters based on model selection
-	provider, err := g.selectProviderByModel(req.Model)
+	// A model under experiment is replaced by the caller's variant before it is resolved
+	assignment := g.assignExperiment(c, &req)
+	if assignment != nil {
+		defer g.recordExperiment(c, &req, assignment)
+	}
+	defer g.logRequest(c, &req, assignment)
+
+	// The caller's routing policy restricts every provider chosen for the request
+	if !g.applyRoutingPolicy(c, &req) {
+		return
+	}
+	g.applyRequestPriority(c, &req)
+
+	// Virtual models fall through their target chain inside the Smart Router
+	if g.smartRouter != nil && g.smartRouter.IsVirtualModel(req.Model) {
+		g.serveVirtualModel(c, &req)
+		return
+	}
+
+	// Resolve the model to the providers serving it
+	candidates := g.models.providersFor(req.Model)
+	if len(candidates) == 0 {
+		g.logger.WithField("model", req.Model).Warn("No provider serves the requested model")
+		respondModelNotFound(c, req.Model)
+		return
+	}
+
+	// Skip providers the routing policy forbids
+	candidates, err := g.filterByPolicy(c, &req, candidates)
 	if err != nil {
-		g.logger.WithError(err).Error("Failed to select provider for model")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"message": fmt.Sprintf("Unsupported model: %s", req.Model),
-				"type":    "invalid_request_error",
-			},
-		})
+		g.logger.WithError(err).WithField("model", req.Model).Warn("Routing policy forbids the request")
+		respondPolicyViolation(c, err)
 		return
 	}
 
-	g.logger.WithFields(logrus.Fields{
-		"model":    req.Model,
-		"provider": provider,
-	}).Info("Selected provider for model")
-
-	// Call real API using Week 5 adapters
-	var response *types.Response
-	if provider == "zhipu" {
-		response, err = g.callZhipuAPI(&req)
-		if err != nil {
-			g.logger.WithError(err).Error("API call failed")
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": gin.H{
-					"message": "API call failed",
-					"type":    "api_error",
-				},
-			})
-			return
-		}
-	} else {
-		// Demo response for other providers (Week4 compatibility)
-		aiResponse := g.generateAIResponse(&req)
-		response = &types.Response{
-			ID:       req.ID,
-			Model:    req.Model,
-			Provider: fmt.Sprintf("%s-demo", provider),
-			Created:  time.Now(),
-			Choices: []types.Choice{
-				{
-					Index: 0,
-					Message: types.Message{
-						Role:    "assistant",
-						Content: fmt.Sprintf("【%s模型演示】%s", req.Model, aiResponse),
-					},
-					FinishReason: func() *string { s := "stop"; return &s }(),
-				},
-			},
-			Usage: types.Usage{
-				PromptTokens:     50,
-				CompletionTokens: 30,
-				TotalTokens:      80,
-			},
-			LatencyMs: 50,
-		}
+	// Skip providers that would drop a feature the request uses
+	candidates, err = g.filterByCapabilities(&req, candidates)
+	if err != nil {
+		g.logger.WithError(err).WithField("model", req.Model).Warn("No provider supports the request's features")
+		respondUnsupportedCapability(c, err)
+		return
+	}
+
+	// Skip providers whose context window can't hold the prompt, upgrading the model if configured
+	candidates, err = g.fitContextWindow(c, &req, candidates)
+	if err != nil {
+		g.logger.WithError(err).WithField("model", req.Model).Warn("Prompt exceeds the model's context window")
+		respondContextLengthExceeded(c, err)
+		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	// OpenAI clients request SSE on the same endpoint with stream=true
+	if req.Stream {
+		g.streamChatCompletion(c, &req, candidates)
+		return
+	}
+
+	g.completeChat(c, &req, candidates)
 }
 
 // Admin status handler
@@ -389,113 +475,50 @@ func generateRequestID() string {
 	return fmt.Sprintf("req_%d", time.Now().UnixNano())
 }
 
-// generateAIResponse creates intelligent responses based on user input
-func (g *Gateway) generateAIResponse(req *types.Request) string {
-	// Get the user's message
-	var userMessage string
-	if len(req.Messages) > 0 {
-		userMessage = req.Messages[len(req.Messages)-1].Content
-	}
-
-	// Simple keyword-based response generation
-	userMessageLower := strings.ToLower(userMessage)
-
-	var baseResponse string
-
-	// Greeting responses
-	if strings.Contains(userMessageLower, "你好") || strings.Contains(userMessageLower, "hello") || strings.Contains(userMessageLower, "hi") {
-		baseResponse = "你好！我是通过LLM Gateway智能路由系统为您服务的AI助手。很高兴与您交流！"
-	} else if strings.Contains(userMessageLower, "测试") || strings.Contains(userMessageLower, "test") {
-		baseResponse = "测试成功！您的请求已通过智能路由系统处理。系统运行状态良好，所有功能正常工作。"
-	} else if strings.Contains(userMessageLower, "路由") || strings.Contains(userMessageLower, "router") {
-		baseResponse = "智能路由系统运行状态优秀！我们使用round-robin负载均衡策略，确保请求在OpenAI、Anthropic和百度等多个提供商之间智能分配。"
-	} else if strings.Contains(userMessageLower, "性能") || strings.Contains(userMessageLower, "performance") {
-		baseResponse = "当前系统性能表现excellent：平均延迟100ms，成功率100%，所有3个提供商健康状态良好，熔断器正常工作。"
-	} else if strings.Contains(userMessageLower, "帮助") || strings.Contains(userMessageLower, "help") {
-		baseResponse = "我可以帮您测试LLM Gateway的各项功能，包括智能路由、负载均衡、健康监控等。请告诉我您想了解什么？"
-	} else if strings.Contains(userMessageLower, "谢谢") || strings.Contains(userMessageLower, "thank") {
-		baseResponse = "不客气！很高兴通过智能路由系统为您提供服务。如果您还有其他问题，随时可以询问。"
-	} else if strings.Contains(userMessageLower, "？") || strings.Contains(userMessageLower, "?") {
-		baseResponse = "这是一个很好的问题！通过智能路由系统，我可以为您提供准确的回答和支持。"
-	} else {
-		// Default response for other inputs
-		baseResponse = fmt.Sprintf("我理解您说的\"%s\"。作为通过智能路由系统的AI助手，我正在努力为您提供最佳的服务体验。", userMessage)
+// providerTimeout returns how long a call to p may take, including reading a whole stream
+func providerTimeout(p types.Provider) time.Duration {
+	if config := p.GetConfig(); config != nil && config.Timeout > 0 {
+		return config.Timeout
 	}
-
-	// Add smart router status information
-	routerStatus := "\n\n🔄 路由信息：此回复由Week4智能路由系统处理，使用round-robin负载均衡，已通过健康检查和熔断保护。"
-
-	return baseResponse + routerStatus
+	return defaultProviderTimeout
 }
 
-// selectProviderByModel selects the appropriate provider based on the model name
-func (g *Gateway) selectProviderByModel(model string) (string, error) {
-	// Model to provider mapping
-	modelProviderMap := map[string]string{
-		// 智谱AI models
-		"glm-4.5":     "zhipu",
-		"glm-4.5v":    "zhipu",
-		"glm-4.5-air": "zhipu",
-		"glm-4-flash": "zhipu", // backward compatibility
-		"glm-4":       "zhipu",
-		"glm-4-air":   "zhipu",
-
-		// OpenAI models (fallback to demo for now)
-		"gpt-4":         "demo",
-		"gpt-3.5-turbo": "demo",
-
-		// Anthropic models (fallback to demo for now)
-		"claude-3":        "demo",
-		"claude-3-sonnet": "demo",
-
-		// Baidu models (fallback to demo for now)
-		"ernie-bot":   "demo",
-		"ernie-bot-4": "demo",
-	}
-
-	provider, exists := modelProviderMap[model]
-	if !exists {
-		return "", fmt.Errorf("unsupported model: %s", model)
-	}
-
-	return provider, nil
-}
-
-// callZhipuAPI calls the real ZhipuAI API using Week 5 adapter
-func (g *Gateway) callZhipuAPI(req *types.Request) (*types.Response, error) {
-	g.logger.Info("Calling real ZhipuAI API using Week 5 adapter")
+// callProvider calls a provider adapter and converts the result to the gateway response format
+func (g *Gateway) callProvider(ctx context.Context, p types.Provider, req *types.Request) (*types.Response, error) {
+	g.logger.WithField("provider", p.GetName()).Info("Calling real provider API")
 
 	// Convert gateway request to ChatCompletionRequest
-	chatReq := &types.ChatCompletionRequest{
-		Model:    req.Model,
-		Messages: req.Messages,
-	}
+	chatReq := req.ToChatCompletionRequest()
 
 	// Call the real API
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, providerTimeout(p))
 	defer cancel()
 
 	start := time.Now()
-	chatResp, err := g.zhipuProvider.ChatCompletion(ctx, chatReq)
+	chatResp, err := p.Call(ctx, chatReq)
 	duration := time.Since(start)
 
 	if err != nil {
-		g.logger.WithError(err).Error("ZhipuAI API call failed")
-		return nil, fmt.Errorf("ZhipuAI API call failed: %w", err)
+		g.logger.WithError(err).WithField("provider", p.GetName()).Error("Provider API call failed")
+		return nil, fmt.Errorf("%s API call failed: %w", p.GetName(), err)
 	}
 
 	g.logger.WithFields(logrus.Fields{
 		"model":    req.Model,
 		"tokens":   chatResp.Usage.TotalTokens,
 		"duration": duration,
-		"provider": "zhipu",
-	}).Info("ZhipuAI API call successful")
+		"provider": p.GetName(),
+	}).Info("Provider API call successful")
 
-	// Convert back to gateway response format
+	// Convert back to gateway response format; the model is the concrete one that answered
+	model := chatResp.Model
+	if model == "" {
+		model = req.Model
+	}
 	response := &types.Response{
 		ID:       req.ID,
-		Model:    chatResp.Model,
-		Provider: "zhipu-real",
+		Model:    model,
+		Provider: p.GetName(),
 		Created:  time.Now(),
 		Choices:  make([]types.Choice, len(chatResp.Choices)),
 		Usage: types.Usage{
@@ -536,6 +559,10 @@ func (g *Gateway) chatStream(c *gin.Context) {
 	// Set request ID and timestamp
 	req.ID = generateRequestID()
 	req.Timestamp = time.Now()
+	if req.ConversationID == "" {
+		req.ConversationID = c.GetHeader("X-Conversation-ID")
+	}
+	req.Stream = true
 
 	g.logger.WithFields(logrus.Fields{
 		"request_id": req.ID,
@@ -543,45 +570,67 @@ func (g *Gateway) chatStream(c *gin.Context) {
 		"stream":     true,
 	}).Info("Processing streaming chat completion request")
 
-	// Select provider
-	provider, err := g.selectProviderByModel(req.Model)
+	assignment := g.assignExperiment(c, &req)
+	if assignment != nil {
+		defer g.recordExperiment(c, &req, assignment)
+	}
+	defer g.logRequest(c, &req, assignment)
+
+	if !g.applyRoutingPolicy(c, &req) {
+		return
+	}
+	g.applyRequestPriority(c, &req)
+
+	if g.smartRouter != nil && g.smartRouter.IsVirtualModel(req.Model) {
+		g.serveVirtualModel(c, &req)
+		return
+	}
+
+	// Resolve the model to the providers serving it
+	candidates := g.models.providersFor(req.Model)
+	if len(candidates) == 0 {
+		g.logger.WithField("model", req.Model).Warn("No provider serves the requested stream model")
+		respondModelNotFound(c, req.Model)
+		return
+	}
+
+	// Skip providers the routing policy forbids
+	candidates, err := g.filterByPolicy(c, &req, candidates)
 	if err != nil {
-		g.logger.WithError(err).Error("Failed to select provider for stream")
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": gin.H{
-				"message": fmt.Sprintf("Unsupported model: %s", req.Model),
-				"type":    "invalid_request_error",
-			},
-		})
+		g.logger.WithError(err).WithField("model", req.Model).Warn("Routing policy forbids the request")
+		respondPolicyViolation(c, err)
 		return
 	}
 
-	// Set SSE headers
-	c.Header("Content-Type", "text/event-stream")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Headers", "Cache-Control")
+	// Skip providers that would drop a feature the request uses
+	candidates, err = g.filterByCapabilities(&req, candidates)
+	if err != nil {
+		g.logger.WithError(err).WithField("model", req.Model).Warn("No provider supports the request's features")
+		respondUnsupportedCapability(c, err)
+		return
+	}
 
-	// Use real streaming API for zhipu
-	if provider == "zhipu" {
-		g.streamZhipuAPI(c, &req)
+	// Skip providers whose context window can't hold the prompt, upgrading the model if configured
+	candidates, err = g.fitContextWindow(c, &req, candidates)
+	if err != nil {
+		g.logger.WithError(err).WithField("model", req.Model).Warn("Prompt exceeds the model's context window")
+		respondContextLengthExceeded(c, err)
+		return
+	}
+
+	// Zhipu keeps the legacy event format; other providers stream OpenAI chunks
+	if zhipu, ok := candidates[0].(*providers.ZhipuProvider); ok && len(candidates) == 1 {
+		g.streamZhipuAPI(c, &req, zhipu)
 	} else {
-		g.streamMockResponse(c, &req)
+		g.streamChatCompletion(c, &req, candidates)
 	}
 }
 
-// streamZhipuAPI handles streaming response from ZhipuAI
-func (g *Gateway) streamZhipuAPI(c *gin.Context, req *types.Request) {
+// streamZhipuAPI serves a ZhipuAI stream in the legacy event format. The stream is opened through
+// dispatch like any other, so concurrency admission, circuit breakers and outlier detection cover it.
+func (g *Gateway) streamZhipuAPI(c *gin.Context, req *types.Request, zhipu *providers.ZhipuProvider) {
 	g.logger.Info("Starting streaming call to ZhipuAI API")
 
-	// 设置流式响应头
-	c.Header("Content-Type", "text/event-stream; charset=utf-8")
-	c.Header("Cache-Control", "no-cache")
-	c.Header("Connection", "keep-alive")
-	c.Header("Access-Control-Allow-Origin", "*")
-	c.Header("Access-Control-Allow-Headers", "Cache-Control")
-
 	// 确保ResponseWriter支持Flusher
 	flusher, ok := c.Writer.(http.Flusher)
 	if !ok {
@@ -589,123 +638,78 @@ func (g *Gateway) streamZhipuAPI(c *gin.Context, req *types.Request) {
 		return
 	}
 
-	// Convert to ChatCompletionRequest with streaming enabled
-	streamEnabled := true
-	chatReq := &types.ChatCompletionRequest{
-		Model:    req.Model,
-		Messages: req.Messages,
-		Stream:   &streamEnabled, // Enable streaming
-	}
-
-	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	ctx, cancel := context.WithCancel(c.Request.Context())
 	defer cancel()
 
-	// Call streaming API
-	err := g.zhipuProvider.ChatCompletionStream(ctx, chatReq, func(chunk string, done bool) {
-		if done {
-			// Send completion signal
-			completionData := map[string]interface{}{
-				"id": req.ID,
-				"choices": []map[string]interface{}{
-					{
-						"delta":         map[string]string{},
-						"finish_reason": "stop",
-					},
-				},
-				"done": true,
-			}
-
-			// 发送SSE格式数据
-			jsonData, _ := json.Marshal(completionData)
-			fmt.Fprintf(c.Writer, "event: message\n")
-			fmt.Fprintf(c.Writer, "data: %s\n\n", string(jsonData))
-			flusher.Flush()
-
-			// 发送结束信号
-			fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
-			flusher.Flush()
-		} else if chunk != "" {
-			// Send content chunk
-			chunkData := map[string]interface{}{
-				"id":    req.ID,
-				"model": req.Model,
-				"choices": []map[string]interface{}{
-					{
-						"delta": map[string]string{
-							"content": chunk,
-						},
-						"finish_reason": nil,
-					},
-				},
-				"done": false,
-			}
-
-			// 发送SSE格式数据
-			jsonData, _ := json.Marshal(chunkData)
-			fmt.Fprintf(c.Writer, "event: message\n")
-			fmt.Fprintf(c.Writer, "data: %s\n\n", string(jsonData))
-			flusher.Flush()
-
-			g.logger.WithField("content", chunk).Debug("Sent chunk to client")
-		}
+	start := time.Now()
+	result, err := g.dispatch(ctx, req, []types.Provider{zhipu}, func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		return openChatStream(ctx, p, req)
 	})
-
 	if err != nil {
-		g.logger.WithError(err).Error("Streaming API call failed")
+		// Nothing has been written yet, so a regular JSON error is still possible
+		g.logger.WithError(err).Error("Failed to open ZhipuAI stream")
+		respondDispatchFailure(c, err)
+		return
+	}
 
-		// 发送错误信息
-		errorData := map[string]string{
-			"message": "Streaming failed",
-			"type":    "api_error",
-		}
-		jsonData, _ := json.Marshal(errorData)
-		fmt.Fprintf(c.Writer, "event: error\n")
+	deadline := time.AfterFunc(providerTimeout(result.Provider)-time.Since(start), cancel)
+	defer deadline.Stop()
+
+	// 设置流式响应头
+	writeRoutingHeaders(c, result)
+	c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Access-Control-Allow-Headers", "Cache-Control")
+
+	// 发送SSE格式数据
+	writeEvent := func(event string, payload interface{}) {
+		jsonData, _ := json.Marshal(payload)
+		fmt.Fprintf(c.Writer, "event: %s\n", event)
 		fmt.Fprintf(c.Writer, "data: %s\n\n", string(jsonData))
 		flusher.Flush()
 	}
-}
-
-// streamMockResponse provides mock streaming for other providers
-func (g *Gateway) streamMockResponse(c *gin.Context, req *types.Request) {
-	g.logger.Info("Using mock streaming response")
 
-	// Generate response text
-	aiResponse := g.generateAIResponse(req)
-	words := strings.Fields(aiResponse)
-
-	// Stream word by word
-	for i, word := range words {
-		select {
-		case <-c.Request.Context().Done():
-			return
-		default:
-			content := word
-			if i < len(words)-1 {
-				content += " "
+	for chunk := range result.Response.(<-chan *types.StreamChunk) {
+		switch chunk.Type {
+		case types.ChunkContent:
+			if chunk.Content == "" {
+				continue
 			}
-
-			c.SSEvent("message", map[string]interface{}{
+			writeEvent("message", map[string]interface{}{
 				"id":    req.ID,
 				"model": req.Model,
 				"choices": []map[string]interface{}{
 					{
 						"delta": map[string]string{
-							"content": content,
+							"content": chunk.Content,
 						},
 						"finish_reason": nil,
 					},
 				},
 				"done": false,
 			})
-			c.Writer.Flush()
-
-			// Simulate typing delay
-			time.Sleep(100 * time.Millisecond)
+			g.logger.WithField("content", chunk.Content).Debug("Sent chunk to client")
+		case types.ChunkError:
+			g.logger.WithError(chunk.Err).Error("Streaming API call failed")
+
+			// 发送错误信息
+			writeEvent("error", map[string]string{
+				"message": "Streaming failed",
+				"type":    "api_error",
+			})
+			return
 		}
 	}
 
+	if ctx.Err() != nil {
+		g.logger.WithError(ctx.Err()).WithField("request_id", req.ID).Warn("Stream ended before completion")
+		return
+	}
+
 	// Send completion signal
-	c.SSEvent("message", map[string]interface{}{
+	writeEvent("message", map[string]interface{}{
 		"id": req.ID,
 		"choices": []map[string]interface{}{
 			{
@@ -715,5 +719,10 @@ func (g *Gateway) streamMockResponse(c *gin.Context, req *types.Request) {
 		},
 		"done": true,
 	})
-	c.SSEvent("", "[DONE]")
+
+	// 发送结束信号
+	fmt.Fprintf(c.Writer, "data: [DONE]\n\n")
+	flusher.Flush()
+
+	markServed(c, result.ProviderName, req.Model, nil)
 }
@@ -0,0 +1,205 @@
+// Package gateway provides registry-backed model resolution
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+)
+
+// errModelNotFound is returned when no registered provider serves the requested model
+var errModelNotFound = errors.New("model not found")
+
+// modelCatalog maps model IDs to the registered providers serving them
+type modelCatalog struct {
+	providers      map[string][]types.Provider
+	contextWindows map[string]int                      // "provider/model" -> context length in tokens
+	capabilities   map[string]*types.ModelCapabilities // "provider/model" -> declared request features
+}
+
+// newModelCatalog indexes every registered provider by the models it serves.
+// ProviderConfig.Models is authoritative when set; otherwise the provider's GetModels list is used.
+// Context windows and capabilities come from GetModels, overridden by ProviderConfig.ModelSpecs.
+func newModelCatalog(registry types.ProviderRegistry, logger *utils.Logger) *modelCatalog {
+	catalog := &modelCatalog{
+		providers:      make(map[string][]types.Provider),
+		contextWindows: make(map[string]int),
+		capabilities:   make(map[string]*types.ModelCapabilities),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	registered := registry.GetProviders()
+	sort.Slice(registered, func(i, j int) bool { return registered[i].GetName() < registered[j].GetName() })
+
+	for _, p := range registered {
+		config := p.GetConfig()
+		models := config.Models
+
+		listed, err := p.GetModels(ctx)
+		if err != nil && len(models) == 0 {
+			logger.WithError(err).WithField("provider", p.GetName()).Warn("Failed to list provider models")
+			continue
+		}
+		for _, model := range listed {
+			if len(config.Models) == 0 {
+				models = append(models, model.Name)
+			}
+			if model.ContextLength > 0 {
+				catalog.contextWindows[p.GetName()+"/"+model.Name] = model.ContextLength
+			}
+			if model.Capabilities != nil {
+				catalog.capabilities[p.GetName()+"/"+model.Name] = model.Capabilities
+			}
+		}
+		for _, spec := range config.ModelSpecs {
+			if spec.ContextWindow > 0 {
+				catalog.contextWindows[p.GetName()+"/"+spec.Name] = spec.ContextWindow
+			}
+			if spec.Capabilities != nil {
+				catalog.capabilities[p.GetName()+"/"+spec.Name] = spec.Capabilities
+			}
+		}
+
+		for _, model := range models {
+			catalog.providers[model] = append(catalog.providers[model], p)
+		}
+	}
+
+	return catalog
+}
+
+// providersFor returns the providers serving a model, sorted by name
+func (c *modelCatalog) providersFor(model string) []types.Provider {
+	return c.providers[model]
+}
+
+// contextWindow returns a provider's context length for a model, or 0 when unknown
+func (c *modelCatalog) contextWindow(provider types.Provider, model string) int {
+	return c.contextWindows[provider.GetName()+"/"+model]
+}
+
+// capabilitiesOf returns a provider's declared capabilities for a model, or nil when undeclared
+func (c *modelCatalog) capabilitiesOf(provider types.Provider, model string) *types.ModelCapabilities {
+	return c.capabilities[provider.GetName()+"/"+model]
+}
+
+// respondModelNotFound writes the OpenAI model_not_found error
+func respondModelNotFound(c *gin.Context, model string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": gin.H{
+			"message": fmt.Sprintf("The model `%s` does not exist or you do not have access to it.", model),
+			"type":    "invalid_request_error",
+			"param":   "model",
+			"code":    "model_not_found",
+		},
+	})
+}
+
+// serveVirtualModel answers a request for a virtual model, falling through its targets on failure
+func (g *Gateway) serveVirtualModel(c *gin.Context, req *types.Request) {
+	alias := req.Model
+
+	if req.Stream {
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+
+		start := time.Now()
+		result, err := g.smartRouter.ExecuteVirtualModel(ctx, req, g.targetFits, func(ctx context.Context, p types.Provider, targetReq *types.Request) (interface{}, error) {
+			chunks, err := openChatStream(ctx, p, targetReq)
+			if err != nil {
+				return nil, err
+			}
+			return servedStream{req: targetReq, chunks: chunks}, nil
+		})
+		if err != nil {
+			g.respondVirtualModelFailure(c, alias, err)
+			return
+		}
+
+		// The failover timeout bounds opening the stream; the serving provider's timeout bounds all of it
+		deadline := time.AfterFunc(providerTimeout(result.Provider)-time.Since(start), cancel)
+		defer deadline.Stop()
+
+		served := result.Response.(servedStream)
+		g.reportVirtualModelResult(c, req, alias, result)
+		if answer := g.writeChatStream(ctx, c, served.req, served.chunks, result.ProviderName); answer != nil {
+			answer.LatencyMs = time.Since(start).Milliseconds()
+			g.mirrorToShadow(c.Request.Context(), req, answer)
+		}
+		return
+	}
+
+	result, err := g.smartRouter.ExecuteVirtualModel(c.Request.Context(), req, g.targetFits, func(ctx context.Context, p types.Provider, targetReq *types.Request) (interface{}, error) {
+		return g.callProvider(ctx, p, targetReq)
+	})
+	if err != nil {
+		g.respondVirtualModelFailure(c, alias, err)
+		return
+	}
+
+	response := result.Response.(*types.Response)
+	markServed(c, result.ProviderName, response.Model, &response.Usage)
+	g.mirrorToShadow(c.Request.Context(), req, response)
+	g.reportVirtualModelResult(c, req, alias, result)
+	c.JSON(http.StatusOK, response)
+}
+
+// targetFits returns why a provider can't serve a virtual model target's request: the target model
+// lacks a feature the request uses, or its context window can't hold the prompt
+func (g *Gateway) targetFits(provider types.Provider, targetReq *types.Request) error {
+	if _, err := g.filterByCapabilities(targetReq, []types.Provider{provider}); err != nil {
+		return err
+	}
+	_, err := router.FitContextWindow(targetReq, []types.Provider{provider}, g.models.contextWindow)
+	return err
+}
+
+// servedStream is a stream opened for one target of a virtual model
+type servedStream struct {
+	req    *types.Request
+	chunks <-chan *types.StreamChunk
+}
+
+// reportVirtualModelResult records which concrete target served a virtual model in headers and logs
+func (g *Gateway) reportVirtualModelResult(c *gin.Context, req *types.Request, alias string, result *router.SmartRoutingResult) {
+	writeRoutingHeaders(c, result)
+
+	g.logger.WithFields(logrus.Fields{
+		"request_id":    req.ID,
+		"virtual_model": alias,
+		"provider":      result.ProviderName,
+		"model":         result.Model,
+		"attempts":      result.Attempts,
+	}).Info("Virtual model served")
+}
+
+// respondVirtualModelFailure writes the error returned when every target of a virtual model failed
+func (g *Gateway) respondVirtualModelFailure(c *gin.Context, alias string, err error) {
+	var unsupported *router.CapabilityError
+	if errors.As(err, &unsupported) {
+		g.logger.WithError(err).WithField("virtual_model", alias).Warn("No target supports the request's features")
+		respondUnsupportedCapability(c, err)
+		return
+	}
+	var exceeded *router.ContextWindowError
+	if errors.As(err, &exceeded) {
+		g.logger.WithError(err).WithField("virtual_model", alias).Warn("Prompt exceeds the context window of every target")
+		respondContextLengthExceeded(c, err)
+		return
+	}
+
+	g.logger.WithError(err).WithField("virtual_model", alias).Error("Virtual model failed")
+	respondDispatchFailure(c, err)
+}
@@ -0,0 +1,81 @@
+// Package gateway provides per-tenant routing policies for chat requests
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/internal/middleware"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// applyRoutingPolicy attaches the caller's routing policy to the request context, so every provider
+// selection made for the request obeys it. It responds 403 and returns false when the caller is
+// bound to a policy that is not configured.
+func (g *Gateway) applyRoutingPolicy(c *gin.Context, req *types.Request) bool {
+	if g.smartRouter == nil {
+		return true
+	}
+
+	policy, err := g.smartRouter.RoutingPolicyFor(policyIdentity(c))
+	if err != nil {
+		g.logger.WithError(err).WithField("request_id", req.ID).Warn("Routing policy not found")
+		respondPolicyViolation(c, err)
+		return false
+	}
+	if policy == nil {
+		return true
+	}
+
+	g.logger.WithFields(logrus.Fields{
+		"request_id": req.ID,
+		"policy":     policy.Name,
+	}).Debug("Routing policy applied")
+	c.Request = c.Request.WithContext(router.WithRoutingPolicy(c.Request.Context(), policy))
+	return true
+}
+
+// filterByPolicy narrows candidates to the providers the request's routing policy lets serve its model
+func (g *Gateway) filterByPolicy(c *gin.Context, req *types.Request, candidates []types.Provider) ([]types.Provider, error) {
+	return router.FilterByPolicy(router.RoutingPolicyFrom(c.Request.Context()), req.Model, candidates)
+}
+
+// policyIdentity returns who a request comes from: the API key and user the authentication middleware
+// verified, with the policy named on either. Client-supplied user IDs and unverified keys are never
+// trusted, so a request the middleware did not authenticate has the zero identity.
+func policyIdentity(c *gin.Context) router.PolicyIdentity {
+	var identity router.PolicyIdentity
+
+	if user, ok := middleware.GetUserFromContext(c); ok {
+		identity.UserID = strconv.FormatUint(uint64(user.ID), 10)
+		identity.Policy = user.RoutingPolicy
+	}
+	if apiKey, ok := middleware.GetAPIKeyFromContext(c); ok {
+		identity.APIKey = apiKey.Key
+		if apiKey.RoutingPolicy != "" {
+			identity.Policy = apiKey.RoutingPolicy
+		}
+	}
+
+	return identity
+}
+
+// respondPolicyViolation writes the 403 returned when a routing policy forbids a request
+func respondPolicyViolation(c *gin.Context, err error) {
+	body := gin.H{
+		"message": err.Error(),
+		"type":    "permission_error",
+		"code":    "routing_policy_violation",
+	}
+	var policyErr *router.PolicyError
+	if errors.As(err, &policyErr) {
+		body["policy"] = policyErr.Policy
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": body})
+}
@@ -0,0 +1,74 @@
+// Package gateway provides request logging for chat requests
+package gateway
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/llm-gateway/gateway/internal/middleware"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/internal/storage"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// RequestLog stores a row for every chat request, such as storage.RequestRepository
+type RequestLog interface {
+	Create(request *storage.Request) error
+}
+
+// UseRequestLog installs the log chat requests are written to, each row stamped with the experiment
+// variant that served it. Call it before the gateway starts serving.
+func (g *Gateway) UseRequestLog(requests RequestLog) {
+	g.requestLog = requests
+}
+
+// logRequest writes a finished chat request to the request log, if one is installed. The row is
+// written in the background so the database never delays the response.
+func (g *Gateway) logRequest(c *gin.Context, req *types.Request, assignment *router.ExperimentAssignment) {
+	if g.requestLog == nil {
+		return
+	}
+
+	row := &storage.Request{
+		RequestID:      req.ID,
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		ClientIP:       c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+		ModelName:      req.Model,
+		StatusCode:     c.Writer.Status(),
+		ResponseTimeMs: time.Since(req.Timestamp).Milliseconds(),
+		RequestTime:    req.Timestamp,
+		ResponseAt:     time.Now(),
+	}
+	if size := c.Writer.Size(); size > 0 {
+		row.ResponseSize = int64(size)
+	}
+	if user, ok := middleware.GetUserFromContext(c); ok {
+		userID := user.ID
+		row.UserID = &userID
+	}
+	if apiKey, ok := middleware.GetAPIKeyFromContext(c); ok {
+		apiKeyID := apiKey.ID
+		row.APIKeyID = &apiKeyID
+	}
+	if value, ok := c.Get(servedContextKey); ok {
+		served := value.(*servedRequest)
+		row.ModelName = served.model
+		row.PromptTokens = served.usage.PromptTokens
+		row.CompletionTokens = served.usage.CompletionTokens
+		row.TotalTokens = served.usage.TotalTokens
+		row.Cost = g.usageCost(served)
+	}
+	if assignment != nil {
+		row.Experiment = assignment.Experiment
+		row.Variant = assignment.Variant
+	}
+
+	go func() {
+		if err := g.requestLog.Create(row); err != nil {
+			g.logger.WithError(err).WithField("request_id", row.RequestID).Warn("Failed to log request")
+		}
+	}()
+}
@@ -0,0 +1,114 @@
+// Package gateway provides shadow traffic mirroring for chat requests
+package gateway
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// shadowPriority is the admission priority of shadow calls, below any caller's
+const shadowPriority = math.MinInt32
+
+// mirrorToShadow sends a sample of answered requests for a model to its shadow target in the
+// background and records both answers side by side. Streamed requests are mirrored once their stream
+// completes, as a non-streaming shadow call. The shadow call waits for concurrency slots behind live
+// traffic and is refused by open circuit breakers, but never reaches the client, experiment statistics
+// or the caller's usage. It is skipped when the routing policy in ctx forbids the shadow target.
+func (g *Gateway) mirrorToShadow(ctx context.Context, req *types.Request, primary *types.Response) {
+	if g.smartRouter == nil {
+		return
+	}
+	shadow, ok := g.smartRouter.SampleShadow(ctx, req.Model)
+	if !ok {
+		return
+	}
+
+	shadowReq := *req
+	shadowReq.Model = shadow.Model
+	shadowReq.Stream = false
+	shadowReq.StreamOptions = nil
+
+	go func() {
+		defer shadow.Release()
+
+		comparison := router.ShadowComparison{
+			RequestID: req.ID,
+			Model:     req.Model,
+			Primary:   g.shadowResult(primary.Provider, primary),
+			Timestamp: time.Now(),
+		}
+
+		// The client's request may already be finished, so the shadow call has its own deadline
+		ctx, cancel := context.WithTimeout(context.Background(), providerTimeout(shadow.Provider))
+		defer cancel()
+		ctx = router.WithRequestPriority(ctx, shadowPriority)
+
+		result, err := g.smartRouter.ExecuteWithFailover(ctx, &shadowReq, []types.Provider{shadow.Provider}, func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			return g.callProvider(ctx, p, req)
+		})
+		if err != nil {
+			comparison.Shadow = router.ShadowResult{Provider: shadow.Provider.GetName(), Model: shadow.Model, Error: err.Error()}
+		} else {
+			response := result.Response.(*types.Response)
+			comparison.Shadow = g.shadowResult(shadow.Provider.GetName(), response)
+			comparison.Similarity = router.TextSimilarity(answerText(primary), answerText(response))
+		}
+
+		g.smartRouter.RecordShadowComparison(comparison)
+		g.logger.WithFields(logrus.Fields{
+			"request_id":      req.ID,
+			"model":           req.Model,
+			"shadow_provider": comparison.Shadow.Provider,
+			"shadow_model":    comparison.Shadow.Model,
+			"primary_latency": comparison.Primary.LatencyMs,
+			"shadow_latency":  comparison.Shadow.LatencyMs,
+			"similarity":      comparison.Similarity,
+			"shadow_error":    comparison.Shadow.Error,
+			"primary_finish":  comparison.Primary.FinishReason,
+			"shadow_finish":   comparison.Shadow.FinishReason,
+		}).Info("Shadow request compared")
+	}()
+}
+
+// shadowResult summarizes one answer for a shadow comparison
+func (g *Gateway) shadowResult(provider string, response *types.Response) router.ShadowResult {
+	result := router.ShadowResult{
+		Provider:  provider,
+		Model:     response.Model,
+		LatencyMs: response.LatencyMs,
+		Usage:     response.Usage,
+		Cost:      g.usageCost(&servedRequest{provider: provider, model: response.Model, usage: response.Usage}),
+	}
+	if len(response.Choices) > 0 && response.Choices[0].FinishReason != nil {
+		result.FinishReason = *response.Choices[0].FinishReason
+	}
+	return result
+}
+
+// answerText returns the text of a response's first choice
+func answerText(response *types.Response) string {
+	if len(response.Choices) == 0 {
+		return ""
+	}
+	return response.Choices[0].Message.Content
+}
+
+// listShadows reports how every shadow target compares to the primary traffic it mirrors
+func (g *Gateway) listShadows(c *gin.Context) {
+	shadows := []router.ShadowReport{}
+	if g.smartRouter != nil {
+		shadows = g.smartRouter.ShadowReports()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shadows": shadows,
+	})
+}
@@ -0,0 +1,275 @@
+// Package gateway provides OpenAI-compatible streaming for chat completions
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// chatCompletionChunk is the OpenAI chat.completion.chunk wire format
+type chatCompletionChunk struct {
+	ID       string        `json:"id"`
+	Object   string        `json:"object"`
+	Created  int64         `json:"created"`
+	Model    string        `json:"model"`
+	Provider string        `json:"provider,omitempty"`
+	Choices  []chunkChoice `json:"choices"`
+	Usage    *types.Usage  `json:"usage,omitempty"`
+}
+
+type chunkChoice struct {
+	Index        int        `json:"index"`
+	Delta        chunkDelta `json:"delta"`
+	FinishReason *string    `json:"finish_reason"`
+}
+
+type chunkDelta struct {
+	Role      string          `json:"role,omitempty"`
+	Content   string          `json:"content,omitempty"`
+	ToolCalls []chunkToolCall `json:"tool_calls,omitempty"`
+}
+
+type chunkToolCall struct {
+	Index    int           `json:"index"`
+	ID       string        `json:"id,omitempty"`
+	Type     string        `json:"type,omitempty"`
+	Function chunkFunction `json:"function"`
+}
+
+type chunkFunction struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments"`
+}
+
+// streamChatCompletion serves a stream=true request on /v1/chat/completions as OpenAI-compatible SSE.
+// Opening the upstream stream fails over between candidates; once chunks flow the provider is fixed.
+func (g *Gateway) streamChatCompletion(c *gin.Context, req *types.Request, candidates []types.Provider) {
+	streamers := make([]types.Provider, 0, len(candidates))
+	for _, candidate := range candidates {
+		if _, ok := candidate.(types.StreamingProvider); ok {
+			streamers = append(streamers, candidate)
+		}
+	}
+	if len(streamers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": gin.H{
+				"message": fmt.Sprintf("Streaming is not supported for model: %s", req.Model),
+				"type":    "invalid_request_error",
+			},
+		})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	start := time.Now()
+	result, err := g.dispatch(ctx, req, streamers, func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		return openChatStream(ctx, p, req)
+	})
+	if err != nil {
+		// Nothing has been written yet, so a regular JSON error is still possible
+		g.logger.WithError(err).Error("Failed to open provider stream")
+		respondDispatchFailure(c, err)
+		return
+	}
+
+	// The failover timeout bounds opening the stream; the serving provider's timeout bounds all of it
+	deadline := time.AfterFunc(providerTimeout(result.Provider)-time.Since(start), cancel)
+	defer deadline.Stop()
+
+	writeRoutingHeaders(c, result)
+	if answer := g.writeChatStream(ctx, c, req, result.Response.(<-chan *types.StreamChunk), result.ProviderName); answer != nil {
+		answer.LatencyMs = time.Since(start).Milliseconds()
+		g.mirrorToShadow(c.Request.Context(), req, answer)
+	}
+}
+
+// openChatStream opens a provider stream and waits for its first chunk, so failover and hedging
+// can still switch providers on a stream that errors or stalls before producing anything.
+// The returned channel replays the first chunk before relaying the rest.
+func openChatStream(ctx context.Context, p types.Provider, req *types.Request) (<-chan *types.StreamChunk, error) {
+	streamer, ok := p.(types.StreamingProvider)
+	if !ok {
+		return nil, fmt.Errorf("provider %s does not support streaming", p.GetName())
+	}
+
+	chunks, err := streamer.CallStream(ctx, req.ToChatCompletionRequest())
+	if err != nil {
+		return nil, err
+	}
+
+	var first *types.StreamChunk
+	select {
+	case chunk, open := <-chunks:
+		if !open {
+			return nil, fmt.Errorf("%s stream ended before the first chunk", p.GetName())
+		}
+		if chunk.Err != nil {
+			return nil, chunk.Err
+		}
+		first = chunk
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	relayed := make(chan *types.StreamChunk)
+	go func() {
+		defer close(relayed)
+		select {
+		case relayed <- first:
+		case <-ctx.Done():
+			return
+		}
+		for chunk := range chunks {
+			select {
+			case relayed <- chunk:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return relayed, nil
+}
+
+// writeChatStream relays provider chunks to the client as chat.completion.chunk events. It returns
+// the streamed answer assembled as a response once the stream completes, or nil when it did not.
+func (g *Gateway) writeChatStream(ctx context.Context, c *gin.Context, req *types.Request, chunks <-chan *types.StreamChunk, providerName string) *types.Response {
+	c.Header("Content-Type", "text/event-stream; charset=utf-8")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+	created := time.Now().Unix()
+	var usage *types.Usage
+	answer := &types.Response{
+		ID:       req.ID,
+		Model:    req.Model,
+		Provider: providerName,
+		Choices:  []types.Choice{{Message: types.Message{Role: "assistant"}}},
+		Created:  time.Unix(created, 0),
+	}
+
+	newChunk := func() *chatCompletionChunk {
+		return &chatCompletionChunk{
+			ID:       req.ID,
+			Object:   "chat.completion.chunk",
+			Created:  created,
+			Model:    req.Model,
+			Provider: providerName,
+			Choices:  []chunkChoice{},
+		}
+	}
+
+	for chunk := range chunks {
+		out := newChunk()
+		if chunk.Model != "" {
+			out.Model = chunk.Model
+			answer.Model = chunk.Model
+		}
+
+		switch chunk.Type {
+		case types.ChunkRole:
+			out.Choices = append(out.Choices, chunkChoice{Index: chunk.Index, Delta: chunkDelta{Role: chunk.Role}})
+		case types.ChunkContent:
+			out.Choices = append(out.Choices, chunkChoice{Index: chunk.Index, Delta: chunkDelta{Content: chunk.Content}})
+			if chunk.Index == 0 {
+				answer.Choices[0].Message.Content += chunk.Content
+			}
+		case types.ChunkToolCall:
+			out.Choices = append(out.Choices, chunkChoice{Index: chunk.Index, Delta: chunkDelta{
+				ToolCalls: []chunkToolCall{{
+					Index: chunk.ToolCallIndex,
+					ID:    chunk.ToolCall.ID,
+					Type:  chunk.ToolCall.Type,
+					Function: chunkFunction{
+						Name:      chunk.ToolCall.Function.Name,
+						Arguments: chunk.ToolCall.Function.Arguments,
+					},
+				}},
+			}})
+		case types.ChunkFinish:
+			out.Choices = append(out.Choices, chunkChoice{Index: chunk.Index, FinishReason: chunk.FinishReason})
+			if chunk.Index == 0 {
+				answer.Choices[0].FinishReason = chunk.FinishReason
+			}
+		case types.ChunkUsage:
+			usage = chunk.Usage
+			continue
+		case types.ChunkError:
+			g.logger.WithError(chunk.Err).WithField("request_id", req.ID).Error("Provider stream failed")
+			g.writeSSEData(c, gin.H{
+				"error": gin.H{
+					"message": "Streaming failed",
+					"type":    "api_error",
+				},
+			})
+			g.writeSSEDone(c)
+			return nil
+		}
+
+		if !g.writeSSEData(c, out) {
+			return nil
+		}
+	}
+
+	if ctx.Err() != nil {
+		g.logger.WithError(ctx.Err()).WithField("request_id", req.ID).Warn("Stream ended before completion")
+		return nil
+	}
+
+	// Final usage chunk carries no choices, matching OpenAI's stream_options.include_usage behaviour
+	if includeUsage {
+		out := newChunk()
+		if usage == nil {
+			usage = &types.Usage{}
+		}
+		out.Usage = usage
+		g.writeSSEData(c, out)
+	}
+
+	g.writeSSEDone(c)
+	markServed(c, providerName, req.Model, usage)
+
+	fields := logrus.Fields{"request_id": req.ID, "provider": providerName}
+	if usage != nil {
+		fields["tokens"] = usage.TotalTokens
+	}
+	g.logger.WithFields(fields).Info("Streaming chat completion finished")
+
+	if usage != nil {
+		answer.Usage = *usage
+	}
+	return answer
+}
+
+// writeSSEData writes a single "data:" event and flushes it, returning false if the client is gone
+func (g *Gateway) writeSSEData(c *gin.Context, payload interface{}) bool {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		g.logger.WithError(err).Error("Failed to marshal stream chunk")
+		return false
+	}
+
+	if _, err := fmt.Fprintf(c.Writer, "data: %s\n\n", data); err != nil {
+		return false
+	}
+	c.Writer.Flush()
+	return true
+}
+
+// writeSSEDone writes the OpenAI stream terminator
+func (g *Gateway) writeSSEDone(c *gin.Context) {
+	fmt.Fprint(c.Writer, "data: [DONE]\n\n")
+	c.Writer.Flush()
+}
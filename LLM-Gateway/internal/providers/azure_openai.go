@@ -0,0 +1,119 @@
+// Package providers implements Azure OpenAI support for the OpenAI provider adapter
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+)
+
+// AzureOpenAIType is the ProviderConfig.Type served by OpenAIProvider in Azure mode
+const AzureOpenAIType = "azure_openai"
+
+// CustomConfig keys understood by Azure OpenAI providers
+const (
+	AzureAPIVersion  = "api_version" // REST api-version query parameter
+	AzureDeployments = "deployments" // Comma separated model=deployment pairs
+	AzureAPIKeyEnv   = "api_key_env" // Environment variable read when api_key is empty
+)
+
+const (
+	defaultAzureAPIVersion = "2024-10-21"
+	defaultAzureAPIKeyEnv  = "AZURE_OPENAI_API_KEY"
+)
+
+// azureOpenAIConfig holds the Azure-specific request layout of an OpenAIProvider
+type azureOpenAIConfig struct {
+	apiVersion  string
+	apiKeyEnv   string
+	deployments map[string]string // gateway model name -> Azure deployment name
+}
+
+// NewAzureOpenAIProvider creates an OpenAI provider that talks to an Azure OpenAI resource.
+// BaseURL is the resource endpoint, e.g. https://my-resource.openai.azure.com; requests go to
+// /openai/deployments/{deployment}/... with an api-key header and the api-version query parameter.
+func NewAzureOpenAIProvider(baseConfig *types.ProviderConfig, logger *utils.Logger) (*OpenAIProvider, error) {
+	if baseConfig.BaseURL == "" {
+		return nil, fmt.Errorf("azure_openai provider %s: base_url is required", baseConfig.Name)
+	}
+
+	custom := baseConfig.CustomConfig
+	deployments, err := parseKeyValuePairs(custom[AzureDeployments], AzureDeployments)
+	if err != nil {
+		return nil, fmt.Errorf("azure_openai provider %s: %w", baseConfig.Name, err)
+	}
+
+	azure := &azureOpenAIConfig{
+		apiVersion:  custom[AzureAPIVersion],
+		apiKeyEnv:   custom[AzureAPIKeyEnv],
+		deployments: deployments,
+	}
+	if azure.apiVersion == "" {
+		azure.apiVersion = defaultAzureAPIVersion
+	}
+	if azure.apiKeyEnv == "" {
+		azure.apiKeyEnv = defaultAzureAPIKeyEnv
+	}
+
+	prodConfig := types.NewProductionConfig(baseConfig)
+	prodConfig.BaseURL = strings.TrimRight(prodConfig.BaseURL, "/")
+	if prodConfig.Timeout == 0 {
+		prodConfig.Timeout = 60 * time.Second
+	}
+	if prodConfig.RetryCount > 0 {
+		prodConfig.RetryPolicy.MaxRetries = prodConfig.RetryCount
+	}
+
+	return &OpenAIProvider{
+		config: prodConfig,
+		secureConfig: &types.SecureConfig{
+			APIKey:  prodConfig.APIKey,
+			BaseURL: prodConfig.BaseURL,
+		},
+		logger:         logger,
+		retryManager:   retry.NewRetryManager(prodConfig.RetryPolicy, logger),
+		costCalculator: cost.NewCostCalculator(logger),
+		azure:          azure,
+		httpClient: &http.Client{
+			Timeout: prodConfig.Timeout,
+		},
+		rateLimits: &types.RateLimitInfo{
+			RequestsPerMinute: prodConfig.RateLimit,
+			ResetTime:         time.Now().Add(time.Minute),
+		},
+	}, nil
+}
+
+// deployment returns the Azure deployment serving a model, defaulting to the model name
+func (a *azureOpenAIConfig) deployment(model string) string {
+	if deployment, ok := a.deployments[model]; ok {
+		return deployment
+	}
+	return model
+}
+
+// url builds the deployment-scoped URL for an API path such as /chat/completions
+func (a *azureOpenAIConfig) url(baseURL, model, path string) string {
+	return fmt.Sprintf("%s/openai/deployments/%s%s?api-version=%s",
+		baseURL, url.PathEscape(a.deployment(model)), path, url.QueryEscape(a.apiVersion))
+}
+
+// ensureAzureCredentials loads the api-key from the environment when it was not configured
+func (p *OpenAIProvider) ensureAzureCredentials() error {
+	if p.secureConfig.APIKey == "" {
+		apiKey := os.Getenv(p.azure.apiKeyEnv)
+		if apiKey == "" {
+			return fmt.Errorf("Azure OpenAI API key not found in environment variable %s", p.azure.apiKeyEnv)
+		}
+		p.secureConfig.APIKey = apiKey
+	}
+	return nil
+}
@@ -279,6 +279,137 @@ func (p *BaiduProvider) ChatCompletion(ctx context.Context, req *types.ChatCompl
 	return p.convertResponse(&baiduResp, req.Model), nil
 }
 
+// CallStream implements the StreamingProvider interface; Baidu streams SSE chunks terminated by is_end
+func (p *BaiduProvider) CallStream(ctx context.Context, req *types.ChatCompletionRequest) (<-chan *types.StreamChunk, error) {
+	// Ensure we have a valid access token
+	if err := p.ensureAccessToken(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	endpoint, exists := baiduModelEndpoints[req.Model]
+	if !exists {
+		endpoint = "completions" // default endpoint
+	}
+
+	baiduReq := p.convertRequest(req)
+	stream := true
+	baiduReq.Stream = &stream
+
+	reqBody, err := json.Marshal(baiduReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stream request: %w", err)
+	}
+
+	requestURL := fmt.Sprintf("%s/rpc/2.0/ai_custom/v1/wenxinworkshop/chat/%s?access_token=%s",
+		p.config.BaseURL, endpoint, p.accessToken)
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", requestURL, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &ProviderError{
+			Provider:  p.GetName(),
+			Operation: "ChatCompletionStream",
+			Message:   fmt.Sprintf("HTTP request failed: %v", err),
+			Retryable: true,
+		}
+	}
+
+	p.updateRateLimits(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &ProviderError{
+			Provider:   p.GetName(),
+			Operation:  "ChatCompletionStream",
+			StatusCode: resp.StatusCode,
+			Message:    string(respBody),
+			Retryable:  resp.StatusCode >= 500,
+		}
+	}
+
+	sender := newChunkSender(ctx)
+	go func() {
+		defer sender.close()
+		defer resp.Body.Close()
+
+		roleSent := false
+		err := readSSE(resp.Body, func(ev sseEvent) bool {
+			// Baidu reports errors as a bare JSON object with HTTP 200
+			var errorResp baiduErrorResponse
+			if json.Unmarshal([]byte(ev.Data), &errorResp) == nil && errorResp.ErrorCode != 0 {
+				sender.fail(&ProviderError{
+					Provider:   p.GetName(),
+					Operation:  "ChatCompletionStream",
+					StatusCode: resp.StatusCode,
+					Message:    errorResp.ErrorMsg,
+					Retryable:  false,
+				})
+				return false
+			}
+
+			var chunk baiduResponse
+			if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+				p.logger.WithError(err).WithField("data", ev.Data).Warn("Failed to parse Baidu stream chunk")
+				return true
+			}
+
+			if !roleSent {
+				roleSent = true
+				if !sender.send(&types.StreamChunk{Type: types.ChunkRole, ID: chunk.ID, Model: req.Model, Role: "assistant"}) {
+					return false
+				}
+			}
+
+			if chunk.Result != "" {
+				if !sender.send(&types.StreamChunk{Type: types.ChunkContent, ID: chunk.ID, Model: req.Model, Content: chunk.Result}) {
+					return false
+				}
+			}
+
+			if !chunk.IsEnd {
+				return true
+			}
+
+			reason := "stop"
+			if chunk.IsTruncated {
+				reason = "length"
+			}
+			if !sender.send(&types.StreamChunk{Type: types.ChunkFinish, ID: chunk.ID, Model: req.Model, FinishReason: &reason}) {
+				return false
+			}
+			sender.send(&types.StreamChunk{
+				Type:  types.ChunkUsage,
+				ID:    chunk.ID,
+				Model: req.Model,
+				Usage: &types.Usage{
+					PromptTokens:     chunk.Usage.PromptTokens,
+					CompletionTokens: chunk.Usage.CompletionTokens,
+					TotalTokens:      chunk.Usage.TotalTokens,
+				},
+			})
+			return false
+		})
+		if err != nil {
+			sender.fail(&ProviderError{
+				Provider:  p.GetName(),
+				Operation: "ChatCompletionStream",
+				Message:   fmt.Sprintf("error reading stream: %v", err),
+				Retryable: true,
+			})
+		}
+	}()
+
+	return sender.ch, nil
+}
+
 // HealthCheck performs a health check
 func (p *BaiduProvider) HealthCheck(ctx context.Context) (*types.HealthStatus, error) {
 	start := time.Now()
@@ -357,6 +488,9 @@ func (p *BaiduProvider) HealthCheck(ctx context.Context) (*types.HealthStatus, e
 
 // GetModels returns available models
 func (p *BaiduProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
+	// Tools and images are not converted for ERNIE
+	capabilities := &types.ModelCapabilities{Streaming: true}
+
 	models := []*types.Model{
 		{
 			Name:               "ernie-bot-4",
@@ -367,6 +501,7 @@ func (p *BaiduProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
 			CostPerInputToken:  0.00012,
 			CostPerOutputToken: 0.00012,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 		{
 			Name:               "ernie-3.5-8k",
@@ -377,6 +512,7 @@ func (p *BaiduProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
 			CostPerInputToken:  0.000012,
 			CostPerOutputToken: 0.000012,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 		{
 			Name:               "ernie-bot-turbo",
@@ -387,6 +523,7 @@ func (p *BaiduProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
 			CostPerInputToken:  0.000008,
 			CostPerOutputToken: 0.000008,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 		{
 			Name:               "ernie-lite-8k-0922",
@@ -397,6 +534,7 @@ func (p *BaiduProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
 			CostPerInputToken:  0.000008,
 			CostPerOutputToken: 0.000008,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 	}
 
@@ -524,7 +662,8 @@ func (p *BaiduProvider) convertRequest(req *types.ChatCompletionRequest) *baiduR
 		}
 	}
 
-	// Convert messages - Baidu handles system messages differently
+	// Convert messages - Baidu handles system messages differently. ERNIE chat
+	// models are text-only, so image parts are dropped and only the text is sent.
 	var systemMessage string
 	for _, msg := range req.Messages {
 		if msg.Role == "system" {
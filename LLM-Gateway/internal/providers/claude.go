@@ -9,6 +9,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/llm-gateway/gateway/internal/config"
@@ -32,36 +33,62 @@ type ClaudeProvider struct {
 
 // Claude API structures
 type claudeRequest struct {
-	Model         string          `json:"model"`
-	MaxTokens     int             `json:"max_tokens"`
-	Messages      []claudeMessage `json:"messages"`
-	System        string          `json:"system,omitempty"`
-	Temperature   *float64        `json:"temperature,omitempty"`
-	TopP          *float64        `json:"top_p,omitempty"`
-	TopK          *int            `json:"top_k,omitempty"`
-	Stream        *bool           `json:"stream,omitempty"`
-	StopSequences []string        `json:"stop_sequences,omitempty"`
+	Model         string            `json:"model"`
+	MaxTokens     int               `json:"max_tokens"`
+	Messages      []claudeMessage   `json:"messages"`
+	System        string            `json:"system,omitempty"`
+	Temperature   *float64          `json:"temperature,omitempty"`
+	TopP          *float64          `json:"top_p,omitempty"`
+	TopK          *int              `json:"top_k,omitempty"`
+	Stream        *bool             `json:"stream,omitempty"`
+	StopSequences []string          `json:"stop_sequences,omitempty"`
+	Tools         []claudeTool      `json:"tools,omitempty"`
+	ToolChoice    *claudeToolChoice `json:"tool_choice,omitempty"`
 }
 
 type claudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"` // string or []claudeContentBlock
+}
+
+type claudeTool struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	InputSchema interface{} `json:"input_schema"`
+}
+
+type claudeToolChoice struct {
+	Type string `json:"type"` // auto, any or tool
+	Name string `json:"name,omitempty"`
 }
 
 type claudeResponse struct {
-	ID           string       `json:"id"`
-	Type         string       `json:"type"`
-	Role         string       `json:"role"`
-	Content      []claudeText `json:"content"`
-	Model        string       `json:"model"`
-	StopReason   *string      `json:"stop_reason"`
-	StopSequence *string      `json:"stop_sequence"`
-	Usage        claudeUsage  `json:"usage"`
+	ID           string               `json:"id"`
+	Type         string               `json:"type"`
+	Role         string               `json:"role"`
+	Content      []claudeContentBlock `json:"content"`
+	Model        string               `json:"model"`
+	StopReason   *string              `json:"stop_reason"`
+	StopSequence *string              `json:"stop_sequence"`
+	Usage        claudeUsage          `json:"usage"`
 }
 
-type claudeText struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+type claudeContentBlock struct {
+	Type      string             `json:"type"` // text, image, tool_use or tool_result
+	Text      string             `json:"text,omitempty"`
+	Source    *claudeImageSource `json:"source,omitempty"`
+	ID        string             `json:"id,omitempty"`
+	Name      string             `json:"name,omitempty"`
+	Input     json.RawMessage    `json:"input,omitempty"`
+	ToolUseID string             `json:"tool_use_id,omitempty"`
+	Content   string             `json:"content,omitempty"`
+}
+
+type claudeImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 type claudeUsage struct {
@@ -69,6 +96,24 @@ type claudeUsage struct {
 	OutputTokens int `json:"output_tokens"`
 }
 
+// Claude streaming event structures
+type claudeStreamEvent struct {
+	Type         string              `json:"type"`
+	Index        int                 `json:"index"`
+	Message      *claudeResponse     `json:"message,omitempty"`
+	ContentBlock *claudeContentBlock `json:"content_block,omitempty"`
+	Delta        *claudeStreamDelta  `json:"delta,omitempty"`
+	Usage        *claudeUsage        `json:"usage,omitempty"`
+	Error        *claudeError        `json:"error,omitempty"`
+}
+
+type claudeStreamDelta struct {
+	Type        string  `json:"type"`
+	Text        string  `json:"text,omitempty"`
+	PartialJSON string  `json:"partial_json,omitempty"`
+	StopReason  *string `json:"stop_reason,omitempty"`
+}
+
 type claudeErrorResponse struct {
 	Type  string      `json:"type"`
 	Error claudeError `json:"error"`
@@ -226,6 +271,174 @@ func (p *ClaudeProvider) ChatCompletion(ctx context.Context, req *types.ChatComp
 	return p.convertResponse(&claudeResp), nil
 }
 
+// CallStream implements the StreamingProvider interface using the Messages streaming API
+func (p *ClaudeProvider) CallStream(ctx context.Context, req *types.ChatCompletionRequest) (<-chan *types.StreamChunk, error) {
+	claudeReq := p.convertRequest(req)
+	stream := true
+	claudeReq.Stream = &stream
+
+	reqBody, err := json.Marshal(claudeReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stream request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/messages", bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stream request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set("x-api-key", p.config.APIKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &ProviderError{
+			Provider:  p.GetName(),
+			Operation: "ChatCompletionStream",
+			Message:   fmt.Sprintf("HTTP request failed: %v", err),
+			Retryable: true,
+		}
+	}
+
+	p.updateRateLimits(resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		message := string(respBody)
+		var errorResp claudeErrorResponse
+		if err := json.Unmarshal(respBody, &errorResp); err == nil && errorResp.Error.Message != "" {
+			message = errorResp.Error.Message
+		}
+		return nil, &ProviderError{
+			Provider:   p.GetName(),
+			Operation:  "ChatCompletionStream",
+			StatusCode: resp.StatusCode,
+			Message:    message,
+			Retryable:  resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests,
+		}
+	}
+
+	sender := newChunkSender(ctx)
+	go func() {
+		defer sender.close()
+		defer resp.Body.Close()
+
+		var id, model string
+		var usage types.Usage
+
+		// Claude numbers content blocks across text and tool_use; OpenAI numbers tool calls only
+		toolIndexes := make(map[int]int)
+
+		err := readSSE(resp.Body, func(ev sseEvent) bool {
+			var event claudeStreamEvent
+			if err := json.Unmarshal([]byte(ev.Data), &event); err != nil {
+				p.logger.WithError(err).WithField("data", ev.Data).Warn("Failed to parse Claude stream event")
+				return true
+			}
+
+			switch event.Type {
+			case "message_start":
+				if event.Message == nil {
+					return true
+				}
+				id, model = event.Message.ID, event.Message.Model
+				usage.PromptTokens = event.Message.Usage.InputTokens
+				return sender.send(&types.StreamChunk{Type: types.ChunkRole, ID: id, Model: model, Role: "assistant"})
+
+			case "content_block_start":
+				if event.ContentBlock == nil || event.ContentBlock.Type != "tool_use" {
+					return true
+				}
+				toolIndex := len(toolIndexes)
+				toolIndexes[event.Index] = toolIndex
+				return sender.send(&types.StreamChunk{
+					Type:          types.ChunkToolCall,
+					ID:            id,
+					Model:         model,
+					ToolCallIndex: toolIndex,
+					ToolCall: &types.ToolCall{
+						ID:       event.ContentBlock.ID,
+						Type:     "function",
+						Function: types.FunctionCall{Name: event.ContentBlock.Name},
+					},
+				})
+
+			case "content_block_delta":
+				if event.Delta == nil {
+					return true
+				}
+				switch event.Delta.Type {
+				case "text_delta":
+					if event.Delta.Text == "" {
+						return true
+					}
+					return sender.send(&types.StreamChunk{Type: types.ChunkContent, ID: id, Model: model, Content: event.Delta.Text})
+				case "input_json_delta":
+					toolIndex, ok := toolIndexes[event.Index]
+					if !ok || event.Delta.PartialJSON == "" {
+						return true
+					}
+					return sender.send(&types.StreamChunk{
+						Type:          types.ChunkToolCall,
+						ID:            id,
+						Model:         model,
+						ToolCallIndex: toolIndex,
+						ToolCall:      &types.ToolCall{Function: types.FunctionCall{Arguments: event.Delta.PartialJSON}},
+					})
+				}
+				return true
+
+			case "message_delta":
+				if event.Usage != nil {
+					usage.CompletionTokens = event.Usage.OutputTokens
+				}
+				if event.Delta != nil && event.Delta.StopReason != nil {
+					reason := claudeFinishReason(*event.Delta.StopReason)
+					if !sender.send(&types.StreamChunk{Type: types.ChunkFinish, ID: id, Model: model, FinishReason: &reason}) {
+						return false
+					}
+				}
+				return true
+
+			case "message_stop":
+				usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+				sender.send(&types.StreamChunk{Type: types.ChunkUsage, ID: id, Model: model, Usage: &usage})
+				return false
+
+			case "error":
+				message := "stream error"
+				if event.Error != nil {
+					message = event.Error.Message
+				}
+				sender.fail(&ProviderError{
+					Provider:  p.GetName(),
+					Operation: "ChatCompletionStream",
+					Message:   message,
+					Retryable: event.Error != nil && event.Error.Type == "overloaded_error",
+				})
+				return false
+			}
+
+			// ping and content_block_stop carry nothing we forward
+			return true
+		})
+		if err != nil {
+			sender.fail(&ProviderError{
+				Provider:  p.GetName(),
+				Operation: "ChatCompletionStream",
+				Message:   fmt.Sprintf("error reading stream: %v", err),
+				Retryable: true,
+			})
+		}
+	}()
+
+	return sender.ch, nil
+}
+
 // HealthCheck performs a health check
 func (p *ClaudeProvider) HealthCheck(ctx context.Context) (*types.HealthStatus, error) {
 	start := time.Now()
@@ -294,6 +507,9 @@ func (p *ClaudeProvider) HealthCheck(ctx context.Context) (*types.HealthStatus,
 
 // GetModels returns available models
 func (p *ClaudeProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
+	// Claude 3 models accept tools and images; Anthropic has no JSON mode
+	capabilities := &types.ModelCapabilities{Tools: true, Vision: true, Streaming: true}
+
 	models := []*types.Model{
 		{
 			Name:               "claude-3-opus-20240229",
@@ -304,6 +520,7 @@ func (p *ClaudeProvider) GetModels(ctx context.Context) ([]*types.Model, error)
 			CostPerInputToken:  0.000015,
 			CostPerOutputToken: 0.000075,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 		{
 			Name:               "claude-3-sonnet-20240229",
@@ -314,6 +531,7 @@ func (p *ClaudeProvider) GetModels(ctx context.Context) ([]*types.Model, error)
 			CostPerInputToken:  0.000003,
 			CostPerOutputToken: 0.000015,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 		{
 			Name:               "claude-3-haiku-20240307",
@@ -324,6 +542,7 @@ func (p *ClaudeProvider) GetModels(ctx context.Context) ([]*types.Model, error)
 			CostPerInputToken:  0.00000025,
 			CostPerOutputToken: 0.00000125,
 			IsEnabled:          true,
+			Capabilities:       capabilities,
 		},
 	}
 
@@ -387,30 +606,69 @@ func (p *ClaudeProvider) convertRequest(req *types.ChatCompletionRequest) *claud
 	}
 
 	// Convert stop sequences
-	if req.Stop != nil {
-		switch stop := req.Stop.(type) {
-		case string:
-			claudeReq.StopSequences = []string{stop}
-		case []string:
-			claudeReq.StopSequences = stop
-		case []interface{}:
-			stopSeqs := make([]string, 0, len(stop))
-			for _, s := range stop {
-				if str, ok := s.(string); ok {
-					stopSeqs = append(stopSeqs, str)
-				}
+	claudeReq.StopSequences = toStopSequences(req.Stop)
+
+	// Convert tools unless the caller disabled tool use
+	if len(req.Tools) > 0 && req.ToolChoice != "none" {
+		claudeReq.Tools = make([]claudeTool, len(req.Tools))
+		for i, tool := range req.Tools {
+			schema := tool.Function.Parameters
+			if schema == nil {
+				schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+			}
+			claudeReq.Tools[i] = claudeTool{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				InputSchema: schema,
 			}
-			claudeReq.StopSequences = stopSeqs
 		}
+		claudeReq.ToolChoice = convertClaudeToolChoice(req.ToolChoice)
 	}
 
 	// Convert messages - Claude has different role handling
 	var systemMessage string
 	for _, msg := range req.Messages {
-		if msg.Role == "system" {
+		switch {
+		case msg.Role == "system":
 			// Claude handles system messages separately
 			systemMessage = msg.Content
-		} else {
+		case msg.Role == "tool":
+			// Tool results are sent back as tool_result blocks in a user turn
+			result := claudeContentBlock{Type: "tool_result", ToolUseID: msg.ToolCallID, Content: msg.Content}
+			if last := len(claudeReq.Messages) - 1; last >= 0 && claudeReq.Messages[last].Role == "user" {
+				if blocks, ok := claudeReq.Messages[last].Content.([]claudeContentBlock); ok && blocks[0].Type == "tool_result" {
+					// Parallel tool results must share a single user message
+					claudeReq.Messages[last].Content = append(blocks, result)
+					continue
+				}
+			}
+			claudeReq.Messages = append(claudeReq.Messages, claudeMessage{
+				Role:    "user",
+				Content: []claudeContentBlock{result},
+			})
+		case len(msg.ToolCalls) > 0:
+			blocks := make([]claudeContentBlock, 0, len(msg.ToolCalls)+1)
+			if msg.Content != "" {
+				blocks = append(blocks, claudeContentBlock{Type: "text", Text: msg.Content})
+			}
+			for _, toolCall := range msg.ToolCalls {
+				blocks = append(blocks, claudeContentBlock{
+					Type:  "tool_use",
+					ID:    toolCall.ID,
+					Name:  toolCall.Function.Name,
+					Input: claudeToolInput(toolCall.Function.Arguments),
+				})
+			}
+			claudeReq.Messages = append(claudeReq.Messages, claudeMessage{
+				Role:    msg.Role,
+				Content: blocks,
+			})
+		case msg.HasImages():
+			claudeReq.Messages = append(claudeReq.Messages, claudeMessage{
+				Role:    msg.Role,
+				Content: toClaudeContentBlocks(msg.Parts),
+			})
+		default:
 			claudeReq.Messages = append(claudeReq.Messages, claudeMessage{
 				Role:    msg.Role,
 				Content: msg.Content,
@@ -425,14 +683,63 @@ func (p *ClaudeProvider) convertRequest(req *types.ChatCompletionRequest) *claud
 	return claudeReq
 }
 
+// toStopSequences converts an OpenAI stop value (string or array of strings) to a list
+func toStopSequences(stop interface{}) []string {
+	switch stop := stop.(type) {
+	case string:
+		return []string{stop}
+	case []string:
+		return stop
+	case []interface{}:
+		stopSeqs := make([]string, 0, len(stop))
+		for _, s := range stop {
+			if str, ok := s.(string); ok {
+				stopSeqs = append(stopSeqs, str)
+			}
+		}
+		return stopSeqs
+	}
+	return nil
+}
+
+// toClaudeContentBlocks converts multimodal content parts to Claude text and image blocks.
+// Data URIs become base64 image sources; http(s) URLs are passed as url sources.
+func toClaudeContentBlocks(parts []types.ContentPart) []claudeContentBlock {
+	blocks := make([]claudeContentBlock, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case types.ContentPartText:
+			blocks = append(blocks, claudeContentBlock{Type: "text", Text: part.Text})
+		case types.ContentPartImageURL:
+			source := &claudeImageSource{Type: "url", URL: part.ImageURL.URL}
+			if mediaType, data, err := part.ImageURL.DataURI(); err == nil {
+				source = &claudeImageSource{Type: "base64", MediaType: mediaType, Data: data}
+			}
+			blocks = append(blocks, claudeContentBlock{Type: "image", Source: source})
+		}
+	}
+	return blocks
+}
+
 // convertResponse converts Claude response to standard format
 func (p *ClaudeProvider) convertResponse(resp *claudeResponse) *types.ChatCompletionResponse {
-	// Extract text content from Claude's content array
+	// Extract text and tool calls from Claude's content array
 	var content string
+	var toolCalls []types.ToolCall
 	for _, c := range resp.Content {
-		if c.Type == "text" {
-			content = c.Text
-			break
+		switch c.Type {
+		case "text":
+			content += c.Text
+		case "tool_use":
+			arguments := string(c.Input)
+			if arguments == "" {
+				arguments = "{}"
+			}
+			toolCalls = append(toolCalls, types.ToolCall{
+				ID:       c.ID,
+				Type:     "function",
+				Function: types.FunctionCall{Name: c.Name, Arguments: arguments},
+			})
 		}
 	}
 
@@ -440,13 +747,19 @@ func (p *ClaudeProvider) convertResponse(resp *claudeResponse) *types.ChatComple
 		{
 			Index: 0,
 			Message: types.Message{
-				Role:    "assistant",
-				Content: content,
+				Role:      "assistant",
+				Content:   content,
+				ToolCalls: toolCalls,
 			},
-			FinishReason: resp.StopReason,
+			FinishReason: nil,
 		},
 	}
 
+	if resp.StopReason != nil {
+		reason := claudeFinishReason(*resp.StopReason)
+		choices[0].FinishReason = &reason
+	}
+
 	return &types.ChatCompletionResponse{
 		ID:      resp.ID,
 		Object:  "chat.completion",
@@ -463,6 +776,48 @@ func (p *ClaudeProvider) convertResponse(resp *claudeResponse) *types.ChatComple
 	}
 }
 
+// convertClaudeToolChoice maps an OpenAI tool_choice onto Claude's tool_choice object
+func convertClaudeToolChoice(toolChoice interface{}) *claudeToolChoice {
+	switch choice := toolChoice.(type) {
+	case string:
+		switch choice {
+		case "auto":
+			return &claudeToolChoice{Type: "auto"}
+		case "required":
+			return &claudeToolChoice{Type: "any"}
+		}
+	case map[string]interface{}:
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return &claudeToolChoice{Type: "tool", Name: name}
+			}
+		}
+	}
+	return nil
+}
+
+// claudeToolInput converts OpenAI JSON-string arguments into a Claude tool_use input object
+func claudeToolInput(arguments string) json.RawMessage {
+	if strings.TrimSpace(arguments) == "" || !json.Valid([]byte(arguments)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(arguments)
+}
+
+// claudeFinishReason maps a Claude stop_reason onto the OpenAI finish_reason vocabulary
+func claudeFinishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "max_tokens":
+		return "length"
+	case "tool_use":
+		return "tool_calls"
+	default:
+		return stopReason
+	}
+}
+
 // updateRateLimits updates rate limit information from response headers
 func (p *ClaudeProvider) updateRateLimits(headers http.Header) {
 	if remaining := headers.Get("anthropic-ratelimit-requests-remaining"); remaining != "" {
@@ -0,0 +1,62 @@
+// Package providers implements shared embedding helpers for OpenAI-compatible APIs
+package providers
+
+import (
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// OpenAI-compatible embeddings wire format, also used by Zhipu
+type openAIEmbeddingRequest struct {
+	Model          string   `json:"model"`
+	Input          []string `json:"input"`
+	EncodingFormat string   `json:"encoding_format,omitempty"`
+	Dimensions     *int     `json:"dimensions,omitempty"`
+	User           string   `json:"user,omitempty"`
+}
+
+type openAIEmbeddingResponse struct {
+	Object string            `json:"object"`
+	Data   []openAIEmbedding `json:"data"`
+	Model  string            `json:"model"`
+	Usage  openAIUsage       `json:"usage"`
+}
+
+type openAIEmbedding struct {
+	Object    string    `json:"object"`
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// toOpenAIEmbeddingRequest converts a unified embeddings request, always asking for float vectors
+func toOpenAIEmbeddingRequest(req *types.EmbeddingRequest, inputs []string) *openAIEmbeddingRequest {
+	return &openAIEmbeddingRequest{
+		Model:          req.Model,
+		Input:          inputs,
+		EncodingFormat: "float",
+		Dimensions:     req.Dimensions,
+		User:           req.User,
+	}
+}
+
+// fromOpenAIEmbeddingResponse converts an OpenAI-compatible embeddings response to the unified format
+func fromOpenAIEmbeddingResponse(resp *openAIEmbeddingResponse, provider string) *types.EmbeddingResponse {
+	data := make([]types.Embedding, len(resp.Data))
+	for i, embedding := range resp.Data {
+		data[i] = types.Embedding{
+			Object:    "embedding",
+			Index:     embedding.Index,
+			Embedding: embedding.Embedding,
+		}
+	}
+
+	return &types.EmbeddingResponse{
+		Object:   "list",
+		Data:     data,
+		Model:    resp.Model,
+		Provider: provider,
+		Usage: types.EmbeddingUsage{
+			PromptTokens: resp.Usage.PromptTokens,
+			TotalTokens:  resp.Usage.TotalTokens,
+		},
+	}
+}
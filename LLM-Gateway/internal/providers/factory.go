@@ -0,0 +1,154 @@
+// Package providers implements the config-driven provider factory
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+)
+
+// ProviderConstructor builds a provider from its configuration
+type ProviderConstructor func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error)
+
+// ProviderFactory creates providers keyed by ProviderConfig.Type
+type ProviderFactory struct {
+	constructors map[string]ProviderConstructor
+	mu           sync.RWMutex
+	logger       *utils.Logger
+}
+
+// NewProviderFactory creates a factory with constructors for all built-in provider types
+func NewProviderFactory(logger *utils.Logger) *ProviderFactory {
+	f := &ProviderFactory{
+		constructors: make(map[string]ProviderConstructor),
+		logger:       logger,
+	}
+
+	f.Register("openai", func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewOpenAIProvider(config, logger), nil
+	})
+	f.Register("anthropic", func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewClaudeProvider(config, logger), nil
+	})
+	f.Register("baidu", func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewBaiduProvider(config, logger), nil
+	})
+	f.Register("zhipu", func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewZhipuProvider(config, logger), nil
+	})
+	f.Register("gemini", func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewGeminiProvider(config, logger)
+	})
+	f.Register(AzureOpenAIType, func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewAzureOpenAIProvider(config, logger)
+	})
+	f.Register(OpenAICompatibleType, func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+		return NewOpenAICompatibleProvider(config, logger)
+	})
+
+	return f
+}
+
+// Register adds or replaces the constructor for a provider type
+func (f *ProviderFactory) Register(providerType string, constructor ProviderConstructor) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.constructors[providerType] = constructor
+}
+
+// Types returns the registered provider types in sorted order
+func (f *ProviderFactory) Types() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	providerTypes := make([]string, 0, len(f.constructors))
+	for providerType := range f.constructors {
+		providerTypes = append(providerTypes, providerType)
+	}
+	sort.Strings(providerTypes)
+	return providerTypes
+}
+
+// Create builds a provider using the constructor registered for config.Type
+func (f *ProviderFactory) Create(config *types.ProviderConfig) (types.Provider, error) {
+	f.mu.RLock()
+	constructor, exists := f.constructors[config.Type]
+	f.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("unsupported provider type %q for provider %s", config.Type, config.Name)
+	}
+
+	return constructor(config, f.logger)
+}
+
+// BuildProviders creates every enabled provider in configs and registers it, in instance name order.
+// The map key names the instance when ProviderConfig.Name is empty and doubles as the type when
+// ProviderConfig.Type is empty, so `providers: {openai: {...}}` needs no explicit type.
+// Failed entries are skipped and reported together; the providers that were registered are returned.
+func (f *ProviderFactory) BuildProviders(registry types.ProviderRegistry, configs map[string]*types.ProviderConfig) ([]types.Provider, error) {
+	keys := make([]string, 0, len(configs))
+	for key := range configs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var built []types.Provider
+	var errs []error
+	for _, key := range keys {
+		config := configs[key]
+		if config == nil || !config.Enabled {
+			continue
+		}
+
+		instanceConfig := *config
+		if instanceConfig.Name == "" {
+			instanceConfig.Name = key
+		}
+		if instanceConfig.Type == "" {
+			instanceConfig.Type = key
+		}
+		instanceConfig.APIKey = expandEnvDefault(instanceConfig.APIKey)
+
+		provider, err := f.Create(&instanceConfig)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := registry.RegisterProvider(provider); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		f.logger.WithField("provider", instanceConfig.Name).
+			WithField("type", instanceConfig.Type).
+			Info("Provider built from config")
+		built = append(built, provider)
+	}
+
+	return built, errors.Join(errs...)
+}
+
+// expandEnvDefault expands ${VAR} and ${VAR:-default} references in a config value
+func expandEnvDefault(value string) string {
+	if !strings.Contains(value, "${") {
+		return value
+	}
+	return os.Expand(value, func(name string) string {
+		name, fallback, hasDefault := strings.Cut(name, ":-")
+		if v, ok := os.LookupEnv(name); ok && v != "" {
+			return v
+		}
+		if hasDefault {
+			return fallback
+		}
+		return ""
+	})
+}
@@ -0,0 +1,908 @@
+// Package providers implements the Google Gemini provider adapter
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+)
+
+// CustomConfig keys understood by GeminiProvider
+const (
+	GeminiSafetySettings  = "safety_settings"  // Comma separated HARM_CATEGORY_X=THRESHOLD pairs
+	GeminiSafetyThreshold = "safety_threshold" // Threshold applied to every harm category not listed in safety_settings
+)
+
+// geminiHarmCategories are the categories a blanket safety_threshold applies to
+var geminiHarmCategories = []string{
+	"HARM_CATEGORY_HARASSMENT",
+	"HARM_CATEGORY_HATE_SPEECH",
+	"HARM_CATEGORY_SEXUALLY_EXPLICIT",
+	"HARM_CATEGORY_DANGEROUS_CONTENT",
+}
+
+// GeminiProvider implements the Provider interface for Google Gemini (Generative Language API)
+type GeminiProvider struct {
+	config         *types.ProductionConfig
+	secureConfig   *types.SecureConfig
+	retryManager   *retry.RetryManager
+	costCalculator *cost.CostCalculator
+	logger         *utils.Logger
+	httpClient     *http.Client
+	rateLimits     *types.RateLimitInfo
+	safetySettings []geminiSafetySetting
+}
+
+// Gemini API structures
+type geminiRequest struct {
+	Contents          []geminiContent         `json:"contents"`
+	SystemInstruction *geminiContent          `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig `json:"generationConfig,omitempty"`
+	SafetySettings    []geminiSafetySetting   `json:"safetySettings,omitempty"`
+	Tools             []geminiTool            `json:"tools,omitempty"`
+	ToolConfig        *geminiToolConfig       `json:"toolConfig,omitempty"`
+}
+
+type geminiContent struct {
+	Role  string       `json:"role,omitempty"` // user or model; omitted for systemInstruction
+	Parts []geminiPart `json:"parts"`
+}
+
+type geminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	Thought          bool                    `json:"thought,omitempty"`
+	InlineData       *geminiBlob             `json:"inlineData,omitempty"`
+	FileData         *geminiFileData         `json:"fileData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+type geminiBlob struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFileData struct {
+	MimeType string `json:"mimeType,omitempty"`
+	FileURI  string `json:"fileUri"`
+}
+
+type geminiFunctionCall struct {
+	ID   string          `json:"id,omitempty"`
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	ID       string          `json:"id,omitempty"`
+	Name     string          `json:"name"`
+	Response json.RawMessage `json:"response"`
+}
+
+type geminiGenerationConfig struct {
+	Temperature      *float64 `json:"temperature,omitempty"`
+	TopP             *float64 `json:"topP,omitempty"`
+	MaxOutputTokens  *int     `json:"maxOutputTokens,omitempty"`
+	CandidateCount   *int     `json:"candidateCount,omitempty"`
+	StopSequences    []string `json:"stopSequences,omitempty"`
+	PresencePenalty  *float64 `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64 `json:"frequencyPenalty,omitempty"`
+}
+
+type geminiSafetySetting struct {
+	Category  string `json:"category"`
+	Threshold string `json:"threshold"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+type geminiFunctionDeclaration struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+type geminiToolConfig struct {
+	FunctionCallingConfig geminiFunctionCallingConfig `json:"functionCallingConfig"`
+}
+
+type geminiFunctionCallingConfig struct {
+	Mode                 string   `json:"mode"` // AUTO, ANY or NONE
+	AllowedFunctionNames []string `json:"allowedFunctionNames,omitempty"`
+}
+
+type geminiResponse struct {
+	Candidates     []geminiCandidate     `json:"candidates"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
+	UsageMetadata  *geminiUsageMetadata  `json:"usageMetadata,omitempty"`
+	ModelVersion   string                `json:"modelVersion,omitempty"`
+	ResponseID     string                `json:"responseId,omitempty"`
+}
+
+type geminiCandidate struct {
+	Index        int           `json:"index"`
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+}
+
+type geminiPromptFeedback struct {
+	BlockReason string `json:"blockReason,omitempty"`
+}
+
+type geminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+type geminiErrorResponse struct {
+	Error geminiError `json:"error"`
+}
+
+type geminiError struct {
+	Code    int                 `json:"code"`
+	Message string              `json:"message"`
+	Status  string              `json:"status"`
+	Details []geminiErrorDetail `json:"details,omitempty"`
+}
+
+type geminiErrorDetail struct {
+	Type       string `json:"@type"`
+	RetryDelay string `json:"retryDelay,omitempty"` // google.rpc.RetryInfo, e.g. "30s"
+}
+
+// NewGeminiProvider creates a new Gemini provider
+func NewGeminiProvider(baseConfig *types.ProviderConfig, logger *utils.Logger) (*GeminiProvider, error) {
+	prodConfig := types.NewProductionConfig(baseConfig)
+
+	if prodConfig.BaseURL == "" {
+		prodConfig.BaseURL = "https://generativelanguage.googleapis.com/v1beta"
+	}
+
+	if prodConfig.Timeout == 0 {
+		prodConfig.Timeout = 60 * time.Second
+	}
+
+	safetySettings, err := parseGeminiSafetySettings(baseConfig.CustomConfig)
+	if err != nil {
+		return nil, fmt.Errorf("gemini provider %s: %w", baseConfig.Name, err)
+	}
+
+	retryPolicy := &types.RetryPolicy{
+		MaxRetries:    3,
+		BaseDelay:     time.Second,
+		BackoffFactor: 2.0,
+		MaxDelay:      30 * time.Second,
+	}
+
+	return &GeminiProvider{
+		config: prodConfig,
+		secureConfig: &types.SecureConfig{
+			APIKey:  prodConfig.APIKey,
+			BaseURL: prodConfig.BaseURL,
+		},
+		retryManager:   retry.NewRetryManager(retryPolicy, logger),
+		costCalculator: cost.NewCostCalculator(logger),
+		logger:         logger,
+		httpClient: &http.Client{
+			Timeout: prodConfig.Timeout,
+		},
+		rateLimits: &types.RateLimitInfo{
+			RequestsPerMinute: prodConfig.RateLimit,
+			ResetTime:         time.Now().Add(time.Minute),
+		},
+		safetySettings: safetySettings,
+	}, nil
+}
+
+// parseGeminiSafetySettings builds safety settings from CustomConfig
+func parseGeminiSafetySettings(custom map[string]string) ([]geminiSafetySetting, error) {
+	explicit, err := parseKeyValuePairs(custom[GeminiSafetySettings], GeminiSafetySettings)
+	if err != nil {
+		return nil, err
+	}
+
+	var settings []geminiSafetySetting
+	if threshold := custom[GeminiSafetyThreshold]; threshold != "" {
+		for _, category := range geminiHarmCategories {
+			if _, overridden := explicit[category]; !overridden {
+				settings = append(settings, geminiSafetySetting{Category: category, Threshold: threshold})
+			}
+		}
+	}
+	categories := make([]string, 0, len(explicit))
+	for category := range explicit {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		settings = append(settings, geminiSafetySetting{Category: category, Threshold: explicit[category]})
+	}
+
+	return settings, nil
+}
+
+// GetName returns the provider name
+func (p *GeminiProvider) GetName() string {
+	return p.config.Name
+}
+
+// GetType returns the provider type
+func (p *GeminiProvider) GetType() string {
+	return "gemini"
+}
+
+// Call implements the Provider interface by wrapping ChatCompletion
+func (p *GeminiProvider) Call(ctx context.Context, req *types.ChatCompletionRequest) (*types.ChatCompletionResponse, error) {
+	return p.ChatCompletion(ctx, req)
+}
+
+// GetConfig returns the provider configuration
+func (p *GeminiProvider) GetConfig() *types.ProviderConfig {
+	return p.config.ProviderConfig
+}
+
+// ValidateConfig validates the provider configuration
+func (p *GeminiProvider) ValidateConfig(config *types.ProviderConfig) error {
+	if config.APIKey == "" {
+		return fmt.Errorf("Gemini API key is required")
+	}
+
+	if config.Type != "gemini" {
+		return fmt.Errorf("invalid provider type: expected 'gemini', got '%s'", config.Type)
+	}
+
+	return nil
+}
+
+// ChatCompletion sends a chat completion request to Gemini generateContent
+func (p *GeminiProvider) ChatCompletion(ctx context.Context, req *types.ChatCompletionRequest) (*types.ChatCompletionResponse, error) {
+	if err := p.ensureValidCredentials(); err != nil {
+		return nil, fmt.Errorf("credential validation failed: %w", err)
+	}
+
+	reqBody, err := json.Marshal(p.convertRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Execute with retry
+	var resp *types.ChatCompletionResponse
+	retryOp := func(ctx context.Context, attempt int) error {
+		httpReq, err := p.newHTTPRequest(ctx, geminiModelPath(req.Model)+":generateContent", reqBody)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+
+		p.logger.WithField("model", req.Model).Info("Sending request to Gemini")
+
+		httpResp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, p.GetName(), "network_error")
+		}
+		defer httpResp.Body.Close()
+
+		respBody, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		if httpResp.StatusCode != http.StatusOK {
+			return classifyGeminiError(p.GetName(), httpResp.StatusCode, respBody, "ChatCompletion")
+		}
+
+		var geminiResp geminiResponse
+		if err := json.Unmarshal(respBody, &geminiResp); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+
+		resp = p.convertResponse(&geminiResp, req.Model)
+		return nil
+	}
+
+	if err := p.retryManager.ExecuteWithRetry(ctx, retryOp); err != nil {
+		return nil, err
+	}
+
+	// Calculate actual cost (for monitoring/logging purposes)
+	if actualCost, err := p.costCalculator.CalculateActualCost(req, resp); err == nil {
+		p.logger.WithField("actual_cost", actualCost.TotalCost).Info("Cost calculated for Gemini request")
+	}
+
+	return resp, nil
+}
+
+// CallStream implements the StreamingProvider interface using streamGenerateContent with SSE
+func (p *GeminiProvider) CallStream(ctx context.Context, req *types.ChatCompletionRequest) (<-chan *types.StreamChunk, error) {
+	if err := p.ensureValidCredentials(); err != nil {
+		return nil, fmt.Errorf("credential validation failed: %w", err)
+	}
+
+	reqBody, err := json.Marshal(p.convertRequest(req))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal stream request: %w", err)
+	}
+
+	p.logger.WithField("model", req.Model).Info("Starting streaming request to Gemini")
+
+	// Retry only while opening the stream; once chunks flow the request can't be replayed
+	var resp *http.Response
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		httpReq, err := p.newHTTPRequest(ctx, geminiModelPath(req.Model)+":streamGenerateContent?alt=sse", reqBody)
+		if err != nil {
+			return fmt.Errorf("failed to create stream request: %w", err)
+		}
+		httpReq.Header.Set("Accept", "text/event-stream")
+
+		attemptResp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, p.GetName(), "network_error")
+		}
+
+		if attemptResp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(attemptResp.Body)
+			attemptResp.Body.Close()
+			return classifyGeminiError(p.GetName(), attemptResp.StatusCode, body, "ChatCompletionStream")
+		}
+
+		resp = attemptResp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sender := newChunkSender(ctx)
+	go func() {
+		defer sender.close()
+		defer resp.Body.Close()
+
+		id := fmt.Sprintf("gemini-%d", time.Now().UnixNano())
+		model := req.Model
+		var usage *geminiUsageMetadata
+		roleSent := make(map[int]bool)
+		toolCalls := make(map[int]int) // candidate index -> tool calls emitted so far
+
+		err := readSSE(resp.Body, func(ev sseEvent) bool {
+			var chunk geminiResponse
+			if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+				var errorResp geminiErrorResponse
+				if json.Unmarshal([]byte(ev.Data), &errorResp) == nil && errorResp.Error.Status != "" {
+					sender.fail(classifyGeminiError(p.GetName(), errorResp.Error.Code, []byte(ev.Data), "ChatCompletionStream"))
+					return false
+				}
+				p.logger.WithError(err).WithField("data", ev.Data).Warn("Failed to parse Gemini stream chunk")
+				return true
+			}
+
+			if chunk.ResponseID != "" {
+				id = chunk.ResponseID
+			}
+			if chunk.ModelVersion != "" {
+				model = chunk.ModelVersion
+			}
+			if chunk.UsageMetadata != nil {
+				usage = chunk.UsageMetadata
+			}
+
+			// A blocked prompt produces no candidates, only promptFeedback
+			if len(chunk.Candidates) == 0 && chunk.PromptFeedback != nil && chunk.PromptFeedback.BlockReason != "" {
+				reason := "content_filter"
+				return sender.send(&types.StreamChunk{Type: types.ChunkFinish, ID: id, Model: model, FinishReason: &reason})
+			}
+
+			for _, candidate := range chunk.Candidates {
+				if !roleSent[candidate.Index] {
+					roleSent[candidate.Index] = true
+					if !sender.send(&types.StreamChunk{Type: types.ChunkRole, ID: id, Model: model, Index: candidate.Index, Role: "assistant"}) {
+						return false
+					}
+				}
+
+				for _, part := range candidate.Content.Parts {
+					switch {
+					case part.FunctionCall != nil:
+						// Gemini sends each function call complete in a single part
+						toolIndex := toolCalls[candidate.Index]
+						toolCalls[candidate.Index]++
+						toolCall := fromGeminiFunctionCall(part.FunctionCall, toolIndex)
+						if !sender.send(&types.StreamChunk{
+							Type:          types.ChunkToolCall,
+							ID:            id,
+							Model:         model,
+							Index:         candidate.Index,
+							ToolCallIndex: toolIndex,
+							ToolCall:      &toolCall,
+						}) {
+							return false
+						}
+					case part.Text != "" && !part.Thought:
+						if !sender.send(&types.StreamChunk{Type: types.ChunkContent, ID: id, Model: model, Index: candidate.Index, Content: part.Text}) {
+							return false
+						}
+					}
+				}
+
+				if candidate.FinishReason != "" {
+					reason := geminiFinishReason(candidate.FinishReason, toolCalls[candidate.Index] > 0)
+					if !sender.send(&types.StreamChunk{Type: types.ChunkFinish, ID: id, Model: model, Index: candidate.Index, FinishReason: &reason}) {
+						return false
+					}
+				}
+			}
+
+			return true
+		})
+		if err != nil {
+			sender.fail(fmt.Errorf("error reading stream: %w", err))
+			return
+		}
+
+		// Every chunk repeats cumulative usage; report the final value once
+		if usage != nil {
+			sender.send(&types.StreamChunk{Type: types.ChunkUsage, ID: id, Model: model, Usage: fromGeminiUsage(usage)})
+		}
+	}()
+
+	return sender.ch, nil
+}
+
+// newHTTPRequest creates a POST request against a model method, e.g. "models/gemini-1.5-flash:generateContent"
+func (p *GeminiProvider) newHTTPRequest(ctx context.Context, method string, reqBody []byte) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+"/"+method, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", p.secureConfig.APIKey)
+	httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
+
+	return httpReq, nil
+}
+
+// geminiModelPath returns the resource name of a model, accepting names with or without the "models/" prefix
+func geminiModelPath(model string) string {
+	if strings.HasPrefix(model, "models/") {
+		return model
+	}
+	return "models/" + model
+}
+
+// classifyGeminiError classifies a google.rpc error payload, honouring RetryInfo delays
+func classifyGeminiError(providerName string, statusCode int, respBody []byte, operation string) *retry.ProviderRetryError {
+	var errorResp geminiErrorResponse
+	if err := json.Unmarshal(respBody, &errorResp); err != nil || errorResp.Error.Status == "" {
+		retryError := retry.ClassifyHTTPError(statusCode, string(respBody))
+		retryError.Provider = providerName
+		retryError.Operation = operation
+		return retryError
+	}
+
+	retryError := retry.ClassifyGeminiError(statusCode, errorResp.Error.Status, errorResp.Error.Message)
+	retryError.Provider = providerName
+	retryError.Operation = operation
+
+	for _, detail := range errorResp.Error.Details {
+		if !strings.HasSuffix(detail.Type, "google.rpc.RetryInfo") {
+			continue
+		}
+		if delay, err := time.ParseDuration(detail.RetryDelay); err == nil && delay > 0 {
+			retryError.RetryAfter = int(delay.Round(time.Second).Seconds())
+		}
+	}
+
+	return retryError
+}
+
+// ensureValidCredentials ensures API key is valid and loaded
+func (p *GeminiProvider) ensureValidCredentials() error {
+	if p.secureConfig.APIKey == "" {
+		// Try to load from environment
+		apiKey := os.Getenv("GEMINI_API_KEY")
+		if apiKey == "" {
+			return fmt.Errorf("Gemini API key not found in environment variable GEMINI_API_KEY")
+		}
+		p.secureConfig.APIKey = apiKey
+	}
+	return nil
+}
+
+// HealthCheck lists models, which validates the key without spending tokens
+func (p *GeminiProvider) HealthCheck(ctx context.Context) (*types.HealthStatus, error) {
+	start := time.Now()
+	status := &types.HealthStatus{
+		LastChecked: time.Now(),
+		Endpoint:    p.config.BaseURL,
+	}
+
+	if err := p.ensureValidCredentials(); err != nil {
+		status.ResponseTime = time.Since(start)
+		status.ErrorMessage = err.Error()
+		return status, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models?pageSize=1", nil)
+	if err != nil {
+		status.ResponseTime = time.Since(start)
+		status.ErrorMessage = fmt.Sprintf("Failed to create health check request: %v", err)
+		return status, nil
+	}
+	req.Header.Set("x-goog-api-key", p.secureConfig.APIKey)
+
+	resp, err := p.httpClient.Do(req)
+	status.ResponseTime = time.Since(start)
+	if err != nil {
+		status.ErrorMessage = fmt.Sprintf("Health check request failed: %v", err)
+		return status, nil
+	}
+	defer resp.Body.Close()
+
+	status.IsHealthy = resp.StatusCode == http.StatusOK
+	if !status.IsHealthy {
+		status.ErrorMessage = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+
+	return status, nil
+}
+
+// GetModels returns available models
+func (p *GeminiProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
+	// response_format is not mapped to responseMimeType, so JSON mode is not declared
+	capabilities := &types.ModelCapabilities{Tools: true, Vision: true, Streaming: true}
+
+	models := []*types.Model{
+		{
+			Name:               "gemini-2.5-pro",
+			DisplayName:        "Gemini 2.5 Pro",
+			Description:        "Most capable thinking model for complex reasoning and coding",
+			ContextLength:      1048576,
+			SupportedModes:     `["chat"]`,
+			CostPerInputToken:  0.00000125,
+			CostPerOutputToken: 0.00001,
+			IsEnabled:          true,
+			Capabilities:       capabilities,
+		},
+		{
+			Name:               "gemini-2.5-flash",
+			DisplayName:        "Gemini 2.5 Flash",
+			Description:        "Fast thinking model with the best price-performance",
+			ContextLength:      1048576,
+			SupportedModes:     `["chat"]`,
+			CostPerInputToken:  0.0000003,
+			CostPerOutputToken: 0.0000025,
+			IsEnabled:          true,
+			Capabilities:       capabilities,
+		},
+		{
+			Name:               "gemini-2.0-flash",
+			DisplayName:        "Gemini 2.0 Flash",
+			Description:        "Low latency multimodal model",
+			ContextLength:      1048576,
+			SupportedModes:     `["chat"]`,
+			CostPerInputToken:  0.0000001,
+			CostPerOutputToken: 0.0000004,
+			IsEnabled:          true,
+			Capabilities:       capabilities,
+		},
+		{
+			Name:               "gemini-1.5-pro",
+			DisplayName:        "Gemini 1.5 Pro",
+			Description:        "Long context multimodal model",
+			ContextLength:      2097152,
+			SupportedModes:     `["chat"]`,
+			CostPerInputToken:  0.00000125,
+			CostPerOutputToken: 0.000005,
+			IsEnabled:          true,
+			Capabilities:       capabilities,
+		},
+		{
+			Name:               "gemini-1.5-flash",
+			DisplayName:        "Gemini 1.5 Flash",
+			Description:        "Fast and versatile multimodal model",
+			ContextLength:      1048576,
+			SupportedModes:     `["chat"]`,
+			CostPerInputToken:  0.000000075,
+			CostPerOutputToken: 0.0000003,
+			IsEnabled:          true,
+			Capabilities:       capabilities,
+		},
+	}
+
+	return models, nil
+}
+
+// EstimateCost estimates the cost for a request using PricingManager data
+func (p *GeminiProvider) EstimateCost(req *types.ChatCompletionRequest) (*types.CostEstimate, error) {
+	estimate, err := p.costCalculator.EstimateRequestCost(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CostEstimate{
+		InputTokens:  estimate.InputTokens,
+		OutputTokens: estimate.OutputTokens,
+		TotalTokens:  estimate.TotalTokens,
+		TotalCost:    estimate.EstimatedCost,
+		Currency:     estimate.Currency,
+	}, nil
+}
+
+// GetRateLimit returns current rate limit information
+func (p *GeminiProvider) GetRateLimit() *types.RateLimitInfo {
+	return p.rateLimits
+}
+
+// convertRequest converts standard request to Gemini format
+func (p *GeminiProvider) convertRequest(req *types.ChatCompletionRequest) *geminiRequest {
+	geminiReq := &geminiRequest{
+		Contents:       make([]geminiContent, 0, len(req.Messages)),
+		SafetySettings: p.safetySettings,
+		GenerationConfig: &geminiGenerationConfig{
+			Temperature:      req.Temperature,
+			TopP:             req.TopP,
+			MaxOutputTokens:  req.MaxTokens,
+			CandidateCount:   req.N,
+			StopSequences:    toStopSequences(req.Stop),
+			PresencePenalty:  req.PresencePenalty,
+			FrequencyPenalty: req.FrequencyPenalty,
+		},
+	}
+
+	if len(req.Tools) > 0 {
+		declarations := make([]geminiFunctionDeclaration, len(req.Tools))
+		for i, tool := range req.Tools {
+			declarations[i] = geminiFunctionDeclaration{
+				Name:        tool.Function.Name,
+				Description: tool.Function.Description,
+				Parameters:  tool.Function.Parameters,
+			}
+		}
+		geminiReq.Tools = []geminiTool{{FunctionDeclarations: declarations}}
+		geminiReq.ToolConfig = convertGeminiToolChoice(req.ToolChoice)
+	}
+
+	// Tool results only carry tool_call_id, but Gemini matches function responses by name
+	toolNames := make(map[string]string)
+
+	var systemParts []geminiPart
+	for _, msg := range req.Messages {
+		var content geminiContent
+		switch {
+		case msg.Role == "system":
+			// Gemini takes system prompts as a separate systemInstruction
+			systemParts = append(systemParts, geminiPart{Text: msg.Content})
+			continue
+		case msg.Role == "tool":
+			name := msg.Name
+			if name == "" {
+				name = toolNames[msg.ToolCallID]
+			}
+			content = geminiContent{Role: "user", Parts: []geminiPart{{
+				FunctionResponse: &geminiFunctionResponse{Name: name, Response: geminiToolResponse(msg.Content)},
+			}}}
+		case msg.Role == "assistant":
+			content = geminiContent{Role: "model"}
+			if msg.Content != "" {
+				content.Parts = append(content.Parts, geminiPart{Text: msg.Content})
+			}
+			for _, toolCall := range msg.ToolCalls {
+				toolNames[toolCall.ID] = toolCall.Function.Name
+				args := json.RawMessage(toolCall.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				content.Parts = append(content.Parts, geminiPart{
+					FunctionCall: &geminiFunctionCall{Name: toolCall.Function.Name, Args: args},
+				})
+			}
+		case msg.HasImages():
+			content = geminiContent{Role: "user", Parts: toGeminiParts(msg.Parts)}
+		default:
+			content = geminiContent{Role: "user", Parts: []geminiPart{{Text: msg.Content}}}
+		}
+
+		if len(content.Parts) == 0 {
+			continue
+		}
+
+		// Consecutive turns of the same role, such as parallel tool results, are merged into one content
+		if last := len(geminiReq.Contents) - 1; last >= 0 && geminiReq.Contents[last].Role == content.Role {
+			geminiReq.Contents[last].Parts = append(geminiReq.Contents[last].Parts, content.Parts...)
+			continue
+		}
+		geminiReq.Contents = append(geminiReq.Contents, content)
+	}
+
+	if len(systemParts) > 0 {
+		geminiReq.SystemInstruction = &geminiContent{Parts: systemParts}
+	}
+
+	return geminiReq
+}
+
+// toGeminiParts converts multimodal content parts. Data URIs become inlineData;
+// other URLs are passed as fileData, which Gemini accepts for File API and GCS URIs.
+func toGeminiParts(parts []types.ContentPart) []geminiPart {
+	geminiParts := make([]geminiPart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case types.ContentPartText:
+			geminiParts = append(geminiParts, geminiPart{Text: part.Text})
+		case types.ContentPartImageURL:
+			if mediaType, data, err := part.ImageURL.DataURI(); err == nil {
+				geminiParts = append(geminiParts, geminiPart{InlineData: &geminiBlob{MimeType: mediaType, Data: data}})
+				continue
+			}
+			geminiParts = append(geminiParts, geminiPart{FileData: &geminiFileData{
+				MimeType: mime.TypeByExtension(path.Ext(part.ImageURL.URL)),
+				FileURI:  part.ImageURL.URL,
+			}})
+		}
+	}
+	return geminiParts
+}
+
+// geminiToolResponse wraps a tool result as the JSON object functionResponse.response requires
+func geminiToolResponse(content string) json.RawMessage {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		return json.RawMessage(trimmed)
+	}
+	wrapped, _ := json.Marshal(map[string]string{"content": content})
+	return wrapped
+}
+
+// convertGeminiToolChoice maps an OpenAI tool_choice onto Gemini's function calling config
+func convertGeminiToolChoice(toolChoice interface{}) *geminiToolConfig {
+	switch choice := toolChoice.(type) {
+	case string:
+		switch choice {
+		case "none":
+			return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "NONE"}}
+		case "required":
+			return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "ANY"}}
+		case "auto":
+			return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{Mode: "AUTO"}}
+		}
+	case map[string]interface{}:
+		if function, ok := choice["function"].(map[string]interface{}); ok {
+			if name, ok := function["name"].(string); ok && name != "" {
+				return &geminiToolConfig{FunctionCallingConfig: geminiFunctionCallingConfig{
+					Mode:                 "ANY",
+					AllowedFunctionNames: []string{name},
+				}}
+			}
+		}
+	}
+	return nil
+}
+
+// convertResponse converts Gemini response to standard format
+func (p *GeminiProvider) convertResponse(resp *geminiResponse, requestModel string) *types.ChatCompletionResponse {
+	model := resp.ModelVersion
+	if model == "" {
+		model = requestModel
+	}
+
+	id := resp.ResponseID
+	if id == "" {
+		id = fmt.Sprintf("gemini-%d", time.Now().UnixNano())
+	}
+
+	choices := make([]types.Choice, 0, len(resp.Candidates))
+	for _, candidate := range resp.Candidates {
+		var content string
+		var toolCalls []types.ToolCall
+		for _, part := range candidate.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				toolCalls = append(toolCalls, fromGeminiFunctionCall(part.FunctionCall, len(toolCalls)))
+			case !part.Thought:
+				content += part.Text
+			}
+		}
+
+		reason := geminiFinishReason(candidate.FinishReason, len(toolCalls) > 0)
+		choices = append(choices, types.Choice{
+			Index: candidate.Index,
+			Message: types.Message{
+				Role:      "assistant",
+				Content:   content,
+				ToolCalls: toolCalls,
+			},
+			FinishReason: &reason,
+		})
+	}
+
+	// A blocked prompt returns promptFeedback instead of candidates
+	if len(choices) == 0 && resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != "" {
+		reason := "content_filter"
+		choices = append(choices, types.Choice{
+			Message:      types.Message{Role: "assistant"},
+			FinishReason: &reason,
+		})
+	}
+
+	response := &types.ChatCompletionResponse{
+		ID:       id,
+		Object:   "chat.completion",
+		Created:  fmt.Sprintf("%d", time.Now().Unix()),
+		Model:    model,
+		Choices:  choices,
+		Provider: p.GetName(),
+	}
+	if resp.UsageMetadata != nil {
+		response.Usage = *fromGeminiUsage(resp.UsageMetadata)
+	}
+
+	return response
+}
+
+// fromGeminiFunctionCall converts a functionCall part; Gemini rarely sets call IDs, so one is derived from the position
+func fromGeminiFunctionCall(call *geminiFunctionCall, index int) types.ToolCall {
+	id := call.ID
+	if id == "" {
+		id = fmt.Sprintf("call_%s_%d", call.Name, index)
+	}
+
+	arguments := string(call.Args)
+	if arguments == "" || arguments == "null" {
+		arguments = "{}"
+	}
+
+	return types.ToolCall{
+		ID:       id,
+		Type:     "function",
+		Function: types.FunctionCall{Name: call.Name, Arguments: arguments},
+	}
+}
+
+// fromGeminiUsage converts usage metadata; thinking tokens are billed as output
+func fromGeminiUsage(usage *geminiUsageMetadata) *types.Usage {
+	completionTokens := usage.CandidatesTokenCount + usage.ThoughtsTokenCount
+	totalTokens := usage.TotalTokenCount
+	if totalTokens == 0 {
+		totalTokens = usage.PromptTokenCount + completionTokens
+	}
+
+	return &types.Usage{
+		PromptTokens:     usage.PromptTokenCount,
+		CompletionTokens: completionTokens,
+		TotalTokens:      totalTokens,
+	}
+}
+
+// geminiFinishReason maps a Gemini finishReason onto the OpenAI finish_reason vocabulary
+func geminiFinishReason(finishReason string, hasToolCalls bool) string {
+	switch finishReason {
+	case "STOP", "":
+		if hasToolCalls {
+			return "tool_calls"
+		}
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII", "IMAGE_SAFETY":
+		return "content_filter"
+	default:
+		return strings.ToLower(finishReason)
+	}
+}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/llm-gateway/gateway/internal/config"
@@ -28,37 +31,55 @@ type OpenAIProvider struct {
 	retryManager   retry.RetryManagerInterface
 	costCalculator *cost.CostCalculator
 	configManager  config.ConfigurationManager
+	azure          *azureOpenAIConfig // Set when serving Azure OpenAI deployments
 }
 
 // OpenAI API structures
 type openAIRequest struct {
-	Model            string           `json:"model"`
-	Messages         []openAIMessage  `json:"messages"`
-	MaxTokens        *int             `json:"max_tokens,omitempty"`
-	Temperature      *float64         `json:"temperature,omitempty"`
-	TopP             *float64         `json:"top_p,omitempty"`
-	N                *int             `json:"n,omitempty"`
-	Stream           *bool            `json:"stream,omitempty"`
-	Stop             interface{}      `json:"stop,omitempty"`
-	PresencePenalty  *float64         `json:"presence_penalty,omitempty"`
-	FrequencyPenalty *float64         `json:"frequency_penalty,omitempty"`
-	LogitBias        map[string]int   `json:"logit_bias,omitempty"`
-	User             *string          `json:"user,omitempty"`
-	Tools            []openAITool     `json:"tools,omitempty"`
-	ToolChoice       interface{}      `json:"tool_choice,omitempty"`
-	Functions        []openAIFunction `json:"functions,omitempty"`
-	FunctionCall     interface{}      `json:"function_call,omitempty"`
+	Model            string                `json:"model"`
+	Messages         []openAIMessage       `json:"messages"`
+	MaxTokens        *int                  `json:"max_tokens,omitempty"`
+	Temperature      *float64              `json:"temperature,omitempty"`
+	TopP             *float64              `json:"top_p,omitempty"`
+	N                *int                  `json:"n,omitempty"`
+	Stream           *bool                 `json:"stream,omitempty"`
+	Stop             interface{}           `json:"stop,omitempty"`
+	PresencePenalty  *float64              `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64              `json:"frequency_penalty,omitempty"`
+	LogitBias        map[string]int        `json:"logit_bias,omitempty"`
+	User             *string               `json:"user,omitempty"`
+	Tools            []openAITool          `json:"tools,omitempty"`
+	ToolChoice       interface{}           `json:"tool_choice,omitempty"`
+	Functions        []openAIFunction      `json:"functions,omitempty"`
+	FunctionCall     interface{}           `json:"function_call,omitempty"`
+	StreamOptions    *openAIStreamOpt      `json:"stream_options,omitempty"`
+	ResponseFormat   *types.ResponseFormat `json:"response_format,omitempty"`
+}
+
+type openAIStreamOpt struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openAIMessage struct {
 	Role         string              `json:"role"`
-	Content      string              `json:"content"`
+	Content      interface{}         `json:"content"` // string or []openAIContentPart
 	Name         *string             `json:"name,omitempty"`
 	ToolCalls    []openAIToolCall    `json:"tool_calls,omitempty"`
 	ToolCallID   *string             `json:"tool_call_id,omitempty"`
 	FunctionCall *openAIFunctionCall `json:"function_call,omitempty"`
 }
 
+type openAIContentPart struct {
+	Type     string          `json:"type"` // text or image_url
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+type openAIImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"`
+}
+
 type openAIResponse struct {
 	ID                string         `json:"id"`
 	Object            string         `json:"object"`
@@ -104,15 +125,55 @@ type openAIFunctionCall struct {
 	Arguments string `json:"arguments"`
 }
 
+type openAIStreamChunk struct {
+	ID      string               `json:"id"`
+	Object  string               `json:"object"`
+	Created int64                `json:"created"`
+	Model   string               `json:"model"`
+	Choices []openAIStreamChoice `json:"choices"`
+	Usage   *openAIUsage         `json:"usage,omitempty"`
+}
+
+type openAIStreamChoice struct {
+	Index        int               `json:"index"`
+	Delta        openAIStreamDelta `json:"delta"`
+	FinishReason *string           `json:"finish_reason"`
+}
+
+type openAIStreamDelta struct {
+	Role      string                 `json:"role,omitempty"`
+	Content   string                 `json:"content,omitempty"`
+	ToolCalls []openAIStreamToolCall `json:"tool_calls,omitempty"`
+}
+
+type openAIStreamToolCall struct {
+	Index    int                `json:"index"`
+	ID       string             `json:"id,omitempty"`
+	Type     string             `json:"type,omitempty"`
+	Function openAIFunctionCall `json:"function"`
+}
+
 type openAIErrorResponse struct {
 	Error openAIError `json:"error"`
 }
 
 type openAIError struct {
-	Message string  `json:"message"`
-	Type    string  `json:"type"`
-	Param   *string `json:"param"`
-	Code    *string `json:"code"`
+	Message    string            `json:"message"`
+	Type       string            `json:"type"`
+	Param      *string           `json:"param"`
+	Code       *string           `json:"code"`
+	InnerError *openAIInnerError `json:"innererror,omitempty"` // Azure OpenAI only
+}
+
+// openAIInnerError carries Azure content management details
+type openAIInnerError struct {
+	Code                string                            `json:"code"` // ResponsibleAIPolicyViolation
+	ContentFilterResult map[string]openAIContentFilterHit `json:"content_filter_result,omitempty"`
+}
+
+type openAIContentFilterHit struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity,omitempty"`
 }
 
 // Model pricing (USD per 1K tokens)
@@ -158,6 +219,7 @@ func NewOpenAIProvider(baseConfig *types.ProviderConfig, logger *utils.Logger) *
 	if err != nil {
 		logger.WithError(err).Warn("Failed to load secure config, will attempt to load on first use")
 		secureConfig = &types.SecureConfig{
+			APIKey:  prodConfig.APIKey,
 			BaseURL: prodConfig.BaseURL,
 		}
 	}
@@ -243,6 +305,9 @@ func (p *OpenAIProvider) GetName() string {
 
 // GetType returns the provider type
 func (p *OpenAIProvider) GetType() string {
+	if p.azure != nil {
+		return AzureOpenAIType
+	}
 	return "openai"
 }
 
@@ -262,8 +327,8 @@ func (p *OpenAIProvider) ValidateConfig(config *types.ProviderConfig) error {
 		return fmt.Errorf("OpenAI API key is required")
 	}
 
-	if config.Type != "openai" {
-		return fmt.Errorf("invalid provider type: expected 'openai', got '%s'", config.Type)
+	if config.Type != p.GetType() {
+		return fmt.Errorf("invalid provider type: expected '%s', got '%s'", p.GetType(), config.Type)
 	}
 
 	return nil
@@ -325,10 +390,233 @@ func (p *OpenAIProvider) ChatCompletion(ctx context.Context, req *types.ChatComp
 	return response, nil
 }
 
+// Embed implements the EmbeddingProvider interface using the OpenAI embeddings API
+func (p *OpenAIProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	// Ensure we have valid API credentials
+	if err := p.ensureValidCredentials(ctx); err != nil {
+		return nil, fmt.Errorf("credential validation failed: %w", err)
+	}
+
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorClient, err.Error(), false)
+	}
+
+	reqBody, err := json.Marshal(toOpenAIEmbeddingRequest(req, inputs))
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorClient,
+			fmt.Sprintf("failed to marshal request: %v", err), false)
+	}
+
+	// Execute request with retry logic
+	var response *types.EmbeddingResponse
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		httpReq, err := p.newHTTPRequest(ctx, req.Model, "/embeddings", reqBody)
+		if err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorClient,
+				fmt.Sprintf("failed to create request: %v", err), false)
+		}
+
+		p.logger.WithFields(map[string]interface{}{
+			"model":   req.Model,
+			"inputs":  len(inputs),
+			"attempt": attempt,
+		}).Info("Sending OpenAI embeddings request")
+
+		resp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, p.GetName(), "Embeddings")
+		}
+		defer resp.Body.Close()
+
+		p.updateRateLimits(resp.Header)
+
+		respBody, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorNetwork,
+				fmt.Sprintf("failed to read response: %v", err), true)
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			return p.classifyErrorResponse(resp, respBody, "Embeddings")
+		}
+
+		var embeddingResp openAIEmbeddingResponse
+		if err := json.Unmarshal(respBody, &embeddingResp); err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorServer,
+				fmt.Sprintf("failed to unmarshal response: %v", err), true)
+		}
+
+		response = fromOpenAIEmbeddingResponse(&embeddingResp, p.GetName())
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Calculate actual cost if cost tracking is enabled
+	if p.config.CostTracking {
+		actualCost, err := p.costCalculator.CalculateEmbeddingCost(req, response)
+		if err != nil {
+			p.logger.WithError(err).Warn("Failed to calculate embedding cost")
+		} else {
+			p.logger.WithFields(map[string]interface{}{
+				"actual_cost":  actualCost.TotalCost,
+				"input_tokens": actualCost.InputTokens,
+			}).Info("OpenAI embeddings cost calculated")
+		}
+	}
+
+	return response, nil
+}
+
+// CallStream implements the StreamingProvider interface using OpenAI server-sent events
+func (p *OpenAIProvider) CallStream(ctx context.Context, req *types.ChatCompletionRequest) (<-chan *types.StreamChunk, error) {
+	// Ensure we have valid API credentials
+	if err := p.ensureValidCredentials(ctx); err != nil {
+		return nil, fmt.Errorf("credential validation failed: %w", err)
+	}
+
+	openAIReq := toOpenAIRequest(req)
+	stream := true
+	openAIReq.Stream = &stream
+	openAIReq.StreamOptions = &openAIStreamOpt{IncludeUsage: true}
+
+	reqBody, err := json.Marshal(openAIReq)
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "ChatCompletionStream", types.ErrorClient,
+			fmt.Sprintf("failed to marshal request: %v", err), false)
+	}
+
+	// Retry only while opening the stream; once chunks flow the request can't be replayed
+	var resp *http.Response
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		httpReq, err := p.newHTTPRequest(ctx, req.Model, "/chat/completions", reqBody)
+		if err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "ChatCompletionStream", types.ErrorClient,
+				fmt.Sprintf("failed to create request: %v", err), false)
+		}
+		httpReq.Header.Set("Accept", "text/event-stream")
+
+		p.logger.WithFields(map[string]interface{}{
+			"model":   req.Model,
+			"attempt": attempt,
+		}).Info("Opening OpenAI stream")
+
+		attemptResp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, p.GetName(), "ChatCompletionStream")
+		}
+
+		p.updateRateLimits(attemptResp.Header)
+
+		if attemptResp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(attemptResp.Body)
+			attemptResp.Body.Close()
+			return p.classifyErrorResponse(attemptResp, respBody, "ChatCompletionStream")
+		}
+
+		resp = attemptResp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sender := newChunkSender(ctx)
+	go readOpenAIStream(sender, resp.Body, p.GetName(), p.logger)
+
+	return sender.ch, nil
+}
+
+// readOpenAIStream parses an OpenAI-format SSE body into typed chunks, closing the sender and body when done
+func readOpenAIStream(sender *chunkSender, body io.ReadCloser, providerName string, logger *utils.Logger) {
+	defer sender.close()
+	defer body.Close()
+
+	err := readSSE(body, func(ev sseEvent) bool {
+		if ev.Data == "[DONE]" {
+			return false
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+			var errorResp openAIErrorResponse
+			if json.Unmarshal([]byte(ev.Data), &errorResp) == nil && errorResp.Error.Message != "" {
+				sender.fail(retry.ClassifyError(fmt.Errorf("API error: %s", errorResp.Error.Message), providerName, "ChatCompletionStream"))
+				return false
+			}
+			logger.WithError(err).WithField("data", ev.Data).Warn("Failed to parse OpenAI stream chunk")
+			return true
+		}
+
+		return emitOpenAIStreamChunk(sender, &chunk)
+	})
+	if err != nil {
+		sender.fail(retry.ClassifyError(err, providerName, "ChatCompletionStream"))
+	}
+}
+
+// emitOpenAIStreamChunk translates an OpenAI stream chunk into typed gateway chunks
+func emitOpenAIStreamChunk(sender *chunkSender, chunk *openAIStreamChunk) bool {
+	for _, choice := range chunk.Choices {
+		if choice.Delta.Role != "" {
+			if !sender.send(&types.StreamChunk{Type: types.ChunkRole, ID: chunk.ID, Model: chunk.Model, Index: choice.Index, Role: choice.Delta.Role}) {
+				return false
+			}
+		}
+		if choice.Delta.Content != "" {
+			if !sender.send(&types.StreamChunk{Type: types.ChunkContent, ID: chunk.ID, Model: chunk.Model, Index: choice.Index, Content: choice.Delta.Content}) {
+				return false
+			}
+		}
+		for _, toolCall := range choice.Delta.ToolCalls {
+			if !sender.send(&types.StreamChunk{
+				Type:          types.ChunkToolCall,
+				ID:            chunk.ID,
+				Model:         chunk.Model,
+				Index:         choice.Index,
+				ToolCallIndex: toolCall.Index,
+				ToolCall: &types.ToolCall{
+					ID:   toolCall.ID,
+					Type: toolCall.Type,
+					Function: types.FunctionCall{
+						Name:      toolCall.Function.Name,
+						Arguments: toolCall.Function.Arguments,
+					},
+				},
+			}) {
+				return false
+			}
+		}
+		if choice.FinishReason != nil && *choice.FinishReason != "" {
+			if !sender.send(&types.StreamChunk{Type: types.ChunkFinish, ID: chunk.ID, Model: chunk.Model, Index: choice.Index, FinishReason: choice.FinishReason}) {
+				return false
+			}
+		}
+	}
+
+	if chunk.Usage != nil {
+		return sender.send(&types.StreamChunk{
+			Type:  types.ChunkUsage,
+			ID:    chunk.ID,
+			Model: chunk.Model,
+			Usage: &types.Usage{
+				PromptTokens:     chunk.Usage.PromptTokens,
+				CompletionTokens: chunk.Usage.CompletionTokens,
+				TotalTokens:      chunk.Usage.TotalTokens,
+			},
+		})
+	}
+
+	return true
+}
+
 // executeAPIRequest executes a single API request attempt
 func (p *OpenAIProvider) executeAPIRequest(ctx context.Context, req *types.ChatCompletionRequest, attempt int) (*types.ChatCompletionResponse, error) {
 	// Convert request to OpenAI format
-	openAIReq := p.convertRequest(req)
+	openAIReq := toOpenAIRequest(req)
 
 	// Serialize request
 	reqBody, err := json.Marshal(openAIReq)
@@ -338,27 +626,12 @@ func (p *OpenAIProvider) executeAPIRequest(ctx context.Context, req *types.ChatC
 	}
 
 	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.secureConfig.BaseURL+"/chat/completions", bytes.NewBuffer(reqBody))
+	httpReq, err := p.newHTTPRequest(ctx, req.Model, "/chat/completions", reqBody)
 	if err != nil {
 		return nil, retry.NewProviderRetryError(p.GetName(), "ChatCompletion", types.ErrorClient,
 			fmt.Sprintf("failed to create request: %v", err), false)
 	}
 
-	// Set headers with real API key
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.secureConfig.APIKey)
-	httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
-
-	// Add organization header if available
-	if p.secureConfig.OrganizationID != "" {
-		httpReq.Header.Set("OpenAI-Organization", p.secureConfig.OrganizationID)
-	}
-
-	// Add project header if available
-	if p.secureConfig.ProjectID != "" {
-		httpReq.Header.Set("OpenAI-Project", p.secureConfig.ProjectID)
-	}
-
 	// Log request attempt
 	p.logger.WithFields(map[string]interface{}{
 		"model":   req.Model,
@@ -397,44 +670,7 @@ func (p *OpenAIProvider) executeAPIRequest(ctx context.Context, req *types.ChatC
 
 	// Handle errors
 	if resp.StatusCode != http.StatusOK {
-		errorMsg := string(respBody)
-
-		// Try to parse OpenAI error format
-		var errorResp openAIErrorResponse
-		if err := json.Unmarshal(respBody, &errorResp); err == nil && errorResp.Error.Message != "" {
-			errorMsg = errorResp.Error.Message
-		}
-
-		// Create detailed retry error
-		retryError := retry.ClassifyError(fmt.Errorf("API error: %s", errorMsg), p.GetName(), "ChatCompletion")
-		retryError.StatusCode = resp.StatusCode
-
-		// Special handling for specific OpenAI error types
-		switch resp.StatusCode {
-		case http.StatusUnauthorized:
-			retryError.Category = types.ErrorAuth
-			retryError.Retryable = false
-		case http.StatusTooManyRequests:
-			retryError.Category = types.ErrorRateLimit
-			retryError.Retryable = true
-			// Try to extract retry-after header
-			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-				if seconds, err := strconv.Atoi(retryAfter); err == nil {
-					retryError.RetryAfter = seconds
-				}
-			}
-		case http.StatusPaymentRequired:
-			retryError.Category = types.ErrorQuota
-			retryError.Retryable = false
-		}
-
-		p.logger.WithFields(map[string]interface{}{
-			"status_code": resp.StatusCode,
-			"error":       errorMsg,
-			"retryable":   retryError.Retryable,
-		}).Error("OpenAI API returned error")
-
-		return nil, retryError
+		return nil, p.classifyErrorResponse(resp, respBody, "ChatCompletion")
 	}
 
 	// Parse successful response
@@ -447,18 +683,151 @@ func (p *OpenAIProvider) executeAPIRequest(ctx context.Context, req *types.ChatC
 	// Log successful response
 	if len(openAIResp.Choices) > 0 {
 		p.logger.WithFields(map[string]interface{}{
-			"response_length": len(openAIResp.Choices[0].Message.Content),
+			"response_length": len(openAIContentText(openAIResp.Choices[0].Message.Content)),
 			"finish_reason":   openAIResp.Choices[0].FinishReason,
 			"tokens_used":     openAIResp.Usage.TotalTokens,
 		}).Info("OpenAI API request completed successfully")
 	}
 
 	// Convert response to standard format
-	return p.convertResponse(&openAIResp), nil
+	return fromOpenAIResponse(&openAIResp, p.GetName()), nil
+}
+
+// newHTTPRequest creates a POST request for an API path with authentication headers set
+func (p *OpenAIProvider) newHTTPRequest(ctx context.Context, model, path string, reqBody []byte) (*http.Request, error) {
+	if p.azure != nil {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", p.azure.url(p.secureConfig.BaseURL, model, path), bytes.NewBuffer(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("api-key", p.secureConfig.APIKey)
+		httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
+		return httpReq, nil
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.secureConfig.BaseURL+path, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	// Set headers with real API key
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.secureConfig.APIKey)
+	httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
+
+	// Add organization header if available
+	if p.secureConfig.OrganizationID != "" {
+		httpReq.Header.Set("OpenAI-Organization", p.secureConfig.OrganizationID)
+	}
+
+	// Add project header if available
+	if p.secureConfig.ProjectID != "" {
+		httpReq.Header.Set("OpenAI-Project", p.secureConfig.ProjectID)
+	}
+
+	return httpReq, nil
+}
+
+// classifyErrorResponse converts a non-200 OpenAI response into a retry error
+func (p *OpenAIProvider) classifyErrorResponse(resp *http.Response, respBody []byte, operation string) *retry.ProviderRetryError {
+	retryError := classifyOpenAIError(p.GetName(), resp, respBody, operation)
+
+	p.logger.WithFields(map[string]interface{}{
+		"status_code": resp.StatusCode,
+		"error":       retryError.Message,
+		"retryable":   retryError.Retryable,
+	}).Error("OpenAI API returned error")
+
+	return retryError
+}
+
+// classifyOpenAIError converts a non-200 response in the OpenAI error format into a retry error
+func classifyOpenAIError(providerName string, resp *http.Response, respBody []byte, operation string) *retry.ProviderRetryError {
+	errorMsg := string(respBody)
+
+	// Try to parse OpenAI error format
+	var errorResp openAIErrorResponse
+	if err := json.Unmarshal(respBody, &errorResp); err == nil && errorResp.Error.Message != "" {
+		errorMsg = errorResp.Error.Message
+		if categories := errorResp.Error.filteredCategories(); len(categories) > 0 {
+			errorMsg = fmt.Sprintf("%s (filtered: %s)", errorMsg, strings.Join(categories, ", "))
+		}
+	}
+
+	// Create detailed retry error
+	retryError := retry.ClassifyError(fmt.Errorf("API error: %s", errorMsg), providerName, operation)
+	retryError.StatusCode = resp.StatusCode
+
+	// Special handling for specific OpenAI error types
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		retryError.Category = types.ErrorAuth
+		retryError.Retryable = false
+	case http.StatusTooManyRequests:
+		retryError.Category = types.ErrorRateLimit
+		retryError.Retryable = true
+		// Try to extract retry-after header; Azure also sends a millisecond precision variant
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil {
+				retryError.RetryAfter = seconds
+			}
+		}
+		if retryAfterMs := resp.Header.Get("retry-after-ms"); retryAfterMs != "" {
+			if ms, err := strconv.Atoi(retryAfterMs); err == nil {
+				retryError.RetryAfter = (ms + 999) / 1000
+			}
+		}
+	case http.StatusPaymentRequired:
+		retryError.Category = types.ErrorQuota
+		retryError.Retryable = false
+	default:
+		// The parsed message hides the status; upstream 5xx is worth retrying elsewhere
+		if resp.StatusCode >= 500 {
+			retryError.Category = types.ErrorServer
+			retryError.Retryable = true
+		}
+	}
+
+	// Azure OpenAI rejects prompts flagged by its content management policy; retrying won't help
+	if errorResp.Error.isContentFilter() {
+		retryError.Category = types.ErrorContentFilter
+		retryError.Retryable = false
+	}
+
+	return retryError
+}
+
+// isContentFilter reports whether the error is an Azure content management rejection
+func (e *openAIError) isContentFilter() bool {
+	if e.Code != nil && *e.Code == "content_filter" {
+		return true
+	}
+	return e.InnerError != nil && e.InnerError.Code == "ResponsibleAIPolicyViolation"
+}
+
+// filteredCategories lists the Azure content filter categories that triggered, sorted by name
+func (e *openAIError) filteredCategories() []string {
+	if e.InnerError == nil {
+		return nil
+	}
+
+	var categories []string
+	for category, hit := range e.InnerError.ContentFilterResult {
+		if hit.Filtered {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
 }
 
 // ensureValidCredentials ensures we have valid API credentials
 func (p *OpenAIProvider) ensureValidCredentials(ctx context.Context) error {
+	if p.azure != nil {
+		return p.ensureAzureCredentials()
+	}
+
 	if p.secureConfig.APIKey == "" {
 		// Try to reload credentials
 		if err := p.configManager.RefreshCredentials("openai"); err != nil {
@@ -486,7 +855,12 @@ func (p *OpenAIProvider) HealthCheck(ctx context.Context) (*types.HealthStatus,
 	start := time.Now()
 
 	// Create a simple test request
-	req, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models", nil)
+	healthURL := p.config.BaseURL + "/models"
+	if p.azure != nil {
+		healthURL = fmt.Sprintf("%s/openai/models?api-version=%s", p.config.BaseURL, url.QueryEscape(p.azure.apiVersion))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", healthURL, nil)
 	if err != nil {
 		return &types.HealthStatus{
 			IsHealthy:    false,
@@ -497,7 +871,11 @@ func (p *OpenAIProvider) HealthCheck(ctx context.Context) (*types.HealthStatus,
 		}, nil
 	}
 
-	req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	if p.azure != nil {
+		req.Header.Set("api-key", p.secureConfig.APIKey)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+p.config.APIKey)
+	}
 
 	resp, err := p.httpClient.Do(req)
 	if err != nil {
@@ -540,16 +918,45 @@ func (p *OpenAIProvider) GetModels(ctx context.Context) ([]*types.Model, error)
 			CostPerInputToken:  0.00003,
 			CostPerOutputToken: 0.00006,
 			IsEnabled:          true,
+			Capabilities:       &types.ModelCapabilities{Tools: true, Streaming: true},
 		},
 		{
 			Name:               "gpt-3.5-turbo",
 			DisplayName:        "GPT-3.5 Turbo",
 			Description:        "Most capable GPT-3.5 model and optimized for chat",
-			ContextLength:      4096,
+			ContextLength:      16385,
 			SupportedModes:     `["chat"]`,
 			CostPerInputToken:  0.000001,
 			CostPerOutputToken: 0.000002,
 			IsEnabled:          true,
+			Capabilities:       &types.ModelCapabilities{Tools: true, Streaming: true, JSONMode: true},
+		},
+		{
+			Name:              "text-embedding-3-small",
+			DisplayName:       "Text Embedding 3 Small",
+			Description:       "Small, efficient embedding model",
+			ContextLength:     8191,
+			SupportedModes:    `["embedding"]`,
+			CostPerInputToken: 0.00000002,
+			IsEnabled:         true,
+		},
+		{
+			Name:              "text-embedding-3-large",
+			DisplayName:       "Text Embedding 3 Large",
+			Description:       "Most capable embedding model",
+			ContextLength:     8191,
+			SupportedModes:    `["embedding"]`,
+			CostPerInputToken: 0.00000013,
+			IsEnabled:         true,
+		},
+		{
+			Name:              "text-embedding-ada-002",
+			DisplayName:       "Ada Embedding v2",
+			Description:       "Previous generation embedding model",
+			ContextLength:     8191,
+			SupportedModes:    `["embedding"]`,
+			CostPerInputToken: 0.0000001,
+			IsEnabled:         true,
 		},
 	}
 
@@ -597,8 +1004,8 @@ func (p *OpenAIProvider) GetRateLimit() *types.RateLimitInfo {
 	return p.rateLimits
 }
 
-// convertRequest converts standard request to OpenAI format
-func (p *OpenAIProvider) convertRequest(req *types.ChatCompletionRequest) *openAIRequest {
+// toOpenAIRequest converts standard request to OpenAI format
+func toOpenAIRequest(req *types.ChatCompletionRequest) *openAIRequest {
 	openAIReq := &openAIRequest{
 		Model:            req.Model,
 		Messages:         make([]openAIMessage, len(req.Messages)),
@@ -612,28 +1019,148 @@ func (p *OpenAIProvider) convertRequest(req *types.ChatCompletionRequest) *openA
 		FrequencyPenalty: req.FrequencyPenalty,
 		LogitBias:        req.LogitBias,
 		User:             req.User,
+		Tools:            toOpenAITools(req.Tools),
+		ToolChoice:       req.ToolChoice,
+		ResponseFormat:   req.ResponseFormat,
 	}
 
 	// Convert messages
 	for i, msg := range req.Messages {
-		openAIReq.Messages[i] = openAIMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
+		openAIReq.Messages[i] = toOpenAIMessage(msg)
 	}
 
 	return openAIReq
 }
 
-// convertResponse converts OpenAI response to standard format
-func (p *OpenAIProvider) convertResponse(resp *openAIResponse) *types.ChatCompletionResponse {
+// toOpenAIMessage converts a unified message, including tool calls and tool results, to the OpenAI format
+func toOpenAIMessage(msg types.Message) openAIMessage {
+	openAIMsg := openAIMessage{
+		Role:      msg.Role,
+		Content:   msg.Content,
+		ToolCalls: toOpenAIToolCalls(msg.ToolCalls),
+	}
+	if msg.HasImages() {
+		openAIMsg.Content = toOpenAIContentParts(msg.Parts)
+	}
+
+	if msg.Name != "" {
+		name := msg.Name
+		openAIMsg.Name = &name
+	}
+	if msg.ToolCallID != "" {
+		toolCallID := msg.ToolCallID
+		openAIMsg.ToolCallID = &toolCallID
+	}
+
+	return openAIMsg
+}
+
+// toOpenAIContentParts converts multimodal content parts to the OpenAI content array
+func toOpenAIContentParts(parts []types.ContentPart) []openAIContentPart {
+	openAIParts := make([]openAIContentPart, len(parts))
+	for i, part := range parts {
+		openAIParts[i] = openAIContentPart{Type: part.Type, Text: part.Text}
+		if part.ImageURL != nil {
+			openAIParts[i].ImageURL = &openAIImageURL{URL: part.ImageURL.URL, Detail: part.ImageURL.Detail}
+		}
+	}
+	return openAIParts
+}
+
+// openAIContentText returns the text of a response message, whose content is a string or null
+func openAIContentText(content interface{}) string {
+	if text, ok := content.(string); ok {
+		return text
+	}
+	return ""
+}
+
+// toOpenAITools converts unified tool definitions to the OpenAI tools schema
+func toOpenAITools(tools []types.Tool) []openAITool {
+	if len(tools) == 0 {
+		return nil
+	}
+
+	openAITools := make([]openAITool, len(tools))
+	for i, tool := range tools {
+		toolType := tool.Type
+		if toolType == "" {
+			toolType = "function"
+		}
+
+		openAITools[i] = openAITool{
+			Type: toolType,
+			Function: openAIFunction{
+				Name:       tool.Function.Name,
+				Parameters: tool.Function.Parameters,
+			},
+		}
+		if tool.Function.Description != "" {
+			description := tool.Function.Description
+			openAITools[i].Function.Description = &description
+		}
+	}
+
+	return openAITools
+}
+
+// toOpenAIToolCalls converts unified tool calls to the OpenAI format
+func toOpenAIToolCalls(toolCalls []types.ToolCall) []openAIToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+
+	openAIToolCalls := make([]openAIToolCall, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		toolType := toolCall.Type
+		if toolType == "" {
+			toolType = "function"
+		}
+
+		openAIToolCalls[i] = openAIToolCall{
+			ID:   toolCall.ID,
+			Type: toolType,
+			Function: openAIFunctionCall{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		}
+	}
+
+	return openAIToolCalls
+}
+
+// fromOpenAIToolCalls converts OpenAI tool calls to the unified format
+func fromOpenAIToolCalls(toolCalls []openAIToolCall) []types.ToolCall {
+	if len(toolCalls) == 0 {
+		return nil
+	}
+
+	unified := make([]types.ToolCall, len(toolCalls))
+	for i, toolCall := range toolCalls {
+		unified[i] = types.ToolCall{
+			ID:   toolCall.ID,
+			Type: toolCall.Type,
+			Function: types.FunctionCall{
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			},
+		}
+	}
+
+	return unified
+}
+
+// fromOpenAIResponse converts OpenAI response to standard format
+func fromOpenAIResponse(resp *openAIResponse, providerName string) *types.ChatCompletionResponse {
 	choices := make([]types.Choice, len(resp.Choices))
 	for i, choice := range resp.Choices {
 		choices[i] = types.Choice{
 			Index: choice.Index,
 			Message: types.Message{
-				Role:    choice.Message.Role,
-				Content: choice.Message.Content,
+				Role:      choice.Message.Role,
+				Content:   openAIContentText(choice.Message.Content),
+				ToolCalls: fromOpenAIToolCalls(choice.Message.ToolCalls),
 			},
 			FinishReason: choice.FinishReason,
 		}
@@ -650,7 +1177,7 @@ func (p *OpenAIProvider) convertResponse(resp *openAIResponse) *types.ChatComple
 			CompletionTokens: resp.Usage.CompletionTokens,
 			TotalTokens:      resp.Usage.TotalTokens,
 		},
-		Provider:  p.GetName(),
+		Provider:  providerName,
 		LatencyMs: 0, // Will be set by caller
 	}
 }
@@ -674,6 +1201,26 @@ func (p *OpenAIProvider) updateRateLimits(headers http.Header) {
 			p.rateLimits.ResetTime = time.Now().Add(duration)
 		}
 	}
+
+	if limit := headers.Get("x-ratelimit-limit-requests"); limit != "" {
+		if val, err := strconv.Atoi(limit); err == nil {
+			p.rateLimits.RequestsPerMinute = val
+		}
+	}
+
+	if limit := headers.Get("x-ratelimit-limit-tokens"); limit != "" {
+		if val, err := strconv.Atoi(limit); err == nil {
+			p.rateLimits.TokensPerMinute = val
+		}
+	}
+
+	// Azure OpenAI has no reset headers; throttled responses carry retry-after-ms instead
+	if retryAfterMs := headers.Get("retry-after-ms"); retryAfterMs != "" {
+		if ms, err := strconv.Atoi(retryAfterMs); err == nil {
+			p.rateLimits.RemainingRequests = 0
+			p.rateLimits.ResetTime = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		}
+	}
 }
 
 // ============================================================================
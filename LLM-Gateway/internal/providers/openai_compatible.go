@@ -0,0 +1,464 @@
+// Package providers implements a config-driven adapter for OpenAI-compatible APIs
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+)
+
+// OpenAICompatibleType is the ProviderConfig.Type handled by OpenAICompatibleProvider
+const OpenAICompatibleType = "openai_compatible"
+
+// CustomConfig keys understood by OpenAICompatibleProvider
+const (
+	CompatAuthHeader   = "auth_header"   // Header carrying the API key, default "Authorization"
+	CompatAuthScheme   = "auth_scheme"   // Key prefix, default "Bearer" for the Authorization header
+	CompatAPIKeyEnv    = "api_key_env"   // Environment variable read when api_key is empty
+	CompatExtraHeaders = "extra_headers" // Comma separated Name=value pairs sent with every request
+	CompatInputPrice   = "input_price"   // USD per 1K input tokens for all configured models
+	CompatOutputPrice  = "output_price"  // USD per 1K output tokens for all configured models
+)
+
+// OpenAICompatibleProvider serves any upstream that speaks the OpenAI wire protocol
+// (DeepSeek, Moonshot, Qwen compatible mode, vLLM, Ollama, ...). Everything that
+// differs between upstreams comes from ProviderConfig and its CustomConfig.
+type OpenAICompatibleProvider struct {
+	config         *types.ProductionConfig
+	logger         *utils.Logger
+	httpClient     *http.Client
+	rateLimits     *types.RateLimitInfo
+	retryManager   retry.RetryManagerInterface
+	costCalculator *cost.CostCalculator
+	apiKey         string
+	authHeader     string
+	authScheme     string
+	extraHeaders   map[string]string
+}
+
+// NewOpenAICompatibleProvider creates an OpenAI-compatible provider from configuration
+func NewOpenAICompatibleProvider(baseConfig *types.ProviderConfig, logger *utils.Logger) (*OpenAICompatibleProvider, error) {
+	if baseConfig.Name == "" {
+		return nil, fmt.Errorf("openai_compatible provider name is required")
+	}
+	if baseConfig.BaseURL == "" {
+		return nil, fmt.Errorf("openai_compatible provider %s: base_url is required", baseConfig.Name)
+	}
+
+	prodConfig := types.NewProductionConfig(baseConfig)
+	if prodConfig.Timeout == 0 {
+		prodConfig.Timeout = 60 * time.Second
+	}
+	if prodConfig.RetryCount > 0 {
+		prodConfig.RetryPolicy.MaxRetries = prodConfig.RetryCount
+	}
+
+	custom := baseConfig.CustomConfig
+
+	// Local servers such as Ollama and vLLM usually run without a key
+	apiKey := baseConfig.APIKey
+	if apiKey == "" && custom[CompatAPIKeyEnv] != "" {
+		apiKey = os.Getenv(custom[CompatAPIKeyEnv])
+	}
+
+	authHeader := "Authorization"
+	if header := custom[CompatAuthHeader]; header != "" {
+		authHeader = header
+	}
+	authScheme, hasScheme := custom[CompatAuthScheme]
+	if !hasScheme && strings.EqualFold(authHeader, "Authorization") {
+		authScheme = "Bearer"
+	}
+
+	extraHeaders, err := parseKeyValuePairs(custom[CompatExtraHeaders], CompatExtraHeaders)
+	if err != nil {
+		return nil, fmt.Errorf("openai_compatible provider %s: %w", baseConfig.Name, err)
+	}
+
+	costCalculator := cost.NewCostCalculator(logger)
+	if err := registerCompatiblePricing(costCalculator, baseConfig); err != nil {
+		return nil, fmt.Errorf("openai_compatible provider %s: %w", baseConfig.Name, err)
+	}
+
+	return &OpenAICompatibleProvider{
+		config:         prodConfig,
+		logger:         logger,
+		retryManager:   retry.NewRetryManager(prodConfig.RetryPolicy, logger),
+		costCalculator: costCalculator,
+		apiKey:         apiKey,
+		authHeader:     authHeader,
+		authScheme:     authScheme,
+		extraHeaders:   extraHeaders,
+		httpClient: &http.Client{
+			Timeout: prodConfig.Timeout,
+		},
+		rateLimits: &types.RateLimitInfo{
+			RequestsPerMinute: prodConfig.RateLimit,
+			ResetTime:         time.Now().Add(time.Minute),
+		},
+	}, nil
+}
+
+// parseKeyValuePairs parses a "Name=value,Name2=value2" CustomConfig value into a map
+func parseKeyValuePairs(raw, key string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(raw, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, value, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid %s entry %q, expected Name=value", key, pair)
+		}
+		pairs[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return pairs, nil
+}
+
+// registerCompatiblePricing registers configured prices for every model under the provider name
+func registerCompatiblePricing(calculator *cost.CostCalculator, config *types.ProviderConfig) error {
+	custom := config.CustomConfig
+	if custom[CompatInputPrice] == "" && custom[CompatOutputPrice] == "" {
+		return nil
+	}
+
+	pricing := &types.ModelPricing{Provider: config.Name, Currency: "USD"}
+	var err error
+	if raw := custom[CompatInputPrice]; raw != "" {
+		if pricing.InputPrice, err = strconv.ParseFloat(raw, 64); err != nil {
+			return fmt.Errorf("invalid %s: %w", CompatInputPrice, err)
+		}
+	}
+	if raw := custom[CompatOutputPrice]; raw != "" {
+		if pricing.OutputPrice, err = strconv.ParseFloat(raw, 64); err != nil {
+			return fmt.Errorf("invalid %s: %w", CompatOutputPrice, err)
+		}
+	}
+
+	for _, model := range config.Models {
+		modelPricing := *pricing
+		modelPricing.Model = model
+		if err := calculator.UpdatePricing(config.Name, model, &modelPricing); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetName returns the provider name
+func (p *OpenAICompatibleProvider) GetName() string {
+	return p.config.Name
+}
+
+// GetType returns the provider type
+func (p *OpenAICompatibleProvider) GetType() string {
+	return OpenAICompatibleType
+}
+
+// GetConfig returns the provider configuration
+func (p *OpenAICompatibleProvider) GetConfig() *types.ProviderConfig {
+	return p.config.ProviderConfig
+}
+
+// GetRateLimit returns current rate limit information
+func (p *OpenAICompatibleProvider) GetRateLimit() *types.RateLimitInfo {
+	return p.rateLimits
+}
+
+// GetModels returns the models declared in configuration with their declared limits and capabilities
+func (p *OpenAICompatibleProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
+	models := make([]*types.Model, len(p.config.Models))
+	for i, name := range p.config.Models {
+		models[i] = &types.Model{
+			Name:           name,
+			DisplayName:    name,
+			SupportedModes: `["chat"]`,
+			IsEnabled:      true,
+		}
+		if spec, ok := p.config.ModelSpec(name); ok {
+			models[i].ContextLength = spec.ContextWindow
+			models[i].Capabilities = spec.Capabilities
+		}
+	}
+	return models, nil
+}
+
+// EstimateCost estimates the cost for a request using the configured pricing
+func (p *OpenAICompatibleProvider) EstimateCost(req *types.ChatCompletionRequest) (*types.CostEstimate, error) {
+	estimate, err := p.costCalculator.EstimateRequestCostFor(p.GetName(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.CostEstimate{
+		InputTokens:  estimate.InputTokens,
+		OutputTokens: estimate.OutputTokens,
+		TotalTokens:  estimate.TotalTokens,
+		TotalCost:    estimate.EstimatedCost,
+		Currency:     estimate.Currency,
+	}, nil
+}
+
+// Call sends a chat completion request to the upstream
+func (p *OpenAICompatibleProvider) Call(ctx context.Context, req *types.ChatCompletionRequest) (*types.ChatCompletionResponse, error) {
+	reqBody, err := json.Marshal(toOpenAIRequest(req))
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "ChatCompletion", types.ErrorClient,
+			fmt.Sprintf("failed to marshal request: %v", err), false)
+	}
+
+	var response *types.ChatCompletionResponse
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		p.logger.WithFields(map[string]interface{}{
+			"provider": p.GetName(),
+			"model":    req.Model,
+			"attempt":  attempt,
+		}).Info("Sending OpenAI-compatible request")
+
+		respBody, err := p.do(ctx, "/chat/completions", reqBody, "ChatCompletion")
+		if err != nil {
+			return err
+		}
+
+		var openAIResp openAIResponse
+		if err := json.Unmarshal(respBody, &openAIResp); err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "ChatCompletion", types.ErrorServer,
+				fmt.Sprintf("failed to unmarshal response: %v", err), true)
+		}
+
+		response = fromOpenAIResponse(&openAIResp, p.GetName())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.config.CostTracking {
+		if actualCost, err := p.costCalculator.CalculateActualCostFor(p.GetName(), req, response); err == nil {
+			p.logger.WithFields(map[string]interface{}{
+				"provider":    p.GetName(),
+				"actual_cost": actualCost.TotalCost,
+			}).Info("OpenAI-compatible request cost calculated")
+		}
+	}
+
+	return response, nil
+}
+
+// CallStream implements the StreamingProvider interface
+func (p *OpenAICompatibleProvider) CallStream(ctx context.Context, req *types.ChatCompletionRequest) (<-chan *types.StreamChunk, error) {
+	openAIReq := toOpenAIRequest(req)
+	stream := true
+	openAIReq.Stream = &stream
+	openAIReq.StreamOptions = &openAIStreamOpt{IncludeUsage: true}
+
+	reqBody, err := json.Marshal(openAIReq)
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "ChatCompletionStream", types.ErrorClient,
+			fmt.Sprintf("failed to marshal request: %v", err), false)
+	}
+
+	// Retry only while opening the stream; once chunks flow the request can't be replayed
+	var resp *http.Response
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		httpReq, err := p.newHTTPRequest(ctx, "/chat/completions", reqBody)
+		if err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "ChatCompletionStream", types.ErrorClient,
+				fmt.Sprintf("failed to create request: %v", err), false)
+		}
+		httpReq.Header.Set("Accept", "text/event-stream")
+
+		attemptResp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, p.GetName(), "ChatCompletionStream")
+		}
+
+		p.updateRateLimits(attemptResp.Header)
+
+		if attemptResp.StatusCode != http.StatusOK {
+			respBody, _ := io.ReadAll(attemptResp.Body)
+			attemptResp.Body.Close()
+			return classifyOpenAIError(p.GetName(), attemptResp, respBody, "ChatCompletionStream")
+		}
+
+		resp = attemptResp
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sender := newChunkSender(ctx)
+	go readOpenAIStream(sender, resp.Body, p.GetName(), p.logger)
+
+	return sender.ch, nil
+}
+
+// Embed implements the EmbeddingProvider interface
+func (p *OpenAICompatibleProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorClient, err.Error(), false)
+	}
+
+	reqBody, err := json.Marshal(toOpenAIEmbeddingRequest(req, inputs))
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorClient,
+			fmt.Sprintf("failed to marshal request: %v", err), false)
+	}
+
+	var response *types.EmbeddingResponse
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		respBody, err := p.do(ctx, "/embeddings", reqBody, "Embeddings")
+		if err != nil {
+			return err
+		}
+
+		var embeddingResp openAIEmbeddingResponse
+		if err := json.Unmarshal(respBody, &embeddingResp); err != nil {
+			return retry.NewProviderRetryError(p.GetName(), "Embeddings", types.ErrorServer,
+				fmt.Sprintf("failed to unmarshal response: %v", err), true)
+		}
+
+		response = fromOpenAIEmbeddingResponse(&embeddingResp, p.GetName())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.config.CostTracking {
+		if actualCost, err := p.costCalculator.CalculateEmbeddingCostFor(p.GetName(), req, response); err == nil {
+			p.logger.WithFields(map[string]interface{}{
+				"provider":    p.GetName(),
+				"actual_cost": actualCost.TotalCost,
+			}).Info("OpenAI-compatible embeddings cost calculated")
+		}
+	}
+
+	return response, nil
+}
+
+// HealthCheck lists models, which is cheap and supported by OpenAI-compatible servers
+func (p *OpenAICompatibleProvider) HealthCheck(ctx context.Context) (*types.HealthStatus, error) {
+	start := time.Now()
+	status := &types.HealthStatus{
+		LastChecked: time.Now(),
+		Endpoint:    p.config.BaseURL,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.config.BaseURL+"/models", nil)
+	if err != nil {
+		status.ResponseTime = time.Since(start)
+		status.ErrorMessage = fmt.Sprintf("Failed to create health check request: %v", err)
+		return status, nil
+	}
+	p.setHeaders(req)
+
+	resp, err := p.httpClient.Do(req)
+	status.ResponseTime = time.Since(start)
+	if err != nil {
+		status.ErrorMessage = fmt.Sprintf("Health check request failed: %v", err)
+		return status, nil
+	}
+	defer resp.Body.Close()
+
+	status.IsHealthy = resp.StatusCode == http.StatusOK
+	if !status.IsHealthy {
+		status.ErrorMessage = fmt.Sprintf("HTTP %d", resp.StatusCode)
+	}
+
+	return status, nil
+}
+
+// do sends a POST request and returns the body of a successful response
+func (p *OpenAICompatibleProvider) do(ctx context.Context, path string, reqBody []byte, operation string) ([]byte, error) {
+	httpReq, err := p.newHTTPRequest(ctx, path, reqBody)
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), operation, types.ErrorClient,
+			fmt.Sprintf("failed to create request: %v", err), false)
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, retry.ClassifyError(err, p.GetName(), operation)
+	}
+	defer resp.Body.Close()
+
+	p.updateRateLimits(resp.Header)
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, retry.NewProviderRetryError(p.GetName(), operation, types.ErrorNetwork,
+			fmt.Sprintf("failed to read response: %v", err), true)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		retryError := classifyOpenAIError(p.GetName(), resp, respBody, operation)
+		p.logger.WithFields(map[string]interface{}{
+			"provider":    p.GetName(),
+			"status_code": resp.StatusCode,
+			"retryable":   retryError.Retryable,
+		}).Error("OpenAI-compatible API returned error")
+		return nil, retryError
+	}
+
+	return respBody, nil
+}
+
+// newHTTPRequest creates a POST request with the configured auth and extra headers
+func (p *OpenAICompatibleProvider) newHTTPRequest(ctx context.Context, path string, reqBody []byte) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.BaseURL+path, bytes.NewBuffer(reqBody))
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "LLM-Gateway/2.0")
+	p.setHeaders(httpReq)
+
+	return httpReq, nil
+}
+
+// setHeaders applies authentication and extra headers
+func (p *OpenAICompatibleProvider) setHeaders(req *http.Request) {
+	if p.apiKey != "" {
+		value := p.apiKey
+		if p.authScheme != "" {
+			value = p.authScheme + " " + p.apiKey
+		}
+		req.Header.Set(p.authHeader, value)
+	}
+
+	for name, value := range p.extraHeaders {
+		req.Header.Set(name, value)
+	}
+}
+
+// updateRateLimits reads the OpenAI-style rate limit headers most compatible servers return
+func (p *OpenAICompatibleProvider) updateRateLimits(headers http.Header) {
+	if remaining := headers.Get("x-ratelimit-remaining-requests"); remaining != "" {
+		if val, err := strconv.Atoi(remaining); err == nil {
+			p.rateLimits.RemainingRequests = val
+		}
+	}
+
+	if remaining := headers.Get("x-ratelimit-remaining-tokens"); remaining != "" {
+		if val, err := strconv.Atoi(remaining); err == nil {
+			p.rateLimits.RemainingTokens = val
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package providers implements shared helpers for streaming provider responses
+package providers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// streamBufferSize is the capacity of the chunk channel returned by CallStream
+const streamBufferSize = 16
+
+// maxSSELineSize bounds a single SSE line; tool call arguments can be large
+const maxSSELineSize = 1024 * 1024
+
+// sseEvent represents a single server-sent event data line
+type sseEvent struct {
+	Event string // Last "event:" field seen, empty for providers that don't name events
+	Data  string
+}
+
+// readSSE reads server-sent events from body and invokes handle for every data line.
+// Lines that are not part of the SSE framing are passed through as data so callers
+// can detect inline JSON errors. Reading stops when handle returns false.
+func readSSE(body io.Reader, handle func(ev sseEvent) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), maxSSELineSize)
+
+	var event string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		switch {
+		case line == "":
+			// Blank line terminates an event
+			event = ""
+		case strings.HasPrefix(line, ":"):
+			// SSE comment / keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			// Some providers (e.g. Zhipu) omit the space after "data:"
+			data := strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " ")
+			if !handle(sseEvent{Event: event, Data: data}) {
+				return nil
+			}
+		case strings.HasPrefix(line, "id:"), strings.HasPrefix(line, "retry:"):
+			// Not used by any provider
+		default:
+			if !handle(sseEvent{Data: line}) {
+				return nil
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// chunkSender delivers stream chunks to the consumer without blocking past cancellation
+type chunkSender struct {
+	ctx context.Context
+	ch  chan *types.StreamChunk
+}
+
+// newChunkSender creates a sender and the channel handed out by CallStream
+func newChunkSender(ctx context.Context) *chunkSender {
+	return &chunkSender{
+		ctx: ctx,
+		ch:  make(chan *types.StreamChunk, streamBufferSize),
+	}
+}
+
+// send delivers a chunk, returning false if the consumer has gone away
+func (s *chunkSender) send(chunk *types.StreamChunk) bool {
+	select {
+	case s.ch <- chunk:
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+// fail delivers a terminal error chunk
+func (s *chunkSender) fail(err error) {
+	s.send(&types.StreamChunk{Type: types.ChunkError, Err: err})
+}
+
+// close closes the chunk channel; it must be called exactly once by the producer
+func (s *chunkSender) close() {
+	close(s.ch)
+}
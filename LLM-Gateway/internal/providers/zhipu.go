@@ -2,7 +2,6 @@
 package providers
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
@@ -10,7 +9,6 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"strings"
 	"time"
 
 	"github.com/llm-gateway/gateway/pkg/cost"
@@ -32,14 +30,19 @@ type ZhipuProvider struct {
 
 // Zhipu API structures
 type zhipuRequest struct {
-	Model    string         `json:"model"`
-	Messages []zhipuMessage `json:"messages"`
-	Stream   bool           `json:"stream"`
+	Model          string                `json:"model"`
+	Messages       []zhipuMessage        `json:"messages"`
+	Stream         bool                  `json:"stream"`
+	Tools          []openAITool          `json:"tools,omitempty"` // GLM uses the OpenAI tool schema
+	ToolChoice     interface{}           `json:"tool_choice,omitempty"`
+	ResponseFormat *types.ResponseFormat `json:"response_format,omitempty"`
 }
 
 type zhipuMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	Role       string           `json:"role"`
+	Content    interface{}      `json:"content"` // string, or []openAIContentPart for GLM-V vision models
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
 }
 
 type zhipuResponse struct {
@@ -77,6 +80,7 @@ type zhipuStreamChunk struct {
 	Created int64               `json:"created"`
 	Model   string              `json:"model"`
 	Choices []zhipuStreamChoice `json:"choices"`
+	Usage   *zhipuUsage         `json:"usage,omitempty"`
 }
 
 type zhipuStreamChoice struct {
@@ -86,8 +90,9 @@ type zhipuStreamChoice struct {
 }
 
 type zhipuStreamDelta struct {
-	Role    string `json:"role,omitempty"`
-	Content string `json:"content,omitempty"`
+	Role      string                 `json:"role,omitempty"`
+	Content   string                 `json:"content,omitempty"`
+	ToolCalls []openAIStreamToolCall `json:"tool_calls,omitempty"`
 }
 
 // NewZhipuProvider creates a new Zhipu provider (compatible version)
@@ -210,11 +215,36 @@ func (p *ZhipuProvider) ChatCompletion(ctx context.Context, req *types.ChatCompl
 	return resp, nil
 }
 
-// ChatCompletionStream handles streaming chat completion requests
+// ChatCompletionStream handles streaming chat completion requests, delivering content through callback
 func (p *ZhipuProvider) ChatCompletionStream(ctx context.Context, req *types.ChatCompletionRequest, callback func(string, bool)) error {
+	chunks, err := p.CallStream(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	for chunk := range chunks {
+		switch chunk.Type {
+		case types.ChunkContent:
+			callback(chunk.Content, false)
+		case types.ChunkError:
+			return chunk.Err
+		}
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	callback("", true) // Signal completion
+	p.logger.Info("Streaming request completed")
+	return nil
+}
+
+// CallStream implements the StreamingProvider interface using GLM server-sent events
+func (p *ZhipuProvider) CallStream(ctx context.Context, req *types.ChatCompletionRequest) (<-chan *types.StreamChunk, error) {
 	// Ensure valid credentials
 	if err := p.ensureValidCredentials(); err != nil {
-		return fmt.Errorf("credential validation failed: %w", err)
+		return nil, fmt.Errorf("credential validation failed: %w", err)
 	}
 
 	// Convert request to Zhipu format with streaming enabled
@@ -224,97 +254,209 @@ func (p *ZhipuProvider) ChatCompletionStream(ctx context.Context, req *types.Cha
 	// Serialize request
 	reqBody, err := json.Marshal(zhipuReq)
 	if err != nil {
-		return fmt.Errorf("failed to marshal stream request: %w", err)
+		return nil, fmt.Errorf("failed to marshal stream request: %w", err)
 	}
 
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.ProviderConfig.BaseURL+"/chat/completions", bytes.NewBuffer(reqBody))
-	if err != nil {
-		return fmt.Errorf("failed to create stream request: %w", err)
-	}
+	p.logger.WithField("model", req.Model).Info("Starting streaming request to Zhipu GLM")
 
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.secureConfig.APIKey)
-	httpReq.Header.Set("Accept", "text/event-stream")
+	// Retry only while opening the stream; once chunks flow the request can't be replayed
+	var resp *http.Response
+	err = p.retryManager.ExecuteWithRetry(ctx, func(ctx context.Context, attempt int) error {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.ProviderConfig.BaseURL+"/chat/completions", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return fmt.Errorf("failed to create stream request: %w", err)
+		}
 
-	p.logger.WithField("model", req.Model).Info("Starting streaming request to Zhipu GLM")
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+p.secureConfig.APIKey)
+		httpReq.Header.Set("Accept", "text/event-stream")
 
-	// Send request
-	resp, err := p.httpClient.Do(httpReq)
+		attemptResp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, "zhipu", "network_error")
+		}
+
+		p.updateRateLimits(attemptResp.Header)
+
+		if attemptResp.StatusCode != http.StatusOK {
+			body, _ := io.ReadAll(attemptResp.Body)
+			attemptResp.Body.Close()
+
+			var errorResp zhipuErrorResponse
+			if err := json.Unmarshal(body, &errorResp); err == nil && errorResp.Error.Code != "" {
+				return retry.ClassifyZhipuError(attemptResp.StatusCode, errorResp.Error.Code, errorResp.Error.Message)
+			}
+			return retry.ClassifyHTTPError(attemptResp.StatusCode, string(body))
+		}
+
+		resp = attemptResp
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("streaming HTTP request failed: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("streaming request failed with status %d: %s", resp.StatusCode, string(body))
-	}
-
-		// Read streaming response
-	scanner := bufio.NewScanner(resp.Body)
-	p.logger.Info("Starting to read streaming response")
-	
-	for scanner.Scan() {
-		line := scanner.Text()
-		p.logger.WithField("raw_line", line).Debug("Received stream line")
-		
-		// Skip empty lines
-		if strings.TrimSpace(line) == "" {
-			continue
-		}
+	sender := newChunkSender(ctx)
+	go func() {
+		defer sender.close()
+		defer resp.Body.Close()
 
-		// Handle SSE format - 智谱AI使用 "data:" 而不是 "data: "
-		if strings.HasPrefix(line, "data:") {
-			data := strings.TrimPrefix(line, "data:")
-			p.logger.WithField("data", data).Debug("Processing SSE data")
-			
+		err := readSSE(resp.Body, func(ev sseEvent) bool {
 			// Check for end signal
-			if data == "[DONE]" {
-				p.logger.Info("Received stream completion signal")
-				callback("", true) // Signal completion
-				break
+			if ev.Data == "[DONE]" {
+				return false
 			}
 
-			// Parse JSON chunk
 			var chunk zhipuStreamChunk
-			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				p.logger.WithError(err).WithField("data", data).Warn("Failed to parse stream chunk")
-				continue
+			if err := json.Unmarshal([]byte(ev.Data), &chunk); err != nil {
+				var errorResp zhipuErrorResponse
+				if json.Unmarshal([]byte(ev.Data), &errorResp) == nil && errorResp.Error.Code != "" {
+					sender.fail(retry.ClassifyZhipuError(resp.StatusCode, errorResp.Error.Code, errorResp.Error.Message))
+					return false
+				}
+				p.logger.WithError(err).WithField("data", ev.Data).Warn("Failed to parse stream chunk")
+				return true
 			}
 
-			p.logger.WithField("chunk", chunk).Debug("Parsed stream chunk")
-
-			// Check if this is the final chunk (done=true or finish_reason present)
-			if len(chunk.Choices) > 0 {
-				choice := chunk.Choices[0]
-				
-				// Send content if available
+			for _, choice := range chunk.Choices {
+				if choice.Delta.Role != "" {
+					if !sender.send(&types.StreamChunk{Type: types.ChunkRole, ID: chunk.ID, Model: chunk.Model, Index: choice.Index, Role: choice.Delta.Role}) {
+						return false
+					}
+				}
 				if choice.Delta.Content != "" {
-					content := choice.Delta.Content
-					p.logger.WithField("content", content).Debug("Sending content to callback")
-					callback(content, false)
+					if !sender.send(&types.StreamChunk{Type: types.ChunkContent, ID: chunk.ID, Model: chunk.Model, Index: choice.Index, Content: choice.Delta.Content}) {
+						return false
+					}
+				}
+				for i, toolCall := range choice.Delta.ToolCalls {
+					// GLM sends each tool call complete in a single delta and may omit the index
+					index := toolCall.Index
+					if index == 0 && i > 0 {
+						index = i
+					}
+					if !sender.send(&types.StreamChunk{
+						Type:          types.ChunkToolCall,
+						ID:            chunk.ID,
+						Model:         chunk.Model,
+						Index:         choice.Index,
+						ToolCallIndex: index,
+						ToolCall: &types.ToolCall{
+							ID:   toolCall.ID,
+							Type: toolCall.Type,
+							Function: types.FunctionCall{
+								Name:      toolCall.Function.Name,
+								Arguments: toolCall.Function.Arguments,
+							},
+						},
+					}) {
+						return false
+					}
 				}
-				
-				// Check for completion
 				if choice.FinishReason != nil && *choice.FinishReason != "" {
-					p.logger.WithField("finish_reason", *choice.FinishReason).Info("Stream completed with finish reason")
-					callback("", true)
-					break
+					if !sender.send(&types.StreamChunk{Type: types.ChunkFinish, ID: chunk.ID, Model: chunk.Model, Index: choice.Index, FinishReason: choice.FinishReason}) {
+						return false
+					}
 				}
-			} else {
-				p.logger.Debug("No choices in chunk")
 			}
+
+			// GLM attaches usage to the final chunk
+			if chunk.Usage != nil {
+				return sender.send(&types.StreamChunk{
+					Type:  types.ChunkUsage,
+					ID:    chunk.ID,
+					Model: chunk.Model,
+					Usage: &types.Usage{
+						PromptTokens:     chunk.Usage.PromptTokens,
+						CompletionTokens: chunk.Usage.CompletionTokens,
+						TotalTokens:      chunk.Usage.TotalTokens,
+					},
+				})
+			}
+
+			return true
+		})
+		if err != nil {
+			sender.fail(fmt.Errorf("error reading stream: %w", err))
+		}
+	}()
+
+	return sender.ch, nil
+}
+
+// Embed implements the EmbeddingProvider interface using the Zhipu embeddings API (embedding-2, embedding-3)
+func (p *ZhipuProvider) Embed(ctx context.Context, req *types.EmbeddingRequest) (*types.EmbeddingResponse, error) {
+	// Ensure valid credentials
+	if err := p.ensureValidCredentials(); err != nil {
+		return nil, fmt.Errorf("credential validation failed: %w", err)
+	}
+
+	inputs, err := req.Inputs()
+	if err != nil {
+		return nil, fmt.Errorf("invalid embedding input: %w", err)
+	}
+
+	// GLM returns float vectors only and does not accept encoding_format
+	zhipuReq := toOpenAIEmbeddingRequest(req, inputs)
+	zhipuReq.EncodingFormat = ""
+
+	reqBody, err := json.Marshal(zhipuReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	// Execute with retry
+	var resp *types.EmbeddingResponse
+	retryOp := func(ctx context.Context, attempt int) error {
+		httpReq, err := http.NewRequestWithContext(ctx, "POST", p.config.ProviderConfig.BaseURL+"/embeddings", bytes.NewBuffer(reqBody))
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		httpReq.Header.Set("Authorization", "Bearer "+p.secureConfig.APIKey)
+
+		p.logger.WithField("model", req.Model).Info("Sending embeddings request to Zhipu GLM")
+
+		httpResp, err := p.httpClient.Do(httpReq)
+		if err != nil {
+			return retry.ClassifyError(err, "zhipu", "network_error")
+		}
+		defer httpResp.Body.Close()
+
+		respBody, err := io.ReadAll(httpResp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response: %w", err)
+		}
+
+		p.updateRateLimits(httpResp.Header)
+
+		if httpResp.StatusCode != http.StatusOK {
+			var errorResp zhipuErrorResponse
+			if err := json.Unmarshal(respBody, &errorResp); err == nil {
+				return retry.ClassifyZhipuError(httpResp.StatusCode, errorResp.Error.Code, errorResp.Error.Message)
+			}
+			return retry.ClassifyHTTPError(httpResp.StatusCode, string(respBody))
+		}
+
+		var embeddingResp openAIEmbeddingResponse
+		if err := json.Unmarshal(respBody, &embeddingResp); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
 		}
+
+		resp = fromOpenAIEmbeddingResponse(&embeddingResp, p.GetName())
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading stream: %w", err)
+	if err := p.retryManager.ExecuteWithRetry(ctx, retryOp); err != nil {
+		return nil, err
 	}
 
-	p.logger.Info("Streaming request completed")
-	return nil
+	// Calculate actual cost (for monitoring/logging purposes)
+	if actualCost, err := p.costCalculator.CalculateEmbeddingCost(req, resp); err == nil {
+		p.logger.WithField("actual_cost", actualCost.TotalCost).Info("Cost calculated for Zhipu embeddings request")
+	}
+
+	return resp, nil
 }
 
 // executeAPIRequest performs a single API request attempt
@@ -420,17 +562,57 @@ func (p *ZhipuProvider) HealthCheck(ctx context.Context) (*types.HealthStatus, e
 
 // GetModels returns supported models
 func (p *ZhipuProvider) GetModels(ctx context.Context) ([]*types.Model, error) {
-	// Return static model list for Zhipu
+	// Return static model list for Zhipu; GLM text models take tools and JSON mode, GLM-V takes images
+	text := &types.ModelCapabilities{Tools: true, Streaming: true, JSONMode: true}
+	vision := &types.ModelCapabilities{Vision: true, Streaming: true}
 	models := []*types.Model{
 		{
-			Name:        "glm-4-flash",
-			DisplayName: "GLM-4-Flash",
-			Description: "智谱最新快速模型",
+			Name:         "glm-4.5",
+			DisplayName:  "GLM-4.5",
+			Description:  "智谱GLM-4.5旗舰模型",
+			Capabilities: text,
 		},
 		{
-			Name:        "glm-4",
-			DisplayName: "GLM-4",
-			Description: "智谱GLM-4标准模型",
+			Name:         "glm-4.5v",
+			DisplayName:  "GLM-4.5V",
+			Description:  "智谱GLM-4.5视觉理解模型",
+			Capabilities: vision,
+		},
+		{
+			Name:         "glm-4.5-air",
+			DisplayName:  "GLM-4.5-Air",
+			Description:  "智谱GLM-4.5轻量模型",
+			Capabilities: text,
+		},
+		{
+			Name:         "glm-4-flash",
+			DisplayName:  "GLM-4-Flash",
+			Description:  "智谱最新快速模型",
+			Capabilities: text,
+		},
+		{
+			Name:         "glm-4",
+			DisplayName:  "GLM-4",
+			Description:  "智谱GLM-4标准模型",
+			Capabilities: text,
+		},
+		{
+			Name:         "glm-4-air",
+			DisplayName:  "GLM-4-Air",
+			Description:  "智谱GLM-4高性价比模型",
+			Capabilities: text,
+		},
+		{
+			Name:           "embedding-3",
+			DisplayName:    "Embedding-3",
+			Description:    "智谱文本向量模型",
+			SupportedModes: `["embedding"]`,
+		},
+		{
+			Name:           "embedding-2",
+			DisplayName:    "Embedding-2",
+			Description:    "智谱文本向量模型",
+			SupportedModes: `["embedding"]`,
 		},
 	}
 	return models, nil
@@ -464,9 +646,12 @@ func (p *ZhipuProvider) convertRequest(req *types.ChatCompletionRequest) *zhipuR
 	}
 
 	zhipuReq := &zhipuRequest{
-		Model:    req.Model,
-		Messages: make([]zhipuMessage, len(req.Messages)),
-		Stream:   stream,
+		Model:          req.Model,
+		Messages:       make([]zhipuMessage, len(req.Messages)),
+		Stream:         stream,
+		Tools:          toOpenAITools(req.Tools),
+		ToolChoice:     req.ToolChoice,
+		ResponseFormat: req.ResponseFormat,
 	}
 
 	// Use default model if not specified
@@ -477,14 +662,36 @@ func (p *ZhipuProvider) convertRequest(req *types.ChatCompletionRequest) *zhipuR
 	// Convert messages
 	for i, msg := range req.Messages {
 		zhipuReq.Messages[i] = zhipuMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
+			Role:       msg.Role,
+			Content:    msg.Content,
+			ToolCalls:  toOpenAIToolCalls(msg.ToolCalls),
+			ToolCallID: msg.ToolCallID,
+		}
+		if msg.HasImages() {
+			zhipuReq.Messages[i].Content = toZhipuContentParts(msg.Parts)
 		}
 	}
 
 	return zhipuReq
 }
 
+// toZhipuContentParts converts content parts for GLM-V, which expects inline images as bare base64
+func toZhipuContentParts(parts []types.ContentPart) []openAIContentPart {
+	zhipuParts := toOpenAIContentParts(parts)
+	for _, part := range zhipuParts {
+		if part.ImageURL == nil {
+			continue
+		}
+		image := types.ImageURL{URL: part.ImageURL.URL}
+		if _, data, err := image.DataURI(); err == nil {
+			part.ImageURL.URL = data
+		}
+		// GLM-V does not accept the detail hint
+		part.ImageURL.Detail = ""
+	}
+	return zhipuParts
+}
+
 // convertResponse converts Zhipu response to standard format
 func (p *ZhipuProvider) convertResponse(resp *zhipuResponse) *types.ChatCompletionResponse {
 	choices := make([]types.Choice, len(resp.Choices))
@@ -493,8 +700,9 @@ func (p *ZhipuProvider) convertResponse(resp *zhipuResponse) *types.ChatCompleti
 		choices[i] = types.Choice{
 			Index: choice.Index,
 			Message: types.Message{
-				Role:    choice.Message.Role,
-				Content: choice.Message.Content,
+				Role:      choice.Message.Role,
+				Content:   openAIContentText(choice.Message.Content),
+				ToolCalls: fromOpenAIToolCalls(choice.Message.ToolCalls),
 			},
 			FinishReason: &finishReason,
 		}
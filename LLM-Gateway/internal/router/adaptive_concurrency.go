@@ -0,0 +1,146 @@
+// Package router implements adaptive AIMD concurrency limits per provider
+package router
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// Adaptive limit defaults
+	DefaultAdaptiveInitialLimit = 20
+	DefaultAdaptiveMinLimit     = 1
+	DefaultAdaptiveMaxLimit     = 200
+	DefaultAdaptiveBackoff      = 0.9
+	DefaultAdaptiveTolerance    = 2.0
+
+	// adaptiveWarmup is the number of calls that set a baseline before latency can lower the limit
+	adaptiveWarmup = 10
+
+	// baselineSmoothing is the weight of each successful call in the baseline latency
+	baselineSmoothing = 0.05
+)
+
+// aimdState is the learned limit of an adaptive concurrency limiter. It grows by one slot per
+// successful call made while at least half the limit is in use, and shrinks by the backoff factor
+// on overload. The baseline is a slow moving average of successful call latencies, so a provider
+// that becomes permanently slower is tolerated after a while instead of being pinned at the minimum.
+type aimdState struct {
+	estimate  float64
+	baseline  time.Duration
+	samples   int64
+	minLimit  int
+	maxLimit  int
+	backoff   float64
+	tolerance float64
+}
+
+// newAIMDState creates adaptive state starting at the configured initial limit
+func newAIMDState(config *types.AdaptiveConcurrencyConfig) *aimdState {
+	state := &aimdState{}
+	state.reconfigure(config)
+	_, initialLimit, _ := adaptiveBounds(config)
+	state.estimate = float64(initialLimit)
+	return state
+}
+
+// reconfigure applies new bounds and factors, keeping the learned limit within the bounds
+func (a *aimdState) reconfigure(config *types.AdaptiveConcurrencyConfig) {
+	a.minLimit, _, a.maxLimit = adaptiveBounds(config)
+	a.backoff = config.Backoff
+	if a.backoff == 0 {
+		a.backoff = DefaultAdaptiveBackoff
+	}
+	a.tolerance = config.Tolerance
+	if a.tolerance == 0 {
+		a.tolerance = DefaultAdaptiveTolerance
+	}
+	a.estimate = math.Min(math.Max(a.estimate, float64(a.minLimit)), float64(a.maxLimit))
+}
+
+// limit returns the whole number of requests currently allowed in flight
+func (a *aimdState) limit() int {
+	return int(a.estimate)
+}
+
+// observe moves the limit after a call that had inFlight requests in flight alongside it
+func (a *aimdState) observe(latency time.Duration, overloaded bool, inFlight int) {
+	inflated := a.samples >= adaptiveWarmup && latency > time.Duration(float64(a.baseline)*a.tolerance)
+
+	switch {
+	case overloaded || inflated:
+		a.estimate = math.Max(float64(a.minLimit), a.estimate*a.backoff)
+	case inFlight*2 >= a.limit():
+		// Only a limit that is actually in use has shown it can grow
+		a.estimate = math.Min(float64(a.maxLimit), a.estimate+1)
+	}
+
+	if !overloaded {
+		if a.samples == 0 {
+			a.baseline = latency
+		} else {
+			a.baseline += time.Duration(baselineSmoothing * float64(latency-a.baseline))
+		}
+		a.samples++
+	}
+}
+
+// observe feeds the outcome of a call the limiter admitted into its adaptive limit, if it has one.
+// It must be called before the call's slot is released.
+func (l *concurrencyLimiter) observe(latency time.Duration, overloaded bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.adaptive == nil {
+		return
+	}
+	l.adaptive.observe(latency, overloaded, l.inFlight)
+	l.setLimit(l.adaptive.limit())
+}
+
+// observeCall reports the outcome of a call to provider to its adaptive concurrency limit.
+// Rate limits and timeouts count as overload; other errors say nothing about capacity, nor do
+// calls abandoned because ctx was canceled, such as the losing leg of a hedge.
+func (sr *SmartRouter) observeCall(ctx context.Context, provider string, latency time.Duration, err error) {
+	if errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+
+	overloaded := false
+	if err != nil {
+		category := classifyAttemptError(err, provider).Category
+		if category != types.ErrorRateLimit && category != types.ErrorTimeout {
+			return
+		}
+		overloaded = true
+	}
+
+	sr.statsMutex.Lock()
+	limiter, exists := sr.limiters[providerModelKey(provider, "")]
+	sr.statsMutex.Unlock()
+	if exists {
+		limiter.observe(latency, overloaded)
+	}
+}
+
+// adaptiveBounds returns the minimum, initial and maximum adaptive limits, filling in defaults
+// that fit the bounds which are configured
+func adaptiveBounds(config *types.AdaptiveConcurrencyConfig) (int, int, int) {
+	minLimit := config.MinLimit
+	if minLimit == 0 {
+		minLimit = DefaultAdaptiveMinLimit
+	}
+	maxLimit := config.MaxLimit
+	if maxLimit == 0 {
+		maxLimit = max(DefaultAdaptiveMaxLimit, minLimit)
+	}
+	initialLimit := config.InitialLimit
+	if initialLimit == 0 {
+		initialLimit = min(max(DefaultAdaptiveInitialLimit, minLimit), maxLimit)
+	}
+	return minLimit, initialLimit, maxLimit
+}
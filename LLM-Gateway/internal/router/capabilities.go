@@ -0,0 +1,73 @@
+// Package router implements capability-aware candidate filtering
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// CapabilitiesFunc returns a provider's declared capabilities for a model, or nil when undeclared
+type CapabilitiesFunc func(provider types.Provider, model string) *types.ModelCapabilities
+
+// CapabilityError is returned when no provider serving a model supports every feature a request uses
+type CapabilityError struct {
+	Model   string
+	Missing []string // Capabilities lacking on the candidate that came closest
+}
+
+func (e *CapabilityError) Error() string {
+	return fmt.Sprintf("No provider serving model %s supports %s", e.Model, strings.Join(e.Missing, ", "))
+}
+
+// FilterByCapabilities returns the candidates whose model supports every optional feature the request
+// uses, keeping their order. Providers that declare nothing for the model are assumed to support it.
+func FilterByCapabilities(req *types.ChatCompletionRequest, candidates []types.Provider, capabilities CapabilitiesFunc) ([]types.Provider, error) {
+	required := req.RequiredCapabilities()
+	if required == (types.ModelCapabilities{}) {
+		return candidates, nil
+	}
+
+	supported := make([]types.Provider, 0, len(candidates))
+	var closest []string
+	for _, provider := range candidates {
+		declared := capabilities(provider, req.Model)
+		if declared == nil {
+			supported = append(supported, provider)
+			continue
+		}
+
+		missing := declared.Missing(required)
+		if len(missing) == 0 {
+			supported = append(supported, provider)
+		} else if closest == nil || len(missing) < len(closest) {
+			closest = missing
+		}
+	}
+
+	if len(supported) == 0 {
+		return nil, &CapabilityError{Model: req.Model, Missing: closest}
+	}
+	return supported, nil
+}
+
+// DeclaredCapabilities returns a provider's capabilities for a model: the configured model spec
+// when it declares them, otherwise the provider's GetModels entry
+func DeclaredCapabilities(ctx context.Context, provider types.Provider, model string) *types.ModelCapabilities {
+	if spec, ok := provider.GetConfig().ModelSpec(model); ok && spec.Capabilities != nil {
+		return spec.Capabilities
+	}
+
+	models, err := provider.GetModels(ctx)
+	if err != nil {
+		return nil
+	}
+	for _, listed := range models {
+		if listed.Name == model {
+			return listed.Capabilities
+		}
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+// Package router implements per-provider concurrency limits with a priority admission queue
+package router
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// DefaultAdmissionQueue is the number of requests that may wait for each concurrency limit
+	DefaultAdmissionQueue = 100
+
+	// DefaultQueueTimeout is how long a request waits for a concurrency slot before overflowing
+	DefaultQueueTimeout = 5 * time.Second
+
+	// saturationHeadroom is the share of a concurrency limit past which a provider is deprioritized
+	saturationHeadroom = 0.8
+)
+
+var (
+	// ErrProviderSaturated is returned for a request that got no concurrency slot at a provider
+	ErrProviderSaturated = errors.New("provider saturated")
+
+	errQueueFull    = errors.New("admission queue full")
+	errQueueTimeout = errors.New("timed out waiting for a concurrency slot")
+)
+
+// requestPriorityKey is the context key of a request's admission priority
+type requestPriorityKey struct{}
+
+// WithRequestPriority returns a context carrying the priority its request waits for concurrency slots with.
+// Higher priorities are admitted first; requests without one have priority 0.
+func WithRequestPriority(ctx context.Context, priority int) context.Context {
+	return context.WithValue(ctx, requestPriorityKey{}, priority)
+}
+
+// RequestPriorityFrom returns the admission priority carried by ctx
+func RequestPriorityFrom(ctx context.Context) int {
+	priority, _ := ctx.Value(requestPriorityKey{}).(int)
+	return priority
+}
+
+// ConcurrencyReport is the live state of one concurrency limit
+type ConcurrencyReport struct {
+	Key             string        `json:"key"` // Provider, or provider/model
+	Limit           int           `json:"limit"`
+	InFlight        int           `json:"in_flight"`
+	QueueDepth      int           `json:"queue_depth"`
+	Adaptive        bool          `json:"adaptive,omitempty"`
+	BaselineLatency time.Duration `json:"baseline_latency,omitempty"` // Latency the adaptive limit compares calls against
+}
+
+// admissionWaiter is a request queued for a concurrency slot
+type admissionWaiter struct {
+	priority int
+	seq      uint64
+	index    int // Position in the queue, -1 once granted or abandoned
+	granted  chan struct{}
+}
+
+// admissionQueue orders waiters by priority, then by arrival
+type admissionQueue []*admissionWaiter
+
+func (q admissionQueue) Len() int { return len(q) }
+
+func (q admissionQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q admissionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *admissionQueue) Push(x interface{}) {
+	waiter := x.(*admissionWaiter)
+	waiter.index = len(*q)
+	*q = append(*q, waiter)
+}
+
+func (q *admissionQueue) Pop() interface{} {
+	old := *q
+	waiter := old[len(old)-1]
+	old[len(old)-1] = nil
+	waiter.index = -1
+	*q = old[:len(old)-1]
+	return waiter
+}
+
+// concurrencyLimiter caps the requests in flight for a provider or provider+model.
+// Requests over the limit wait in a bounded priority queue for a slot to be released.
+// An adaptive limiter moves its limit with the outcomes of the calls it admits.
+type concurrencyLimiter struct {
+	mutex    sync.Mutex
+	limit    int
+	maxQueue int
+	inFlight int
+	queue    admissionQueue
+	seq      uint64
+	adaptive *aimdState
+}
+
+// acquire takes a slot, waiting up to timeout behind higher priority and earlier requests.
+// It returns how long the request waited and the queue depth it found, itself included.
+func (l *concurrencyLimiter) acquire(ctx context.Context, priority int, timeout time.Duration) (time.Duration, int, error) {
+	l.mutex.Lock()
+	if l.inFlight < l.limit && len(l.queue) == 0 {
+		l.inFlight++
+		l.mutex.Unlock()
+		return 0, 0, nil
+	}
+	if len(l.queue) >= l.maxQueue {
+		depth := len(l.queue)
+		l.mutex.Unlock()
+		return 0, depth, errQueueFull
+	}
+	l.seq++
+	waiter := &admissionWaiter{priority: priority, seq: l.seq, granted: make(chan struct{})}
+	heap.Push(&l.queue, waiter)
+	depth := len(l.queue)
+	l.mutex.Unlock()
+
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-waiter.granted:
+		return time.Since(start), depth, nil
+	case <-timer.C:
+		err = errQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if waiter.index < 0 {
+		// The slot was handed over while giving up, so it is ours to use or release
+		return time.Since(start), depth, nil
+	}
+	heap.Remove(&l.queue, waiter.index)
+	return time.Since(start), depth, err
+}
+
+// release frees a slot, handing it straight to the first waiter while the limit allows
+func (l *concurrencyLimiter) release() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if len(l.queue) > 0 && l.inFlight <= l.limit {
+		close(heap.Pop(&l.queue).(*admissionWaiter).granted)
+		return
+	}
+	l.inFlight--
+}
+
+// configure applies a limit's configuration. A static limit replaces the current one, while an
+// adaptive limit keeps its learned value within the configured bounds.
+func (l *concurrencyLimiter) configure(limit concurrencyLimit, maxQueue int) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.maxQueue = maxQueue
+	if limit.adaptive == nil {
+		l.adaptive = nil
+		l.setLimit(limit.limit)
+		return
+	}
+	if l.adaptive == nil {
+		l.adaptive = newAIMDState(limit.adaptive)
+	} else {
+		l.adaptive.reconfigure(limit.adaptive)
+	}
+	l.setLimit(l.adaptive.limit())
+}
+
+// setLimit changes the limit, admitting waiters when it grows. Requests over a lowered limit
+// finish normally; their slots are not handed on until the limit is respected again.
+// The caller must hold l.mutex.
+func (l *concurrencyLimiter) setLimit(limit int) {
+	l.limit = limit
+	for len(l.queue) > 0 && l.inFlight < l.limit {
+		l.inFlight++
+		close(heap.Pop(&l.queue).(*admissionWaiter).granted)
+	}
+}
+
+// utilization returns the share of the limit in use, counting queued requests
+func (l *concurrencyLimiter) utilization() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return float64(l.inFlight+len(l.queue)) / float64(l.limit)
+}
+
+// report returns the live state of the limiter
+func (l *concurrencyLimiter) report(key string) ConcurrencyReport {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	report := ConcurrencyReport{Key: key, Limit: l.limit, InFlight: l.inFlight, QueueDepth: len(l.queue)}
+	if l.adaptive != nil {
+		report.Adaptive = true
+		report.BaselineLatency = l.adaptive.baseline
+	}
+	return report
+}
+
+// concurrencyLimit is a configured limit that applies to a call
+type concurrencyLimit struct {
+	key      string
+	limit    int
+	adaptive *types.AdaptiveConcurrencyConfig // Set for a provider limited adaptively
+}
+
+// admit waits for a slot on every concurrency limit covering a call to provider for model: the
+// provider+model limit first, then the provider's. It returns the function releasing the slots and
+// the time spent queued. A call that gets no slot fails with a retryable error wrapping
+// ErrProviderSaturated, so failover overflows it to another provider.
+func (sr *SmartRouter) admit(ctx context.Context, provider, model string) (func(), time.Duration, error) {
+	limits, maxQueue, timeout := sr.concurrencyLimits(provider, model)
+	if len(limits) == 0 {
+		return func() {}, 0, nil
+	}
+
+	priority := RequestPriorityFrom(ctx)
+	deadline := time.Now().Add(timeout)
+	held := make([]*concurrencyLimiter, 0, len(limits))
+	releaseHeld := func() {
+		for _, limiter := range held {
+			limiter.release()
+		}
+	}
+
+	var queued time.Duration
+	for _, limit := range limits {
+		limiter := sr.limiterFor(limit, maxQueue)
+		wait, depth, err := limiter.acquire(ctx, priority, time.Until(deadline))
+		queued += wait
+		if sr.metricsCollector != nil {
+			sr.metricsCollector.RecordAdmission(limit.key, wait, err == nil, depth)
+		}
+
+		if err != nil {
+			releaseHeld()
+			saturated := retry.NewProviderRetryError(provider, "admission", types.ErrorRateLimit,
+				fmt.Sprintf("%s: %v", limit.key, err), true)
+			saturated.RetryAfter = int(math.Ceil(timeout.Seconds()))
+			saturated.OriginalError = fmt.Errorf("%w: %s: %v", ErrProviderSaturated, limit.key, err)
+			return nil, queued, saturated
+		}
+		held = append(held, limiter)
+	}
+
+	var once sync.Once
+	return func() { once.Do(releaseHeld) }, queued, nil
+}
+
+// leastSaturated returns the providers with the most room under their concurrency limits for model:
+// those below saturationHeadroom of every limit, else those with a free slot, else all of them so the
+// request queues rather than fails. Providers nearing their limit are passed over before they fill up.
+func (sr *SmartRouter) leastSaturated(providers []types.Provider, model string) []types.Provider {
+	roomy := make([]types.Provider, 0, len(providers))
+	free := make([]types.Provider, 0, len(providers))
+	for _, provider := range providers {
+		utilization := sr.utilization(provider.GetName(), model)
+		if utilization < saturationHeadroom {
+			roomy = append(roomy, provider)
+		}
+		if utilization < 1 {
+			free = append(free, provider)
+		}
+	}
+
+	switch {
+	case len(roomy) > 0:
+		return roomy
+	case len(free) > 0:
+		return free
+	default:
+		return providers
+	}
+}
+
+// utilization returns the highest share in use of the concurrency limits covering provider for model
+func (sr *SmartRouter) utilization(provider, model string) float64 {
+	limits, _, _ := sr.concurrencyLimits(provider, model)
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+	highest := 0.0
+	for _, limit := range limits {
+		if limiter, exists := sr.limiters[limit.key]; exists {
+			highest = math.Max(highest, limiter.utilization())
+		}
+	}
+	return highest
+}
+
+// ConcurrencyReports returns the live state of every concurrency limit that has seen a request
+func (sr *SmartRouter) ConcurrencyReports() []ConcurrencyReport {
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	reports := make([]ConcurrencyReport, 0, len(sr.limiters))
+	for key, limiter := range sr.limiters {
+		reports = append(reports, limiter.report(key))
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Key < reports[j].Key })
+	return reports
+}
+
+// TierPriority returns the admission priority of requests made with an API key: the priority of the
+// named tier when the key's record has one, else of the first tier listing the key, else 0
+func (sr *SmartRouter) TierPriority(apiKey, tier string) int {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	if sr.config.Concurrency == nil {
+		return 0
+	}
+	for _, candidate := range sr.config.Concurrency.Tiers {
+		if (tier != "" && candidate.Name == tier) || (tier == "" && apiKey != "" && containsString(candidate.APIKeys, apiKey)) {
+			return candidate.Priority
+		}
+	}
+	return 0
+}
+
+// concurrencyLimits returns the limits covering a call to provider for model, the provider+model
+// limit first, along with the queue bound and timeout
+func (sr *SmartRouter) concurrencyLimits(provider, model string) ([]concurrencyLimit, int, time.Duration) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	concurrency := sr.config.Concurrency
+	if concurrency == nil {
+		return nil, 0, 0
+	}
+
+	var limits []concurrencyLimit
+	for _, limit := range concurrency.Limits {
+		if limit.Provider == provider && limit.Model != "" && limit.Model == model {
+			limits = append(limits, concurrencyLimit{key: providerModelKey(provider, model), limit: limit.MaxInFlight})
+		}
+	}
+	static := false
+	for _, limit := range concurrency.Limits {
+		if limit.Provider == provider && limit.Model == "" {
+			limits = append(limits, concurrencyLimit{key: providerModelKey(provider, ""), limit: limit.MaxInFlight})
+			static = true
+		}
+	}
+	if adaptive := concurrency.Adaptive; adaptive != nil && !static &&
+		(len(adaptive.Providers) == 0 || containsString(adaptive.Providers, provider)) {
+		limits = append(limits, concurrencyLimit{key: providerModelKey(provider, ""), adaptive: adaptive})
+	}
+
+	maxQueue := concurrency.MaxQueue
+	if maxQueue == 0 {
+		maxQueue = DefaultAdmissionQueue
+	}
+	timeout := concurrency.QueueTimeout
+	if timeout == 0 {
+		timeout = DefaultQueueTimeout
+	}
+	return limits, maxQueue, timeout
+}
+
+// limiterFor returns the limiter of a concurrency limit, creating it on first use and
+// applying configuration changes
+func (sr *SmartRouter) limiterFor(limit concurrencyLimit, maxQueue int) *concurrencyLimiter {
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	limiter, exists := sr.limiters[limit.key]
+	if !exists {
+		limiter = &concurrencyLimiter{}
+		sr.limiters[limit.key] = limiter
+	}
+	limiter.configure(limit, maxQueue)
+	return limiter
+}
+
+// providerModelKey identifies a provider, or one of its models, for per-model limits and breakers
+func providerModelKey(provider, model string) string {
+	if model == "" {
+		return provider
+	}
+	return provider + "/" + model
+}
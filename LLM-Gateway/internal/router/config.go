@@ -4,6 +4,8 @@ package router
 import (
 	"fmt"
 	"time"
+
+	"github.com/llm-gateway/gateway/pkg/types"
 )
 
 // DefaultSmartRouterConfig returns a default router configuration
@@ -13,6 +15,7 @@ func DefaultSmartRouterConfig() *SmartRouterConfig {
 		HealthCheckInterval: 30 * time.Second,
 		FailoverEnabled:     true,
 		MaxRetries:          3,
+		FailoverTimeout:     60 * time.Second,
 		Weights:             make(map[string]int),
 		CircuitBreaker: CircuitBreakerConfig{
 			Enabled:     true,
@@ -48,6 +51,8 @@ func (c *SmartRouterConfig) ValidateConfig() error {
 		"health_based":         true,
 		"least_latency":        true,
 		"cost_optimized":       true,
+		"peak_ewma":            true,
+		"prefix_affinity":      true,
 		"random":               true,
 	}
 
@@ -63,6 +68,10 @@ func (c *SmartRouterConfig) ValidateConfig() error {
 		return fmt.Errorf("max retries cannot be negative")
 	}
 
+	if c.FailoverTimeout < 0 {
+		return fmt.Errorf("failover timeout cannot be negative")
+	}
+
 	// Validate circuit breaker config
 	if c.CircuitBreaker.Enabled {
 		if c.CircuitBreaker.Threshold <= 0 {
@@ -74,6 +83,15 @@ func (c *SmartRouterConfig) ValidateConfig() error {
 		if c.CircuitBreaker.MaxRequests <= 0 {
 			return fmt.Errorf("circuit breaker max requests must be positive")
 		}
+		if c.CircuitBreaker.FailureRate < 0 || c.CircuitBreaker.FailureRate > 1 {
+			return fmt.Errorf("circuit breaker failure rate must be between 0 and 1")
+		}
+		if c.CircuitBreaker.MinRequests < 0 || c.CircuitBreaker.Window < 0 || c.CircuitBreaker.WindowSize < 0 {
+			return fmt.Errorf("circuit breaker window settings cannot be negative")
+		}
+		if settings := effectiveBreakerConfig(c.CircuitBreaker); settings.MinRequests > settings.WindowSize {
+			return fmt.Errorf("circuit breaker min requests (%d) cannot exceed the window size (%d)", settings.MinRequests, settings.WindowSize)
+		}
 	}
 
 	// Validate weights
@@ -83,6 +101,300 @@ func (c *SmartRouterConfig) ValidateConfig() error {
 		}
 	}
 
+	// Validate virtual models
+	for alias, virtual := range c.VirtualModels {
+		if err := validateVirtualModel(alias, virtual); err != nil {
+			return err
+		}
+	}
+
+	for model, hedging := range c.Hedging {
+		if err := validateHedging(model, hedging); err != nil {
+			return err
+		}
+	}
+
+	if c.CostOptimized != nil {
+		if c.CostOptimized.LatencyTolerance < 0 {
+			return fmt.Errorf("cost optimized latency tolerance cannot be negative")
+		}
+		if c.CostOptimized.QualityTolerance < 0 || c.CostOptimized.QualityTolerance > 1 {
+			return fmt.Errorf("cost optimized quality tolerance must be in [0, 1]")
+		}
+	}
+
+	if c.PrefixAffinity != nil {
+		if c.PrefixAffinity.PrefixMessages < 0 {
+			return fmt.Errorf("prefix affinity prefix messages cannot be negative")
+		}
+		if c.PrefixAffinity.LoadFactor != 0 && c.PrefixAffinity.LoadFactor <= 1 {
+			return fmt.Errorf("prefix affinity load factor must be greater than 1")
+		}
+		switch c.PrefixAffinity.Hash {
+		case "", types.PrefixAffinityConsistent, types.PrefixAffinityRendezvous:
+		default:
+			return fmt.Errorf("invalid prefix affinity hash: %s", c.PrefixAffinity.Hash)
+		}
+	}
+
+	for model, larger := range c.ContextUpgrades {
+		if larger == "" || larger == model {
+			return fmt.Errorf("context upgrade for %s must name another model", model)
+		}
+	}
+
+	names := make(map[string]bool, len(c.Experiments))
+	for i := range c.Experiments {
+		if err := validateExperiment(&c.Experiments[i]); err != nil {
+			return err
+		}
+		if names[c.Experiments[i].Name] {
+			return fmt.Errorf("duplicate experiment: %s", c.Experiments[i].Name)
+		}
+		names[c.Experiments[i].Name] = true
+	}
+
+	shadowed := make(map[string]bool, len(c.Shadows))
+	for _, shadow := range c.Shadows {
+		if err := validateShadow(shadow); err != nil {
+			return err
+		}
+		if shadowed[shadow.Model] {
+			return fmt.Errorf("duplicate shadow for model %s", shadow.Model)
+		}
+		shadowed[shadow.Model] = true
+	}
+
+	policies := make(map[string]bool, len(c.Policies))
+	bound := make(map[string]string)
+	for _, policy := range c.Policies {
+		if policy.Name == "" {
+			return fmt.Errorf("routing policy needs a name")
+		}
+		if policies[policy.Name] {
+			return fmt.Errorf("duplicate routing policy: %s", policy.Name)
+		}
+		policies[policy.Name] = true
+
+		// A user or key bound to two policies would get whichever is listed first
+		for _, user := range policy.Users {
+			if other, exists := bound["user:"+user]; exists {
+				return fmt.Errorf("user %s is bound to both routing policies %s and %s", user, other, policy.Name)
+			}
+			bound["user:"+user] = policy.Name
+		}
+		for _, key := range policy.APIKeys {
+			if other, exists := bound["key:"+key]; exists {
+				return fmt.Errorf("an API key is bound to both routing policies %s and %s", other, policy.Name)
+			}
+			bound["key:"+key] = policy.Name
+		}
+	}
+	if c.DefaultPolicy != "" && !policies[c.DefaultPolicy] {
+		return fmt.Errorf("default routing policy %s is not configured", c.DefaultPolicy)
+	}
+
+	if c.Concurrency != nil {
+		if err := validateConcurrency(c.Concurrency); err != nil {
+			return err
+		}
+	}
+
+	if c.OutlierDetection != nil {
+		if err := validateOutlierDetection(c.OutlierDetection); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateOutlierDetection validates passive outlier detection settings
+func validateOutlierDetection(outliers *types.OutlierDetectionConfig) error {
+	if outliers.ConsecutiveErrors < 0 {
+		return fmt.Errorf("outlier detection consecutive errors cannot be negative")
+	}
+	if outliers.LatencyMultiplier != 0 && outliers.LatencyMultiplier <= 1 {
+		return fmt.Errorf("outlier detection latency multiplier must be greater than 1")
+	}
+	if outliers.BaseEjectionTime < 0 || outliers.MaxEjectionTime < 0 {
+		return fmt.Errorf("outlier detection ejection times cannot be negative")
+	}
+	if settings := effectiveOutlierConfig(outliers); settings.MaxEjectionTime < settings.BaseEjectionTime {
+		return fmt.Errorf("outlier detection max ejection time (%v) cannot be shorter than the base ejection time (%v)",
+			settings.MaxEjectionTime, settings.BaseEjectionTime)
+	}
+	if outliers.MaxEjectionPercent < 0 || outliers.MaxEjectionPercent > 100 {
+		return fmt.Errorf("outlier detection max ejection percent must be in [0, 100]")
+	}
+
+	return nil
+}
+
+// validateConcurrency validates concurrency limits and priority tiers
+func validateConcurrency(concurrency *types.ConcurrencyConfig) error {
+	if concurrency.MaxQueue < 0 {
+		return fmt.Errorf("concurrency max queue cannot be negative")
+	}
+	if concurrency.QueueTimeout < 0 {
+		return fmt.Errorf("concurrency queue timeout cannot be negative")
+	}
+
+	limited := make(map[string]bool, len(concurrency.Limits))
+	for _, limit := range concurrency.Limits {
+		if limit.Provider == "" {
+			return fmt.Errorf("concurrency limit needs a provider")
+		}
+		if limit.MaxInFlight <= 0 {
+			return fmt.Errorf("concurrency limit for %s must allow at least one request in flight", providerModelKey(limit.Provider, limit.Model))
+		}
+		if limited[providerModelKey(limit.Provider, limit.Model)] {
+			return fmt.Errorf("duplicate concurrency limit for %s", providerModelKey(limit.Provider, limit.Model))
+		}
+		limited[providerModelKey(limit.Provider, limit.Model)] = true
+	}
+
+	tiers := make(map[string]bool, len(concurrency.Tiers))
+	tiered := make(map[string]string)
+	for _, tier := range concurrency.Tiers {
+		if tier.Name == "" {
+			return fmt.Errorf("priority tier needs a name")
+		}
+		if tiers[tier.Name] {
+			return fmt.Errorf("duplicate priority tier: %s", tier.Name)
+		}
+		tiers[tier.Name] = true
+		for _, key := range tier.APIKeys {
+			if other, exists := tiered[key]; exists {
+				return fmt.Errorf("an API key is in both priority tiers %s and %s", other, tier.Name)
+			}
+			tiered[key] = tier.Name
+		}
+	}
+
+	if adaptive := concurrency.Adaptive; adaptive != nil {
+		if adaptive.InitialLimit < 0 || adaptive.MinLimit < 0 || adaptive.MaxLimit < 0 {
+			return fmt.Errorf("adaptive concurrency limits cannot be negative")
+		}
+		minLimit, initialLimit, maxLimit := adaptiveBounds(adaptive)
+		if minLimit > initialLimit || initialLimit > maxLimit {
+			return fmt.Errorf("adaptive concurrency needs min_limit <= initial_limit <= max_limit")
+		}
+		if adaptive.Backoff < 0 || adaptive.Backoff >= 1 {
+			return fmt.Errorf("adaptive concurrency backoff must be in (0, 1)")
+		}
+		if adaptive.Tolerance != 0 && adaptive.Tolerance <= 1 {
+			return fmt.Errorf("adaptive concurrency tolerance must be greater than 1")
+		}
+		for _, provider := range adaptive.Providers {
+			if limited[provider] {
+				return fmt.Errorf("provider %s has both a static and an adaptive concurrency limit", provider)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateShadow validates a shadow traffic target
+func validateShadow(shadow types.ShadowConfig) error {
+	if shadow.Model == "" || shadow.Provider == "" {
+		return fmt.Errorf("shadow needs both model and provider")
+	}
+	if shadow.SampleRate <= 0 || shadow.SampleRate > 1 {
+		return fmt.Errorf("shadow sample rate for %s must be in (0, 1]", shadow.Model)
+	}
+	if shadow.MaxConcurrent < 0 {
+		return fmt.Errorf("shadow max concurrent for %s cannot be negative", shadow.Model)
+	}
+
+	return nil
+}
+
+// validateExperiment validates an experiment's variants and schedule
+func validateExperiment(experiment *types.ExperimentConfig) error {
+	if experiment.Name == "" || experiment.Model == "" {
+		return fmt.Errorf("experiment needs both name and model")
+	}
+
+	switch experiment.StickyBy {
+	case "", types.ExperimentStickyUser, types.ExperimentStickyAPIKey:
+	default:
+		return fmt.Errorf("invalid sticky_by for experiment %s: %s", experiment.Name, experiment.StickyBy)
+	}
+
+	start, end, err := experimentWindow(experiment)
+	if err != nil {
+		return err
+	}
+	if !start.IsZero() && !end.IsZero() && !end.After(start) {
+		return fmt.Errorf("experiment %s must end after it starts", experiment.Name)
+	}
+
+	if len(experiment.Variants) == 0 {
+		return fmt.Errorf("experiment %s must have at least one variant", experiment.Name)
+	}
+	variants := make(map[string]bool, len(experiment.Variants))
+	totalWeight := 0
+	for i, variant := range experiment.Variants {
+		if variant.Name == "" || variant.Model == "" {
+			return fmt.Errorf("experiment %s variant %d needs both name and model", experiment.Name, i)
+		}
+		if variants[variant.Name] {
+			return fmt.Errorf("experiment %s has duplicate variant %s", experiment.Name, variant.Name)
+		}
+		variants[variant.Name] = true
+		if variant.Weight < 0 {
+			return fmt.Errorf("experiment %s variant %s weight cannot be negative", experiment.Name, variant.Name)
+		}
+		totalWeight += variant.Weight
+	}
+	if totalWeight == 0 {
+		return fmt.Errorf("experiment %s needs a variant with positive weight", experiment.Name)
+	}
+
+	return nil
+}
+
+// validateVirtualModel validates a virtual model target chain
+func validateVirtualModel(alias string, virtual *types.VirtualModelConfig) error {
+	if virtual == nil || len(virtual.Targets) == 0 {
+		return fmt.Errorf("virtual model %s must have at least one target", alias)
+	}
+
+	switch virtual.Strategy {
+	case "", types.VirtualModelOrdered, types.VirtualModelWeighted:
+	default:
+		return fmt.Errorf("invalid strategy for virtual model %s: %s", alias, virtual.Strategy)
+	}
+
+	for i, target := range virtual.Targets {
+		if target.Provider == "" || target.Model == "" {
+			return fmt.Errorf("virtual model %s target %d needs both provider and model", alias, i)
+		}
+		if target.Weight < 0 {
+			return fmt.Errorf("virtual model %s target %d weight cannot be negative", alias, i)
+		}
+	}
+
+	return nil
+}
+
+// validateHedging validates a hedged request policy
+func validateHedging(model string, hedging *types.HedgingConfig) error {
+	if hedging == nil {
+		return fmt.Errorf("hedging for %s cannot be empty", model)
+	}
+	if hedging.Delay < 0 {
+		return fmt.Errorf("hedging delay for %s cannot be negative", model)
+	}
+	if hedging.MinSamples < 0 {
+		return fmt.Errorf("hedging min samples for %s cannot be negative", model)
+	}
+	if hedging.BudgetRatio <= 0 || hedging.BudgetRatio > 1 {
+		return fmt.Errorf("hedging budget ratio for %s must be in (0, 1]", model)
+	}
+
 	return nil
 }
 
@@ -108,6 +420,12 @@ func (c *HealthCheckConfig) ValidateHealthCheckConfig() error {
 		return fmt.Errorf("success threshold must be positive")
 	}
 
+	if c.OutlierDetection != nil {
+		if err := validateOutlierDetection(c.OutlierDetection); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -118,9 +436,11 @@ func (c *SmartRouterConfig) Clone() *SmartRouterConfig {
 		HealthCheckInterval: c.HealthCheckInterval,
 		FailoverEnabled:     c.FailoverEnabled,
 		MaxRetries:          c.MaxRetries,
+		FailoverTimeout:     c.FailoverTimeout,
 		CircuitBreaker:      c.CircuitBreaker,
 		MetricsEnabled:      c.MetricsEnabled,
 		Weights:             make(map[string]int),
+		DefaultPolicy:       c.DefaultPolicy,
 	}
 
 	// Copy weights map
@@ -128,16 +448,116 @@ func (c *SmartRouterConfig) Clone() *SmartRouterConfig {
 		clone.Weights[k] = v
 	}
 
+	// Copy virtual models; target slices are copied so callers can't mutate the chain
+	if c.VirtualModels != nil {
+		clone.VirtualModels = make(map[string]*types.VirtualModelConfig, len(c.VirtualModels))
+		for alias, virtual := range c.VirtualModels {
+			if virtual == nil {
+				clone.VirtualModels[alias] = nil
+				continue
+			}
+			virtualClone := *virtual
+			virtualClone.Targets = append([]types.VirtualModelTarget(nil), virtual.Targets...)
+			clone.VirtualModels[alias] = &virtualClone
+		}
+	}
+
+	if c.Hedging != nil {
+		clone.Hedging = make(map[string]*types.HedgingConfig, len(c.Hedging))
+		for model, hedging := range c.Hedging {
+			if hedging == nil {
+				clone.Hedging[model] = nil
+				continue
+			}
+			hedgingClone := *hedging
+			clone.Hedging[model] = &hedgingClone
+		}
+	}
+
+	if c.CostOptimized != nil {
+		costClone := *c.CostOptimized
+		if c.CostOptimized.Quality != nil {
+			costClone.Quality = make(map[string]float64, len(c.CostOptimized.Quality))
+			for provider, quality := range c.CostOptimized.Quality {
+				costClone.Quality[provider] = quality
+			}
+		}
+		clone.CostOptimized = &costClone
+	}
+
+	if c.PrefixAffinity != nil {
+		affinityClone := *c.PrefixAffinity
+		clone.PrefixAffinity = &affinityClone
+	}
+
+	if c.Experiments != nil {
+		clone.Experiments = make([]types.ExperimentConfig, len(c.Experiments))
+		for i, experiment := range c.Experiments {
+			experiment.Variants = append([]types.ExperimentVariant(nil), experiment.Variants...)
+			clone.Experiments[i] = experiment
+		}
+	}
+
+	if c.Shadows != nil {
+		clone.Shadows = append([]types.ShadowConfig(nil), c.Shadows...)
+	}
+
+	if c.Concurrency != nil {
+		concurrencyClone := *c.Concurrency
+		concurrencyClone.Limits = append([]types.ConcurrencyLimit(nil), c.Concurrency.Limits...)
+		concurrencyClone.Tiers = make([]types.PriorityTier, len(c.Concurrency.Tiers))
+		for i, tier := range c.Concurrency.Tiers {
+			tier.APIKeys = append([]string(nil), tier.APIKeys...)
+			concurrencyClone.Tiers[i] = tier
+		}
+		if c.Concurrency.Adaptive != nil {
+			adaptiveClone := *c.Concurrency.Adaptive
+			adaptiveClone.Providers = append([]string(nil), c.Concurrency.Adaptive.Providers...)
+			concurrencyClone.Adaptive = &adaptiveClone
+		}
+		clone.Concurrency = &concurrencyClone
+	}
+
+	if c.OutlierDetection != nil {
+		outliersClone := *c.OutlierDetection
+		clone.OutlierDetection = &outliersClone
+	}
+
+	if c.Policies != nil {
+		clone.Policies = make([]types.RoutingPolicyConfig, len(c.Policies))
+		for i, policy := range c.Policies {
+			policy.Users = append([]string(nil), policy.Users...)
+			policy.APIKeys = append([]string(nil), policy.APIKeys...)
+			policy.AllowedProviders = append([]string(nil), policy.AllowedProviders...)
+			policy.AllowedModels = append([]string(nil), policy.AllowedModels...)
+			policy.ForbiddenProviders = append([]string(nil), policy.ForbiddenProviders...)
+			policy.Residency = append([]string(nil), policy.Residency...)
+			clone.Policies[i] = policy
+		}
+	}
+
+	if c.ContextUpgrades != nil {
+		clone.ContextUpgrades = make(map[string]string, len(c.ContextUpgrades))
+		for model, larger := range c.ContextUpgrades {
+			clone.ContextUpgrades[model] = larger
+		}
+	}
+
 	return clone
 }
 
 // Clone creates a deep copy of the health check configuration
 func (c *HealthCheckConfig) Clone() *HealthCheckConfig {
-	return &HealthCheckConfig{
+	clone := &HealthCheckConfig{
 		Interval:         c.Interval,
 		Timeout:          c.Timeout,
 		FailureThreshold: c.FailureThreshold,
 		SuccessThreshold: c.SuccessThreshold,
 		Path:             c.Path,
 	}
+	if c.OutlierDetection != nil {
+		outliersClone := *c.OutlierDetection
+		clone.OutlierDetection = &outliersClone
+	}
+	return clone
 }
@@ -0,0 +1,80 @@
+// Package router implements context-window-aware candidate filtering
+package router
+
+import (
+	"fmt"
+
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// contextEstimator estimates prompt tokens; its rules are read-only after construction
+var contextEstimator = cost.NewTokenEstimator()
+
+// ContextWindowFunc returns a provider's context window in tokens for a model, or 0 when unknown
+type ContextWindowFunc func(provider types.Provider, model string) int
+
+// ContextWindowError is returned when a request's prompt and completion don't fit the
+// context window of any provider serving its model
+type ContextWindowError struct {
+	Model            string
+	ContextLength    int // Largest window among the candidates
+	PromptTokens     int // Estimated
+	CompletionTokens int // Requested max_tokens
+}
+
+func (e *ContextWindowError) Error() string {
+	return fmt.Sprintf("This model's maximum context length is %d tokens. However, you requested %d tokens "+
+		"(%d in the messages, %d in the completion). Please reduce the length of the messages or completion.",
+		e.ContextLength, e.PromptTokens+e.CompletionTokens, e.PromptTokens, e.CompletionTokens)
+}
+
+// FitContextWindow returns the candidates whose context window for req.Model holds the estimated
+// prompt plus max_tokens, keeping their order. Prompts are estimated with each provider's own
+// tokenization rules, and providers with an unknown window are assumed to fit.
+func FitContextWindow(req *types.Request, candidates []types.Provider, window ContextWindowFunc) ([]types.Provider, error) {
+	chatReq := req.ToChatCompletionRequest()
+	completionTokens := 0
+	if req.MaxTokens > 0 {
+		completionTokens = req.MaxTokens
+	}
+
+	fitting := make([]types.Provider, 0, len(candidates))
+	exceeded := &ContextWindowError{Model: req.Model, CompletionTokens: completionTokens}
+	for _, provider := range candidates {
+		contextLength := window(provider, req.Model)
+		if contextLength <= 0 {
+			fitting = append(fitting, provider)
+			continue
+		}
+
+		estimate, err := contextEstimator.EstimateTokens(chatReq, provider.GetType())
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate prompt tokens: %w", err)
+		}
+		if estimate.InputTokens+completionTokens <= contextLength {
+			fitting = append(fitting, provider)
+			continue
+		}
+
+		// Report the largest window the request missed
+		if contextLength > exceeded.ContextLength {
+			exceeded.ContextLength = contextLength
+			exceeded.PromptTokens = estimate.InputTokens
+		}
+	}
+
+	if len(fitting) == 0 {
+		return nil, exceeded
+	}
+	return fitting, nil
+}
+
+// ContextUpgrade returns the larger sibling configured to serve prompts too long for model
+func (sr *SmartRouter) ContextUpgrade(model string) (string, bool) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	larger, exists := sr.config.ContextUpgrades[model]
+	return larger, exists
+}
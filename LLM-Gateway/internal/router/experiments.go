@@ -0,0 +1,221 @@
+// Package router implements traffic splitting experiments between variant models
+package router
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// ExperimentIdentity is who a request comes from; the experiment's sticky_by picks which part is hashed
+type ExperimentIdentity struct {
+	UserID    string
+	APIKey    string
+	RequestID string // Used when the caller is anonymous, so each request is assigned independently
+}
+
+// ExperimentAssignment is the variant of an experiment a request was assigned to
+type ExperimentAssignment struct {
+	Experiment string `json:"experiment"`
+	Variant    string `json:"variant"`
+	Model      string `json:"model"` // The variant's model, which replaces the requested one
+}
+
+// ExperimentOutcome is the result of serving one request assigned to a variant
+type ExperimentOutcome struct {
+	Latency time.Duration
+	Success bool
+	Usage   types.Usage
+	Cost    float64 // USD
+}
+
+// variantStats accumulates the outcomes of one variant
+type variantStats struct {
+	requests         int64
+	errors           int64
+	totalLatency     time.Duration
+	cost             float64
+	promptTokens     int64
+	completionTokens int64
+}
+
+// ExperimentReport summarizes an experiment's variants for comparison
+type ExperimentReport struct {
+	Name     string          `json:"name"`
+	Model    string          `json:"model"`
+	Active   bool            `json:"active"`
+	Start    string          `json:"start,omitempty"`
+	End      string          `json:"end,omitempty"`
+	Variants []VariantReport `json:"variants"`
+}
+
+// VariantReport is the latency, error rate, cost and token usage observed for a variant
+type VariantReport struct {
+	Name             string  `json:"name"`
+	Model            string  `json:"model"`
+	Weight           int     `json:"weight"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	ErrorRate        float64 `json:"error_rate"`
+	AvgLatencyMs     float64 `json:"avg_latency_ms"`
+	TotalCost        float64 `json:"total_cost"`
+	AvgCost          float64 `json:"avg_cost"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+	TotalTokens      int64   `json:"total_tokens"`
+}
+
+// AssignExperiment assigns a request for model to a variant of the first experiment running on it.
+// The same identity always lands on the same variant while the weights are unchanged.
+func (sr *SmartRouter) AssignExperiment(model string, identity ExperimentIdentity, now time.Time) (*ExperimentAssignment, bool) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	for i := range sr.config.Experiments {
+		experiment := &sr.config.Experiments[i]
+		if experiment.Model != model || !experimentActive(experiment, now) {
+			continue
+		}
+
+		variant := pickVariant(experiment, stickyKey(experiment, identity))
+		return &ExperimentAssignment{
+			Experiment: experiment.Name,
+			Variant:    variant.Name,
+			Model:      variant.Model,
+		}, true
+	}
+	return nil, false
+}
+
+// RecordExperimentOutcome adds the outcome of a request to its variant's statistics
+func (sr *SmartRouter) RecordExperimentOutcome(assignment *ExperimentAssignment, outcome ExperimentOutcome) {
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	key := variantKey(assignment.Experiment, assignment.Variant)
+	stats, exists := sr.experimentStats[key]
+	if !exists {
+		stats = &variantStats{}
+		sr.experimentStats[key] = stats
+	}
+
+	stats.requests++
+	if !outcome.Success {
+		stats.errors++
+	}
+	stats.totalLatency += outcome.Latency
+	stats.cost += outcome.Cost
+	stats.promptTokens += int64(outcome.Usage.PromptTokens)
+	stats.completionTokens += int64(outcome.Usage.CompletionTokens)
+}
+
+// ExperimentReports returns the statistics of every configured experiment, in configuration order
+func (sr *SmartRouter) ExperimentReports() []ExperimentReport {
+	sr.mutex.RLock()
+	experiments := make([]types.ExperimentConfig, len(sr.config.Experiments))
+	copy(experiments, sr.config.Experiments)
+	sr.mutex.RUnlock()
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	now := time.Now()
+	reports := make([]ExperimentReport, 0, len(experiments))
+	for i := range experiments {
+		experiment := &experiments[i]
+		report := ExperimentReport{
+			Name:     experiment.Name,
+			Model:    experiment.Model,
+			Active:   experimentActive(experiment, now),
+			Start:    experiment.Start,
+			End:      experiment.End,
+			Variants: make([]VariantReport, 0, len(experiment.Variants)),
+		}
+
+		for _, variant := range experiment.Variants {
+			variantReport := VariantReport{Name: variant.Name, Model: variant.Model, Weight: variant.Weight}
+			if stats, exists := sr.experimentStats[variantKey(experiment.Name, variant.Name)]; exists && stats.requests > 0 {
+				variantReport.Requests = stats.requests
+				variantReport.Errors = stats.errors
+				variantReport.ErrorRate = float64(stats.errors) / float64(stats.requests)
+				variantReport.AvgLatencyMs = float64(stats.totalLatency) / float64(time.Millisecond) / float64(stats.requests)
+				variantReport.TotalCost = stats.cost
+				variantReport.AvgCost = stats.cost / float64(stats.requests)
+				variantReport.PromptTokens = stats.promptTokens
+				variantReport.CompletionTokens = stats.completionTokens
+				variantReport.TotalTokens = stats.promptTokens + stats.completionTokens
+			}
+			report.Variants = append(report.Variants, variantReport)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// experimentActive reports whether now falls within the experiment's start and end times
+func experimentActive(experiment *types.ExperimentConfig, now time.Time) bool {
+	start, end, err := experimentWindow(experiment)
+	if err != nil {
+		return false
+	}
+	return (start.IsZero() || !now.Before(start)) && (end.IsZero() || now.Before(end))
+}
+
+// experimentWindow parses the experiment's start and end times; either may be zero
+func experimentWindow(experiment *types.ExperimentConfig) (time.Time, time.Time, error) {
+	var start, end time.Time
+	var err error
+	if experiment.Start != "" {
+		if start, err = time.Parse(time.RFC3339, experiment.Start); err != nil {
+			return start, end, fmt.Errorf("experiment %s start: %w", experiment.Name, err)
+		}
+	}
+	if experiment.End != "" {
+		if end, err = time.Parse(time.RFC3339, experiment.End); err != nil {
+			return start, end, fmt.Errorf("experiment %s end: %w", experiment.Name, err)
+		}
+	}
+	return start, end, nil
+}
+
+// stickyKey returns the identity an experiment hashes, falling back to the other identity and then
+// the request ID when the preferred one is missing
+func stickyKey(experiment *types.ExperimentConfig, identity ExperimentIdentity) string {
+	switch {
+	case experiment.StickyBy == types.ExperimentStickyAPIKey && identity.APIKey != "":
+		return "key:" + identity.APIKey
+	case identity.UserID != "":
+		return "user:" + identity.UserID
+	case identity.APIKey != "":
+		return "key:" + identity.APIKey
+	default:
+		return "request:" + identity.RequestID
+	}
+}
+
+// pickVariant hashes key with the experiment name into the variants' cumulative weights,
+// so assignments in different experiments are independent
+func pickVariant(experiment *types.ExperimentConfig, key string) types.ExperimentVariant {
+	total := 0
+	for _, variant := range experiment.Variants {
+		total += variant.Weight
+	}
+
+	sum := sha256.Sum256([]byte(experiment.Name + "|" + key))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, variant := range experiment.Variants {
+		if bucket < variant.Weight {
+			return variant
+		}
+		bucket -= variant.Weight
+	}
+	return experiment.Variants[len(experiment.Variants)-1]
+}
+
+// variantKey identifies a variant's statistics
+func variantKey(experiment, variant string) string {
+	return experiment + "/" + variant
+}
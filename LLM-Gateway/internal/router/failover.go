@@ -0,0 +1,156 @@
+// Package router implements cross-provider failover for a single request
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// RoutingAttempt records one dispatch of a request to a provider
+type RoutingAttempt struct {
+	ProviderName string              `json:"provider_name"`
+	Latency      time.Duration       `json:"latency"`
+	Error        string              `json:"error,omitempty"`
+	Category     types.ErrorCategory `json:"category,omitempty"`
+	Retryable    bool                `json:"retryable,omitempty"`
+	Hedged       bool                `json:"hedged,omitempty"`       // Sent as a hedge against a slow attempt
+	Queued       time.Duration       `json:"queued,omitempty"`       // Time spent waiting for a concurrency slot
+	Saturated    bool                `json:"saturated,omitempty"`    // Never sent: no concurrency slot was free
+	CircuitOpen  bool                `json:"circuit_open,omitempty"` // Never sent: the circuit breaker refused it
+}
+
+// FailoverError is returned when no attempt of a failover dispatch succeeded
+type FailoverError struct {
+	Attempts []RoutingAttempt
+	Err      error
+}
+
+func (e *FailoverError) Error() string {
+	return fmt.Sprintf("request failed after %d attempt(s): %v", len(e.Attempts), e.Err)
+}
+
+func (e *FailoverError) Unwrap() error {
+	return e.Err
+}
+
+// ExecuteWithFailover routes req among candidates and sends it with call.
+// When a provider fails with a retryable error it is excluded and the request is re-routed
+// to the next best candidate, up to MaxRetries extra attempts within FailoverTimeout.
+// Non-retryable errors are returned immediately since another provider would fail the same way.
+// Hedging configured for the model races the next best candidate against a slow attempt.
+// Candidates the routing policy in ctx forbids are never tried, and candidates near their concurrency
+// limit are only chosen when every candidate is; a request that times out in their queue overflows.
+func (sr *SmartRouter) ExecuteWithFailover(ctx context.Context, req *types.Request, candidates []types.Provider, call ProviderCall) (*SmartRoutingResult, error) {
+	startTime := time.Now()
+
+	sr.mutex.RLock()
+	maxAttempts := 1
+	if sr.config.FailoverEnabled {
+		maxAttempts += sr.config.MaxRetries
+	}
+	timeout := sr.config.FailoverTimeout
+	remaining := make([]types.Provider, 0, len(candidates))
+	for _, candidate := range candidates {
+		if provider, exists := sr.providers[candidate.GetName()]; exists {
+			remaining = append(remaining, provider)
+		}
+	}
+	sr.mutex.RUnlock()
+
+	remaining, err := FilterByPolicy(RoutingPolicyFrom(ctx), req.Model, remaining)
+	if err != nil {
+		return nil, err
+	}
+	if len(remaining) == 0 {
+		return nil, ErrNoAvailableProvider
+	}
+
+	sr.depositHedgeBudget(req.Model)
+	scope := newAttemptScope(ctx, timeout)
+
+	var tried []RoutingAttempt
+	var errs []error
+	for len(tried) < maxAttempts && len(remaining) > 0 {
+		result, err := sr.route(req, sr.leastSaturated(remaining, req.Model))
+		if err != nil {
+			errs = append(errs, err)
+			break
+		}
+
+		primary := hedgeLeg{provider: result.Provider, req: req}
+		others := excludeProvider(remaining, result.ProviderName)
+		backup := func() (hedgeLeg, bool) {
+			if len(tried)+2 > maxAttempts || len(others) == 0 {
+				return hedgeLeg{}, false
+			}
+			hedge, err := sr.route(req, sr.leastSaturated(others, req.Model))
+			if err != nil {
+				return hedgeLeg{}, false
+			}
+			return hedgeLeg{provider: hedge.Provider, req: req}, true
+		}
+
+		winner, response, attempts, err := sr.dispatchHedged(scope.ctx, req.Model, primary, backup, call)
+		if err == nil {
+			scope.settle(true)
+			tried = append(tried, attempts...)
+			result.Provider = winner.provider
+			result.ProviderName = winner.provider.GetName()
+			result.Response = response
+			result.Attempts = len(tried)
+			result.BackupUsed = result.BackupUsed || result.ProviderName != tried[0].ProviderName
+			result.Tried = tried
+			result.SelectionTime = time.Since(startTime)
+			if len(tried) > 1 {
+				result.Reason = fmt.Sprintf("Served by %s after %d attempts", result.ProviderName, len(tried))
+			}
+			return result, nil
+		}
+
+		// Another provider is only worth trying if every failure here was retryable
+		retryable := true
+		for _, attempt := range attempts {
+			retryable = retryable && attempt.Retryable
+			remaining = excludeProvider(remaining, attempt.ProviderName)
+		}
+		tried = append(tried, attempts...)
+		errs = append(errs, err)
+
+		if !retryable || scope.ctx.Err() != nil {
+			break
+		}
+
+		sr.logger.WithError(err).
+			WithField("provider", result.ProviderName).
+			WithField("attempt", len(tried)).
+			Warn("Provider failed with retryable error, failing over")
+	}
+
+	scope.settle(false)
+	return nil, &FailoverError{Attempts: tried, Err: errors.Join(errs...)}
+}
+
+// classifyAttemptError keeps the classification a provider already attached to its error
+func classifyAttemptError(err error, providerName string) *retry.ProviderRetryError {
+	var retryErr *retry.ProviderRetryError
+	if errors.As(err, &retryErr) {
+		return retryErr
+	}
+	return retry.ClassifyError(err, providerName, "chat_completion")
+}
+
+// excludeProvider returns providers without the named one
+func excludeProvider(providers []types.Provider, name string) []types.Provider {
+	kept := make([]types.Provider, 0, len(providers))
+	for _, provider := range providers {
+		if provider.GetName() != name {
+			kept = append(kept, provider)
+		}
+	}
+	return kept
+}
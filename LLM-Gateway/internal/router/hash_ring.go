@@ -109,9 +109,10 @@ func (hr *HashRing) GetNodes(key string, count int) []string {
 
 	seen := make(map[string]bool)
 	result := make([]string, 0, count)
+	physicalNodes := len(hr.getUniqueNodes())
 
 	// Collect unique nodes (virtual nodes may map to same physical node)
-	for len(result) < count && len(seen) < len(hr.getUniqueNodes()) {
+	for len(result) < count && len(seen) < physicalNodes {
 		node := hr.nodes[hr.sortedKeys[idx]]
 		if !seen[node] {
 			seen[node] = true
@@ -26,6 +26,9 @@ type DefaultHealthChecker struct {
 	failureCounts map[string]int
 	successCounts map[string]int
 
+	// Passive outlier detection from live calls
+	outliers map[string]*outlierState
+
 	// Runtime state
 	running bool
 }
@@ -51,6 +54,7 @@ func NewHealthChecker(config *HealthCheckConfig, logger *utils.Logger) (HealthCh
 		cancel:        cancel,
 		failureCounts: make(map[string]int),
 		successCounts: make(map[string]int),
+		outliers:      make(map[string]*outlierState),
 		running:       false,
 	}
 
@@ -113,15 +117,16 @@ func (hc *DefaultHealthChecker) CheckProvider(providerID string) (*HealthResult,
 	return hc.performHealthCheck(providerID, provider)
 }
 
-// GetHealthyProviders returns list of healthy providers
+// GetHealthyProviders returns list of healthy providers, leaving out those outlier detection ejected
 func (hc *DefaultHealthChecker) GetHealthyProviders() []*types.Provider {
 	hc.mutex.RLock()
 	defer hc.mutex.RUnlock()
 
 	var healthy []*types.Provider
 
+	now := time.Now()
 	for providerID, result := range hc.results {
-		if result.IsHealthy {
+		if result.IsHealthy && !hc.ejected(providerID, now) {
 			if provider, exists := hc.providers[providerID]; exists {
 				healthy = append(healthy, provider)
 			}
@@ -138,16 +143,9 @@ func (hc *DefaultHealthChecker) GetProviderHealth(providerID string) (*HealthRes
 
 	if result, exists := hc.results[providerID]; exists {
 		// Return a copy to avoid race conditions
-		return &HealthResult{
-			ProviderID:   result.ProviderID,
-			IsHealthy:    result.IsHealthy,
-			ResponseTime: result.ResponseTime,
-			ErrorCount:   result.ErrorCount,
-			LastCheck:    result.LastCheck,
-			ErrorRate:    result.ErrorRate,
-			Status:       result.Status,
-			ErrorMessage: result.ErrorMessage,
-		}, nil
+		snapshot := *result
+		hc.mergeEjection(&snapshot, time.Now())
+		return &snapshot, nil
 	}
 
 	return nil, fmt.Errorf("no health data for provider %s", providerID)
@@ -160,17 +158,11 @@ func (hc *DefaultHealthChecker) GetAllHealthResults() map[string]*HealthResult {
 
 	results := make(map[string]*HealthResult)
 
+	now := time.Now()
 	for providerID, result := range hc.results {
-		results[providerID] = &HealthResult{
-			ProviderID:   result.ProviderID,
-			IsHealthy:    result.IsHealthy,
-			ResponseTime: result.ResponseTime,
-			ErrorCount:   result.ErrorCount,
-			LastCheck:    result.LastCheck,
-			ErrorRate:    result.ErrorRate,
-			Status:       result.Status,
-			ErrorMessage: result.ErrorMessage,
-		}
+		snapshot := *result
+		hc.mergeEjection(&snapshot, now)
+		results[providerID] = &snapshot
 	}
 
 	return results
@@ -246,6 +238,7 @@ func (hc *DefaultHealthChecker) RemoveProvider(providerID string) error {
 	delete(hc.results, providerID)
 	delete(hc.failureCounts, providerID)
 	delete(hc.successCounts, providerID)
+	delete(hc.outliers, providerID)
 
 	hc.logger.Info(fmt.Sprintf("Removed provider from health checker: %s", providerID))
 
@@ -0,0 +1,312 @@
+// Package router implements hedged requests that race a second provider against a slow first one
+package router
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/router/strategies"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// errHedgeLost marks the attempt cancelled because the other hedged attempt answered first
+var errHedgeLost = errors.New("cancelled: hedged attempt lost")
+
+const (
+	// defaultHedgeMinSamples is the number of latencies needed before a provider's p95 is trusted
+	defaultHedgeMinSamples = 20
+
+	// hedgeBudgetScale is the budget in thousandths of a hedge, so ratios add up exactly
+	hedgeBudgetScale = 1000
+)
+
+// hedgeBudget caps hedging to a fraction of requests: every request deposits the budget ratio,
+// every hedge spends one whole token, and at most one token is banked
+type hedgeBudget struct {
+	tokens int64
+}
+
+// hedgeLeg is one provider/request pair a hedged dispatch may send
+type hedgeLeg struct {
+	provider types.Provider
+	req      *types.Request
+	index    int // Caller's index for the leg, e.g. the virtual model target
+}
+
+// legResult is the outcome of one hedged leg
+type legResult struct {
+	leg         int
+	response    interface{}
+	err         error
+	latency     time.Duration
+	queued      time.Duration // Time spent waiting for a concurrency slot
+	saturated   bool          // The leg got no concurrency slot and never reached the provider
+	circuitOpen bool          // The leg was refused by the provider's circuit breaker
+}
+
+// attemptScope bounds a request's attempts by a total deadline. A winning stream keeps reading
+// from its context after dispatch returns, so successful scopes are left open and released
+// together with the caller's context; only failed scopes are cancelled.
+type attemptScope struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	timer  *time.Timer
+}
+
+// newAttemptScope creates a scope whose attempts are cancelled after timeout, if positive
+func newAttemptScope(parent context.Context, timeout time.Duration) *attemptScope {
+	scope := &attemptScope{}
+	scope.ctx, scope.cancel = context.WithCancel(parent)
+	if timeout > 0 {
+		scope.timer = time.AfterFunc(timeout, scope.cancel)
+	}
+	return scope
+}
+
+// settle stops the deadline and cancels the scope unless an attempt succeeded
+func (s *attemptScope) settle(success bool) {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	if !success {
+		s.cancel()
+	}
+}
+
+// dispatchHedged sends primary and, when hedging is configured for model, races backup against it
+// once primary has been outstanding longer than the hedge delay. The first success wins and the
+// other leg's context is cancelled. A primary that fails before the hedge fires is returned as is,
+// so failover decides what to try next. backup is resolved lazily and may report no leg.
+func (sr *SmartRouter) dispatchHedged(ctx context.Context, model string, primary hedgeLeg, backup func() (hedgeLeg, bool), call ProviderCall) (hedgeLeg, interface{}, []RoutingAttempt, error) {
+	legs := []hedgeLeg{primary}
+	cancels := make([]context.CancelFunc, 0, 2)
+	results := make(chan legResult, 2)
+
+	launch := func(i int) {
+		legCtx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		leg := legs[i]
+		go func() {
+			breaker, ticket, err := sr.allowCall(leg.provider.GetName(), leg.req.Model)
+			if err != nil {
+				results <- legResult{leg: i, err: err, circuitOpen: true}
+				return
+			}
+			release, queued, err := sr.admit(legCtx, leg.provider.GetName(), leg.req.Model)
+			if err != nil {
+				if breaker != nil {
+					breaker.Abandon(ticket)
+				}
+				results <- legResult{leg: i, err: err, queued: queued, saturated: true}
+				return
+			}
+
+			done := sr.trackLoad(leg.provider.GetName())
+			start := time.Now()
+			response, err := call(legCtx, leg.provider, leg.req)
+			latency := time.Since(start)
+			sr.observeCall(legCtx, leg.provider.GetName(), latency, err)
+			finishCall(legCtx, breaker, ticket, leg.provider.GetName(), err)
+			if err == nil && leg.req.Stream {
+				// A stream holds its slot and counts as load until it is read to the end or abandoned
+				context.AfterFunc(legCtx, func() {
+					release()
+					done()
+				})
+			} else {
+				release()
+				done()
+			}
+			results <- legResult{leg: i, response: response, err: err, latency: latency, queued: queued}
+		}()
+	}
+	launch(0)
+
+	var hedgeTimer <-chan time.Time
+	if delay := sr.hedgeDelay(model, primary.provider.GetName()); delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		hedgeTimer = timer.C
+	}
+
+	tried := make([]RoutingAttempt, 1, 2)
+	tried[0] = RoutingAttempt{ProviderName: primary.provider.GetName()}
+	pending := 1
+	var errs []error
+
+	for {
+		select {
+		case <-hedgeTimer:
+			hedgeTimer = nil
+			leg, ok := backup()
+			if !ok || !sr.spendHedgeBudget(model) {
+				continue
+			}
+			legs = append(legs, leg)
+			tried = append(tried, RoutingAttempt{ProviderName: leg.provider.GetName(), Hedged: true})
+			launch(1)
+			pending++
+			sr.logger.WithField("model", model).
+				WithField("provider", primary.provider.GetName()).
+				WithField("hedge_provider", leg.provider.GetName()).
+				Info("Primary attempt is slow, sending hedged request")
+
+		case result := <-results:
+			pending--
+			attempt := &tried[result.leg]
+			attempt.Latency = result.latency
+			attempt.Queued = result.queued
+			attempt.Saturated = result.saturated
+			attempt.CircuitOpen = result.circuitOpen
+			if !result.saturated && !result.circuitOpen {
+				sr.recordAttempt(attempt.ProviderName, result.latency, result.err)
+			}
+
+			if result.err == nil {
+				// Cancel the loser; the winner's context stays open for a stream it may have opened
+				for i, cancel := range cancels {
+					if i != result.leg {
+						cancel()
+						if tried[i].Error == "" {
+							tried[i].Error = errHedgeLost.Error()
+						}
+					}
+				}
+				return legs[result.leg], result.response, tried, nil
+			}
+
+			classified := classifyAttemptError(result.err, attempt.ProviderName)
+			attempt.Error = result.err.Error()
+			attempt.Category = classified.Category
+			attempt.Retryable = classified.Retryable
+			errs = append(errs, result.err)
+			if pending == 0 {
+				for _, cancel := range cancels {
+					cancel()
+				}
+				return primary, nil, tried, errors.Join(errs...)
+			}
+			// The other leg is still in flight and may yet answer
+		}
+	}
+}
+
+// hedgeDelay returns how long a provider may take before a hedge is sent, or 0 when model isn't hedged
+func (sr *SmartRouter) hedgeDelay(model, providerName string) time.Duration {
+	sr.mutex.RLock()
+	policy := sr.config.Hedging[model]
+	sr.mutex.RUnlock()
+	if policy == nil {
+		return 0
+	}
+
+	minSamples := policy.MinSamples
+	if minSamples == 0 {
+		minSamples = defaultHedgeMinSamples
+	}
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	if stats, exists := sr.latencyStats[providerName]; exists && stats.Count >= int64(minSamples) && stats.P95 > 0 {
+		return stats.P95
+	}
+	return policy.Delay
+}
+
+// depositHedgeBudget credits model's hedge budget for one request
+func (sr *SmartRouter) depositHedgeBudget(model string) {
+	sr.mutex.RLock()
+	policy := sr.config.Hedging[model]
+	sr.mutex.RUnlock()
+	if policy == nil {
+		return
+	}
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	budget, exists := sr.hedgeBudgets[model]
+	if !exists {
+		budget = &hedgeBudget{}
+		sr.hedgeBudgets[model] = budget
+	}
+	budget.tokens += int64(policy.BudgetRatio * hedgeBudgetScale)
+	if budget.tokens > hedgeBudgetScale {
+		budget.tokens = hedgeBudgetScale
+	}
+}
+
+// spendHedgeBudget takes one hedge from model's budget, reporting whether one was available
+func (sr *SmartRouter) spendHedgeBudget(model string) bool {
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	budget, exists := sr.hedgeBudgets[model]
+	if !exists || budget.tokens < hedgeBudgetScale {
+		return false
+	}
+	budget.tokens -= hedgeBudgetScale
+	return true
+}
+
+// recordAttempt records the outcome of a provider call in metrics, the strategy and outlier detection and,
+// on success, its latency
+func (sr *SmartRouter) recordAttempt(providerName string, latency time.Duration, err error) {
+	success := err == nil
+	if detector, ok := sr.healthChecker.(OutlierDetector); ok {
+		detector.RecordCallOutcome(providerName, latency, err)
+	}
+	if sr.metricsCollector != nil {
+		sr.metricsCollector.RecordProvider(providerName, latency, success)
+	}
+
+	sr.mutex.RLock()
+	updater, ok := sr.strategy.(strategies.HealthUpdater)
+	sr.mutex.RUnlock()
+	if ok {
+		updater.UpdateProviderHealth(providerName, latency, success)
+	}
+	if !success {
+		return
+	}
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	stats, exists := sr.latencyStats[providerName]
+	if !exists {
+		stats = newLatencyStats()
+		sr.latencyStats[providerName] = stats
+	}
+	stats.record(latency)
+}
+
+// trackLoad tells a strategy that weighs providers by load that a call to a provider was sent,
+// returning the func that reports the call finished
+func (sr *SmartRouter) trackLoad(providerName string) func() {
+	sr.mutex.RLock()
+	tracker, ok := sr.strategy.(strategies.LoadTracker)
+	sr.mutex.RUnlock()
+	if !ok {
+		return func() {}
+	}
+
+	tracker.CallStarted(providerName)
+	return func() { tracker.CallFinished(providerName) }
+}
+
+// GetLatencyStats returns a copy of the call latencies observed for a provider
+func (sr *SmartRouter) GetLatencyStats(providerName string) (*LatencyStats, bool) {
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	stats, exists := sr.latencyStats[providerName]
+	if !exists {
+		return nil, false
+	}
+	statsCopy := *stats
+	statsCopy.Samples = nil
+	return &statsCopy, true
+}
@@ -38,6 +38,15 @@ type HealthChecker interface {
 	RemoveProvider(providerID string) error
 }
 
+// OutlierDetector is implemented by health checkers that also judge providers by the outcome of live calls
+type OutlierDetector interface {
+	// RecordCallOutcome feeds the latency and error of a call to a provider into outlier detection
+	RecordCallOutcome(providerID string, latency time.Duration, err error)
+
+	// IsEjected reports whether outlier detection currently keeps a provider out of the healthy set
+	IsEjected(providerID string) bool
+}
+
 // HealthResult represents the result of a health check
 type HealthResult struct {
 	ProviderID   string        `json:"provider_id"`
@@ -48,6 +57,8 @@ type HealthResult struct {
 	ErrorRate    float64       `json:"error_rate"`
 	Status       string        `json:"status"`
 	ErrorMessage string        `json:"error_message,omitempty"`
+	EjectedUntil time.Time     `json:"ejected_until,omitempty"` // Set while outlier detection ejects the provider
+	Ejections    int           `json:"ejections,omitempty"`     // Recent ejections, which lengthen the next one
 }
 
 // HealthCheckConfig defines configuration for health checking
@@ -57,6 +68,8 @@ type HealthCheckConfig struct {
 	FailureThreshold int           `json:"failure_threshold"` // Failures before marking unhealthy
 	SuccessThreshold int           `json:"success_threshold"` // Successes before marking healthy
 	Path             string        `json:"path"`              // Health check endpoint path
+
+	OutlierDetection *types.OutlierDetectionConfig `json:"outlier_detection,omitempty"` // Passive checks of live calls; nil disables them
 }
 
 // MetricsCollector interface defines metrics collection functionality
@@ -78,6 +91,13 @@ type MetricsCollector interface {
 
 	// GetProviderMetrics returns provider-specific metrics
 	GetProviderMetrics() map[string]*ProviderMetrics
+
+	// RecordAdmission records how long a request waited for a concurrency slot, whether it got one,
+	// and how many requests were queued, itself included, when it arrived
+	RecordAdmission(key string, wait time.Duration, admitted bool, queueDepth int)
+
+	// GetAdmissionMetrics returns the admission queue metrics of each concurrency limit
+	GetAdmissionMetrics() map[string]*AdmissionMetrics
 }
 
 // RoutingMetrics represents overall routing metrics
@@ -102,15 +122,41 @@ type ProviderMetrics struct {
 	LastUsed          time.Time     `json:"last_used"`
 }
 
+// AdmissionMetrics represents the admission queue of one concurrency limit
+type AdmissionMetrics struct {
+	Key           string        `json:"key"` // Provider, or provider/model
+	Admitted      int64         `json:"admitted"`
+	Queued        int64         `json:"queued"`      // Admitted after waiting for a slot
+	Rejected      int64         `json:"rejected"`    // Queue full or wait timed out
+	QueueDepth    int           `json:"queue_depth"` // At the latest arrival
+	MaxQueueDepth int           `json:"max_queue_depth"`
+	AverageWait   time.Duration `json:"average_wait"` // Over queued requests
+	MaxWait       time.Duration `json:"max_wait"`
+}
+
 // SmartRouterConfig defines configuration for the smart router
 type SmartRouterConfig struct {
 	Strategy            string               `json:"strategy"` // Load balancing strategy
 	HealthCheckInterval time.Duration        `json:"health_check_interval"`
 	FailoverEnabled     bool                 `json:"failover_enabled"`
 	MaxRetries          int                  `json:"max_retries"`
-	Weights             map[string]int       `json:"weights"` // Provider weights
+	FailoverTimeout     time.Duration        `json:"failover_timeout"` // Total deadline across failover attempts
+	Weights             map[string]int       `json:"weights"`          // Provider weights
 	CircuitBreaker      CircuitBreakerConfig `json:"circuit_breaker"`
 	MetricsEnabled      bool                 `json:"metrics_enabled"`
+
+	VirtualModels   map[string]*types.VirtualModelConfig `json:"virtual_models,omitempty"`   // Alias -> target chain
+	Hedging         map[string]*types.HedgingConfig      `json:"hedging,omitempty"`          // Model or alias -> hedge policy
+	CostOptimized   *types.CostOptimizedConfig           `json:"cost_optimized,omitempty"`   // Tolerances for the cost_optimized strategy
+	ContextUpgrades map[string]string                    `json:"context_upgrades,omitempty"` // Model -> larger sibling for long prompts
+	PrefixAffinity  *types.PrefixAffinityConfig          `json:"prefix_affinity,omitempty"`  // Keying and load bound for the prefix_affinity strategy
+	Experiments     []types.ExperimentConfig             `json:"experiments,omitempty"`      // Traffic splits between variant models
+	Shadows         []types.ShadowConfig                 `json:"shadows,omitempty"`          // Models mirrored to shadow targets
+	Policies        []types.RoutingPolicyConfig          `json:"policies,omitempty"`         // Provider and model restrictions per tenant
+	DefaultPolicy   string                               `json:"default_policy,omitempty"`   // Policy of unauthenticated requests
+	Concurrency     *types.ConcurrencyConfig             `json:"concurrency,omitempty"`      // In-flight limits and admission queue
+
+	OutlierDetection *types.OutlierDetectionConfig `json:"outlier_detection,omitempty"` // Ejection of providers failing live traffic
 }
 
 // CircuitBreakerConfig defines circuit breaker configuration
@@ -119,16 +165,27 @@ type CircuitBreakerConfig struct {
 	Threshold   int           `json:"threshold"`    // Failure threshold
 	Timeout     time.Duration `json:"timeout"`      // Open state timeout
 	MaxRequests int           `json:"max_requests"` // Max requests in half-open
+
+	// The Smart Router opens a provider+model breaker when FailureRate of the calls in the rolling
+	// window fail, once the window holds MinRequests calls. The window keeps the last WindowSize
+	// calls made within Window.
+	FailureRate float64       `json:"failure_rate,omitempty"` // 0 uses the default
+	MinRequests int           `json:"min_requests,omitempty"` // 0 uses Threshold
+	Window      time.Duration `json:"window,omitempty"`       // 0 uses the default
+	WindowSize  int           `json:"window_size,omitempty"`  // 0 uses the default
 }
 
 // SmartRoutingResult contains the result of provider selection
 type SmartRoutingResult struct {
-	Provider      types.Provider `json:"-"`
-	ProviderName  string         `json:"provider_name"`
-	Reason        string         `json:"reason"`
-	Attempts      int            `json:"attempts"`
-	BackupUsed    bool           `json:"backup_used"`
-	Strategy      string         `json:"strategy"`
-	LoadFactor    float64        `json:"load_factor"`
-	SelectionTime time.Duration  `json:"selection_time"`
+	Provider      types.Provider   `json:"-"`
+	ProviderName  string           `json:"provider_name"`
+	Model         string           `json:"model,omitempty"` // Concrete model that served a virtual model
+	Reason        string           `json:"reason"`
+	Attempts      int              `json:"attempts"`
+	BackupUsed    bool             `json:"backup_used"`
+	Tried         []RoutingAttempt `json:"tried,omitempty"` // Every dispatch made for the request, in order
+	Response      interface{}      `json:"-"`               // Value returned by the winning ProviderCall
+	Strategy      string           `json:"strategy"`
+	LoadFactor    float64          `json:"load_factor"`
+	SelectionTime time.Duration    `json:"selection_time"`
 }
@@ -14,6 +14,7 @@ type DefaultMetricsCollector struct {
 	routingMetrics  *RoutingMetrics
 	strategyMetrics map[string]*strategies.StrategyMetrics
 	providerMetrics map[string]*ProviderMetrics
+	admission       map[string]*admissionStats
 	mutex           sync.RWMutex
 
 	// Internal counters
@@ -37,6 +38,7 @@ func NewMetricsCollector() (MetricsCollector, error) {
 		},
 		strategyMetrics:  make(map[string]*strategies.StrategyMetrics),
 		providerMetrics:  make(map[string]*ProviderMetrics),
+		admission:        make(map[string]*admissionStats),
 		totalRequests:    0,
 		successfulRoutes: 0,
 		failedRoutes:     0,
@@ -224,6 +226,56 @@ func (mc *DefaultMetricsCollector) GetProviderMetrics() map[string]*ProviderMetr
 	return metrics
 }
 
+// admissionStats accumulates the admission outcomes of one concurrency limit
+type admissionStats struct {
+	metrics   AdmissionMetrics
+	totalWait time.Duration
+}
+
+// RecordAdmission records how long a request waited for a concurrency slot and the queue left behind
+func (mc *DefaultMetricsCollector) RecordAdmission(key string, wait time.Duration, admitted bool, queueDepth int) {
+	mc.mutex.Lock()
+	defer mc.mutex.Unlock()
+
+	stats, exists := mc.admission[key]
+	if !exists {
+		stats = &admissionStats{metrics: AdmissionMetrics{Key: key}}
+		mc.admission[key] = stats
+	}
+
+	if admitted {
+		stats.metrics.Admitted++
+		if wait > 0 {
+			stats.metrics.Queued++
+			stats.totalWait += wait
+			stats.metrics.AverageWait = stats.totalWait / time.Duration(stats.metrics.Queued)
+		}
+	} else {
+		stats.metrics.Rejected++
+	}
+	if wait > stats.metrics.MaxWait {
+		stats.metrics.MaxWait = wait
+	}
+
+	stats.metrics.QueueDepth = queueDepth
+	if queueDepth > stats.metrics.MaxQueueDepth {
+		stats.metrics.MaxQueueDepth = queueDepth
+	}
+}
+
+// GetAdmissionMetrics returns the admission queue metrics of each concurrency limit
+func (mc *DefaultMetricsCollector) GetAdmissionMetrics() map[string]*AdmissionMetrics {
+	mc.mutex.RLock()
+	defer mc.mutex.RUnlock()
+
+	metrics := make(map[string]*AdmissionMetrics, len(mc.admission))
+	for key, stats := range mc.admission {
+		metricsCopy := stats.metrics
+		metrics[key] = &metricsCopy
+	}
+	return metrics
+}
+
 // IncrementActiveConnections increments the active connection count for a provider
 func (mc *DefaultMetricsCollector) IncrementActiveConnections(providerID string) {
 	mc.mutex.Lock()
@@ -286,6 +338,9 @@ func (mc *DefaultMetricsCollector) Reset() error {
 	// Reset provider metrics
 	mc.providerMetrics = make(map[string]*ProviderMetrics)
 
+	// Reset admission metrics
+	mc.admission = make(map[string]*admissionStats)
+
 	mc.startTime = time.Now()
 
 	return nil
@@ -301,11 +356,13 @@ func (mc *DefaultMetricsCollector) GetMetricsSummary() map[string]interface{} {
 	routing := mc.GetRoutingMetrics()
 	strategies := mc.GetStrategyMetrics()
 	providers := mc.GetProviderMetrics()
+	admission := mc.GetAdmissionMetrics()
 
 	summary := map[string]interface{}{
 		"routing":    routing,
 		"strategies": strategies,
 		"providers":  providers,
+		"admission":  admission,
 		"uptime":     mc.GetUptime(),
 		"timestamp":  time.Now(),
 	}
@@ -0,0 +1,209 @@
+// Package router implements passive outlier detection from live traffic
+package router
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/router/strategies"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// Outlier detection defaults
+	DefaultOutlierConsecutiveErrors  = 5
+	DefaultOutlierBaseEjectionTime   = 30 * time.Second
+	DefaultOutlierMaxEjectionTime    = 300 * time.Second
+	DefaultOutlierMaxEjectionPercent = 50
+
+	// outlierWarmup is the number of successful calls that set a baseline before latency can count as an error
+	outlierWarmup = 10
+)
+
+// outlierState is what outlier detection has learned about a provider from its live calls
+type outlierState struct {
+	consecutive  int           // Errors in a row since the last success or ejection
+	baseline     time.Duration // Moving average of successful call latencies
+	samples      int64
+	ejections    int // Recent ejections; each one doubles the length of the next
+	ejectedUntil time.Time
+}
+
+// effectiveOutlierConfig fills in the defaults of outlier detection settings
+func effectiveOutlierConfig(config *types.OutlierDetectionConfig) types.OutlierDetectionConfig {
+	settings := *config
+	if settings.ConsecutiveErrors == 0 {
+		settings.ConsecutiveErrors = DefaultOutlierConsecutiveErrors
+	}
+	if settings.BaseEjectionTime == 0 {
+		settings.BaseEjectionTime = DefaultOutlierBaseEjectionTime
+	}
+	if settings.MaxEjectionTime == 0 {
+		settings.MaxEjectionTime = max(DefaultOutlierMaxEjectionTime, settings.BaseEjectionTime)
+	}
+	if settings.MaxEjectionPercent == 0 {
+		settings.MaxEjectionPercent = DefaultOutlierMaxEjectionPercent
+	}
+	return settings
+}
+
+// maxEjections returns how many of total providers may be ejected at once. At least one may be,
+// unless it is the only provider, and one always stays.
+func maxEjections(total, percent int) int {
+	return min(max(total*percent/100, 1), total-1)
+}
+
+// RecordCallOutcome feeds the latency and error of a live call to a provider into outlier detection.
+// Server errors, network errors and timeouts count towards ejection, as does a call slower than the
+// configured multiple of the provider's baseline latency; other successes reset the count. Errors the
+// caller caused, such as bad requests, and calls canceled by the caller count for nothing.
+func (hc *DefaultHealthChecker) RecordCallOutcome(providerID string, latency time.Duration, err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	hc.mutex.Lock()
+	defer hc.mutex.Unlock()
+
+	if hc.config.OutlierDetection == nil {
+		return
+	}
+	if _, exists := hc.providers[providerID]; !exists {
+		return
+	}
+	config := effectiveOutlierConfig(hc.config.OutlierDetection)
+
+	state, exists := hc.outliers[providerID]
+	if !exists {
+		state = &outlierState{}
+		hc.outliers[providerID] = state
+	}
+
+	var reason string
+	if err != nil {
+		switch classifyAttemptError(err, providerID).Category {
+		case types.ErrorServer, types.ErrorNetwork, types.ErrorTimeout:
+			reason = "consecutive errors"
+		default:
+			return
+		}
+	} else {
+		if config.LatencyMultiplier > 0 && state.samples >= outlierWarmup &&
+			latency > time.Duration(float64(state.baseline)*config.LatencyMultiplier) {
+			reason = "latency spike"
+		}
+		if state.samples == 0 {
+			state.baseline = latency
+		} else {
+			state.baseline += time.Duration(baselineSmoothing * float64(latency-state.baseline))
+		}
+		state.samples++
+	}
+
+	if reason == "" {
+		state.consecutive = 0
+		return
+	}
+	state.consecutive++
+	if state.consecutive >= config.ConsecutiveErrors {
+		hc.eject(providerID, state, config, reason)
+	}
+}
+
+// eject takes a provider out of the healthy set for an exponentially growing period, unless it already
+// is or the maximum share of providers is already ejected. The caller must hold hc.mutex.
+func (hc *DefaultHealthChecker) eject(providerID string, state *outlierState, config types.OutlierDetectionConfig, reason string) {
+	now := time.Now()
+	if now.Before(state.ejectedUntil) {
+		return
+	}
+
+	ejected := 0
+	for _, other := range hc.outliers {
+		if now.Before(other.ejectedUntil) {
+			ejected++
+		}
+	}
+	if ejected >= maxEjections(len(hc.providers), config.MaxEjectionPercent) {
+		hc.logger.WithField("provider", providerID).
+			WithField("ejected", ejected).
+			Debug("Outlier detection is not ejecting provider, too many providers are ejected")
+		return
+	}
+
+	// Each base ejection time spent back in the healthy set earns back one doubling
+	if !state.ejectedUntil.IsZero() {
+		state.ejections = max(state.ejections-int(now.Sub(state.ejectedUntil)/config.BaseEjectionTime), 0)
+	}
+	state.ejections++
+
+	duration := config.BaseEjectionTime
+	for i := 1; i < state.ejections && duration < config.MaxEjectionTime; i++ {
+		duration *= 2
+	}
+	duration = min(duration, config.MaxEjectionTime)
+	state.ejectedUntil = now.Add(duration)
+	state.consecutive = 0
+
+	hc.logger.WithField("provider", providerID).
+		WithField("reason", reason).
+		WithField("ejections", state.ejections).
+		WithField("duration", duration.String()).
+		Warn("Outlier detection ejected provider")
+}
+
+// IsEjected reports whether outlier detection currently keeps a provider out of the healthy set
+func (hc *DefaultHealthChecker) IsEjected(providerID string) bool {
+	hc.mutex.RLock()
+	defer hc.mutex.RUnlock()
+
+	return hc.ejected(providerID, time.Now())
+}
+
+// ejected reports whether a provider is ejected at now. The caller must hold hc.mutex.
+func (hc *DefaultHealthChecker) ejected(providerID string, now time.Time) bool {
+	state, exists := hc.outliers[providerID]
+	return exists && now.Before(state.ejectedUntil)
+}
+
+// mergeEjection marks a copied health result unhealthy while its provider is ejected.
+// The caller must hold hc.mutex.
+func (hc *DefaultHealthChecker) mergeEjection(result *HealthResult, now time.Time) {
+	state, exists := hc.outliers[result.ProviderID]
+	if !exists {
+		return
+	}
+	result.Ejections = state.ejections
+	if now.Before(state.ejectedUntil) {
+		result.IsHealthy = false
+		result.Status = "ejected"
+		result.EjectedUntil = state.ejectedUntil
+	}
+}
+
+// healthCheckConfig returns the health checker configuration, carrying the router's outlier detection settings
+func (sr *SmartRouter) healthCheckConfig() *HealthCheckConfig {
+	config := DefaultHealthCheckConfig()
+	config.OutlierDetection = sr.config.OutlierDetection
+	return config
+}
+
+// syncEjections tells a strategy that scores ejected providers which of providers are ejected
+func (sr *SmartRouter) syncEjections(providers []types.Provider) {
+	detector, ok := sr.healthChecker.(OutlierDetector)
+	if !ok {
+		return
+	}
+
+	sr.mutex.RLock()
+	aware, ok := sr.strategy.(strategies.OutlierAware)
+	sr.mutex.RUnlock()
+	if !ok {
+		return
+	}
+
+	for _, provider := range providers {
+		aware.SetProviderEjected(provider.GetName(), detector.IsEjected(provider.GetName()))
+	}
+}
@@ -0,0 +1,171 @@
+// Package router implements per-tenant routing policies
+package router
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// PolicyIdentity is who an authenticated request comes from, for choosing its routing policy.
+// The zero identity is an unauthenticated request.
+type PolicyIdentity struct {
+	UserID string
+	APIKey string
+	Policy string // Policy named on the authenticated API key or user, which takes precedence
+}
+
+// PolicyError is returned when a routing policy keeps a request from every provider that could serve it
+type PolicyError struct {
+	Policy string
+	Model  string
+	Reason string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("Routing policy %s forbids this request: %s", e.Policy, e.Reason)
+}
+
+// routingPolicyKey is the context key of the routing policy a request obeys
+type routingPolicyKey struct{}
+
+// WithRoutingPolicy returns a context carrying the routing policy its request must obey.
+// Every provider selection made with the context is restricted by the policy.
+func WithRoutingPolicy(ctx context.Context, policy *types.RoutingPolicyConfig) context.Context {
+	return context.WithValue(ctx, routingPolicyKey{}, policy)
+}
+
+// RoutingPolicyFrom returns the routing policy carried by ctx, or nil when the request is unrestricted
+func RoutingPolicyFrom(ctx context.Context) *types.RoutingPolicyConfig {
+	policy, _ := ctx.Value(routingPolicyKey{}).(*types.RoutingPolicyConfig)
+	return policy
+}
+
+// RoutingPolicyFor returns the policy a caller's requests obey: the one named on its API key or user,
+// else the first policy listing its API key, else the first listing its user. Unauthenticated callers
+// obey the default policy. It returns nil when no policy applies, and a PolicyError for a named or
+// default policy that is not configured so the request fails closed.
+func (sr *SmartRouter) RoutingPolicyFor(identity PolicyIdentity) (*types.RoutingPolicyConfig, error) {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	if identity == (PolicyIdentity{}) {
+		identity.Policy = sr.config.DefaultPolicy
+	}
+	if identity.Policy != "" {
+		for i := range sr.config.Policies {
+			if sr.config.Policies[i].Name == identity.Policy {
+				policy := sr.config.Policies[i]
+				return &policy, nil
+			}
+		}
+		return nil, &PolicyError{Policy: identity.Policy, Reason: "the policy is not configured"}
+	}
+
+	if identity.APIKey != "" {
+		for i := range sr.config.Policies {
+			if containsString(sr.config.Policies[i].APIKeys, identity.APIKey) {
+				policy := sr.config.Policies[i]
+				return &policy, nil
+			}
+		}
+	}
+	if identity.UserID != "" {
+		for i := range sr.config.Policies {
+			if containsString(sr.config.Policies[i].Users, identity.UserID) {
+				policy := sr.config.Policies[i]
+				return &policy, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// FilterByPolicy returns the candidates a policy lets serve model, keeping their order.
+// A nil policy allows everything.
+func FilterByPolicy(policy *types.RoutingPolicyConfig, model string, candidates []types.Provider) ([]types.Provider, error) {
+	if policy == nil || len(candidates) == 0 {
+		return candidates, nil
+	}
+	if !policyAllowsModel(policy, model) {
+		return nil, &PolicyError{Policy: policy.Name, Model: model, Reason: fmt.Sprintf("model %s is not allowed", model)}
+	}
+
+	allowed := make([]types.Provider, 0, len(candidates))
+	var denied []string
+	for _, provider := range candidates {
+		if reason := policyDeniesProvider(policy, provider); reason != "" {
+			denied = append(denied, provider.GetName()+" "+reason)
+			continue
+		}
+		allowed = append(allowed, provider)
+	}
+
+	if len(allowed) == 0 {
+		return nil, &PolicyError{
+			Policy: policy.Name,
+			Model:  model,
+			Reason: fmt.Sprintf("no allowed provider serves model %s (%s)", model, strings.Join(denied, "; ")),
+		}
+	}
+	return allowed, nil
+}
+
+// PolicyAllows reports whether a policy lets provider serve model. A nil policy allows everything.
+func PolicyAllows(policy *types.RoutingPolicyConfig, provider types.Provider, model string) bool {
+	return policy == nil || (policyAllowsModel(policy, model) && policyDeniesProvider(policy, provider) == "")
+}
+
+// policyAllowsModel reports whether model is in the policy's allowed models, if it lists any
+func policyAllowsModel(policy *types.RoutingPolicyConfig, model string) bool {
+	return len(policy.AllowedModels) == 0 || containsString(policy.AllowedModels, model)
+}
+
+// policyDeniesProvider returns why a policy keeps requests from a provider, or "" when it doesn't
+func policyDeniesProvider(policy *types.RoutingPolicyConfig, provider types.Provider) string {
+	if matchesProvider(policy.ForbiddenProviders, provider) {
+		return "is forbidden"
+	}
+	if len(policy.AllowedProviders) > 0 && !matchesProvider(policy.AllowedProviders, provider) {
+		return "is not an allowed provider"
+	}
+
+	if len(policy.Residency) > 0 {
+		residency := ""
+		if config := provider.GetConfig(); config != nil {
+			residency = config.Residency
+		}
+		for _, allowed := range policy.Residency {
+			if strings.EqualFold(allowed, residency) {
+				return ""
+			}
+		}
+		if residency == "" {
+			return "has no residency tag"
+		}
+		return fmt.Sprintf("has residency %s, not %s", residency, strings.Join(policy.Residency, " or "))
+	}
+	return ""
+}
+
+// matchesProvider reports whether any entry names the provider or its type
+func matchesProvider(entries []string, provider types.Provider) bool {
+	for _, entry := range entries {
+		if strings.EqualFold(entry, provider.GetName()) || strings.EqualFold(entry, provider.GetType()) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
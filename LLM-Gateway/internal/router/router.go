@@ -151,8 +151,11 @@ func (r *Router) RouteRequest(ctx context.Context, req *types.ChatCompletionRequ
 		return nil, fmt.Errorf("no available providers for model: %s", req.Model)
 	}
 
-	// Apply strategy-specific filtering and sorting
-	providers = r.filterProviders(providers, req)
+	// Apply capability and strategy-specific filtering
+	providers, err := r.filterProviders(ctx, providers, req)
+	if err != nil {
+		return nil, err
+	}
 
 	var lastErr error
 	attempts := 0
@@ -217,34 +220,9 @@ func (r *Router) RecordSuccess(providerName string, latency time.Duration) {
 
 	// Update latency stats
 	if r.stats.LatencyStats[providerName] == nil {
-		r.stats.LatencyStats[providerName] = &LatencyStats{
-			Min:     latency,
-			Max:     latency,
-			Samples: make([]time.Duration, 0, 1000),
-		}
+		r.stats.LatencyStats[providerName] = newLatencyStats()
 	}
-
-	stats := r.stats.LatencyStats[providerName]
-	stats.Count++
-	stats.Sum += latency
-
-	if latency < stats.Min {
-		stats.Min = latency
-	}
-	if latency > stats.Max {
-		stats.Max = latency
-	}
-
-	stats.Average = stats.Sum / time.Duration(stats.Count)
-
-	// Keep last 1000 samples for percentile calculations
-	stats.Samples = append(stats.Samples, latency)
-	if len(stats.Samples) > 1000 {
-		stats.Samples = stats.Samples[1:]
-	}
-
-	// Calculate percentiles
-	r.calculatePercentiles(stats)
+	r.stats.LatencyStats[providerName].record(latency)
 
 	// Record success in circuit breaker
 	if r.config.CircuitBreakerEnabled {
@@ -370,8 +348,21 @@ func (r *Router) getAvailableProviders(model string) []types.Provider {
 	return availableProviders
 }
 
-// filterProviders applies additional filtering based on strategy
-func (r *Router) filterProviders(providers []types.Provider, req *types.ChatCompletionRequest) []types.Provider {
+// filterProviders drops providers the routing policy in ctx forbids and those whose model lacks
+// a feature the request uses, then applies additional filtering based on strategy
+func (r *Router) filterProviders(ctx context.Context, providers []types.Provider, req *types.ChatCompletionRequest) ([]types.Provider, error) {
+	providers, err := FilterByPolicy(RoutingPolicyFrom(ctx), req.Model, providers)
+	if err != nil {
+		return nil, err
+	}
+
+	providers, err = FilterByCapabilities(req, providers, func(p types.Provider, model string) *types.ModelCapabilities {
+		return DeclaredCapabilities(ctx, p, model)
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	// Apply preferred providers filter
 	if len(r.config.PreferredProviders) > 0 {
 		var filtered []types.Provider
@@ -387,7 +378,7 @@ func (r *Router) filterProviders(providers []types.Provider, req *types.ChatComp
 		}
 	}
 
-	return providers
+	return providers, nil
 }
 
 // providerSupportsModel checks if a provider supports a specific model
@@ -458,8 +449,37 @@ func (r *Router) getSelectionReason(strategy RoutingStrategy, provider types.Pro
 	}
 }
 
+// newLatencyStats creates empty latency stats
+func newLatencyStats() *LatencyStats {
+	return &LatencyStats{Samples: make([]time.Duration, 0, 1000)}
+}
+
+// record adds a latency sample and refreshes the aggregates
+func (stats *LatencyStats) record(latency time.Duration) {
+	stats.Count++
+	stats.Sum += latency
+
+	if stats.Count == 1 || latency < stats.Min {
+		stats.Min = latency
+	}
+	if latency > stats.Max {
+		stats.Max = latency
+	}
+
+	stats.Average = stats.Sum / time.Duration(stats.Count)
+
+	// Keep last 1000 samples for percentile calculations
+	stats.Samples = append(stats.Samples, latency)
+	if len(stats.Samples) > 1000 {
+		stats.Samples = stats.Samples[1:]
+	}
+
+	// Calculate percentiles
+	stats.calculatePercentiles()
+}
+
 // calculatePercentiles calculates P95 and P99 latencies
-func (r *Router) calculatePercentiles(stats *LatencyStats) {
+func (stats *LatencyStats) calculatePercentiles() {
 	if len(stats.Samples) == 0 {
 		return
 	}
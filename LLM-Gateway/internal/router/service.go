@@ -159,19 +159,10 @@ func (s *Service) AddProvider(providerConfig *types.ProviderConfig) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	var provider types.Provider
-	var err error
-
 	// Create provider based on type
-	switch providerConfig.Type {
-	case "openai":
-		provider = providers.NewOpenAIProvider(providerConfig, s.logger)
-	case "anthropic":
-		provider = providers.NewClaudeProvider(providerConfig, s.logger)
-	case "baidu":
-		provider = providers.NewBaiduProvider(providerConfig, s.logger)
-	default:
-		return fmt.Errorf("unsupported provider type: %s", providerConfig.Type)
+	provider, err := providers.NewProviderFactory(s.logger).Create(providerConfig)
+	if err != nil {
+		return err
 	}
 
 	// Note: Provider validation is now handled within each provider's implementation
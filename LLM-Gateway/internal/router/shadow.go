@@ -0,0 +1,292 @@
+// Package router implements shadow traffic mirrored to candidate providers
+package router
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// DefaultShadowConcurrency is the number of shadow calls per model in flight before samples are dropped
+	DefaultShadowConcurrency = 4
+
+	// maxRecentShadowComparisons is how many comparisons per model are kept for inspection
+	maxRecentShadowComparisons = 20
+)
+
+// ShadowCall is a sampled request to mirror; Release must be called once the shadow call ends
+type ShadowCall struct {
+	Provider types.Provider
+	Model    string
+	slots    chan struct{}
+}
+
+// Release frees the shadow call's concurrency slot
+func (s *ShadowCall) Release() {
+	<-s.slots
+}
+
+// ShadowResult is one side of a shadow comparison
+type ShadowResult struct {
+	Provider     string      `json:"provider"`
+	Model        string      `json:"model"`
+	LatencyMs    int64       `json:"latency_ms"`
+	Usage        types.Usage `json:"usage"`
+	Cost         float64     `json:"cost"`
+	FinishReason string      `json:"finish_reason,omitempty"`
+	Error        string      `json:"error,omitempty"`
+}
+
+// ShadowComparison records the primary answer to a request next to its shadow's
+type ShadowComparison struct {
+	RequestID  string       `json:"request_id"`
+	Model      string       `json:"model"`
+	Primary    ShadowResult `json:"primary"`
+	Shadow     ShadowResult `json:"shadow"`
+	Similarity float64      `json:"similarity"` // Cosine similarity of the answers' words, in [0, 1]
+	Timestamp  time.Time    `json:"timestamp"`
+}
+
+// shadowState is the concurrency limit and accumulated comparisons of one shadowed model
+type shadowState struct {
+	slots               chan struct{}
+	mirrored            int64
+	dropped             int64
+	shadowErrors        int64
+	finishReasonMatches int64
+	primaryLatency      time.Duration
+	shadowLatency       time.Duration
+	primaryCost         float64
+	shadowCost          float64
+	primaryTokens       int64
+	shadowTokens        int64
+	similarity          float64 // Sum over successful shadow calls
+	recent              []ShadowComparison
+}
+
+// ShadowReport summarizes how a shadow target compares to the primary traffic of a model
+type ShadowReport struct {
+	Model               string             `json:"model"`
+	Provider            string             `json:"provider"`
+	TargetModel         string             `json:"target_model"`
+	SampleRate          float64            `json:"sample_rate"`
+	Mirrored            int64              `json:"mirrored"`
+	Dropped             int64              `json:"dropped"` // Samples skipped at the concurrency limit
+	ShadowErrors        int64              `json:"shadow_errors"`
+	FinishReasonMatches int64              `json:"finish_reason_matches"`
+	AvgPrimaryLatencyMs float64            `json:"avg_primary_latency_ms"`
+	AvgShadowLatencyMs  float64            `json:"avg_shadow_latency_ms"` // Over successful shadow calls
+	PrimaryCost         float64            `json:"primary_cost"`
+	ShadowCost          float64            `json:"shadow_cost"`
+	PrimaryTokens       int64              `json:"primary_tokens"`
+	ShadowTokens        int64              `json:"shadow_tokens"`
+	AvgSimilarity       float64            `json:"avg_similarity"` // Over successful shadow calls
+	Recent              []ShadowComparison `json:"recent"`
+}
+
+// SampleShadow decides whether an answered request for model is mirrored. It returns the shadow
+// target when the request is sampled and a concurrency slot is free; samples over the limit are dropped.
+// Requests whose routing policy in ctx forbids the shadow target are never mirrored.
+func (sr *SmartRouter) SampleShadow(ctx context.Context, model string) (*ShadowCall, bool) {
+	sr.mutex.RLock()
+	var shadow *types.ShadowConfig
+	for i := range sr.config.Shadows {
+		if sr.config.Shadows[i].Model == model {
+			shadow = &sr.config.Shadows[i]
+			break
+		}
+	}
+	var provider types.Provider
+	var config types.ShadowConfig
+	if shadow != nil {
+		provider = sr.providers[shadow.Provider]
+		config = *shadow
+	}
+	sr.mutex.RUnlock()
+
+	if provider == nil || rand.Float64() >= config.SampleRate {
+		return nil, false
+	}
+
+	target := config.TargetModel
+	if target == "" {
+		target = config.Model
+	}
+	if !PolicyAllows(RoutingPolicyFrom(ctx), provider, target) {
+		return nil, false
+	}
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	state := sr.shadowStateFor(config)
+	select {
+	case state.slots <- struct{}{}:
+	default:
+		state.dropped++
+		return nil, false
+	}
+
+	return &ShadowCall{Provider: provider, Model: target, slots: state.slots}, true
+}
+
+// RecordShadowComparison adds a finished shadow comparison to its model's report
+func (sr *SmartRouter) RecordShadowComparison(comparison ShadowComparison) {
+	sr.mutex.RLock()
+	var config *types.ShadowConfig
+	for i := range sr.config.Shadows {
+		if sr.config.Shadows[i].Model == comparison.Model {
+			shadow := sr.config.Shadows[i]
+			config = &shadow
+			break
+		}
+	}
+	sr.mutex.RUnlock()
+
+	if config == nil {
+		// The shadow was removed while the call was in flight
+		return
+	}
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	state := sr.shadowStateFor(*config)
+	state.mirrored++
+	state.primaryLatency += time.Duration(comparison.Primary.LatencyMs) * time.Millisecond
+	state.primaryCost += comparison.Primary.Cost
+	state.primaryTokens += int64(comparison.Primary.Usage.TotalTokens)
+	if comparison.Shadow.Error != "" {
+		state.shadowErrors++
+	} else {
+		state.shadowLatency += time.Duration(comparison.Shadow.LatencyMs) * time.Millisecond
+		state.shadowCost += comparison.Shadow.Cost
+		state.shadowTokens += int64(comparison.Shadow.Usage.TotalTokens)
+		state.similarity += comparison.Similarity
+		if comparison.Shadow.FinishReason == comparison.Primary.FinishReason {
+			state.finishReasonMatches++
+		}
+	}
+
+	state.recent = append(state.recent, comparison)
+	if len(state.recent) > maxRecentShadowComparisons {
+		state.recent = state.recent[len(state.recent)-maxRecentShadowComparisons:]
+	}
+}
+
+// ShadowReports returns the comparison of every configured shadow target, in configuration order
+func (sr *SmartRouter) ShadowReports() []ShadowReport {
+	sr.mutex.RLock()
+	shadows := append([]types.ShadowConfig(nil), sr.config.Shadows...)
+	sr.mutex.RUnlock()
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	reports := make([]ShadowReport, 0, len(shadows))
+	for _, shadow := range shadows {
+		state := sr.shadowStateFor(shadow)
+		report := ShadowReport{
+			Model:               shadow.Model,
+			Provider:            shadow.Provider,
+			TargetModel:         shadow.TargetModel,
+			SampleRate:          shadow.SampleRate,
+			Mirrored:            state.mirrored,
+			Dropped:             state.dropped,
+			ShadowErrors:        state.shadowErrors,
+			FinishReasonMatches: state.finishReasonMatches,
+			PrimaryCost:         state.primaryCost,
+			ShadowCost:          state.shadowCost,
+			PrimaryTokens:       state.primaryTokens,
+			ShadowTokens:        state.shadowTokens,
+			Recent:              append([]ShadowComparison{}, state.recent...),
+		}
+		if report.TargetModel == "" {
+			report.TargetModel = shadow.Model
+		}
+		if state.mirrored > 0 {
+			report.AvgPrimaryLatencyMs = float64(state.primaryLatency) / float64(time.Millisecond) / float64(state.mirrored)
+		}
+		if answered := state.mirrored - state.shadowErrors; answered > 0 {
+			report.AvgShadowLatencyMs = float64(state.shadowLatency) / float64(time.Millisecond) / float64(answered)
+			report.AvgSimilarity = state.similarity / float64(answered)
+		}
+		reports = append(reports, report)
+	}
+	return reports
+}
+
+// shadowStateFor returns a shadowed model's state, creating it on first use and resizing its
+// concurrency limit when the configuration changed. Callers must hold statsMutex.
+func (sr *SmartRouter) shadowStateFor(shadow types.ShadowConfig) *shadowState {
+	limit := shadow.MaxConcurrent
+	if limit <= 0 {
+		limit = DefaultShadowConcurrency
+	}
+
+	state, exists := sr.shadows[shadow.Model]
+	if !exists {
+		state = &shadowState{}
+		sr.shadows[shadow.Model] = state
+	}
+	if cap(state.slots) != limit {
+		// Calls in flight release the old channel, so the new limit applies to new samples
+		state.slots = make(chan struct{}, limit)
+	}
+	return state
+}
+
+// TextSimilarity returns the cosine similarity of the word counts of two texts, ignoring case and
+// punctuation: 1 for the same words in any order, 0 for no words in common.
+// Han characters count as words of their own since Chinese text has no spaces.
+func TextSimilarity(a, b string) float64 {
+	countsA, countsB := wordCounts(a), wordCounts(b)
+	if len(countsA) == 0 && len(countsB) == 0 {
+		return 1
+	}
+
+	var dot, normA, normB float64
+	for word, countA := range countsA {
+		dot += countA * countsB[word]
+		normA += countA * countA
+	}
+	for _, countB := range countsB {
+		normB += countB * countB
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
+
+// wordCounts counts the lowercased words of a text
+func wordCounts(text string) map[string]float64 {
+	counts := make(map[string]float64)
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			counts[word.String()]++
+			word.Reset()
+		}
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			flush()
+			counts[string(r)]++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return counts
+}
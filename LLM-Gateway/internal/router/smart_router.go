@@ -29,6 +29,18 @@ type SmartRouter struct {
 	logger           *utils.Logger
 	mutex            sync.RWMutex
 
+	// Per-provider call latencies, per-model hedge budgets and shadow comparisons, per-variant experiment outcomes,
+	// the concurrency limiters of providers and provider+model pairs, and provider+model circuit breakers
+	latencyStats     map[string]*LatencyStats
+	hedgeBudgets     map[string]*hedgeBudget
+	experimentStats  map[string]*variantStats
+	shadows          map[string]*shadowState
+	limiters         map[string]*concurrencyLimiter
+	breakers         map[string]*breakerEntry
+	breakerEvents    []BreakerEvent
+	breakerListeners []func(BreakerEvent)
+	statsMutex       sync.Mutex
+
 	// Runtime state
 	started bool
 	ctx     context.Context
@@ -48,12 +60,18 @@ func NewSmartRouter(config *SmartRouterConfig, logger *utils.Logger) (*SmartRout
 	ctx, cancel := context.WithCancel(context.Background())
 
 	router := &SmartRouter{
-		config:    config.Clone(),
-		providers: make(map[string]types.Provider),
-		logger:    logger,
-		started:   false,
-		ctx:       ctx,
-		cancel:    cancel,
+		config:          config.Clone(),
+		providers:       make(map[string]types.Provider),
+		latencyStats:    make(map[string]*LatencyStats),
+		hedgeBudgets:    make(map[string]*hedgeBudget),
+		experimentStats: make(map[string]*variantStats),
+		shadows:         make(map[string]*shadowState),
+		limiters:        make(map[string]*concurrencyLimiter),
+		breakers:        make(map[string]*breakerEntry),
+		logger:          logger,
+		started:         false,
+		ctx:             ctx,
+		cancel:          cancel,
 	}
 
 	// Initialize components
@@ -75,7 +93,7 @@ func (sr *SmartRouter) initializeComponents() error {
 	}
 
 	// Initialize health checker
-	if sr.healthChecker, err = NewHealthChecker(DefaultHealthCheckConfig(), sr.logger); err != nil {
+	if sr.healthChecker, err = NewHealthChecker(sr.healthCheckConfig(), sr.logger); err != nil {
 		return fmt.Errorf("failed to create health checker: %w", err)
 	}
 
@@ -89,14 +107,42 @@ func (sr *SmartRouter) initializeComponents() error {
 	return nil
 }
 
-// RouteRequest routes a request to the best available provider
+// RouteRequest routes a request to the best available provider the request's routing policy allows
 func (sr *SmartRouter) RouteRequest(ctx context.Context, req *types.Request) (*SmartRoutingResult, error) {
-	startTime := time.Now()
-
 	sr.mutex.RLock()
 	providers := sr.getAvailableProviders()
 	sr.mutex.RUnlock()
 
+	providers, err := FilterByPolicy(RoutingPolicyFrom(ctx), req.Model, providers)
+	if err != nil {
+		return nil, err
+	}
+	return sr.route(req, providers)
+}
+
+// RouteRequestAmong routes a request to the best provider among candidates, e.g. those serving its model.
+// Candidates that were never added to the router are ignored.
+func (sr *SmartRouter) RouteRequestAmong(ctx context.Context, req *types.Request, candidates []types.Provider) (*SmartRoutingResult, error) {
+	sr.mutex.RLock()
+	providers := make([]types.Provider, 0, len(candidates))
+	for _, candidate := range candidates {
+		if provider, exists := sr.providers[candidate.GetName()]; exists {
+			providers = append(providers, provider)
+		}
+	}
+	sr.mutex.RUnlock()
+
+	providers, err := FilterByPolicy(RoutingPolicyFrom(ctx), req.Model, providers)
+	if err != nil {
+		return nil, err
+	}
+	return sr.route(req, providers)
+}
+
+// route selects one of providers with the configured strategy, preferring healthy ones
+func (sr *SmartRouter) route(req *types.Request, providers []types.Provider) (*SmartRoutingResult, error) {
+	startTime := time.Now()
+
 	if len(providers) == 0 {
 		if sr.metricsCollector != nil {
 			sr.metricsCollector.RecordRouting("", time.Since(startTime), false)
@@ -104,8 +150,17 @@ func (sr *SmartRouter) RouteRequest(ctx context.Context, req *types.Request) (*S
 		return nil, ErrNoAvailableProvider
 	}
 
+	// Providers whose circuit breaker for the model is open are skipped until it lets a probe through
+	providers, err := sr.withClosedCircuits(providers, req.Model)
+	if err != nil {
+		if sr.metricsCollector != nil {
+			sr.metricsCollector.RecordRouting("", time.Since(startTime), false)
+		}
+		return nil, err
+	}
+
 	// Get healthy providers
-	healthyProviders := sr.healthChecker.GetHealthyProviders()
+	healthyProviders := sr.healthyAmong(providers)
 	if len(healthyProviders) == 0 {
 		// Fall back to all providers if none are healthy
 		healthyProviders = sr.convertToProviderSlice(providers)
@@ -113,6 +168,7 @@ func (sr *SmartRouter) RouteRequest(ctx context.Context, req *types.Request) (*S
 	}
 
 	// Select provider using strategy
+	sr.syncEjections(providers)
 	strategyStart := time.Now()
 	selectedProvider, err := sr.strategy.SelectProvider(healthyProviders, req)
 	strategyLatency := time.Since(strategyStart)
@@ -243,6 +299,11 @@ func (sr *SmartRouter) UpdateConfig(config *SmartRouterConfig) error {
 		return fmt.Errorf("failed to update strategy weights: %w", err)
 	}
 
+	// Outlier detection lives in the health checker
+	if err := sr.healthChecker.UpdateConfig(sr.healthCheckConfig()); err != nil {
+		return fmt.Errorf("failed to update health checker: %w", err)
+	}
+
 	sr.logger.Info("Router configuration updated")
 
 	return nil
@@ -256,6 +317,22 @@ func (sr *SmartRouter) GetMetrics() *RoutingMetrics {
 	return sr.metricsCollector.GetRoutingMetrics()
 }
 
+// GetStrategy returns the load balancing strategy in use
+func (sr *SmartRouter) GetStrategy() strategies.LoadBalanceStrategy {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	return sr.strategy
+}
+
+// GetAdmissionMetrics returns the admission queue metrics of each concurrency limit
+func (sr *SmartRouter) GetAdmissionMetrics() map[string]*AdmissionMetrics {
+	if sr.metricsCollector == nil {
+		return map[string]*AdmissionMetrics{}
+	}
+	return sr.metricsCollector.GetAdmissionMetrics()
+}
+
 // GetHealthStatus returns health status of all providers
 func (sr *SmartRouter) GetHealthStatus() map[string]*HealthResult {
 	return sr.healthChecker.GetAllHealthResults()
@@ -313,6 +390,24 @@ func (sr *SmartRouter) getAvailableProviders() []types.Provider {
 	return providers
 }
 
+// healthyAmong returns the providers the health checker currently reports as healthy
+func (sr *SmartRouter) healthyAmong(providers []types.Provider) []*types.Provider {
+	healthyNames := make(map[string]bool)
+	for _, provider := range sr.healthChecker.GetHealthyProviders() {
+		healthyNames[(*provider).GetName()] = true
+	}
+
+	// Keep the candidates' order so order-sensitive strategies such as round robin stay even
+	var healthy []*types.Provider
+	for _, provider := range providers {
+		if healthyNames[provider.GetName()] {
+			provider := provider
+			healthy = append(healthy, &provider)
+		}
+	}
+	return healthy
+}
+
 // convertToProviderSlice converts a slice of Provider interfaces to *types.Provider
 func (sr *SmartRouter) convertToProviderSlice(providers []types.Provider) []*types.Provider {
 	result := make([]*types.Provider, len(providers))
@@ -356,7 +451,21 @@ func (sr *SmartRouter) createStrategy(strategyName string) (strategies.LoadBalan
 		return strategies.NewLeastConnectionsStrategy(), nil
 	case "health_based":
 		return strategies.NewHealthBasedStrategy(), nil
+	case "cost_optimized":
+		return strategies.NewCostOptimizedStrategy(sr.config.CostOptimized, nil), nil
+	case "peak_ewma":
+		return strategies.NewPeakEWMAStrategy(strategies.DefaultPeakEWMADecay), nil
+	case "prefix_affinity":
+		return strategies.NewPrefixAffinityStrategy(sr.config.PrefixAffinity, sr.newAffinityHasher()), nil
 	default:
 		return nil, fmt.Errorf("unsupported strategy: %s", strategyName)
 	}
 }
+
+// newAffinityHasher returns the hash the prefix_affinity strategy places conversations with
+func (sr *SmartRouter) newAffinityHasher() strategies.NodeHasher {
+	if sr.config.PrefixAffinity != nil && sr.config.PrefixAffinity.Hash == types.PrefixAffinityRendezvous {
+		return NewRendezvousHash()
+	}
+	return NewHashRing()
+}
@@ -0,0 +1,240 @@
+// Package strategies implements load balancing strategies
+package strategies
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// costLatencyAlpha is the weight of the newest sample in a provider's latency average
+	costLatencyAlpha = 0.3
+
+	// costEpsilon is the difference below which two cost estimates are considered equal
+	costEpsilon = 1e-12
+)
+
+// CostOptimizedStrategy implements cost-optimized load balancing
+// It estimates what the concrete request would cost on each candidate and picks the cheapest,
+// optionally only among candidates within a latency or quality tolerance of the best one
+type CostOptimizedStrategy struct {
+	pricing   *cost.PricingManager
+	estimator *cost.TokenEstimator
+	config    types.CostOptimizedConfig
+	latencies map[string]time.Duration // Provider name -> average successful call latency
+	ties      uint64                   // Rotates among equally cheap candidates
+	metrics   *StrategyMetrics
+	mutex     sync.RWMutex
+}
+
+// NewCostOptimizedStrategy creates a new cost-optimized strategy.
+// A nil config applies no tolerance and a nil pricing manager uses the default pricing data.
+func NewCostOptimizedStrategy(config *types.CostOptimizedConfig, pricing *cost.PricingManager) LoadBalanceStrategy {
+	if pricing == nil {
+		pricing = cost.NewPricingManager()
+	}
+
+	co := &CostOptimizedStrategy{
+		pricing:   pricing,
+		estimator: cost.NewTokenEstimator(),
+		latencies: make(map[string]time.Duration),
+		metrics: &StrategyMetrics{
+			StrategyName:      "cost_optimized",
+			SelectionCount:    0,
+			SelectionLatency:  0,
+			DistributionStats: make(map[string]float64),
+			LastUsed:          time.Now(),
+		},
+	}
+	if config != nil {
+		co.config = *config
+	}
+
+	return co
+}
+
+// SelectProvider selects the cheapest provider for the request among those within tolerance
+func (co *CostOptimizedStrategy) SelectProvider(providers []*types.Provider, request *types.Request) (*types.Provider, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoAvailableProvider
+	}
+
+	start := time.Now()
+
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+
+	eligible := co.withinQuality(co.withinLatency(providers))
+
+	var chatReq *types.ChatCompletionRequest
+	if request != nil {
+		chatReq = request.ToChatCompletionRequest()
+	}
+
+	// Collect the cheapest candidates; equal estimates share the traffic
+	var cheapest []*types.Provider
+	minCost := math.MaxFloat64
+	for _, provider := range eligible {
+		estimate := co.estimateCost(*provider, chatReq)
+		switch {
+		case estimate < minCost-costEpsilon:
+			minCost = estimate
+			cheapest = []*types.Provider{provider}
+		case estimate <= minCost+costEpsilon:
+			cheapest = append(cheapest, provider)
+		}
+	}
+
+	selected := cheapest[co.ties%uint64(len(cheapest))]
+	co.ties++
+
+	co.updateMetrics((*selected).GetName(), minCost, time.Since(start))
+
+	return selected, nil
+}
+
+// estimateCost estimates the request's cost in USD on a provider, priced as the instance (see PricingFor)
+func (co *CostOptimizedStrategy) estimateCost(provider types.Provider, req *types.ChatCompletionRequest) float64 {
+	if req == nil {
+		return 0
+	}
+
+	providerType := provider.GetType()
+	tokens, err := co.estimator.EstimateTokens(req, providerType)
+	if err != nil {
+		return math.MaxFloat64
+	}
+
+	pricing := co.pricing.PricingFor(provider.GetName(), providerType, req.Model)
+	return cost.TokenCost(pricing, tokens.InputTokens, tokens.OutputTokens)
+}
+
+// withinLatency keeps providers whose average latency is within the tolerance of the fastest.
+// Providers without latency data are kept so they get a chance to be measured.
+func (co *CostOptimizedStrategy) withinLatency(providers []*types.Provider) []*types.Provider {
+	if co.config.LatencyTolerance <= 0 {
+		return providers
+	}
+
+	var fastest time.Duration
+	for _, provider := range providers {
+		if latency, exists := co.latencies[(*provider).GetName()]; exists && (fastest == 0 || latency < fastest) {
+			fastest = latency
+		}
+	}
+	if fastest == 0 {
+		return providers
+	}
+
+	limit := time.Duration(float64(fastest) * (1 + co.config.LatencyTolerance))
+	kept := make([]*types.Provider, 0, len(providers))
+	for _, provider := range providers {
+		if latency, exists := co.latencies[(*provider).GetName()]; !exists || latency <= limit {
+			kept = append(kept, provider)
+		}
+	}
+	return kept
+}
+
+// withinQuality keeps providers whose quality score is within the tolerance of the best
+func (co *CostOptimizedStrategy) withinQuality(providers []*types.Provider) []*types.Provider {
+	if co.config.QualityTolerance <= 0 || len(co.config.Quality) == 0 {
+		return providers
+	}
+
+	best := 0.0
+	for _, provider := range providers {
+		if quality := co.config.Quality[(*provider).GetName()]; quality > best {
+			best = quality
+		}
+	}
+
+	floor := best * (1 - co.config.QualityTolerance)
+	kept := make([]*types.Provider, 0, len(providers))
+	for _, provider := range providers {
+		if co.config.Quality[(*provider).GetName()] >= floor {
+			kept = append(kept, provider)
+		}
+	}
+	return kept
+}
+
+// UpdateProviderHealth folds a successful call's latency into the provider's average
+func (co *CostOptimizedStrategy) UpdateProviderHealth(providerName string, responseTime time.Duration, success bool) {
+	if !success {
+		return
+	}
+
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+
+	if current, exists := co.latencies[providerName]; exists {
+		co.latencies[providerName] = time.Duration(costLatencyAlpha*float64(responseTime) + (1-costLatencyAlpha)*float64(current))
+	} else {
+		co.latencies[providerName] = responseTime
+	}
+}
+
+// UpdateWeights is a no-op for cost-optimized (weights not applicable)
+func (co *CostOptimizedStrategy) UpdateWeights(weights map[string]int) error {
+	// Cost-optimized selection is driven by pricing, so this is a no-op
+	return nil
+}
+
+// GetStrategyName returns the strategy name
+func (co *CostOptimizedStrategy) GetStrategyName() string {
+	return "cost_optimized"
+}
+
+// GetMetrics returns strategy metrics
+func (co *CostOptimizedStrategy) GetMetrics() *StrategyMetrics {
+	co.mutex.RLock()
+	defer co.mutex.RUnlock()
+
+	return co.metrics
+}
+
+// Reset resets the strategy state
+func (co *CostOptimizedStrategy) Reset() error {
+	co.mutex.Lock()
+	defer co.mutex.Unlock()
+
+	co.latencies = make(map[string]time.Duration)
+	co.ties = 0
+
+	co.metrics.SelectionCount = 0
+	co.metrics.SelectionLatency = 0
+	co.metrics.DistributionStats = make(map[string]float64)
+	co.metrics.LastUsed = time.Now()
+
+	return nil
+}
+
+// updateMetrics updates strategy metrics
+func (co *CostOptimizedStrategy) updateMetrics(providerName string, estimate float64, latency time.Duration) {
+	co.metrics.SelectionCount++
+
+	// Update average latency
+	if co.metrics.SelectionCount == 1 {
+		co.metrics.SelectionLatency = latency
+	} else {
+		// Running average calculation
+		count := co.metrics.SelectionCount
+		avgNanos := int64(co.metrics.SelectionLatency)
+		newAvgNanos := (avgNanos*(count-1) + int64(latency)) / count
+		co.metrics.SelectionLatency = time.Duration(newAvgNanos)
+	}
+
+	// Update distribution stats, including the cost estimate the last selection was made on
+	if co.metrics.DistributionStats == nil {
+		co.metrics.DistributionStats = make(map[string]float64)
+	}
+	co.metrics.DistributionStats[providerName]++
+	co.metrics.DistributionStats[providerName+"_estimated_cost"] = estimate
+
+	co.metrics.LastUsed = time.Now()
+}
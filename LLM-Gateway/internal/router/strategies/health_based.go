@@ -27,6 +27,7 @@ type ProviderHealth struct {
 	RequestCount int64         `json:"request_count"`
 	SuccessCount int64         `json:"success_count"`
 	IsHealthy    bool          `json:"is_healthy"`
+	Ejected      bool          `json:"ejected,omitempty"` // Ejected by passive outlier detection
 }
 
 // NewHealthBasedStrategy creates a new health-based strategy
@@ -147,7 +148,7 @@ func (hb *HealthBasedStrategy) UpdateProviderHealth(providerName string, respons
 	health.ResponseTime = time.Duration(float64(health.ResponseTime)*(1-alpha) + float64(responseTime)*alpha)
 
 	// Update health status
-	health.IsHealthy = health.SuccessRate >= 0.8 && health.ResponseTime < 5*time.Second
+	health.IsHealthy = hb.isHealthy(health)
 
 	// Recalculate health score
 	health.HealthScore = hb.calculateHealthScore(health)
@@ -155,8 +156,40 @@ func (hb *HealthBasedStrategy) UpdateProviderHealth(providerName string, respons
 	health.LastUpdate = time.Now()
 }
 
+// SetProviderEjected marks a provider as ejected by outlier detection, which scores it zero until it returns
+func (hb *HealthBasedStrategy) SetProviderEjected(providerName string, ejected bool) {
+	hb.mutex.Lock()
+	defer hb.mutex.Unlock()
+
+	health, exists := hb.healthScores[providerName]
+	if !exists {
+		if !ejected {
+			return
+		}
+		health = &ProviderHealth{
+			ResponseTime: 100 * time.Millisecond,
+			SuccessRate:  1.0,
+			LastUpdate:   time.Now(),
+		}
+		hb.healthScores[providerName] = health
+	}
+
+	health.Ejected = ejected
+	health.IsHealthy = hb.isHealthy(health)
+	health.HealthScore = hb.calculateHealthScore(health)
+}
+
+// isHealthy reports whether a provider's recent calls were successful and fast enough
+func (hb *HealthBasedStrategy) isHealthy(health *ProviderHealth) bool {
+	return !health.Ejected && health.SuccessRate >= 0.8 && health.ResponseTime < 5*time.Second
+}
+
 // calculateHealthScore computes a composite health score (0.0 to 1.0)
 func (hb *HealthBasedStrategy) calculateHealthScore(health *ProviderHealth) float64 {
+	if health.Ejected {
+		return 0
+	}
+
 	// Weight factors for different metrics
 	const (
 		successRateWeight  = 0.5 // 50% weight on success rate
@@ -258,6 +291,7 @@ func (hb *HealthBasedStrategy) GetProviderHealth(providerName string) *ProviderH
 			RequestCount: health.RequestCount,
 			SuccessCount: health.SuccessCount,
 			IsHealthy:    health.IsHealthy,
+			Ejected:      health.Ejected,
 		}
 	}
 	return nil
@@ -25,6 +25,28 @@ type LoadBalanceStrategy interface {
 	Reset() error
 }
 
+// HealthUpdater is implemented by strategies that learn from the outcome of provider calls
+type HealthUpdater interface {
+	// UpdateProviderHealth records the response time and result of a call to a provider
+	UpdateProviderHealth(providerName string, responseTime time.Duration, success bool)
+}
+
+// OutlierAware is implemented by strategies that score providers ejected by passive outlier detection
+type OutlierAware interface {
+	// SetProviderEjected marks a provider as ejected from, or returned to, the healthy set
+	SetProviderEjected(providerName string, ejected bool)
+}
+
+// LoadTracker is implemented by strategies that weigh providers by the requests in flight to them.
+// Calls are counted from when they are sent, so selections that are never sent add no load.
+type LoadTracker interface {
+	// CallStarted records that a request was sent to a provider
+	CallStarted(providerName string)
+
+	// CallFinished records that a request sent to a provider ended, however it ended
+	CallFinished(providerName string)
+}
+
 // StrategyMetrics represents metrics for a specific strategy
 type StrategyMetrics struct {
 	StrategyName      string             `json:"strategy_name"`
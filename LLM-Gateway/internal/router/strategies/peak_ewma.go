@@ -0,0 +1,248 @@
+// Package strategies implements load balancing strategies
+package strategies
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// DefaultPeakEWMADecay is the time over which a latency sample loses most of its weight
+	DefaultPeakEWMADecay = 10 * time.Second
+
+	// peakEWMAPenalty is the latency assumed for failed calls and for unmeasured providers with requests in flight
+	peakEWMAPenalty = float64(time.Second)
+)
+
+// PeakEWMAStrategy implements peak-EWMA load balancing as in Finagle and Linkerd.
+// Each provider's latency is an exponentially weighted moving average that decays with time
+// but jumps straight to any slower sample, so a degrading provider is avoided at once and
+// recovers gradually. The expected completion time is that average times the requests
+// already in flight plus one, and the provider with the lowest is selected.
+type PeakEWMAStrategy struct {
+	decay   time.Duration
+	states  map[string]*peakEWMAState
+	ties    uint64 // Rotates among providers with equal cost
+	metrics *StrategyMetrics
+	mutex   sync.Mutex
+}
+
+// peakEWMAState is the latency average and load of one provider
+type peakEWMAState struct {
+	ewma    float64 // Nanoseconds
+	stamp   time.Time
+	pending int64
+}
+
+// NewPeakEWMAStrategy creates a new peak-EWMA strategy; a non-positive decay uses DefaultPeakEWMADecay
+func NewPeakEWMAStrategy(decay time.Duration) LoadBalanceStrategy {
+	if decay <= 0 {
+		decay = DefaultPeakEWMADecay
+	}
+
+	return &PeakEWMAStrategy{
+		decay:  decay,
+		states: make(map[string]*peakEWMAState),
+		metrics: &StrategyMetrics{
+			StrategyName:      "peak_ewma",
+			SelectionCount:    0,
+			SelectionLatency:  0,
+			DistributionStats: make(map[string]float64),
+			LastUsed:          time.Now(),
+		},
+	}
+}
+
+// SelectProvider selects the provider with the lowest expected completion time
+func (pe *PeakEWMAStrategy) SelectProvider(providers []*types.Provider, request *types.Request) (*types.Provider, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoAvailableProvider
+	}
+
+	start := time.Now()
+
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	// Start the scan at a rotating offset so equal costs share the traffic
+	offset := int(pe.ties % uint64(len(providers)))
+	pe.ties++
+
+	var selected *types.Provider
+	minCost := math.MaxFloat64
+	for i := range providers {
+		provider := providers[(offset+i)%len(providers)]
+		state := pe.stateFor((*provider).GetName(), start)
+		if cost := state.cost(start, pe.decay); cost < minCost {
+			minCost = cost
+			selected = provider
+		}
+	}
+
+	pe.updateMetrics((*selected).GetName(), time.Since(start))
+
+	return selected, nil
+}
+
+// CallStarted puts a request to a provider in flight
+func (pe *PeakEWMAStrategy) CallStarted(providerName string) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	pe.stateFor(providerName, time.Now()).pending++
+}
+
+// CallFinished takes a request to a provider out of flight
+func (pe *PeakEWMAStrategy) CallFinished(providerName string) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	if state := pe.stateFor(providerName, time.Now()); state.pending > 0 {
+		state.pending--
+	}
+}
+
+// UpdateProviderHealth folds the latency of a call into the provider's average.
+// A failed call counts as at least the penalty latency so fast failures don't attract traffic.
+func (pe *PeakEWMAStrategy) UpdateProviderHealth(providerName string, responseTime time.Duration, success bool) {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	now := time.Now()
+	state := pe.stateFor(providerName, now)
+
+	rtt := float64(responseTime)
+	if !success && rtt < peakEWMAPenalty {
+		rtt = peakEWMAPenalty
+	}
+	state.observe(rtt, now, pe.decay)
+}
+
+// stateFor returns a provider's state, creating it on first use
+func (pe *PeakEWMAStrategy) stateFor(providerName string, now time.Time) *peakEWMAState {
+	state, exists := pe.states[providerName]
+	if !exists {
+		state = &peakEWMAState{stamp: now}
+		pe.states[providerName] = state
+	}
+	return state
+}
+
+// observe adds a latency sample: slower samples replace the average, faster ones are blended
+// in with a weight that grows with the time since the previous sample
+func (s *peakEWMAState) observe(rtt float64, now time.Time, decay time.Duration) {
+	elapsed := now.Sub(s.stamp)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	s.stamp = now
+
+	if rtt > s.ewma {
+		s.ewma = rtt
+		return
+	}
+	weight := math.Exp(-float64(elapsed) / float64(decay))
+	s.ewma = s.ewma*weight + rtt*(1-weight)
+}
+
+// cost returns the expected completion time of one more request. The average first decays
+// towards zero for the time since the last sample, so an idle provider is eventually retried.
+func (s *peakEWMAState) cost(now time.Time, decay time.Duration) float64 {
+	s.observe(0, now, decay)
+
+	if s.ewma == 0 && s.pending > 0 {
+		// Unmeasured but busy: assume the penalty latency rather than free capacity
+		return peakEWMAPenalty + float64(s.pending)
+	}
+	return s.ewma * float64(s.pending+1)
+}
+
+// GetLatency returns the current latency average of a provider
+func (pe *PeakEWMAStrategy) GetLatency(providerName string) time.Duration {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	if state, exists := pe.states[providerName]; exists {
+		return time.Duration(state.ewma)
+	}
+	return 0
+}
+
+// GetPending returns the number of requests in flight to a provider
+func (pe *PeakEWMAStrategy) GetPending(providerName string) int64 {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	if state, exists := pe.states[providerName]; exists {
+		return state.pending
+	}
+	return 0
+}
+
+// UpdateWeights is a no-op for peak-EWMA (weights not applicable)
+func (pe *PeakEWMAStrategy) UpdateWeights(weights map[string]int) error {
+	// Peak-EWMA is driven by observed latency, so this is a no-op
+	return nil
+}
+
+// GetStrategyName returns the strategy name
+func (pe *PeakEWMAStrategy) GetStrategyName() string {
+	return "peak_ewma"
+}
+
+// GetMetrics returns strategy metrics
+func (pe *PeakEWMAStrategy) GetMetrics() *StrategyMetrics {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	// Add current latency averages and loads to metrics
+	for provider, state := range pe.states {
+		pe.metrics.DistributionStats[provider+"_ewma_ms"] = state.ewma / float64(time.Millisecond)
+		pe.metrics.DistributionStats[provider+"_pending"] = float64(state.pending)
+	}
+
+	return pe.metrics
+}
+
+// Reset resets the strategy state
+func (pe *PeakEWMAStrategy) Reset() error {
+	pe.mutex.Lock()
+	defer pe.mutex.Unlock()
+
+	pe.states = make(map[string]*peakEWMAState)
+	pe.ties = 0
+
+	pe.metrics.SelectionCount = 0
+	pe.metrics.SelectionLatency = 0
+	pe.metrics.DistributionStats = make(map[string]float64)
+	pe.metrics.LastUsed = time.Now()
+
+	return nil
+}
+
+// updateMetrics updates strategy metrics
+func (pe *PeakEWMAStrategy) updateMetrics(providerName string, latency time.Duration) {
+	pe.metrics.SelectionCount++
+
+	// Update average latency
+	if pe.metrics.SelectionCount == 1 {
+		pe.metrics.SelectionLatency = latency
+	} else {
+		// Running average calculation
+		count := pe.metrics.SelectionCount
+		avgNanos := int64(pe.metrics.SelectionLatency)
+		newAvgNanos := (avgNanos*(count-1) + int64(latency)) / count
+		pe.metrics.SelectionLatency = time.Duration(newAvgNanos)
+	}
+
+	// Update distribution stats
+	if pe.metrics.DistributionStats == nil {
+		pe.metrics.DistributionStats = make(map[string]float64)
+	}
+	pe.metrics.DistributionStats[providerName]++
+
+	pe.metrics.LastUsed = time.Now()
+}
@@ -0,0 +1,292 @@
+// Package strategies implements load balancing strategies
+package strategies
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// DefaultPrefixMessages hashes the system prompt with the first message, which every turn of a conversation shares
+	DefaultPrefixMessages = 1
+
+	// DefaultAffinityLoadFactor lets a provider take 25% more than its share of in-flight requests before spilling
+	DefaultAffinityLoadFactor = 1.25
+)
+
+// NodeHasher places keys on named nodes; HashRing and RendezvousHash in the router package implement it
+type NodeHasher interface {
+	// UpdateNodes replaces the set of nodes
+	UpdateNodes(nodes []string)
+
+	// GetNodes returns up to count distinct nodes for key, most preferred first
+	GetNodes(key string, count int) []string
+}
+
+// PrefixAffinityStrategy sends requests that share a prompt prefix to the same provider so upstream
+// prompt caches (Anthropic, OpenAI, DeepSeek) are hit. The affinity key is the request's conversation
+// ID or else its system prompt and leading messages, placed with consistent hashing with bounded
+// loads: a provider already holding more than the load factor times the average in-flight requests,
+// or missing from the healthy candidates, is skipped for the next one in the key's preference order.
+type PrefixAffinityStrategy struct {
+	prefixMessages int
+	loadFactor     float64
+	hasher         NodeHasher
+	members        map[string]bool // Providers placed on the hasher
+	pending        map[string]int64
+	ties           uint64 // Rotates among equally loaded providers for requests without a key
+	metrics        *StrategyMetrics
+	mutex          sync.Mutex
+}
+
+// NewPrefixAffinityStrategy creates a new prefix affinity strategy placing keys with hasher
+func NewPrefixAffinityStrategy(config *types.PrefixAffinityConfig, hasher NodeHasher) LoadBalanceStrategy {
+	prefixMessages := DefaultPrefixMessages
+	loadFactor := DefaultAffinityLoadFactor
+	if config != nil {
+		if config.PrefixMessages > 0 {
+			prefixMessages = config.PrefixMessages
+		}
+		if config.LoadFactor > 1 {
+			loadFactor = config.LoadFactor
+		}
+	}
+
+	return &PrefixAffinityStrategy{
+		prefixMessages: prefixMessages,
+		loadFactor:     loadFactor,
+		hasher:         hasher,
+		members:        make(map[string]bool),
+		pending:        make(map[string]int64),
+		metrics: &StrategyMetrics{
+			StrategyName:      "prefix_affinity",
+			SelectionCount:    0,
+			SelectionLatency:  0,
+			DistributionStats: make(map[string]float64),
+			LastUsed:          time.Now(),
+		},
+	}
+}
+
+// SelectProvider selects the first provider in the request's preference order that is under the load bound
+func (pa *PrefixAffinityStrategy) SelectProvider(providers []*types.Provider, request *types.Request) (*types.Provider, error) {
+	if len(providers) == 0 {
+		return nil, ErrNoAvailableProvider
+	}
+
+	start := time.Now()
+
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	candidates := make(map[string]*types.Provider, len(providers))
+	var inFlight int64
+	for _, provider := range providers {
+		name := (*provider).GetName()
+		candidates[name] = provider
+		inFlight += pa.pending[name]
+	}
+	pa.place(candidates)
+
+	// Each provider may hold its share of the in-flight requests, this one included, times the load factor
+	capacity := int64(math.Ceil(pa.loadFactor * float64(inFlight+1) / float64(len(providers))))
+
+	var selected *types.Provider
+	key := AffinityKey(request, pa.prefixMessages)
+	if key == "" {
+		selected = pa.leastLoaded(providers)
+	} else {
+		var preferred *types.Provider
+		for _, name := range pa.hasher.GetNodes(key, len(pa.members)) {
+			provider, ok := candidates[name]
+			if !ok {
+				// Unhealthy or not serving the model: fall through to the next provider for the key
+				continue
+			}
+			if preferred == nil {
+				preferred = provider
+			}
+			if pa.pending[name] < capacity {
+				selected = provider
+				break
+			}
+		}
+		if selected == nil {
+			selected = preferred
+		}
+		if selected != preferred {
+			pa.metrics.DistributionStats["affinity_spilled"]++
+		}
+	}
+
+	pa.updateMetrics((*selected).GetName(), time.Since(start))
+
+	return selected, nil
+}
+
+// CallStarted puts a request to a provider in flight
+func (pa *PrefixAffinityStrategy) CallStarted(providerName string) {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	pa.pending[providerName]++
+}
+
+// CallFinished takes a request to a provider out of flight
+func (pa *PrefixAffinityStrategy) CallFinished(providerName string) {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	if pa.pending[providerName] > 0 {
+		pa.pending[providerName]--
+	}
+}
+
+// AffinityKey returns the key requests sharing a cacheable prefix have in common: the conversation ID
+// when set, otherwise a digest of the system messages and the first prefixMessages other messages.
+// Both are scoped to the model since prompt caches are. It is empty when there is nothing to key on.
+func AffinityKey(request *types.Request, prefixMessages int) string {
+	if request == nil {
+		return ""
+	}
+	if request.ConversationID != "" {
+		return request.Model + "|conversation|" + request.ConversationID
+	}
+
+	digest := sha256.New()
+	hashed, leading := 0, 0
+	for _, message := range request.Messages {
+		if message.Role != "system" {
+			if leading == prefixMessages {
+				break
+			}
+			leading++
+		}
+		digest.Write([]byte(message.Role))
+		digest.Write([]byte{0})
+		digest.Write([]byte(message.Content))
+		digest.Write([]byte{0})
+		hashed++
+	}
+	if hashed == 0 {
+		return ""
+	}
+	return request.Model + "|prefix|" + hex.EncodeToString(digest.Sum(nil))
+}
+
+// place adds providers not yet on the hasher; nodes are never removed so keys keep their provider
+// while it is briefly unhealthy
+func (pa *PrefixAffinityStrategy) place(candidates map[string]*types.Provider) {
+	added := false
+	for name := range candidates {
+		if !pa.members[name] {
+			pa.members[name] = true
+			added = true
+		}
+	}
+	if !added {
+		return
+	}
+
+	nodes := make([]string, 0, len(pa.members))
+	for name := range pa.members {
+		nodes = append(nodes, name)
+	}
+	sort.Strings(nodes)
+	pa.hasher.UpdateNodes(nodes)
+}
+
+// leastLoaded returns the provider with the fewest requests in flight, rotating among ties
+func (pa *PrefixAffinityStrategy) leastLoaded(providers []*types.Provider) *types.Provider {
+	offset := int(pa.ties % uint64(len(providers)))
+	pa.ties++
+
+	var selected *types.Provider
+	for i := range providers {
+		provider := providers[(offset+i)%len(providers)]
+		if selected == nil || pa.pending[(*provider).GetName()] < pa.pending[(*selected).GetName()] {
+			selected = provider
+		}
+	}
+	return selected
+}
+
+// GetPending returns the number of requests in flight to a provider
+func (pa *PrefixAffinityStrategy) GetPending(providerName string) int64 {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	return pa.pending[providerName]
+}
+
+// UpdateWeights is a no-op for prefix affinity (weights not applicable)
+func (pa *PrefixAffinityStrategy) UpdateWeights(weights map[string]int) error {
+	// Placement is decided by the hash, so this is a no-op
+	return nil
+}
+
+// GetStrategyName returns the strategy name
+func (pa *PrefixAffinityStrategy) GetStrategyName() string {
+	return "prefix_affinity"
+}
+
+// GetMetrics returns strategy metrics
+func (pa *PrefixAffinityStrategy) GetMetrics() *StrategyMetrics {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	// Add current loads to metrics
+	for provider, pending := range pa.pending {
+		pa.metrics.DistributionStats[provider+"_pending"] = float64(pending)
+	}
+
+	return pa.metrics
+}
+
+// Reset resets the strategy state
+func (pa *PrefixAffinityStrategy) Reset() error {
+	pa.mutex.Lock()
+	defer pa.mutex.Unlock()
+
+	pa.members = make(map[string]bool)
+	pa.pending = make(map[string]int64)
+	pa.hasher.UpdateNodes(nil)
+	pa.ties = 0
+
+	pa.metrics.SelectionCount = 0
+	pa.metrics.SelectionLatency = 0
+	pa.metrics.DistributionStats = make(map[string]float64)
+	pa.metrics.LastUsed = time.Now()
+
+	return nil
+}
+
+// updateMetrics updates strategy metrics
+func (pa *PrefixAffinityStrategy) updateMetrics(providerName string, latency time.Duration) {
+	pa.metrics.SelectionCount++
+
+	// Update average latency
+	if pa.metrics.SelectionCount == 1 {
+		pa.metrics.SelectionLatency = latency
+	} else {
+		// Running average calculation
+		count := pa.metrics.SelectionCount
+		avgNanos := int64(pa.metrics.SelectionLatency)
+		newAvgNanos := (avgNanos*(count-1) + int64(latency)) / count
+		pa.metrics.SelectionLatency = time.Duration(newAvgNanos)
+	}
+
+	// Update distribution stats
+	if pa.metrics.DistributionStats == nil {
+		pa.metrics.DistributionStats = make(map[string]float64)
+	}
+	pa.metrics.DistributionStats[providerName]++
+
+	pa.metrics.LastUsed = time.Now()
+}
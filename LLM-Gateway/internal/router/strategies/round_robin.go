@@ -81,6 +81,9 @@ func (rr *RoundRobinStrategy) Reset() error {
 
 // updateMetrics updates strategy metrics
 func (rr *RoundRobinStrategy) updateMetrics(providerName string, latency time.Duration) {
+	rr.mutex.Lock()
+	defer rr.mutex.Unlock()
+
 	atomic.AddInt64(&rr.metrics.SelectionCount, 1)
 
 	// Update average latency
@@ -94,13 +97,11 @@ func (rr *RoundRobinStrategy) updateMetrics(providerName string, latency time.Du
 		rr.metrics.SelectionLatency = time.Duration(newAvgNanos)
 	}
 
-	// Update distribution stats (thread-safe)
-	rr.mutex.Lock()
+	// Update distribution stats
 	if rr.metrics.DistributionStats == nil {
 		rr.metrics.DistributionStats = make(map[string]float64)
 	}
 	rr.metrics.DistributionStats[providerName]++
-	rr.mutex.Unlock()
 
 	rr.metrics.LastUsed = time.Now()
 }
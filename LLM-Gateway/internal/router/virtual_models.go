@@ -0,0 +1,240 @@
+// Package router implements virtual models served by fallback chains of concrete targets
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+// ErrUnknownVirtualModel is returned when a model is not a configured virtual model
+var ErrUnknownVirtualModel = errors.New("unknown virtual model")
+
+// ProviderCall sends a request to a provider and returns its answer; a non-nil error moves on
+// to the next target. Hedged requests run calls concurrently, so a call must not share state.
+type ProviderCall func(ctx context.Context, provider types.Provider, req *types.Request) (interface{}, error)
+
+// TargetFilter returns why a provider can't serve a virtual model target's request, or nil when it can
+type TargetFilter func(provider types.Provider, targetReq *types.Request) error
+
+// IsVirtualModel reports whether model is a configured virtual model alias
+func (sr *SmartRouter) IsVirtualModel(model string) bool {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	_, exists := sr.config.VirtualModels[model]
+	return exists
+}
+
+// VirtualModels returns the configured virtual model aliases in sorted order
+func (sr *SmartRouter) VirtualModels() []string {
+	sr.mutex.RLock()
+	defer sr.mutex.RUnlock()
+
+	aliases := make([]string, 0, len(sr.config.VirtualModels))
+	for alias := range sr.config.VirtualModels {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
+
+// ExecuteVirtualModel sends req to the targets of its virtual model until one succeeds.
+// Each target receives a copy of req with the concrete model and the target's parameter overrides;
+// the returned result names the provider and model that answered. Targets eligible rules out are
+// skipped, and when it rules out every target the reason given for the first is returned.
+// The whole chain is bounded by FailoverTimeout, like failover between the providers of a model.
+func (sr *SmartRouter) ExecuteVirtualModel(ctx context.Context, req *types.Request, eligible TargetFilter, call ProviderCall) (*SmartRoutingResult, error) {
+	startTime := time.Now()
+
+	sr.mutex.RLock()
+	virtual, exists := sr.config.VirtualModels[req.Model]
+	timeout := sr.config.FailoverTimeout
+	var targets []types.VirtualModelTarget
+	if exists {
+		targets = orderVirtualTargets(virtual)
+	}
+	providers := make(map[string]types.Provider, len(targets))
+	for _, target := range targets {
+		if provider, ok := sr.providers[target.Provider]; ok {
+			providers[target.Provider] = provider
+		}
+	}
+	sr.mutex.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownVirtualModel, req.Model)
+	}
+
+	// Targets the routing policy forbids are skipped as if they were not configured
+	if policy := RoutingPolicyFrom(ctx); policy != nil {
+		allowed := make([]types.VirtualModelTarget, 0, len(targets))
+		for _, target := range targets {
+			if provider, ok := providers[target.Provider]; ok && PolicyAllows(policy, provider, target.Model) {
+				allowed = append(allowed, target)
+			}
+		}
+		if len(allowed) == 0 {
+			return nil, &PolicyError{
+				Policy: policy.Name,
+				Model:  req.Model,
+				Reason: fmt.Sprintf("no target of virtual model %s is allowed", req.Model),
+			}
+		}
+		targets = allowed
+	}
+
+	// Targets that can't take the request are skipped before any is tried
+	if eligible != nil {
+		kept := make([]types.VirtualModelTarget, 0, len(targets))
+		servable := false
+		var reason error
+		for _, target := range targets {
+			if provider, ok := providers[target.Provider]; ok {
+				if err := eligible(provider, applyVirtualTarget(req, target)); err != nil {
+					sr.logger.WithError(err).
+						WithField("virtual_model", req.Model).
+						WithField("provider", target.Provider).
+						WithField("model", target.Model).
+						Debug("Virtual model target can't serve the request, skipping")
+					if reason == nil {
+						reason = err
+					}
+					continue
+				}
+				servable = true
+			}
+			kept = append(kept, target)
+		}
+		if !servable && reason != nil {
+			return nil, reason
+		}
+		targets = kept
+	}
+
+	sr.depositHedgeBudget(req.Model)
+	scope := newAttemptScope(ctx, timeout)
+
+	// nextLeg returns the first target from start on whose provider is registered
+	nextLeg := func(start int) (hedgeLeg, bool) {
+		for j := start; j < len(targets); j++ {
+			if provider, ok := providers[targets[j].Provider]; ok {
+				return hedgeLeg{provider: provider, req: applyVirtualTarget(req, targets[j]), index: j}, true
+			}
+		}
+		return hedgeLeg{}, false
+	}
+
+	var errs []error
+	var tried []RoutingAttempt
+	for i := 0; i < len(targets); i++ {
+		target := targets[i]
+		if _, ok := providers[target.Provider]; !ok {
+			errs = append(errs, fmt.Errorf("target %s/%s: %w", target.Provider, target.Model, ErrProviderNotFound))
+			continue
+		}
+
+		primary, _ := nextLeg(i)
+		backup := func() (hedgeLeg, bool) { return nextLeg(i + 1) }
+		winner, response, attempts, err := sr.dispatchHedged(scope.ctx, req.Model, primary, backup, call)
+		tried = append(tried, attempts...)
+
+		if err == nil {
+			scope.settle(true)
+			served := targets[winner.index]
+			return &SmartRoutingResult{
+				Provider:      winner.provider,
+				ProviderName:  served.Provider,
+				Model:         served.Model,
+				Reason:        fmt.Sprintf("Virtual model %s target %d of %d", req.Model, winner.index+1, len(targets)),
+				Attempts:      len(tried),
+				BackupUsed:    winner.index > 0,
+				Tried:         tried,
+				Response:      response,
+				Strategy:      virtualStrategy(virtual),
+				SelectionTime: time.Since(startTime),
+			}, nil
+		}
+
+		sr.logger.WithError(err).
+			WithField("virtual_model", req.Model).
+			WithField("provider", target.Provider).
+			WithField("model", target.Model).
+			Warn("Virtual model target failed, falling through")
+		errs = append(errs, fmt.Errorf("target %s/%s: %w", target.Provider, target.Model, err))
+
+		// A failed hedge used up the next target as well
+		if len(attempts) > 1 {
+			if hedge, ok := nextLeg(i + 1); ok {
+				i = hedge.index
+			}
+		}
+
+		if scope.ctx.Err() != nil {
+			break
+		}
+	}
+
+	scope.settle(false)
+	return nil, &FailoverError{
+		Attempts: tried,
+		Err:      fmt.Errorf("all targets of virtual model %s failed: %w", req.Model, errors.Join(errs...)),
+	}
+}
+
+// orderVirtualTargets returns the targets in the order they should be tried
+func orderVirtualTargets(virtual *types.VirtualModelConfig) []types.VirtualModelTarget {
+	targets := append([]types.VirtualModelTarget(nil), virtual.Targets...)
+	if virtual.Strategy != types.VirtualModelWeighted {
+		return targets
+	}
+
+	// Weighted random order (Efraimidis-Spirakis): key = u^(1/w), highest first.
+	// Zero-weight targets sort last and only serve as fallbacks.
+	keys := make([]float64, len(targets))
+	for i, target := range targets {
+		if target.Weight > 0 {
+			keys[i] = math.Pow(rand.Float64(), 1/float64(target.Weight))
+		} else {
+			keys[i] = -1
+		}
+	}
+	indexes := make([]int, len(targets))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(a, b int) bool { return keys[indexes[a]] > keys[indexes[b]] })
+
+	ordered := make([]types.VirtualModelTarget, len(targets))
+	for i, index := range indexes {
+		ordered[i] = targets[index]
+	}
+	return ordered
+}
+
+// applyVirtualTarget copies req for a target, swapping in its model and parameter overrides
+func applyVirtualTarget(req *types.Request, target types.VirtualModelTarget) *types.Request {
+	targetReq := *req
+	targetReq.Model = target.Model
+	if target.Temperature != nil {
+		targetReq.Temperature = *target.Temperature
+	}
+	if target.MaxTokens != nil {
+		targetReq.MaxTokens = *target.MaxTokens
+	}
+	return &targetReq
+}
+
+// virtualStrategy returns the strategy name reported for a virtual model
+func virtualStrategy(virtual *types.VirtualModelConfig) string {
+	if virtual.Strategy == "" {
+		return "virtual_" + types.VirtualModelOrdered
+	}
+	return "virtual_" + virtual.Strategy
+}
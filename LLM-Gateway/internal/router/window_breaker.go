@@ -0,0 +1,443 @@
+// Package router implements error-rate circuit breakers per provider and model
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+)
+
+const (
+	// Window breaker defaults
+	DefaultBreakerFailureRate = 0.5
+	DefaultBreakerWindow      = 60 * time.Second
+	DefaultBreakerWindowSize  = 100
+
+	// maxBreakerEvents is the number of recent state transitions kept for reporting
+	maxBreakerEvents = 100
+)
+
+// ErrCircuitOpen is returned for calls refused by an open circuit breaker
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned when open circuit breakers keep a request from a provider, or from every
+// provider serving its model when Provider is empty
+type CircuitOpenError struct {
+	Provider   string
+	Model      string
+	RetryAfter time.Duration // Until the first breaker lets a probe through
+}
+
+func (e *CircuitOpenError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("circuit breaker open for every provider of %s", e.Model)
+	}
+	return fmt.Sprintf("circuit breaker open for %s", providerModelKey(e.Provider, e.Model))
+}
+
+func (e *CircuitOpenError) Unwrap() error {
+	return ErrCircuitOpen
+}
+
+// BreakerEvent is a circuit breaker state transition
+type BreakerEvent struct {
+	Provider string       `json:"provider"`
+	Model    string       `json:"model"`
+	From     CircuitState `json:"from"`
+	To       CircuitState `json:"to"`
+	Reason   string       `json:"reason"`
+	Time     time.Time    `json:"time"`
+}
+
+// BreakerTicket is the permission Allow grants a call, handed back with its outcome
+type BreakerTicket struct {
+	probe      bool
+	generation uint64
+}
+
+// WindowBreakerStats contains the state and rolling window of a window circuit breaker
+type WindowBreakerStats struct {
+	Provider    string       `json:"provider,omitempty"`
+	Model       string       `json:"model,omitempty"`
+	State       CircuitState `json:"state"`
+	Requests    int          `json:"requests"` // Calls in the window
+	Failures    int          `json:"failures"`
+	FailureRate float64      `json:"failure_rate"`
+	Probes      int          `json:"probes,omitempty"` // Half-open probes in flight
+	OpenedAt    time.Time    `json:"opened_at,omitempty"`
+}
+
+// breakerEntry is the circuit breaker of one provider+model
+type breakerEntry struct {
+	provider string
+	model    string
+	breaker  *WindowCircuitBreaker
+}
+
+// breakerOutcome is one call in a breaker's rolling window
+type breakerOutcome struct {
+	at      time.Time
+	success bool
+}
+
+// WindowCircuitBreaker opens when the error rate over a rolling window of calls reaches a threshold,
+// once the window holds a minimum volume. After Timeout it turns half-open and lets up to MaxRequests
+// probes through at once: a failed probe reopens it, and MaxRequests successful probes close it.
+type WindowCircuitBreaker struct {
+	config       CircuitBreakerConfig
+	onTransition func(from, to CircuitState, reason string)
+
+	mu         sync.Mutex
+	state      CircuitState
+	outcomes   []breakerOutcome // Ring of the last WindowSize calls
+	next       int
+	openedAt   time.Time
+	generation uint64 // Incremented on every transition so stale tickets are ignored
+	probes     int
+	successes  int // Successful probes in the current half-open period
+}
+
+// NewWindowCircuitBreaker creates a closed breaker. onTransition, if set, is called after every state
+// change, outside the breaker's lock.
+func NewWindowCircuitBreaker(config CircuitBreakerConfig, onTransition func(from, to CircuitState, reason string)) *WindowCircuitBreaker {
+	config = effectiveBreakerConfig(config)
+	return &WindowCircuitBreaker{
+		config:       config,
+		onTransition: onTransition,
+		state:        StateClosed,
+		outcomes:     make([]breakerOutcome, 0, config.WindowSize),
+	}
+}
+
+// Allow reports whether a call may proceed. An open breaker past its timeout turns half-open and
+// admits up to MaxRequests concurrent probes. Every allowed call must be finished with Record or Abandon.
+func (b *WindowCircuitBreaker) Allow() (BreakerTicket, bool) {
+	b.mu.Lock()
+	var transition func()
+	defer func() {
+		b.mu.Unlock()
+		if transition != nil {
+			transition()
+		}
+	}()
+
+	now := time.Now()
+	if b.state == StateOpen {
+		if now.Sub(b.openedAt) < b.config.Timeout {
+			return BreakerTicket{}, false
+		}
+		transition = b.transition(StateHalfOpen, "open timeout elapsed")
+	}
+
+	if b.state == StateHalfOpen {
+		if b.probes+b.successes >= b.config.MaxRequests {
+			return BreakerTicket{}, false
+		}
+		b.probes++
+		return BreakerTicket{probe: true, generation: b.generation}, true
+	}
+	return BreakerTicket{generation: b.generation}, true
+}
+
+// Record reports the outcome of a call Allow let through
+func (b *WindowCircuitBreaker) Record(ticket BreakerTicket, success bool) {
+	b.mu.Lock()
+	var transition func()
+	defer func() {
+		b.mu.Unlock()
+		if transition != nil {
+			transition()
+		}
+	}()
+
+	if ticket.probe {
+		if ticket.generation != b.generation {
+			return
+		}
+		b.probes--
+		if !success {
+			transition = b.transition(StateOpen, "half-open probe failed")
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.MaxRequests {
+			transition = b.transition(StateClosed, fmt.Sprintf("%d half-open probes succeeded", b.successes))
+		}
+		return
+	}
+
+	// Calls that started before the breaker opened say nothing about its recovery
+	if b.state != StateClosed {
+		return
+	}
+	now := time.Now()
+	if len(b.outcomes) < b.config.WindowSize {
+		b.outcomes = append(b.outcomes, breakerOutcome{at: now, success: success})
+	} else {
+		b.outcomes[b.next] = breakerOutcome{at: now, success: success}
+		b.next = (b.next + 1) % b.config.WindowSize
+	}
+
+	if success {
+		return
+	}
+	requests, failures := b.window(now)
+	if requests >= b.config.MinRequests && float64(failures) >= b.config.FailureRate*float64(requests) {
+		transition = b.transition(StateOpen, fmt.Sprintf("%d of %d calls failed", failures, requests))
+	}
+}
+
+// Abandon gives back a call's permission without an outcome, as when the caller canceled it
+func (b *WindowCircuitBreaker) Abandon(ticket BreakerTicket) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if ticket.probe && ticket.generation == b.generation {
+		b.probes--
+	}
+}
+
+// Available reports whether Allow would currently let a call through, without changing state
+func (b *WindowCircuitBreaker) Available() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		return time.Since(b.openedAt) >= b.config.Timeout
+	case StateHalfOpen:
+		return b.probes+b.successes < b.config.MaxRequests
+	default:
+		return true
+	}
+}
+
+// RetryAfter returns how long until an open breaker lets a probe through
+func (b *WindowCircuitBreaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateOpen {
+		return 0
+	}
+	return max(b.config.Timeout-time.Since(b.openedAt), 0)
+}
+
+// GetStats returns the breaker's state and rolling window
+func (b *WindowCircuitBreaker) GetStats() WindowBreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	requests, failures := b.window(time.Now())
+	stats := WindowBreakerStats{
+		State:    b.state,
+		Requests: requests,
+		Failures: failures,
+		Probes:   b.probes,
+		OpenedAt: b.openedAt,
+	}
+	if requests > 0 {
+		stats.FailureRate = float64(failures) / float64(requests)
+	}
+	return stats
+}
+
+// window counts the calls and failures in the rolling window. The caller must hold b.mu.
+func (b *WindowCircuitBreaker) window(now time.Time) (int, int) {
+	requests, failures := 0, 0
+	for _, outcome := range b.outcomes {
+		if now.Sub(outcome.at) > b.config.Window {
+			continue
+		}
+		requests++
+		if !outcome.success {
+			failures++
+		}
+	}
+	return requests, failures
+}
+
+// transition moves the breaker to state and returns the notification to send once b.mu is released.
+// The caller must hold b.mu.
+func (b *WindowCircuitBreaker) transition(state CircuitState, reason string) func() {
+	from := b.state
+	b.state = state
+	b.generation++
+	b.probes = 0
+	b.successes = 0
+	switch state {
+	case StateOpen:
+		b.openedAt = time.Now()
+	case StateClosed:
+		b.openedAt = time.Time{}
+		b.outcomes = b.outcomes[:0]
+		b.next = 0
+	}
+
+	if b.onTransition == nil {
+		return nil
+	}
+	return func() { b.onTransition(from, state, reason) }
+}
+
+// effectiveBreakerConfig fills in the defaults of the rolling window settings
+func effectiveBreakerConfig(config CircuitBreakerConfig) CircuitBreakerConfig {
+	if config.FailureRate == 0 {
+		config.FailureRate = DefaultBreakerFailureRate
+	}
+	if config.MinRequests == 0 {
+		config.MinRequests = config.Threshold
+	}
+	if config.Window == 0 {
+		config.Window = DefaultBreakerWindow
+	}
+	if config.WindowSize == 0 {
+		config.WindowSize = DefaultBreakerWindowSize
+	}
+	return config
+}
+
+// breakerFor returns the circuit breaker of provider for model, creating it on first use,
+// or nil when circuit breaking is disabled
+func (sr *SmartRouter) breakerFor(provider, model string) *WindowCircuitBreaker {
+	sr.mutex.RLock()
+	config := sr.config.CircuitBreaker
+	sr.mutex.RUnlock()
+	if !config.Enabled {
+		return nil
+	}
+
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+
+	key := providerModelKey(provider, model)
+	entry, exists := sr.breakers[key]
+	if !exists || entry.breaker.config != effectiveBreakerConfig(config) {
+		entry = &breakerEntry{provider: provider, model: model}
+		entry.breaker = NewWindowCircuitBreaker(config, func(from, to CircuitState, reason string) {
+			sr.emitBreakerEvent(BreakerEvent{Provider: provider, Model: model, From: from, To: to, Reason: reason, Time: time.Now()})
+		})
+		sr.breakers[key] = entry
+	}
+	return entry.breaker
+}
+
+// allowCall asks the breaker of provider for model to let a call through. A refused call fails with
+// a retryable error wrapping a CircuitOpenError, so failover moves on to another provider.
+func (sr *SmartRouter) allowCall(provider, model string) (*WindowCircuitBreaker, BreakerTicket, error) {
+	breaker := sr.breakerFor(provider, model)
+	if breaker == nil {
+		return nil, BreakerTicket{}, nil
+	}
+
+	ticket, ok := breaker.Allow()
+	if !ok {
+		retryAfter := breaker.RetryAfter()
+		refused := retry.NewProviderRetryError(provider, "circuit_breaker", types.ErrorServer, ErrCircuitOpen.Error(), true)
+		refused.RetryAfter = int(math.Ceil(retryAfter.Seconds()))
+		refused.OriginalError = &CircuitOpenError{Provider: provider, Model: model, RetryAfter: retryAfter}
+		return nil, BreakerTicket{}, refused
+	}
+	return breaker, ticket, nil
+}
+
+// finishCall reports a call's outcome to the breaker that allowed it. Retryable errors count as
+// failures; errors the caller caused do not, and calls abandoned through ctx report nothing.
+func finishCall(ctx context.Context, breaker *WindowCircuitBreaker, ticket BreakerTicket, provider string, err error) {
+	if breaker == nil {
+		return
+	}
+	if errors.Is(ctx.Err(), context.Canceled) {
+		breaker.Abandon(ticket)
+		return
+	}
+	breaker.Record(ticket, err == nil || !classifyAttemptError(err, provider).Retryable)
+}
+
+// withClosedCircuits returns the providers whose breaker for model lets calls through.
+// When every breaker is open it returns a CircuitOpenError naming the soonest retry.
+func (sr *SmartRouter) withClosedCircuits(providers []types.Provider, model string) ([]types.Provider, error) {
+	closed := make([]types.Provider, 0, len(providers))
+	retryAfter := time.Duration(-1)
+	for _, provider := range providers {
+		breaker := sr.breakerFor(provider.GetName(), model)
+		if breaker == nil || breaker.Available() {
+			closed = append(closed, provider)
+			continue
+		}
+		if wait := breaker.RetryAfter(); retryAfter < 0 || wait < retryAfter {
+			retryAfter = wait
+		}
+	}
+
+	if len(closed) == 0 && len(providers) > 0 {
+		return nil, &CircuitOpenError{Model: model, RetryAfter: max(retryAfter, 0)}
+	}
+	return closed, nil
+}
+
+// OnBreakerTransition registers a listener called with every circuit breaker state transition
+func (sr *SmartRouter) OnBreakerTransition(listener func(BreakerEvent)) {
+	sr.statsMutex.Lock()
+	defer sr.statsMutex.Unlock()
+	sr.breakerListeners = append(sr.breakerListeners, listener)
+}
+
+// emitBreakerEvent logs a transition, keeps it for reporting and passes it to the listeners
+func (sr *SmartRouter) emitBreakerEvent(event BreakerEvent) {
+	entry := sr.logger.WithField("provider", event.Provider).
+		WithField("model", event.Model).
+		WithField("from", event.From.String()).
+		WithField("to", event.To.String()).
+		WithField("reason", event.Reason)
+	if event.To == StateOpen {
+		entry.Warn("Circuit breaker opened")
+	} else {
+		entry.Info("Circuit breaker state changed")
+	}
+
+	sr.statsMutex.Lock()
+	sr.breakerEvents = append(sr.breakerEvents, event)
+	if len(sr.breakerEvents) > maxBreakerEvents {
+		sr.breakerEvents = sr.breakerEvents[len(sr.breakerEvents)-maxBreakerEvents:]
+	}
+	listeners := make([]func(BreakerEvent), len(sr.breakerListeners))
+	copy(listeners, sr.breakerListeners)
+	sr.statsMutex.Unlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+// BreakerReports returns the state of every provider+model circuit breaker and the recent transitions
+func (sr *SmartRouter) BreakerReports() ([]WindowBreakerStats, []BreakerEvent) {
+	sr.statsMutex.Lock()
+	entries := make([]*breakerEntry, 0, len(sr.breakers))
+	for _, entry := range sr.breakers {
+		entries = append(entries, entry)
+	}
+	events := append([]BreakerEvent(nil), sr.breakerEvents...)
+	sr.statsMutex.Unlock()
+
+	reports := make([]WindowBreakerStats, len(entries))
+	for i, entry := range entries {
+		reports[i] = entry.breaker.GetStats()
+		reports[i].Provider = entry.provider
+		reports[i].Model = entry.model
+	}
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Provider != reports[j].Provider {
+			return reports[i].Provider < reports[j].Provider
+		}
+		return reports[i].Model < reports[j].Model
+	})
+	return reports, events
+}
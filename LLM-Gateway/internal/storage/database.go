@@ -339,6 +339,37 @@ func (r *RequestRepository) GetStats(userID *uint, startTime, endTime time.Time)
 	return result, nil
 }
 
+// VariantStats aggregates the logged requests of one experiment variant
+type VariantStats struct {
+	Variant          string  `json:"variant"`
+	Requests         int64   `json:"requests"`
+	Errors           int64   `json:"errors"`
+	AvgResponseTime  float64 `json:"avg_response_time_ms"`
+	TotalCost        float64 `json:"total_cost"`
+	PromptTokens     int64   `json:"prompt_tokens"`
+	CompletionTokens int64   `json:"completion_tokens"`
+}
+
+// GetVariantStats compares the variants of an experiment over the logged requests in a time range
+func (r *RequestRepository) GetVariantStats(experiment string, startTime, endTime time.Time) ([]VariantStats, error) {
+	var stats []VariantStats
+	err := r.db.Model(&Request{}).
+		Where("experiment = ? AND created_at BETWEEN ? AND ?", experiment, startTime, endTime).
+		Select(
+			"variant",
+			"COUNT(*) as requests",
+			"COUNT(CASE WHEN status_code >= 400 THEN 1 END) as errors",
+			"AVG(response_time_ms) as avg_response_time",
+			"SUM(cost) as total_cost",
+			"SUM(prompt_tokens) as prompt_tokens",
+			"SUM(completion_tokens) as completion_tokens",
+		).
+		Group("variant").
+		Order("variant").
+		Scan(&stats).Error
+	return stats, err
+}
+
 // Global database instance
 var DefaultDB *Database
 
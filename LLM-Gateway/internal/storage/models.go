@@ -19,6 +19,9 @@ type User struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 
+	// RoutingPolicy names the smart router policy the user's requests obey
+	RoutingPolicy string `json:"routing_policy,omitempty" gorm:"default:''"`
+
 	// Relationships
 	APIKeys  []APIKey  `json:"api_keys,omitempty"`
 	Quotas   []Quota   `json:"quotas,omitempty"`
@@ -38,6 +41,11 @@ type APIKey struct {
 	CreatedAt  time.Time  `json:"created_at"`
 	UpdatedAt  time.Time  `json:"updated_at"`
 
+	// RoutingPolicy names the smart router policy requests made with the key obey, overriding the user's
+	RoutingPolicy string `json:"routing_policy,omitempty" gorm:"default:''"`
+	// Tier names the concurrency priority tier of requests made with the key
+	Tier string `json:"tier,omitempty" gorm:"default:''"`
+
 	// Relationships
 	User     User      `json:"user,omitempty" gorm:"foreignKey:UserID"`
 	Requests []Request `json:"-" gorm:"foreignKey:APIKeyID"`
@@ -129,6 +137,10 @@ type Request struct {
 	// Cost calculation
 	Cost float64 `json:"cost" gorm:"default:0"`
 
+	// Experiment assignment, empty when no experiment ran on the requested model
+	Experiment string `json:"experiment,omitempty" gorm:"index"`
+	Variant    string `json:"variant,omitempty"`
+
 	// Timestamps
 	RequestTime time.Time `json:"request_time"`
 	ResponseAt  time.Time `json:"response_at"`
@@ -52,7 +52,15 @@ func (cc *CostCalculator) EstimateRequestCost(req *types.ChatCompletionRequest)
 	}
 
 	// Determine provider from request context or default
-	provider := cc.determineProvider(req)
+	return cc.EstimateRequestCostFor(cc.determineProvider(req), req)
+}
+
+// EstimateRequestCostFor estimates the cost for a request using the pricing registered under provider.
+// Use it when the provider cannot be inferred from the model name, e.g. openai_compatible instances.
+func (cc *CostCalculator) EstimateRequestCostFor(provider string, req *types.ChatCompletionRequest) (*CostEstimate, error) {
+	if req == nil {
+		return nil, fmt.Errorf("request cannot be nil")
+	}
 
 	// Get token estimation
 	tokenEstimate, err := cc.tokenEstimator.EstimateTokens(req, provider)
@@ -101,7 +109,14 @@ func (cc *CostCalculator) CalculateActualCost(req *types.ChatCompletionRequest,
 	}
 
 	// Determine provider
-	provider := cc.determineProvider(req)
+	return cc.CalculateActualCostFor(cc.determineProvider(req), req, resp)
+}
+
+// CalculateActualCostFor calculates the actual cost using the pricing registered under provider
+func (cc *CostCalculator) CalculateActualCostFor(provider string, req *types.ChatCompletionRequest, resp *types.ChatCompletionResponse) (*types.CostBreakdown, error) {
+	if req == nil || resp == nil {
+		return nil, fmt.Errorf("request and response cannot be nil")
+	}
 
 	// Get actual token counts from response
 	var inputTokens, outputTokens int
@@ -162,6 +177,61 @@ func (cc *CostCalculator) CalculateActualCost(req *types.ChatCompletionRequest,
 	return breakdown, nil
 }
 
+// CalculateEmbeddingCost calculates the cost of an embeddings request, which only bills input tokens
+func (cc *CostCalculator) CalculateEmbeddingCost(req *types.EmbeddingRequest, resp *types.EmbeddingResponse) (*types.CostBreakdown, error) {
+	if req == nil || resp == nil {
+		return nil, fmt.Errorf("request and response cannot be nil")
+	}
+
+	return cc.CalculateEmbeddingCostFor(cc.providerForModel(req.Model), req, resp)
+}
+
+// CalculateEmbeddingCostFor calculates the cost of an embeddings request using the pricing registered under provider
+func (cc *CostCalculator) CalculateEmbeddingCostFor(provider string, req *types.EmbeddingRequest, resp *types.EmbeddingResponse) (*types.CostBreakdown, error) {
+	if req == nil || resp == nil {
+		return nil, fmt.Errorf("request and response cannot be nil")
+	}
+
+	inputTokens := resp.Usage.PromptTokens
+	if inputTokens == 0 {
+		// Fallback to estimation if usage not provided
+		cc.logger.WithField("model", req.Model).Warn("No usage data in embedding response, falling back to estimation")
+		inputs, err := req.Inputs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to estimate tokens for embedding cost: %w", err)
+		}
+		inputTokens = cc.tokenEstimator.EstimateEmbeddingTokens(inputs, provider)
+	}
+
+	// Get pricing information
+	pricing, err := cc.pricingManager.GetPricing(provider, req.Model)
+	if err != nil {
+		pricing = cc.pricingManager.GetDefaultPricing(provider)
+	}
+
+	inputCost := float64(inputTokens) / 1000.0 * pricing.InputPrice
+
+	breakdown := &types.CostBreakdown{
+		InputTokens: inputTokens,
+		InputCost:   math.Round(inputCost*1000000) / 1000000, // Embeddings are cheap, keep 6 decimal places
+		TotalCost:   math.Round(inputCost*1000000) / 1000000,
+		Currency:    pricing.Currency,
+		Model:       req.Model,
+		Provider:    provider,
+		Timestamp:   time.Now(),
+	}
+
+	cc.logger.WithFields(map[string]interface{}{
+		"provider":     provider,
+		"model":        req.Model,
+		"input_tokens": inputTokens,
+		"total_cost":   breakdown.TotalCost,
+		"currency":     breakdown.Currency,
+	}).Info("Embedding cost calculated")
+
+	return breakdown, nil
+}
+
 // GetPricingInfo returns pricing information for a specific provider and model
 func (cc *CostCalculator) GetPricingInfo(provider, model string) (*types.ModelPricing, error) {
 	pricing, err := cc.pricingManager.GetPricing(provider, model)
@@ -193,16 +263,24 @@ func (cc *CostCalculator) UpdatePricing(provider, model string, pricing *types.M
 
 // determineProvider determines the provider from the request context
 func (cc *CostCalculator) determineProvider(req *types.ChatCompletionRequest) string {
-	// Try to determine from model name patterns
-	model := strings.ToLower(req.Model)
+	return cc.providerForModel(req.Model)
+}
+
+// providerForModel determines the provider from model name patterns
+func (cc *CostCalculator) providerForModel(model string) string {
+	model = strings.ToLower(model)
 
 	switch {
-	case strings.Contains(model, "gpt"):
+	case strings.Contains(model, "gpt") || strings.HasPrefix(model, "text-embedding"):
 		return "openai"
 	case strings.Contains(model, "claude"):
 		return "anthropic"
 	case strings.Contains(model, "ernie") || strings.Contains(model, "wenxin"):
 		return "baidu"
+	case strings.HasPrefix(model, "glm") || strings.HasPrefix(model, "embedding-"):
+		return "zhipu"
+	case strings.Contains(model, "gemini"):
+		return "gemini"
 	default:
 		// Default fallback
 		return "unknown"
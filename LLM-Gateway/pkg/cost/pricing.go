@@ -3,6 +3,7 @@ package cost
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -46,6 +47,24 @@ func (pm *PricingManager) GetPricing(provider, model string) (*types.ModelPricin
 	return pricing, nil
 }
 
+// PricingFor returns the pricing of a model on a provider instance. Pricing registered under the
+// instance name comes first, so deployments can price an instance individually, then pricing under
+// its type, then the type's default pricing.
+func (pm *PricingManager) PricingFor(name, providerType, model string) *types.ModelPricing {
+	if pricing, err := pm.GetPricing(name, model); err == nil {
+		return pricing
+	}
+	if pricing, err := pm.GetPricing(providerType, model); err == nil {
+		return pricing
+	}
+	return pm.GetDefaultPricing(providerType)
+}
+
+// TokenCost returns the cost of input and output tokens at pricing quoted per 1K tokens
+func TokenCost(pricing *types.ModelPricing, inputTokens, outputTokens int) float64 {
+	return float64(inputTokens)/1000.0*pricing.InputPrice + float64(outputTokens)/1000.0*pricing.OutputPrice
+}
+
 // UpdatePricing updates pricing information for a specific model
 func (pm *PricingManager) UpdatePricing(provider, model string, pricing *types.ModelPricing) {
 	pm.mutex.Lock()
@@ -89,6 +108,15 @@ func (pm *PricingManager) GetDefaultPricing(provider string) *types.ModelPricing
 			Currency:    "USD",
 			LastUpdated: time.Now(),
 		}
+	case "gemini":
+		return &types.ModelPricing{
+			Model:       "default",
+			Provider:    "gemini",
+			InputPrice:  0.0001, // Gemini 2.0 Flash pricing as default
+			OutputPrice: 0.0004,
+			Currency:    "USD",
+			LastUpdated: time.Now(),
+		}
 	default:
 		return &types.ModelPricing{
 			Model:       "default",
@@ -166,14 +194,31 @@ func (pm *PricingManager) modelsAreSimilar(model1, model2 string) bool {
 		return true
 	}
 
+	// Gemini model similarity (pinned versions such as gemini-1.5-flash-002 or -latest)
+	if strings.HasPrefix(model1, "gemini") && geminiBaseModel(model1) == geminiBaseModel(model2) {
+		return true
+	}
+
 	return false
 }
 
+// geminiBaseModel strips the version suffix from a Gemini model name
+func geminiBaseModel(model string) string {
+	model = strings.TrimPrefix(model, "models/")
+	model = strings.TrimSuffix(model, "-latest")
+	if i := strings.LastIndex(model, "-"); i > 0 {
+		if _, err := strconv.Atoi(model[i+1:]); err == nil {
+			return model[:i]
+		}
+	}
+	return model
+}
+
 // initializeDefaultPricing initializes the pricing manager with current pricing data
 func (pm *PricingManager) initializeDefaultPricing() {
 	now := time.Now()
 
-	// OpenAI pricing (as of 2024)
+	// OpenAI pricing (per 1K tokens, as of 2024)
 	openaiModels := map[string]struct{ input, output float64 }{
 		"gpt-3.5-turbo":     {0.0015, 0.002},   // $1.50 / $2.00 per 1M tokens
 		"gpt-3.5-turbo-16k": {0.003, 0.004},    // $3.00 / $4.00 per 1M tokens
@@ -189,14 +234,14 @@ func (pm *PricingManager) initializeDefaultPricing() {
 		pm.pricing[key] = &types.ModelPricing{
 			Model:       model,
 			Provider:    "openai",
-			InputPrice:  prices.input / 1000, // Convert to per 1K tokens
-			OutputPrice: prices.output / 1000,
+			InputPrice:  prices.input,
+			OutputPrice: prices.output,
 			Currency:    "USD",
 			LastUpdated: now,
 		}
 	}
 
-	// Anthropic pricing (as of 2024)
+	// Anthropic pricing (per 1K tokens, as of 2024)
 	anthropicModels := map[string]struct{ input, output float64 }{
 		"claude-3-haiku":    {0.00025, 0.00125}, // $0.25 / $1.25 per 1M tokens
 		"claude-3-sonnet":   {0.003, 0.015},     // $3.00 / $15.00 per 1M tokens
@@ -209,14 +254,14 @@ func (pm *PricingManager) initializeDefaultPricing() {
 		pm.pricing[key] = &types.ModelPricing{
 			Model:       model,
 			Provider:    "anthropic",
-			InputPrice:  prices.input / 1000, // Convert to per 1K tokens
-			OutputPrice: prices.output / 1000,
+			InputPrice:  prices.input,
+			OutputPrice: prices.output,
 			Currency:    "USD",
 			LastUpdated: now,
 		}
 	}
 
-	// Baidu pricing (estimated, as official pricing may vary)
+	// Baidu pricing (per 1K tokens, estimated, as official pricing may vary)
 	baiduModels := map[string]struct{ input, output float64 }{
 		"ernie-bot":       {0.0002, 0.0004}, // Estimated pricing
 		"ernie-bot-turbo": {0.0001, 0.0002}, // Estimated pricing
@@ -229,10 +274,57 @@ func (pm *PricingManager) initializeDefaultPricing() {
 		pm.pricing[key] = &types.ModelPricing{
 			Model:       model,
 			Provider:    "baidu",
-			InputPrice:  prices.input / 1000, // Convert to per 1K tokens
-			OutputPrice: prices.output / 1000,
+			InputPrice:  prices.input,
+			OutputPrice: prices.output,
 			Currency:    "USD", // Convert from CNY to USD for consistency
 			LastUpdated: now,
 		}
 	}
+
+	// Google Gemini pricing (per 1K tokens, paid tier, prompts up to 128K/200K tokens)
+	geminiModels := map[string]struct{ input, output float64 }{
+		"gemini-2.5-pro":        {0.00125, 0.01},      // $1.25 / $10.00 per 1M tokens
+		"gemini-2.5-flash":      {0.0003, 0.0025},     // $0.30 / $2.50 per 1M tokens
+		"gemini-2.0-flash":      {0.0001, 0.0004},     // $0.10 / $0.40 per 1M tokens
+		"gemini-2.0-flash-lite": {0.000075, 0.0003},   // $0.075 / $0.30 per 1M tokens
+		"gemini-1.5-pro":        {0.00125, 0.005},     // $1.25 / $5.00 per 1M tokens
+		"gemini-1.5-flash":      {0.000075, 0.0003},   // $0.075 / $0.30 per 1M tokens
+		"gemini-1.5-flash-8b":   {0.0000375, 0.00015}, // $0.0375 / $0.15 per 1M tokens
+	}
+
+	for model, prices := range geminiModels {
+		key := fmt.Sprintf("gemini/%s", model)
+		pm.pricing[key] = &types.ModelPricing{
+			Model:       model,
+			Provider:    "gemini",
+			InputPrice:  prices.input,
+			OutputPrice: prices.output,
+			Currency:    "USD",
+			LastUpdated: now,
+		}
+	}
+
+	// Embedding pricing (per 1K input tokens, embeddings have no output tokens)
+	embeddingModels := map[string]struct {
+		provider string
+		input    float64
+	}{
+		"text-embedding-3-small": {"openai", 0.00002}, // $0.02 per 1M tokens
+		"text-embedding-3-large": {"openai", 0.00013}, // $0.13 per 1M tokens
+		"text-embedding-ada-002": {"openai", 0.0001},  // $0.10 per 1M tokens
+		"embedding-2":            {"zhipu", 0.00007},  // ¥0.5 per 1M tokens
+		"embedding-3":            {"zhipu", 0.00007},  // ¥0.5 per 1M tokens
+	}
+
+	for model, prices := range embeddingModels {
+		key := fmt.Sprintf("%s/%s", prices.provider, model)
+		pm.pricing[key] = &types.ModelPricing{
+			Model:       model,
+			Provider:    prices.provider,
+			InputPrice:  prices.input,
+			OutputPrice: 0,
+			Currency:    "USD",
+			LastUpdated: now,
+		}
+	}
 }
@@ -17,12 +17,14 @@ type TokenEstimator struct {
 
 // EstimationRule defines token estimation parameters for different providers
 type EstimationRule struct {
-	Provider        string  `json:"provider"`
-	CharsPerToken   float64 `json:"chars_per_token"`  // Average characters per token
-	WordsPerToken   float64 `json:"words_per_token"`  // Average words per token
-	SystemTokens    int     `json:"system_tokens"`    // Fixed tokens for system messages
-	MessageOverhead int     `json:"message_overhead"` // Overhead tokens per message
-	ModelMultiplier float64 `json:"model_multiplier"` // Model-specific multiplier
+	Provider             string  `json:"provider"`
+	CharsPerToken        float64 `json:"chars_per_token"`         // Average characters per token
+	WordsPerToken        float64 `json:"words_per_token"`         // Average words per token
+	SystemTokens         int     `json:"system_tokens"`           // Fixed tokens for system messages
+	MessageOverhead      int     `json:"message_overhead"`        // Overhead tokens per message
+	ModelMultiplier      float64 `json:"model_multiplier"`        // Model-specific multiplier
+	ImageTokens          int     `json:"image_tokens"`            // Tokens per image at default or high detail
+	LowDetailImageTokens int     `json:"low_detail_image_tokens"` // Tokens per image with detail "low"
 }
 
 // NewTokenEstimator creates a new token estimator with default rules
@@ -69,6 +71,11 @@ func (te *TokenEstimator) EstimateTokens(req *types.ChatCompletionRequest, provi
 	// Apply model-specific multiplier
 	inputTokens = int(float64(inputTokens) * rule.ModelMultiplier)
 
+	// Images have a fixed cost that does not depend on text tokenization
+	for _, message := range req.Messages {
+		inputTokens += te.estimateImageTokens(message, rule)
+	}
+
 	// Estimate output tokens based on max_tokens or default
 	var outputTokens int
 	if req.MaxTokens != nil && *req.MaxTokens > 0 {
@@ -101,6 +108,20 @@ func (te *TokenEstimator) EstimateOutputTokens(content, provider string) int {
 	return te.estimateMessageTokens(content, rule)
 }
 
+// EstimateEmbeddingTokens estimates input tokens for a batch of embedding inputs
+func (te *TokenEstimator) EstimateEmbeddingTokens(inputs []string, provider string) int {
+	rule := te.getEstimationRule(provider)
+
+	tokens := 0
+	for _, input := range inputs {
+		tokens += te.estimateMessageTokens(input, rule)
+	}
+	if tokens < 1 {
+		tokens = 1
+	}
+	return tokens
+}
+
 // estimateMessageTokens estimates tokens for a single message
 func (te *TokenEstimator) estimateMessageTokens(content string, rule *EstimationRule) int {
 	if content == "" {
@@ -135,6 +156,22 @@ func (te *TokenEstimator) estimateMessageTokens(content string, rule *Estimation
 	return tokens
 }
 
+// estimateImageTokens estimates tokens for the image parts of a message
+func (te *TokenEstimator) estimateImageTokens(message types.Message, rule *EstimationRule) int {
+	tokens := 0
+	for _, part := range message.Parts {
+		if part.Type != types.ContentPartImageURL || part.ImageURL == nil {
+			continue
+		}
+		if part.ImageURL.Detail == "low" && rule.LowDetailImageTokens > 0 {
+			tokens += rule.LowDetailImageTokens
+		} else {
+			tokens += rule.ImageTokens
+		}
+	}
+	return tokens
+}
+
 // countWords counts the number of words in a text
 func (te *TokenEstimator) countWords(text string) int {
 	// Split by common word separators
@@ -247,42 +284,62 @@ func (te *TokenEstimator) getEstimationRule(provider string) *EstimationRule {
 func (te *TokenEstimator) initializeEstimationRules() {
 	// OpenAI estimation rules (based on tiktoken analysis)
 	te.estimationRules["openai"] = &EstimationRule{
-		Provider:        "openai",
-		CharsPerToken:   4.0,  // GPT models average ~4 characters per token
-		WordsPerToken:   0.75, // ~0.75 words per token
-		SystemTokens:    3,    // System message overhead
-		MessageOverhead: 4,    // Per message overhead (role, formatting)
-		ModelMultiplier: 1.0,  // Base multiplier
+		Provider:             "openai",
+		CharsPerToken:        4.0,  // GPT models average ~4 characters per token
+		WordsPerToken:        0.75, // ~0.75 words per token
+		SystemTokens:         3,    // System message overhead
+		MessageOverhead:      4,    // Per message overhead (role, formatting)
+		ModelMultiplier:      1.0,  // Base multiplier
+		ImageTokens:          765,  // 1024x1024 image at high detail (4 tiles)
+		LowDetailImageTokens: 85,   // Fixed cost for low detail images
 	}
 
 	// Anthropic estimation rules (Claude tokenization)
 	te.estimationRules["anthropic"] = &EstimationRule{
-		Provider:        "anthropic",
-		CharsPerToken:   4.2, // Claude slightly higher chars per token
-		WordsPerToken:   0.8, // ~0.8 words per token
-		SystemTokens:    5,   // System message overhead
-		MessageOverhead: 6,   // Higher overhead for Claude format
-		ModelMultiplier: 1.1, // Slightly higher token count
+		Provider:             "anthropic",
+		CharsPerToken:        4.2,  // Claude slightly higher chars per token
+		WordsPerToken:        0.8,  // ~0.8 words per token
+		SystemTokens:         5,    // System message overhead
+		MessageOverhead:      6,    // Higher overhead for Claude format
+		ModelMultiplier:      1.1,  // Slightly higher token count
+		ImageTokens:          1600, // Claude caps images at ~1.15 megapixels (~1600 tokens)
+		LowDetailImageTokens: 1600, // Claude has no detail setting
 	}
 
 	// Baidu estimation rules (Chinese optimized)
 	te.estimationRules["baidu"] = &EstimationRule{
-		Provider:        "baidu",
-		CharsPerToken:   2.5, // Chinese characters are more token-dense
-		WordsPerToken:   0.6, // Chinese word segmentation
-		SystemTokens:    2,   // System message overhead
-		MessageOverhead: 3,   // Per message overhead
-		ModelMultiplier: 1.2, // Account for Chinese tokenization
+		Provider:             "baidu",
+		CharsPerToken:        2.5, // Chinese characters are more token-dense
+		WordsPerToken:        0.6, // Chinese word segmentation
+		SystemTokens:         2,   // System message overhead
+		MessageOverhead:      3,   // Per message overhead
+		ModelMultiplier:      1.2, // Account for Chinese tokenization
+		ImageTokens:          0,   // ERNIE chat models are text-only, images are dropped
+		LowDetailImageTokens: 0,
+	}
+
+	// Gemini estimation rules (SentencePiece tokenization)
+	te.estimationRules["gemini"] = &EstimationRule{
+		Provider:             "gemini",
+		CharsPerToken:        4.0,  // Google documents ~4 characters per token
+		WordsPerToken:        0.75, // ~100 tokens per 60-80 English words
+		SystemTokens:         3,    // systemInstruction overhead
+		MessageOverhead:      4,    // Per content overhead (role, parts)
+		ModelMultiplier:      1.0,  // Base multiplier
+		ImageTokens:          258,  // Images up to 384px per side cost a flat 258 tokens
+		LowDetailImageTokens: 258,  // Gemini has no detail setting
 	}
 
 	// Default estimation rule
 	te.estimationRules["default"] = &EstimationRule{
-		Provider:        "default",
-		CharsPerToken:   4.0,
-		WordsPerToken:   0.75,
-		SystemTokens:    3,
-		MessageOverhead: 4,
-		ModelMultiplier: 1.0,
+		Provider:             "default",
+		CharsPerToken:        4.0,
+		WordsPerToken:        0.75,
+		SystemTokens:         3,
+		MessageOverhead:      4,
+		ModelMultiplier:      1.0,
+		ImageTokens:          765,
+		LowDetailImageTokens: 85,
 	}
 }
 
@@ -232,6 +232,45 @@ func ClassifyZhipuError(statusCode int, errorCode, message string) *ProviderRetr
 	return retryError
 }
 
+// ClassifyGeminiError classifies Google Gemini errors using the google.rpc status string
+func ClassifyGeminiError(statusCode int, status, message string) *ProviderRetryError {
+	retryError := &ProviderRetryError{
+		Provider:   "gemini",
+		Operation:  "generate_content",
+		StatusCode: statusCode,
+		Message:    fmt.Sprintf("[%s] %s", status, message),
+	}
+
+	// Classify based on google.rpc.Code names
+	switch status {
+	case "UNAUTHENTICATED", "PERMISSION_DENIED": // Invalid or unauthorized API key
+		retryError.Category = types.ErrorAuth
+		retryError.Retryable = false
+	case "INVALID_ARGUMENT", "NOT_FOUND": // Malformed request or unknown model
+		retryError.Category = types.ErrorClient
+		retryError.Retryable = false
+	case "FAILED_PRECONDITION": // Billing not enabled or region not supported
+		retryError.Category = types.ErrorQuota
+		retryError.Retryable = false
+	case "RESOURCE_EXHAUSTED": // Per-minute rate limit or quota exceeded
+		retryError.Category = types.ErrorRateLimit
+		retryError.Retryable = true
+		retryError.RetryAfter = 60
+	case "UNAVAILABLE", "INTERNAL": // Model overloaded or transient backend failure
+		retryError.Category = types.ErrorServer
+		retryError.Retryable = true
+		retryError.RetryAfter = 30
+	case "DEADLINE_EXCEEDED":
+		retryError.Category = types.ErrorTimeout
+		retryError.Retryable = true
+	default:
+		// Fallback to HTTP status code classification
+		return ClassifyHTTPError(statusCode, message)
+	}
+
+	return retryError
+}
+
 // ClassifyHTTPError classifies generic HTTP errors
 func ClassifyHTTPError(statusCode int, message string) *ProviderRetryError {
 	retryError := &ProviderRetryError{
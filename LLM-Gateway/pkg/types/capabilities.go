@@ -0,0 +1,69 @@
+// Package types defines the request features a model may or may not accept
+package types
+
+// Capability names reported when a request needs a feature no candidate offers
+const (
+	CapabilityTools     = "tools"
+	CapabilityVision    = "vision"
+	CapabilityStreaming = "streaming"
+	CapabilityJSONMode  = "json_mode"
+)
+
+// Response format types; "text" is the default and needs no capability
+const (
+	ResponseFormatText       = "text"
+	ResponseFormatJSONObject = "json_object"
+	ResponseFormatJSONSchema = "json_schema"
+)
+
+// ResponseFormat constrains the shape of the model's answer (OpenAI response_format)
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema interface{} `json:"json_schema,omitempty"`
+}
+
+// ModelCapabilities describes the optional request features a model accepts.
+// Providers silently drop fields a model doesn't support, so requests that use a
+// feature are only routed to models that declare it.
+type ModelCapabilities struct {
+	Tools     bool `json:"tools" mapstructure:"tools"`         // Function tools and tool_choice
+	Vision    bool `json:"vision" mapstructure:"vision"`       // Image content parts
+	Streaming bool `json:"streaming" mapstructure:"streaming"` // stream=true
+	JSONMode  bool `json:"json_mode" mapstructure:"json_mode"` // response_format json_object or json_schema
+}
+
+// Missing returns the names of the capabilities required that c lacks
+func (c ModelCapabilities) Missing(required ModelCapabilities) []string {
+	var missing []string
+	if required.Tools && !c.Tools {
+		missing = append(missing, CapabilityTools)
+	}
+	if required.Vision && !c.Vision {
+		missing = append(missing, CapabilityVision)
+	}
+	if required.Streaming && !c.Streaming {
+		missing = append(missing, CapabilityStreaming)
+	}
+	if required.JSONMode && !c.JSONMode {
+		missing = append(missing, CapabilityJSONMode)
+	}
+	return missing
+}
+
+// RequiredCapabilities returns the optional features the request actually uses
+func (r *ChatCompletionRequest) RequiredCapabilities() ModelCapabilities {
+	required := ModelCapabilities{
+		Tools:     len(r.Tools) > 0,
+		Streaming: r.Stream != nil && *r.Stream,
+	}
+	for i := range r.Messages {
+		required.Vision = required.Vision || r.Messages[i].HasImages()
+	}
+	if r.ResponseFormat != nil {
+		switch r.ResponseFormat.Type {
+		case ResponseFormatJSONObject, ResponseFormatJSONSchema:
+			required.JSONMode = true
+		}
+	}
+	return required
+}
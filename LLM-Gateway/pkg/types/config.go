@@ -53,6 +53,8 @@ const (
 	ErrorServer    ErrorCategory = "server"     // Server errors, retryable
 	ErrorClient    ErrorCategory = "client"     // Client errors, not retryable
 	ErrorTimeout   ErrorCategory = "timeout"    // Timeout errors, retryable
+
+	ErrorContentFilter ErrorCategory = "content_filter" // Blocked by provider content policy, not retryable
 )
 
 // TokenEstimate represents token count estimation for cost calculation
@@ -0,0 +1,143 @@
+// Package types defines multimodal message content shared by the API and providers
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Content part types accepted in Message content arrays
+const (
+	ContentPartText     = "text"
+	ContentPartImageURL = "image_url"
+)
+
+// ContentPart is a single element of an OpenAI-style content array
+type ContentPart struct {
+	Type     string    `json:"type"` // text or image_url
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+}
+
+// ImageURL references an image by http(s) URL or base64 data: URI
+type ImageURL struct {
+	URL    string `json:"url"`
+	Detail string `json:"detail,omitempty"` // auto, low or high
+}
+
+// IsDataURI reports whether the image is inlined as a data: URI
+func (u *ImageURL) IsDataURI() bool {
+	return strings.HasPrefix(u.URL, "data:")
+}
+
+// DataURI splits a base64 data: URI into its media type and payload
+func (u *ImageURL) DataURI() (mediaType, data string, err error) {
+	if !u.IsDataURI() {
+		return "", "", fmt.Errorf("image url is not a data URI")
+	}
+
+	header, data, found := strings.Cut(strings.TrimPrefix(u.URL, "data:"), ",")
+	if !found || !strings.HasSuffix(header, ";base64") {
+		return "", "", fmt.Errorf("image data URI must be base64 encoded")
+	}
+
+	mediaType = strings.TrimSuffix(header, ";base64")
+	if mediaType == "" {
+		return "", "", fmt.Errorf("image data URI is missing a media type")
+	}
+
+	return mediaType, data, nil
+}
+
+// HasImages reports whether the message carries image parts
+func (m *Message) HasImages() bool {
+	for _, part := range m.Parts {
+		if part.Type == ContentPartImageURL {
+			return true
+		}
+	}
+	return false
+}
+
+// ContentParts returns the message content as parts, wrapping plain text in a single text part
+func (m *Message) ContentParts() []ContentPart {
+	if len(m.Parts) > 0 {
+		return m.Parts
+	}
+	if m.Content == "" {
+		return nil
+	}
+	return []ContentPart{{Type: ContentPartText, Text: m.Content}}
+}
+
+// MarshalJSON writes content as a part array when the message has parts, otherwise as a string
+func (m Message) MarshalJSON() ([]byte, error) {
+	type message Message
+	if len(m.Parts) == 0 {
+		return json.Marshal(message(m))
+	}
+
+	return json.Marshal(struct {
+		message
+		Content []ContentPart `json:"content"`
+	}{message(m), m.Parts})
+}
+
+// UnmarshalJSON accepts content as either a string or an array of content parts.
+// For arrays, Parts keeps the original parts and Content holds the joined text so
+// text-only consumers keep working.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message
+	var raw struct {
+		message
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*m = Message(raw.message)
+	m.Content, m.Parts = "", nil
+
+	content := bytes.TrimSpace(raw.Content)
+	switch {
+	case len(content) == 0 || bytes.Equal(content, []byte("null")):
+		return nil
+	case content[0] == '"':
+		return json.Unmarshal(content, &m.Content)
+	case content[0] == '[':
+		var parts []ContentPart
+		if err := json.Unmarshal(content, &parts); err != nil {
+			return fmt.Errorf("invalid message content parts: %w", err)
+		}
+
+		texts := make([]string, 0, len(parts))
+		for i, part := range parts {
+			switch part.Type {
+			case ContentPartText:
+				texts = append(texts, part.Text)
+			case ContentPartImageURL:
+				if part.ImageURL == nil || part.ImageURL.URL == "" {
+					return fmt.Errorf("content part %d: image_url.url is required", i)
+				}
+				if part.ImageURL.IsDataURI() {
+					if _, _, err := part.ImageURL.DataURI(); err != nil {
+						return fmt.Errorf("content part %d: %w", i, err)
+					}
+				} else if url := part.ImageURL.URL; !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+					return fmt.Errorf("content part %d: image_url.url must be an http(s) or data: URI", i)
+				}
+			default:
+				return fmt.Errorf("content part %d: unsupported type %q", i, part.Type)
+			}
+		}
+
+		m.Parts = parts
+		m.Content = strings.Join(texts, "\n")
+		return nil
+	default:
+		return fmt.Errorf("message content must be a string or an array of content parts")
+	}
+}
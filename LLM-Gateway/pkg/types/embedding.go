@@ -0,0 +1,72 @@
+// Package types defines embedding structures shared by providers and the gateway
+package types
+
+import (
+	"context"
+	"fmt"
+)
+
+// EmbeddingRequest represents an OpenAI-compatible embeddings request
+type EmbeddingRequest struct {
+	Model          string      `json:"model" binding:"required"`
+	Input          interface{} `json:"input" binding:"required"`  // string or []string
+	EncodingFormat string      `json:"encoding_format,omitempty"` // float or base64, applied by the gateway
+	Dimensions     *int        `json:"dimensions,omitempty"`
+	User           string      `json:"user,omitempty"`
+	RequestID      string      `json:"-"`
+}
+
+// Inputs returns the request input as a list of texts
+func (r *EmbeddingRequest) Inputs() ([]string, error) {
+	var texts []string
+	switch input := r.Input.(type) {
+	case string:
+		texts = []string{input}
+	case []string:
+		texts = input
+	case []interface{}:
+		texts = make([]string, len(input))
+		for i, item := range input {
+			text, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("input[%d] must be a string, token arrays are not supported", i)
+			}
+			texts[i] = text
+		}
+	default:
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+
+	if len(texts) == 0 {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	return texts, nil
+}
+
+// EmbeddingResponse represents an OpenAI-compatible embeddings response
+type EmbeddingResponse struct {
+	Object   string         `json:"object"` // Always "list"
+	Data     []Embedding    `json:"data"`
+	Model    string         `json:"model"`
+	Provider string         `json:"provider,omitempty"`
+	Usage    EmbeddingUsage `json:"usage"`
+}
+
+// Embedding is a single embedding vector, Index matches the position in the request input
+type Embedding struct {
+	Object    string    `json:"object"` // Always "embedding"
+	Index     int       `json:"index"`
+	Embedding []float64 `json:"embedding"`
+}
+
+// EmbeddingUsage reports token usage for an embeddings request
+type EmbeddingUsage struct {
+	PromptTokens int `json:"prompt_tokens"`
+	TotalTokens  int `json:"total_tokens"`
+}
+
+// EmbeddingProvider is implemented by providers that serve embedding models
+type EmbeddingProvider interface {
+	Provider
+	Embed(ctx context.Context, request *EmbeddingRequest) (*EmbeddingResponse, error)
+}
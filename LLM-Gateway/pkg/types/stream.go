@@ -0,0 +1,48 @@
+// Package types defines streaming structures shared by providers and the gateway
+package types
+
+import "context"
+
+// StreamChunkType identifies what a StreamChunk carries
+type StreamChunkType string
+
+const (
+	ChunkRole     StreamChunkType = "role"          // Assistant role announcement, sent once at the start
+	ChunkContent  StreamChunkType = "content"       // Incremental content delta
+	ChunkFinish   StreamChunkType = "finish_reason" // Choice finished, FinishReason is set
+	ChunkToolCall StreamChunkType = "tool_call"     // Tool call start or argument delta, ToolCall is set
+	ChunkUsage    StreamChunkType = "usage"         // Token usage for the whole completion
+	ChunkError    StreamChunkType = "error"         // Stream failed, Err is set and no more chunks follow
+)
+
+// StreamChunk represents a single typed event of a streaming chat completion
+type StreamChunk struct {
+	Type         StreamChunkType `json:"type"`
+	ID           string          `json:"id,omitempty"`
+	Model        string          `json:"model,omitempty"`
+	Index        int             `json:"index"`
+	Role         string          `json:"role,omitempty"`
+	Content      string          `json:"content,omitempty"`
+	FinishReason *string         `json:"finish_reason,omitempty"`
+	// ToolCall carries a tool call delta. ToolCallIndex identifies the call within the
+	// choice; ID, Type and Function.Name are only set on the first delta of each call,
+	// later deltas append to Function.Arguments.
+	ToolCallIndex int       `json:"tool_call_index"`
+	ToolCall      *ToolCall `json:"tool_call,omitempty"`
+	Usage         *Usage    `json:"usage,omitempty"`
+	Err           error     `json:"-"`
+}
+
+// StreamOptions mirrors the OpenAI stream_options request field
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// StreamingProvider is implemented by providers that can stream chat completions.
+// CallStream returns an error if the stream could not be opened; once it returns a
+// channel, failures are reported as a ChunkError chunk. The channel is closed when
+// the stream ends or ctx is cancelled.
+type StreamingProvider interface {
+	Provider
+	CallStream(ctx context.Context, request *ChatCompletionRequest) (<-chan *StreamChunk, error)
+}
@@ -8,21 +8,85 @@ import (
 
 // Request represents a standardized LLM request
 type Request struct {
-	ID          string                 `json:"id"`
-	Model       string                 `json:"model"`
-	Messages    []Message              `json:"messages"`
-	Temperature float64                `json:"temperature,omitempty"`
-	MaxTokens   int                    `json:"max_tokens,omitempty"`
-	Stream      bool                   `json:"stream,omitempty"`
-	UserID      string                 `json:"user_id,omitempty"`
-	Extra       map[string]interface{} `json:"extra,omitempty"`
-	Timestamp   time.Time              `json:"timestamp"`
+	ID             string                 `json:"id"`
+	Model          string                 `json:"model"`
+	Messages       []Message              `json:"messages"`
+	Temperature    float64                `json:"temperature,omitempty"`
+	MaxTokens      int                    `json:"max_tokens,omitempty"`
+	Stream         bool                   `json:"stream,omitempty"`
+	StreamOptions  *StreamOptions         `json:"stream_options,omitempty"`
+	Tools          []Tool                 `json:"tools,omitempty"`
+	ToolChoice     interface{}            `json:"tool_choice,omitempty"`
+	ResponseFormat *ResponseFormat        `json:"response_format,omitempty"`
+	UserID         string                 `json:"user_id,omitempty"`
+	ConversationID string                 `json:"conversation_id,omitempty"` // Pins a conversation to one provider under prefix_affinity
+	Extra          map[string]interface{} `json:"extra,omitempty"`
+	Timestamp      time.Time              `json:"timestamp"`
 }
 
 // Message represents a single message in the conversation
 type Message struct {
-	Role    string `json:"role"` // system, user, assistant
-	Content string `json:"content"`
+	Role       string     `json:"role"` // system, user, assistant, tool
+	Content    string     `json:"content"`
+	Name       string     `json:"name,omitempty"`
+	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // Set on assistant messages that invoke tools
+	ToolCallID string     `json:"tool_call_id,omitempty"` // Set on tool messages carrying a tool result
+	// Parts holds multimodal content when the request sent a content array;
+	// Content then carries only the joined text parts (see content.go)
+	Parts []ContentPart `json:"-"`
+}
+
+// Tool represents a tool the model may call (OpenAI function tool schema)
+type Tool struct {
+	Type     string             `json:"type"` // Only "function" is supported
+	Function FunctionDefinition `json:"function"`
+}
+
+// FunctionDefinition describes a callable function and its JSON schema parameters
+type FunctionDefinition struct {
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Parameters  interface{} `json:"parameters,omitempty"`
+}
+
+// ToolCall represents a tool invocation requested by the model
+type ToolCall struct {
+	ID       string       `json:"id"`
+	Type     string       `json:"type"`
+	Function FunctionCall `json:"function"`
+}
+
+// FunctionCall holds the function name and JSON-encoded arguments of a tool call
+type FunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToChatCompletionRequest converts a gateway request into the provider request format
+func (r *Request) ToChatCompletionRequest() *ChatCompletionRequest {
+	chatReq := &ChatCompletionRequest{
+		Model:          r.Model,
+		Messages:       r.Messages,
+		Tools:          r.Tools,
+		ToolChoice:     r.ToolChoice,
+		ResponseFormat: r.ResponseFormat,
+		RequestID:      r.ID,
+	}
+
+	if r.MaxTokens > 0 {
+		maxTokens := r.MaxTokens
+		chatReq.MaxTokens = &maxTokens
+	}
+	if r.Temperature > 0 {
+		temperature := r.Temperature
+		chatReq.Temperature = &temperature
+	}
+	if r.Stream {
+		stream := true
+		chatReq.Stream = &stream
+	}
+
+	return chatReq
 }
 
 // Response represents a standardized LLM response
@@ -32,8 +96,8 @@ type Response struct {
 	Choices   []Choice  `json:"choices"`
 	Usage     Usage     `json:"usage"`
 	Provider  string    `json:"provider"`
-	Latency   int64     `json:"latency_ms"`
-	LatencyMs int64     `json:"latency_ms"` // Alias for backward compatibility
+	Latency   int64     `json:"-"`          // Deprecated: use LatencyMs
+	LatencyMs int64     `json:"latency_ms"` // Serialized latency in milliseconds
 	Created   time.Time `json:"created"`
 }
 
@@ -116,6 +180,8 @@ type Config struct {
 	Logging     LoggingConfig      `mapstructure:"logging"`
 	Metrics     MetricsConfig      `mapstructure:"metrics"`
 	SmartRouter *SmartRouterConfig `mapstructure:"smart_router"`
+	// Providers is keyed by instance name; the key is used when ProviderConfig.Name is empty
+	Providers map[string]*ProviderConfig `mapstructure:"providers"`
 }
 
 // ServerConfig represents server configuration
@@ -186,19 +252,22 @@ type RegisterResponse struct {
 
 // ChatCompletionRequest represents a chat completion request
 type ChatCompletionRequest struct {
-	Model            string         `json:"model" binding:"required"`
-	Messages         []Message      `json:"messages" binding:"required"`
-	MaxTokens        *int           `json:"max_tokens,omitempty"`
-	Temperature      *float64       `json:"temperature,omitempty"`
-	TopP             *float64       `json:"top_p,omitempty"`
-	N                *int           `json:"n,omitempty"`
-	Stream           *bool          `json:"stream,omitempty"`
-	Stop             interface{}    `json:"stop,omitempty"`
-	PresencePenalty  *float64       `json:"presence_penalty,omitempty"`
-	FrequencyPenalty *float64       `json:"frequency_penalty,omitempty"`
-	LogitBias        map[string]int `json:"logit_bias,omitempty"`
-	User             *string        `json:"user,omitempty"`
-	RequestID        string         `json:"-"` // Internal field, not serialized
+	Model            string          `json:"model" binding:"required"`
+	Messages         []Message       `json:"messages" binding:"required"`
+	MaxTokens        *int            `json:"max_tokens,omitempty"`
+	Temperature      *float64        `json:"temperature,omitempty"`
+	TopP             *float64        `json:"top_p,omitempty"`
+	N                *int            `json:"n,omitempty"`
+	Stream           *bool           `json:"stream,omitempty"`
+	Stop             interface{}     `json:"stop,omitempty"`
+	PresencePenalty  *float64        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float64        `json:"frequency_penalty,omitempty"`
+	LogitBias        map[string]int  `json:"logit_bias,omitempty"`
+	User             *string         `json:"user,omitempty"`
+	Tools            []Tool          `json:"tools,omitempty"`
+	ToolChoice       interface{}     `json:"tool_choice,omitempty"` // "none", "auto", "required" or {"type":"function","function":{"name":...}}
+	ResponseFormat   *ResponseFormat `json:"response_format,omitempty"`
+	RequestID        string          `json:"-"` // Internal field, not serialized
 }
 
 // ChatCompletionResponse represents a chat completion response
@@ -216,34 +285,61 @@ type ChatCompletionResponse struct {
 
 // ProviderConfig represents provider configuration
 type ProviderConfig struct {
-	Name         string            `json:"name"`
-	Type         string            `json:"type"`
-	Enabled      bool              `json:"enabled"`
-	BaseURL      string            `json:"base_url"`
-	APIKey       string            `json:"api_key"`
-	Priority     int               `json:"priority"`
-	Weight       int               `json:"weight"`
-	Timeout      time.Duration     `json:"timeout"`
-	RetryCount   int               `json:"retry_count"`
-	RateLimit    int               `json:"rate_limit"`
-	Models       []string          `json:"models"`
-	CustomConfig map[string]string `json:"custom_config"`
+	Name         string            `json:"name" mapstructure:"name"`
+	Type         string            `json:"type" mapstructure:"type"`
+	Enabled      bool              `json:"enabled" mapstructure:"enabled"`
+	BaseURL      string            `json:"base_url" mapstructure:"base_url"`
+	APIKey       string            `json:"api_key" mapstructure:"api_key"`
+	Priority     int               `json:"priority" mapstructure:"priority"`
+	Weight       int               `json:"weight" mapstructure:"weight"`
+	Timeout      time.Duration     `json:"timeout" mapstructure:"timeout"`
+	RetryCount   int               `json:"retry_count" mapstructure:"retry_count"`
+	RateLimit    int               `json:"rate_limit" mapstructure:"rate_limit"`
+	Models       []string          `json:"models" mapstructure:"models"`
+	CustomConfig map[string]string `json:"custom_config" mapstructure:"custom_config"`
+	// ModelSpecs declares per-model limits, overriding what the provider reports
+	ModelSpecs []ModelSpec `json:"model_specs,omitempty" mapstructure:"model_specs"`
+	// Residency tags where the provider processes data, such as cn or us, for routing policies
+	Residency string `json:"residency,omitempty" mapstructure:"residency"`
+}
+
+// ModelSpec describes one model served by a provider. It is a list entry rather than a map
+// keyed by model because model names such as gpt-3.5-turbo contain the config key delimiter.
+type ModelSpec struct {
+	Name          string `json:"name" mapstructure:"name"`
+	ContextWindow int    `json:"context_window,omitempty" mapstructure:"context_window"` // Prompt + completion tokens
+	// Capabilities replaces the capabilities the provider reports for the model
+	Capabilities *ModelCapabilities `json:"capabilities,omitempty" mapstructure:"capabilities"`
+}
+
+// ModelSpec returns the declared spec for a model, if any
+func (c *ProviderConfig) ModelSpec(model string) (ModelSpec, bool) {
+	if c == nil {
+		return ModelSpec{}, false
+	}
+	for _, spec := range c.ModelSpecs {
+		if spec.Name == model {
+			return spec, true
+		}
+	}
+	return ModelSpec{}, false
 }
 
 // Model represents an AI model
 type Model struct {
-	ID                 uint      `json:"id" gorm:"primaryKey"`
-	ProviderID         uint      `json:"provider_id" gorm:"not null"`
-	Name               string    `json:"name" gorm:"not null"`
-	DisplayName        string    `json:"display_name" gorm:"not null"`
-	Description        string    `json:"description"`
-	ContextLength      int       `json:"context_length" gorm:"default:4096"`
-	SupportedModes     string    `json:"supported_modes"` // JSON array: ["chat", "completion"]
-	CostPerInputToken  float64   `json:"cost_per_input_token" gorm:"default:0"`
-	CostPerOutputToken float64   `json:"cost_per_output_token" gorm:"default:0"`
-	IsEnabled          bool      `json:"is_enabled" gorm:"default:true"`
-	CreatedAt          time.Time `json:"created_at"`
-	UpdatedAt          time.Time `json:"updated_at"`
+	ID                 uint               `json:"id" gorm:"primaryKey"`
+	ProviderID         uint               `json:"provider_id" gorm:"not null"`
+	Name               string             `json:"name" gorm:"not null"`
+	DisplayName        string             `json:"display_name" gorm:"not null"`
+	Description        string             `json:"description"`
+	ContextLength      int                `json:"context_length" gorm:"default:4096"`
+	SupportedModes     string             `json:"supported_modes"` // JSON array: ["chat", "completion"]
+	CostPerInputToken  float64            `json:"cost_per_input_token" gorm:"default:0"`
+	CostPerOutputToken float64            `json:"cost_per_output_token" gorm:"default:0"`
+	IsEnabled          bool               `json:"is_enabled" gorm:"default:true"`
+	Capabilities       *ModelCapabilities `json:"capabilities,omitempty" gorm:"-"` // Optional request features accepted; nil when undeclared
+	CreatedAt          time.Time          `json:"created_at"`
+	UpdatedAt          time.Time          `json:"updated_at"`
 }
 
 // HealthStatus represents the health status of a provider
@@ -302,9 +398,135 @@ type SmartRouterConfig struct {
 	HealthCheckTimeout   time.Duration           `mapstructure:"health_check_timeout" json:"health_check_timeout"`
 	FailoverEnabled      bool                    `mapstructure:"failover_enabled" json:"failover_enabled"`
 	MaxRetries           int                     `mapstructure:"max_retries" json:"max_retries"`
+	FailoverTimeout      time.Duration           `mapstructure:"failover_timeout" json:"failover_timeout"`
 	Weights              map[string]int          `mapstructure:"weights" json:"weights"`
 	CircuitBreaker       *CircuitBreakerConfig   `mapstructure:"circuit_breaker" json:"circuit_breaker"`
 	MetricsEnabled       bool                    `mapstructure:"metrics_enabled" json:"metrics_enabled"`
+	// VirtualModels maps aliases such as "smart" or "fast" to concrete provider/model targets
+	VirtualModels map[string]*VirtualModelConfig `mapstructure:"virtual_models" json:"virtual_models,omitempty"`
+	// Hedging maps models or virtual model aliases to their hedged request policy
+	Hedging map[string]*HedgingConfig `mapstructure:"hedging" json:"hedging,omitempty"`
+	// CostOptimized tunes the cost_optimized strategy
+	CostOptimized *CostOptimizedConfig `mapstructure:"cost_optimized" json:"cost_optimized,omitempty"`
+	// ContextUpgrades names larger siblings that serve prompts too long for a model
+	ContextUpgrades []ContextUpgrade `mapstructure:"context_upgrades" json:"context_upgrades,omitempty"`
+	// PrefixAffinity tunes the prefix_affinity strategy
+	PrefixAffinity *PrefixAffinityConfig `mapstructure:"prefix_affinity" json:"prefix_affinity,omitempty"`
+	// Experiments split the traffic of a model alias between variant models
+	Experiments []ExperimentConfig `mapstructure:"experiments" json:"experiments,omitempty"`
+	// Shadows mirror a sample of a model's requests to a candidate provider for comparison
+	Shadows []ShadowConfig `mapstructure:"shadows" json:"shadows,omitempty"`
+	// Policies restrict the providers and models the requests of users or API keys may reach
+	Policies []RoutingPolicyConfig `mapstructure:"policies" json:"policies,omitempty"`
+	// DefaultPolicy names the policy requests without an authenticated user or API key obey
+	DefaultPolicy string `mapstructure:"default_policy" json:"default_policy,omitempty"`
+	// Concurrency caps the requests in flight per provider and per provider+model, queueing the excess
+	Concurrency *ConcurrencyConfig `mapstructure:"concurrency" json:"concurrency,omitempty"`
+	// OutlierDetection ejects providers from the healthy set when live requests to them fail or slow down
+	OutlierDetection *OutlierDetectionConfig `mapstructure:"outlier_detection" json:"outlier_detection,omitempty"`
+}
+
+// OutlierDetectionConfig tunes passive health checking from live traffic. A provider is ejected after
+// ConsecutiveErrors server errors, network errors or timeouts in a row, where a call slower than
+// LatencyMultiplier times the provider's baseline latency also counts as an error. Each ejection lasts
+// twice as long as the one before, from BaseEjectionTime up to MaxEjectionTime, and no more than
+// MaxEjectionPercent of the providers are ejected at once.
+type OutlierDetectionConfig struct {
+	ConsecutiveErrors  int           `mapstructure:"consecutive_errors" json:"consecutive_errors,omitempty"`     // 0 uses the default
+	LatencyMultiplier  float64       `mapstructure:"latency_multiplier" json:"latency_multiplier,omitempty"`     // 0 ignores latency
+	BaseEjectionTime   time.Duration `mapstructure:"base_ejection_time" json:"base_ejection_time,omitempty"`     // 0 uses the default
+	MaxEjectionTime    time.Duration `mapstructure:"max_ejection_time" json:"max_ejection_time,omitempty"`       // 0 uses the default
+	MaxEjectionPercent int           `mapstructure:"max_ejection_percent" json:"max_ejection_percent,omitempty"` // 0 uses the default
+}
+
+// ConcurrencyConfig caps the requests in flight to providers. Requests over a limit wait in a bounded
+// queue, highest priority first, and overflow to another provider when the queue is full or the wait times out.
+type ConcurrencyConfig struct {
+	Limits       []ConcurrencyLimit `mapstructure:"limits" json:"limits"`
+	MaxQueue     int                `mapstructure:"max_queue" json:"max_queue,omitempty"`         // Requests waiting per limit; 0 uses the default
+	QueueTimeout time.Duration      `mapstructure:"queue_timeout" json:"queue_timeout,omitempty"` // Longest wait for a slot; 0 uses the default
+	Tiers        []PriorityTier     `mapstructure:"tiers" json:"tiers,omitempty"`                 // Queue priorities of API keys
+
+	// Adaptive limits providers without a static provider-wide limit by their observed latency and errors
+	Adaptive *AdaptiveConcurrencyConfig `mapstructure:"adaptive" json:"adaptive,omitempty"`
+}
+
+// AdaptiveConcurrencyConfig tunes an AIMD concurrency limit per provider. The limit grows by one slot
+// per successful call while latency stays within Tolerance times the provider's baseline, and is
+// multiplied by Backoff on a 429, a timeout or an inflated latency.
+type AdaptiveConcurrencyConfig struct {
+	Providers    []string `mapstructure:"providers" json:"providers,omitempty"` // Empty adapts every provider without a static limit
+	InitialLimit int      `mapstructure:"initial_limit" json:"initial_limit,omitempty"`
+	MinLimit     int      `mapstructure:"min_limit" json:"min_limit,omitempty"`
+	MaxLimit     int      `mapstructure:"max_limit" json:"max_limit,omitempty"`
+	Backoff      float64  `mapstructure:"backoff" json:"backoff,omitempty"`     // Factor in (0, 1) applied on overload
+	Tolerance    float64  `mapstructure:"tolerance" json:"tolerance,omitempty"` // Latency multiple of the baseline treated as overload
+}
+
+// ConcurrencyLimit caps the requests in flight to a provider, or to one of its models
+type ConcurrencyLimit struct {
+	Provider    string `mapstructure:"provider" json:"provider"`
+	Model       string `mapstructure:"model" json:"model,omitempty"` // Empty limits the whole provider
+	MaxInFlight int    `mapstructure:"max_in_flight" json:"max_in_flight"`
+}
+
+// PriorityTier gives the queued requests of its API keys a priority; higher priorities are admitted first
+type PriorityTier struct {
+	Name     string   `mapstructure:"name" json:"name"`
+	Priority int      `mapstructure:"priority" json:"priority"`
+	APIKeys  []string `mapstructure:"api_keys" json:"-"`
+}
+
+// RoutingPolicyConfig restricts where a tenant's requests may be sent. Provider lists match either a
+// provider's name or its type, so "openai" covers every OpenAI provider. Empty lists allow everything.
+type RoutingPolicyConfig struct {
+	Name               string   `mapstructure:"name" json:"name"`
+	Users              []string `mapstructure:"users" json:"users,omitempty"`                             // User IDs the policy applies to
+	APIKeys            []string `mapstructure:"api_keys" json:"-"`                                        // API keys the policy applies to
+	AllowedProviders   []string `mapstructure:"allowed_providers" json:"allowed_providers,omitempty"`     // Providers requests may go to
+	AllowedModels      []string `mapstructure:"allowed_models" json:"allowed_models,omitempty"`           // Models requests may ask for
+	ForbiddenProviders []string `mapstructure:"forbidden_providers" json:"forbidden_providers,omitempty"` // Providers requests never go to
+	Residency          []string `mapstructure:"residency" json:"residency,omitempty"`                     // Residency tags a provider must carry
+}
+
+// ShadowConfig mirrors a sample of answered requests for Model to a shadow target in the background.
+// The client only ever gets the primary answer; both are recorded side by side.
+type ShadowConfig struct {
+	Model         string  `mapstructure:"model" json:"model"`                             // Model or alias whose requests are mirrored
+	Provider      string  `mapstructure:"provider" json:"provider"`                       // Provider the shadow calls go to
+	TargetModel   string  `mapstructure:"target_model" json:"target_model,omitempty"`     // Model the shadow is asked for; defaults to Model
+	SampleRate    float64 `mapstructure:"sample_rate" json:"sample_rate"`                 // Fraction of requests mirrored, (0, 1]
+	MaxConcurrent int     `mapstructure:"max_concurrent" json:"max_concurrent,omitempty"` // Shadow calls in flight before samples are dropped; 0 uses the default
+}
+
+// Identities an experiment keeps assignments sticky by
+const (
+	ExperimentStickyUser   = "user"    // The authenticated user, else the request's user_id (default)
+	ExperimentStickyAPIKey = "api_key" // The API key the request was made with
+)
+
+// ExperimentConfig splits requests for Model between weighted variants while the experiment runs.
+// A caller always gets the same variant: its sticky identity is hashed into the weights.
+type ExperimentConfig struct {
+	Name     string              `mapstructure:"name" json:"name"`
+	Model    string              `mapstructure:"model" json:"model"`                   // Model or alias clients request
+	StickyBy string              `mapstructure:"sticky_by" json:"sticky_by,omitempty"` // user (default) or api_key
+	Start    string              `mapstructure:"start" json:"start,omitempty"`         // RFC 3339; empty starts immediately
+	End      string              `mapstructure:"end" json:"end,omitempty"`             // RFC 3339; empty runs until removed
+	Variants []ExperimentVariant `mapstructure:"variants" json:"variants"`             // Include the current model as the control
+}
+
+// ExperimentVariant is one arm of an experiment, served by Model (a model or virtual model)
+type ExperimentVariant struct {
+	Name   string `mapstructure:"name" json:"name"`
+	Model  string `mapstructure:"model" json:"model"`
+	Weight int    `mapstructure:"weight" json:"weight"` // Share of callers relative to the other variants
+}
+
+// ContextUpgrade sends requests whose prompt doesn't fit model From to the larger model To
+type ContextUpgrade struct {
+	From string `mapstructure:"from" json:"from"`
+	To   string `mapstructure:"to" json:"to"`
 }
 
 // CircuitBreakerConfig represents circuit breaker configuration  
@@ -313,4 +535,65 @@ type CircuitBreakerConfig struct {
 	Threshold   int           `mapstructure:"threshold" json:"threshold"`
 	Timeout     time.Duration `mapstructure:"timeout" json:"timeout"`
 	MaxRequests int           `mapstructure:"max_requests" json:"max_requests"`
+	FailureRate float64       `mapstructure:"failure_rate" json:"failure_rate,omitempty"`
+	MinRequests int           `mapstructure:"min_requests" json:"min_requests,omitempty"`
+	Window      time.Duration `mapstructure:"window" json:"window,omitempty"`
+	WindowSize  int           `mapstructure:"window_size" json:"window_size,omitempty"`
+}
+
+// Virtual model target ordering strategies
+const (
+	VirtualModelOrdered  = "ordered"  // Try targets in the listed order
+	VirtualModelWeighted = "weighted" // Pick the first target by weight; the rest follow as fallbacks
+)
+
+// VirtualModelConfig represents an alias served by an ordered or weighted chain of targets
+type VirtualModelConfig struct {
+	Strategy string               `mapstructure:"strategy" json:"strategy"` // ordered (default) or weighted
+	Targets  []VirtualModelTarget `mapstructure:"targets" json:"targets"`
+}
+
+// VirtualModelTarget represents one concrete provider/model behind a virtual model
+type VirtualModelTarget struct {
+	Provider string `mapstructure:"provider" json:"provider"` // Registered provider instance name
+	Model    string `mapstructure:"model" json:"model"`
+	Weight   int    `mapstructure:"weight" json:"weight,omitempty"` // weighted only; 0 makes the target fallback-only
+
+	// Per-target parameter overrides applied to the forwarded request
+	Temperature *float64 `mapstructure:"temperature" json:"temperature,omitempty"`
+	MaxTokens   *int     `mapstructure:"max_tokens" json:"max_tokens,omitempty"`
+}
+
+// HedgingConfig represents hedged requests for a model or virtual model alias: when the first
+// provider hasn't answered (or sent a first token) within the hedge delay, a duplicate request
+// goes to a second provider and the first answer wins
+type HedgingConfig struct {
+	Delay       time.Duration `mapstructure:"delay" json:"delay"`               // Used until the provider has MinSamples latencies, then its p95
+	MinSamples  int           `mapstructure:"min_samples" json:"min_samples"`   // Latencies needed before p95 is trusted (default 20)
+	BudgetRatio float64       `mapstructure:"budget_ratio" json:"budget_ratio"` // Max fraction of requests that may be hedged, (0, 1]
+}
+
+// CostOptimizedConfig represents the trade-offs the cost_optimized strategy accepts: the cheapest
+// candidate is picked among those within the latency and quality tolerances. A zero tolerance
+// disables that filter.
+type CostOptimizedConfig struct {
+	LatencyTolerance float64            `mapstructure:"latency_tolerance" json:"latency_tolerance"` // Max fractional slowdown vs the fastest candidate, e.g. 0.5 = 50% slower
+	QualityTolerance float64            `mapstructure:"quality_tolerance" json:"quality_tolerance"` // Max fractional quality drop vs the best candidate, e.g. 0.1
+	Quality          map[string]float64 `mapstructure:"quality" json:"quality,omitempty"`           // Provider name -> quality score; unscored providers count as 0
+}
+
+// Hash functions the prefix_affinity strategy can place conversations with
+const (
+	PrefixAffinityConsistent = "consistent" // Consistent hash ring (default)
+	PrefixAffinityRendezvous = "rendezvous" // Highest random weight hashing
+)
+
+// PrefixAffinityConfig represents how the prefix_affinity strategy keys and spreads requests.
+// Requests sharing a conversation ID, or the same system prompt and leading messages, hash to
+// the same provider so its prompt cache is reused; a provider holding more than LoadFactor times
+// the average in-flight requests passes new work to the next provider on the ring.
+type PrefixAffinityConfig struct {
+	PrefixMessages int     `mapstructure:"prefix_messages" json:"prefix_messages"` // Non-system messages hashed with the system prompt; 0 uses the default
+	LoadFactor     float64 `mapstructure:"load_factor" json:"load_factor"`         // Max in-flight requests relative to the average, > 1; 0 uses the default
+	Hash           string  `mapstructure:"hash" json:"hash,omitempty"`             // consistent (default) or rendezvous
 }
@@ -0,0 +1,120 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestAzureProvider(t *testing.T, baseURL string) *providers.OpenAIProvider {
+	provider, err := providers.NewAzureOpenAIProvider(&types.ProviderConfig{
+		Name:    "azure",
+		Type:    providers.AzureOpenAIType,
+		BaseURL: baseURL + "/",
+		APIKey:  "azure-key",
+		CustomConfig: map[string]string{
+			providers.AzureAPIVersion:  "2024-06-01",
+			providers.AzureDeployments: "gpt-4o=prod-gpt4o, gpt-3.5-turbo=gpt-35-turbo",
+		},
+	}, newTestLogger())
+	require.NoError(t, err)
+	return provider
+}
+
+// TestAzureOpenAIChatCompletion tests deployment URLs, api-key auth and Azure rate limit headers
+func TestAzureOpenAIChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/openai/deployments/prod-gpt4o/chat/completions", r.URL.Path)
+		assert.Equal(t, "2024-06-01", r.URL.Query().Get("api-version"))
+		assert.Equal(t, "azure-key", r.Header.Get("api-key"))
+		assert.Empty(t, r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-limit-requests", "600")
+		w.Header().Set("x-ratelimit-remaining-requests", "599")
+		w.Header().Set("x-ratelimit-limit-tokens", "90000")
+		w.Header().Set("x-ratelimit-remaining-tokens", "89000")
+		fmt.Fprint(w, `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"gpt-4o-2024-05-13",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	provider := newTestAzureProvider(t, server.URL)
+	assert.Equal(t, providers.AzureOpenAIType, provider.GetType())
+
+	resp, err := provider.Call(context.Background(), &types.ChatCompletionRequest{
+		Model:    "gpt-4o",
+		Messages: []types.Message{{Role: "user", Content: "ping"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "pong", resp.Choices[0].Message.Content)
+
+	limits := provider.GetRateLimit()
+	assert.Equal(t, 600, limits.RequestsPerMinute)
+	assert.Equal(t, 599, limits.RemainingRequests)
+	assert.Equal(t, 90000, limits.TokensPerMinute)
+	assert.Equal(t, 89000, limits.RemainingTokens)
+}
+
+// TestAzureOpenAIErrorClassification tests content filter rejections and millisecond retry hints
+func TestAzureOpenAIErrorClassification(t *testing.T) {
+	req := &types.ChatCompletionRequest{
+		Model:    "gpt-3.5-turbo",
+		Messages: []types.Message{{Role: "user", Content: "..."}},
+	}
+
+	t.Run("ContentFilter", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			assert.Equal(t, "/openai/deployments/gpt-35-turbo/chat/completions", r.URL.Path)
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"The response was filtered","type":null,"param":"prompt","code":"content_filter","status":400,
+				"innererror":{"code":"ResponsibleAIPolicyViolation","content_filter_result":{
+					"hate":{"filtered":false,"severity":"safe"},"violence":{"filtered":true,"severity":"high"}}}}}`)
+		}))
+		defer server.Close()
+
+		_, err := newTestAzureProvider(t, server.URL).Call(context.Background(), req)
+		require.Error(t, err)
+
+		var retryErr *retry.ProviderRetryError
+		require.True(t, errors.As(err, &retryErr))
+		assert.Equal(t, types.ErrorContentFilter, retryErr.Category)
+		assert.False(t, retryErr.Retryable)
+		assert.Contains(t, retryErr.Message, "violence")
+		assert.Equal(t, 1, requests, "content filter rejections are not retried")
+	})
+
+	t.Run("RetryAfterMs", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("retry-after-ms", "1500")
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"error":{"code":"429","message":"Requests to the deployment have exceeded the rate limit"}}`)
+		}))
+		defer server.Close()
+
+		// The deadline cuts the retry backoff short; only the throttling state matters here
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		provider := newTestAzureProvider(t, server.URL)
+		_, err := provider.Call(ctx, req)
+		require.Error(t, err)
+
+		limits := provider.GetRateLimit()
+		assert.Equal(t, 0, limits.RemainingRequests)
+		assert.WithinDuration(t, time.Now().Add(1500*time.Millisecond), limits.ResetTime, time.Second)
+	})
+}
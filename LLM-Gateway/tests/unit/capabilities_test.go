@@ -0,0 +1,225 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestRequiredCapabilities tests detecting the optional features a request uses
+func TestRequiredCapabilities(t *testing.T) {
+	var req types.ChatCompletionRequest
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"model": "m",
+		"messages": [{"role": "user", "content": [
+			{"type": "text", "text": "What is this?"},
+			{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}
+		]}],
+		"tools": [{"type": "function", "function": {"name": "lookup"}}],
+		"response_format": {"type": "json_object"},
+		"stream": true
+	}`), &req))
+
+	assert.Equal(t, types.ModelCapabilities{Tools: true, Vision: true, Streaming: true, JSONMode: true}, req.RequiredCapabilities())
+
+	plain := types.ChatCompletionRequest{
+		Model:          "m",
+		Messages:       []types.Message{{Role: "user", Content: "hi"}},
+		ResponseFormat: &types.ResponseFormat{Type: types.ResponseFormatText},
+	}
+	assert.Equal(t, types.ModelCapabilities{}, plain.RequiredCapabilities(), "a text response format needs nothing")
+}
+
+// TestFilterByCapabilities tests dropping candidates whose model lacks a feature the request uses
+func TestFilterByCapabilities(t *testing.T) {
+	basic := router.NewMockProvider(&types.ProviderConfig{Name: "basic"}, newTestLogger())
+	tooling := router.NewMockProvider(&types.ProviderConfig{Name: "tooling"}, newTestLogger())
+	undeclared := router.NewMockProvider(&types.ProviderConfig{Name: "undeclared"}, newTestLogger())
+	declared := map[string]*types.ModelCapabilities{
+		"basic":   {Streaming: true},
+		"tooling": {Tools: true, Streaming: true},
+	}
+	capabilities := func(p types.Provider, model string) *types.ModelCapabilities { return declared[p.GetName()] }
+
+	toolReq := &types.ChatCompletionRequest{Model: "m", Tools: []types.Tool{{Type: "function", Function: types.FunctionDefinition{Name: "lookup"}}}}
+
+	t.Run("KeepsSupportingProviders", func(t *testing.T) {
+		supported, err := router.FilterByCapabilities(toolReq, []types.Provider{basic, tooling}, capabilities)
+		require.NoError(t, err)
+		require.Len(t, supported, 1)
+		assert.Equal(t, "tooling", supported[0].GetName())
+	})
+
+	t.Run("UndeclaredIsAssumedSupported", func(t *testing.T) {
+		supported, err := router.FilterByCapabilities(toolReq, []types.Provider{basic, undeclared}, capabilities)
+		require.NoError(t, err)
+		require.Len(t, supported, 1)
+		assert.Equal(t, "undeclared", supported[0].GetName())
+	})
+
+	t.Run("PlainRequestKeepsAll", func(t *testing.T) {
+		supported, err := router.FilterByCapabilities(&types.ChatCompletionRequest{Model: "m"}, []types.Provider{basic, tooling}, capabilities)
+		require.NoError(t, err)
+		assert.Len(t, supported, 2)
+	})
+
+	t.Run("NoneSupports", func(t *testing.T) {
+		jsonReq := &types.ChatCompletionRequest{Model: "m", ResponseFormat: &types.ResponseFormat{Type: types.ResponseFormatJSONObject}}
+		_, err := router.FilterByCapabilities(jsonReq, []types.Provider{basic, tooling}, capabilities)
+
+		var capabilityErr *router.CapabilityError
+		require.ErrorAs(t, err, &capabilityErr)
+		assert.Equal(t, []string{types.CapabilityJSONMode}, capabilityErr.Missing)
+	})
+
+	t.Run("DeclaredCapabilitiesPrefersModelSpec", func(t *testing.T) {
+		configured := router.NewMockProvider(&types.ProviderConfig{
+			Name:       "configured",
+			ModelSpecs: []types.ModelSpec{{Name: "mock-gpt-3.5", Capabilities: &types.ModelCapabilities{Vision: true}}},
+		}, newTestLogger())
+
+		assert.Equal(t, &types.ModelCapabilities{Vision: true},
+			router.DeclaredCapabilities(context.Background(), configured, "mock-gpt-3.5"))
+		assert.Nil(t, router.DeclaredCapabilities(context.Background(), configured, "other-model"))
+	})
+}
+
+// TestGatewayCapabilities tests that the gateway only sends a request to providers supporting its features
+func TestGatewayCapabilities(t *testing.T) {
+	var mu sync.Mutex
+	var received []map[string]interface{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		received = append(received, body)
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"shared-model",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"{}"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`)
+	}))
+	defer upstream.Close()
+
+	compat := func(capabilities *types.ModelCapabilities) *types.ProviderConfig {
+		return &types.ProviderConfig{
+			Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+			Models:     []string{"shared-model"},
+			ModelSpecs: []types.ModelSpec{{Name: "shared-model", Capabilities: capabilities}},
+		}
+	}
+	gw := gateway.New(&types.Config{
+		Logging:     types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{FailoverTimeout: 10 * time.Second},
+		Providers: map[string]*types.ProviderConfig{
+			"plain":   compat(&types.ModelCapabilities{Streaming: true}),
+			"tooling": compat(&types.ModelCapabilities{Tools: true, Streaming: true}),
+		},
+	})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("ToolsGoToToolCapableProvider", func(t *testing.T) {
+		for i := 0; i < 2; i++ {
+			recorder := send(`{"model":"shared-model","messages":[{"role":"user","content":"hi"}],
+				"tools":[{"type":"function","function":{"name":"lookup"}}]}`)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "tooling", recorder.Header().Get("X-Gateway-Provider"))
+		}
+	})
+
+	t.Run("UnsupportedCombinationIsRejected", func(t *testing.T) {
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		recorder := send(`{"model":"shared-model","messages":[{"role":"user","content":"hi"}],
+			"tools":[{"type":"function","function":{"name":"lookup"}}],"response_format":{"type":"json_object"}}`)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "unsupported_capability")
+		assert.Contains(t, recorder.Body.String(), types.CapabilityJSONMode)
+		assert.Empty(t, received)
+	})
+
+	t.Run("VirtualModelSkipsUnsupportedTargets", func(t *testing.T) {
+		newVirtualGateway := func(targets ...types.VirtualModelTarget) *gateway.Gateway {
+			return gateway.New(&types.Config{
+				Logging: types.LoggingConfig{Level: "error", Format: "text"},
+				SmartRouter: &types.SmartRouterConfig{
+					FailoverTimeout: 10 * time.Second,
+					VirtualModels:   map[string]*types.VirtualModelConfig{"agent": {Targets: targets}},
+				},
+				Providers: map[string]*types.ProviderConfig{
+					"plain":   compat(&types.ModelCapabilities{Streaming: true}),
+					"tooling": compat(&types.ModelCapabilities{Tools: true, Streaming: true}),
+				},
+			})
+		}
+		body := `{"model":"agent","messages":[{"role":"user","content":"hi"}],
+			"tools":[{"type":"function","function":{"name":"lookup"}}]}`
+		sendTo := func(gw *gateway.Gateway) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			gw.Handler().ServeHTTP(recorder, req)
+			return recorder
+		}
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		recorder := sendTo(newVirtualGateway(
+			types.VirtualModelTarget{Provider: "plain", Model: "shared-model"},
+			types.VirtualModelTarget{Provider: "tooling", Model: "shared-model"},
+		))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "tooling", recorder.Header().Get("X-Gateway-Provider"))
+		assert.Len(t, received, 1, "the target without tools is never sent the request")
+
+		recorder = sendTo(newVirtualGateway(types.VirtualModelTarget{Provider: "plain", Model: "shared-model"}))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "unsupported_capability")
+		assert.Len(t, received, 1)
+	})
+
+	t.Run("ResponseFormatIsForwarded", func(t *testing.T) {
+		jsonGateway := gateway.New(&types.Config{
+			Logging:     types.LoggingConfig{Level: "error", Format: "text"},
+			SmartRouter: &types.SmartRouterConfig{FailoverTimeout: 10 * time.Second},
+			Providers:   map[string]*types.ProviderConfig{"json": compat(&types.ModelCapabilities{JSONMode: true})},
+		})
+		mu.Lock()
+		received = nil
+		mu.Unlock()
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(
+			`{"model":"shared-model","messages":[{"role":"user","content":"hi"}],"response_format":{"type":"json_object"}}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		jsonGateway.Handler().ServeHTTP(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.Len(t, received, 1)
+		assert.Equal(t, map[string]interface{}{"type": "json_object"}, received[0]["response_format"])
+	})
+}
@@ -0,0 +1,526 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/internal/storage"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLimitedRouter returns a smart router over provider-0 and provider-1 with the given concurrency config
+func newLimitedRouter(t *testing.T, concurrency *types.ConcurrencyConfig) (*router.SmartRouter, []types.Provider) {
+	config := router.DefaultSmartRouterConfig()
+	config.Concurrency = concurrency
+	return newTestSmartRouter(t, config, "provider-0", "provider-1")
+}
+
+// queueDepth returns the number of requests waiting on a concurrency limit
+func queueDepth(smartRouter *router.SmartRouter, key string) int {
+	for _, report := range smartRouter.ConcurrencyReports() {
+		if report.Key == key {
+			return report.QueueDepth
+		}
+	}
+	return 0
+}
+
+// TestConcurrencyLimit tests admission order, overflow and saturation of per-provider concurrency limits
+func TestConcurrencyLimit(t *testing.T) {
+	req := &types.Request{Model: "chat"}
+
+	t.Run("PriorityOrder", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Limits:       []types.ConcurrencyLimit{{Provider: "provider-0", MaxInFlight: 1}},
+			QueueTimeout: 5 * time.Second,
+		})
+		only := providerList[:1]
+
+		hold := make(chan struct{})
+		var mutex sync.Mutex
+		var order []int
+		call := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			mutex.Lock()
+			order = append(order, router.RequestPriorityFrom(ctx))
+			mutex.Unlock()
+			if router.RequestPriorityFrom(ctx) == 100 {
+				<-hold
+			}
+			return "ok", nil
+		}
+
+		var wg sync.WaitGroup
+		send := func(priority int) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := smartRouter.ExecuteWithFailover(router.WithRequestPriority(context.Background(), priority), req, only, call)
+				assert.NoError(t, err)
+			}()
+		}
+
+		send(100)
+		require.Eventually(t, func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return len(order) == 1
+		}, 5*time.Second, time.Millisecond)
+
+		send(0)
+		require.Eventually(t, func() bool { return queueDepth(smartRouter, "provider-0") == 1 }, 5*time.Second, time.Millisecond)
+		send(-5)
+		require.Eventually(t, func() bool { return queueDepth(smartRouter, "provider-0") == 2 }, 5*time.Second, time.Millisecond)
+		send(5)
+		require.Eventually(t, func() bool { return queueDepth(smartRouter, "provider-0") == 3 }, 5*time.Second, time.Millisecond)
+
+		close(hold)
+		wg.Wait()
+		assert.Equal(t, []int{100, 5, 0, -5}, order, "waiters are admitted by priority")
+
+		admission := smartRouter.GetAdmissionMetrics()["provider-0"]
+		require.NotNil(t, admission)
+		assert.Equal(t, int64(4), admission.Admitted)
+		assert.Equal(t, int64(3), admission.Queued)
+		assert.Zero(t, admission.Rejected)
+		assert.Equal(t, 3, admission.MaxQueueDepth)
+		assert.Positive(t, admission.MaxWait)
+	})
+
+	t.Run("OverflowOnTimeout", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Limits: []types.ConcurrencyLimit{
+				{Provider: "provider-0", MaxInFlight: 1},
+				{Provider: "provider-1", MaxInFlight: 1},
+			},
+			QueueTimeout: 100 * time.Millisecond,
+		})
+
+		holds := map[string]chan struct{}{"provider-0": make(chan struct{}), "provider-1": make(chan struct{})}
+		started := make(chan string, 2)
+		holding := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			started <- p.GetName()
+			<-holds[p.GetName()]
+			return p.GetName(), nil
+		}
+		var wg sync.WaitGroup
+		for _, provider := range providerList {
+			wg.Add(1)
+			go func(provider types.Provider) {
+				defer wg.Done()
+				_, err := smartRouter.ExecuteWithFailover(context.Background(), req, []types.Provider{provider}, holding)
+				assert.NoError(t, err)
+			}(provider)
+		}
+		<-started
+		<-started
+
+		// Both providers are busy; free whichever one the request did not queue on
+		done := make(chan *router.SmartRoutingResult, 1)
+		go func() {
+			result, err := smartRouter.ExecuteWithFailover(context.Background(), req, providerList,
+				func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+					return p.GetName(), nil
+				})
+			assert.NoError(t, err)
+			done <- result
+		}()
+		require.Eventually(t, func() bool {
+			return queueDepth(smartRouter, "provider-0")+queueDepth(smartRouter, "provider-1") == 1
+		}, 5*time.Second, time.Millisecond)
+		queuedOn, other := "provider-0", "provider-1"
+		if queueDepth(smartRouter, "provider-1") == 1 {
+			queuedOn, other = other, queuedOn
+		}
+		close(holds[other])
+
+		result := <-done
+		require.NotNil(t, result)
+		assert.Equal(t, other, result.ProviderName)
+		require.Len(t, result.Tried, 2)
+		assert.Equal(t, queuedOn, result.Tried[0].ProviderName)
+		assert.True(t, result.Tried[0].Saturated)
+		assert.GreaterOrEqual(t, result.Tried[0].Queued, 100*time.Millisecond)
+		assert.False(t, result.Tried[1].Saturated)
+
+		close(holds[queuedOn])
+		wg.Wait()
+		assert.Equal(t, int64(1), smartRouter.GetAdmissionMetrics()[queuedOn].Rejected)
+	})
+
+	t.Run("PrefersUnsaturated", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Limits: []types.ConcurrencyLimit{{Provider: "provider-0", Model: "chat", MaxInFlight: 1}},
+		})
+
+		hold := make(chan struct{})
+		go func() {
+			_, _ = smartRouter.ExecuteWithFailover(context.Background(), req, providerList[:1],
+				func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+					<-hold
+					return nil, nil
+				})
+		}()
+		defer close(hold)
+		require.Eventually(t, func() bool {
+			reports := smartRouter.ConcurrencyReports()
+			return len(reports) == 1 && reports[0].Key == "provider-0/chat" && reports[0].InFlight == 1
+		}, 5*time.Second, time.Millisecond)
+
+		for i := 0; i < 5; i++ {
+			result, err := smartRouter.ExecuteWithFailover(context.Background(), req, providerList,
+				func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+					return nil, nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, "provider-1", result.ProviderName)
+			assert.Len(t, result.Tried, 1, "the saturated provider is not tried")
+		}
+	})
+
+	t.Run("Saturated", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Limits:   []types.ConcurrencyLimit{{Provider: "provider-0", MaxInFlight: 1}},
+			MaxQueue: 1,
+		})
+		only := providerList[:1]
+
+		hold := make(chan struct{})
+		call := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			<-hold
+			return nil, nil
+		}
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only, call)
+				assert.NoError(t, err)
+			}()
+		}
+		require.Eventually(t, func() bool { return queueDepth(smartRouter, "provider-0") == 1 }, 5*time.Second, time.Millisecond)
+
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only, call)
+		assert.True(t, errors.Is(err, router.ErrProviderSaturated), "the queue is full")
+		var failoverErr *router.FailoverError
+		require.True(t, errors.As(err, &failoverErr))
+		require.Len(t, failoverErr.Attempts, 1)
+		assert.True(t, failoverErr.Attempts[0].Saturated)
+
+		close(hold)
+		wg.Wait()
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string]*types.ConcurrencyConfig{
+			"MissingProvider":  {Limits: []types.ConcurrencyLimit{{MaxInFlight: 1}}},
+			"ZeroLimit":        {Limits: []types.ConcurrencyLimit{{Provider: "p"}}},
+			"DuplicateLimit":   {Limits: []types.ConcurrencyLimit{{Provider: "p", Model: "m", MaxInFlight: 1}, {Provider: "p", Model: "m", MaxInFlight: 2}}},
+			"NegativeQueue":    {MaxQueue: -1},
+			"NegativeTimeout":  {QueueTimeout: -time.Second},
+			"DuplicateTier":    {Tiers: []types.PriorityTier{{Name: "a"}, {Name: "a"}}},
+			"APIKeyInTwoTiers": {Tiers: []types.PriorityTier{{Name: "a", APIKeys: []string{"k"}}, {Name: "b", APIKeys: []string{"k"}}}},
+		}
+		for name, concurrency := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.Concurrency = concurrency
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+	})
+}
+
+// TestGatewayConcurrency tests request priorities and the 503 returned when every provider is saturated
+func TestGatewayConcurrency(t *testing.T) {
+	release := make(chan struct{})
+	var mutex sync.Mutex
+	var served []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mutex.Lock()
+		served = append(served, body.Messages[0].Content)
+		mutex.Unlock()
+		<-release
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"chat-model",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverTimeout: 10 * time.Second,
+			MetricsEnabled:  true,
+			Concurrency: &types.ConcurrencyConfig{
+				Limits:       []types.ConcurrencyLimit{{Provider: "limited", MaxInFlight: 1}},
+				QueueTimeout: 1500 * time.Millisecond,
+				Tiers:        []types.PriorityTier{{Name: "premium", Priority: 10, APIKeys: []string{"premium-key"}}},
+			},
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"limited": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+				Models: []string{"chat-model"},
+			},
+		},
+	})
+
+	gw.UseAuthentication(authenticateAPIKeys(map[string]*storage.APIKey{"premium-key": {Key: "premium-key", UserID: 1}}))
+
+	send := func(content, apiKey, priority string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(fmt.Sprintf(`{"model":"chat-model","messages":[{"role":"user","content":%q}]}`, content)))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		if priority != "" {
+			req.Header.Set("X-Priority", priority)
+		}
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+	type concurrencyState struct {
+		Limits    []router.ConcurrencyReport          `json:"limits"`
+		Admission map[string]*router.AdmissionMetrics `json:"admission"`
+	}
+	state := func() concurrencyState {
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/admin/concurrency", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		var body concurrencyState
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return body
+	}
+
+	// The first request holds the only slot
+	held := make(chan int, 1)
+	go func() { held <- send("held", "", "").Code }()
+	require.Eventually(t, func() bool {
+		limits := state().Limits
+		return len(limits) == 1 && limits[0].InFlight == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	recorder := send("rejected", "", "")
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "2", recorder.Header().Get("Retry-After"))
+	assert.Equal(t, "limited", recorder.Header().Get("X-Gateway-Tried-Providers"))
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "provider_saturated", body.Error.Code)
+
+	// Premium keys jump the queue unless they lower their priority, and X-Priority cannot raise it
+	codes := make(chan int, 4)
+	queue := func(content, apiKey, priority string, depth int) {
+		go func() { codes <- send(content, apiKey, priority).Code }()
+		require.Eventually(t, func() bool { return state().Limits[0].QueueDepth == depth }, 5*time.Second, 10*time.Millisecond)
+	}
+	queue("default", "", "", 1)
+	queue("raised", "", "50", 2)
+	queue("lowered", "premium-key", "-1", 3)
+	queue("premium", "premium-key", "", 4)
+
+	close(release)
+	assert.Equal(t, http.StatusOK, <-held)
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, http.StatusOK, <-codes)
+	}
+	mutex.Lock()
+	assert.Equal(t, []string{"held", "premium", "default", "raised", "lowered"}, served)
+	mutex.Unlock()
+
+	admission := state().Admission["limited"]
+	require.NotNil(t, admission)
+	assert.Equal(t, int64(5), admission.Admitted)
+	assert.Equal(t, int64(1), admission.Rejected)
+	assert.Equal(t, 4, admission.MaxQueueDepth)
+}
+
+// adaptiveLimit returns the report of provider-0's adaptive concurrency limit
+func adaptiveLimit(t *testing.T, smartRouter *router.SmartRouter) router.ConcurrencyReport {
+	for _, report := range smartRouter.ConcurrencyReports() {
+		if report.Key == "provider-0" {
+			require.True(t, report.Adaptive)
+			return report
+		}
+	}
+	t.Fatal("provider-0 has no concurrency limit")
+	return router.ConcurrencyReport{}
+}
+
+// TestAdaptiveConcurrency tests that adaptive limits grow with use and back off on overload
+func TestAdaptiveConcurrency(t *testing.T) {
+	req := &types.Request{Model: "chat"}
+	instant := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		return nil, nil
+	}
+
+	t.Run("GrowsAndBacksOff", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Adaptive: &types.AdaptiveConcurrencyConfig{
+				Providers: []string{"provider-0"}, InitialLimit: 2, MaxLimit: 6, Backoff: 0.5, Tolerance: 1e6,
+			},
+		})
+		only := providerList[:1]
+
+		// Rounds that fill the limit show it is in use, so it grows up to the maximum
+		for round, limit := 0, 2; round < 20 && limit < 6; round++ {
+			var started, done sync.WaitGroup
+			started.Add(limit)
+			for i := 0; i < limit; i++ {
+				done.Add(1)
+				go func() {
+					defer done.Done()
+					_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only,
+						func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+							started.Done()
+							started.Wait()
+							return nil, nil
+						})
+					assert.NoError(t, err)
+				}()
+			}
+			done.Wait()
+			limit = adaptiveLimit(t, smartRouter).Limit
+		}
+		assert.Equal(t, 6, adaptiveLimit(t, smartRouter).Limit)
+
+		// A limit that is barely used does not grow further
+		for i := 0; i < 5; i++ {
+			_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only, instant)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 6, adaptiveLimit(t, smartRouter).Limit)
+
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				return nil, errors.New("status code: 429, rate limit exceeded")
+			})
+		require.Error(t, err)
+		assert.Equal(t, 3, adaptiveLimit(t, smartRouter).Limit, "a 429 halves the limit")
+
+		_, err = smartRouter.ExecuteWithFailover(context.Background(), req, only,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				return nil, errors.New("status code: 400, invalid request")
+			})
+		require.Error(t, err)
+		assert.Equal(t, 3, adaptiveLimit(t, smartRouter).Limit, "client errors say nothing about capacity")
+	})
+
+	t.Run("LatencyInflation", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Adaptive: &types.AdaptiveConcurrencyConfig{InitialLimit: 8, MinLimit: 3, Backoff: 0.5},
+		})
+		only := providerList[:1]
+
+		// A steady latency keeps scheduler jitter from reading as inflation against a near-zero baseline
+		steady := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			time.Sleep(5 * time.Millisecond)
+			return nil, nil
+		}
+		for i := 0; i < 20; i++ {
+			_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only, steady)
+			require.NoError(t, err)
+		}
+		report := adaptiveLimit(t, smartRouter)
+		assert.Equal(t, 8, report.Limit)
+		assert.Positive(t, report.BaselineLatency)
+
+		slow := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			return nil, nil
+		}
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), req, only, slow)
+		require.NoError(t, err)
+		assert.Equal(t, 4, adaptiveLimit(t, smartRouter).Limit)
+
+		_, err = smartRouter.ExecuteWithFailover(context.Background(), req, only, slow)
+		require.NoError(t, err)
+		assert.Equal(t, 3, adaptiveLimit(t, smartRouter).Limit, "the limit never drops below the minimum")
+	})
+
+	t.Run("DeprioritizedNearLimit", func(t *testing.T) {
+		smartRouter, providerList := newLimitedRouter(t, &types.ConcurrencyConfig{
+			Adaptive: &types.AdaptiveConcurrencyConfig{Providers: []string{"provider-0"}, InitialLimit: 5, MaxLimit: 5},
+		})
+
+		hold := make(chan struct{})
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = smartRouter.ExecuteWithFailover(context.Background(), req, providerList[:1],
+					func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+						<-hold
+						return nil, nil
+					})
+			}()
+		}
+		require.Eventually(t, func() bool {
+			reports := smartRouter.ConcurrencyReports()
+			return len(reports) == 1 && reports[0].InFlight == 4
+		}, 5*time.Second, time.Millisecond)
+
+		// provider-0 still has a free slot, but is too close to its limit while provider-1 has room
+		for i := 0; i < 5; i++ {
+			result, err := smartRouter.ExecuteWithFailover(context.Background(), req, providerList, instant)
+			require.NoError(t, err)
+			assert.Equal(t, "provider-1", result.ProviderName)
+		}
+		close(hold)
+		wg.Wait()
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string]*types.ConcurrencyConfig{
+			"NegativeLimit":     {Adaptive: &types.AdaptiveConcurrencyConfig{MinLimit: -1}},
+			"MinAboveMax":       {Adaptive: &types.AdaptiveConcurrencyConfig{MinLimit: 10, MaxLimit: 5}},
+			"InitialAboveMax":   {Adaptive: &types.AdaptiveConcurrencyConfig{InitialLimit: 50, MaxLimit: 10}},
+			"BackoffOfOne":      {Adaptive: &types.AdaptiveConcurrencyConfig{Backoff: 1}},
+			"ToleranceBelowOne": {Adaptive: &types.AdaptiveConcurrencyConfig{Tolerance: 0.5}},
+			"StaticAndAdaptive": {
+				Limits:   []types.ConcurrencyLimit{{Provider: "p", MaxInFlight: 1}},
+				Adaptive: &types.AdaptiveConcurrencyConfig{Providers: []string{"p"}},
+			},
+		}
+		for name, concurrency := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.Concurrency = concurrency
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+
+		config := router.DefaultSmartRouterConfig()
+		config.Concurrency = &types.ConcurrencyConfig{Adaptive: &types.AdaptiveConcurrencyConfig{MaxLimit: 10}}
+		assert.NoError(t, config.ValidateConfig(), "defaults fit the configured bounds")
+	})
+}
@@ -0,0 +1,204 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// longPrompt returns a user message of roughly words tokens
+func longPrompt(words int) []types.Message {
+	return []types.Message{{Role: "user", Content: strings.Repeat("context ", words)}}
+}
+
+// TestFitContextWindow tests filtering candidates by whether the prompt fits their context window
+func TestFitContextWindow(t *testing.T) {
+	small := router.NewMockProvider(&types.ProviderConfig{Name: "small"}, newTestLogger())
+	large := router.NewMockProvider(&types.ProviderConfig{Name: "large"}, newTestLogger())
+	unknown := router.NewMockProvider(&types.ProviderConfig{Name: "unknown"}, newTestLogger())
+	windows := map[string]int{"small": 100, "large": 1000}
+	window := func(p types.Provider, model string) int { return windows[p.GetName()] }
+
+	t.Run("ShortPromptFitsAll", func(t *testing.T) {
+		fitting, err := router.FitContextWindow(&types.Request{Model: "m", Messages: longPrompt(10)},
+			[]types.Provider{small, large}, window)
+		require.NoError(t, err)
+		assert.Len(t, fitting, 2)
+	})
+
+	t.Run("LongPromptSkipsSmallWindow", func(t *testing.T) {
+		fitting, err := router.FitContextWindow(&types.Request{Model: "m", Messages: longPrompt(300)},
+			[]types.Provider{small, large}, window)
+		require.NoError(t, err)
+		require.Len(t, fitting, 1)
+		assert.Equal(t, "large", fitting[0].GetName())
+	})
+
+	t.Run("MaxTokensCounts", func(t *testing.T) {
+		_, err := router.FitContextWindow(&types.Request{Model: "m", Messages: longPrompt(10), MaxTokens: 2000},
+			[]types.Provider{small, large}, window)
+
+		var exceeded *router.ContextWindowError
+		require.ErrorAs(t, err, &exceeded)
+		assert.Equal(t, 1000, exceeded.ContextLength, "the largest window is reported")
+		assert.Equal(t, 2000, exceeded.CompletionTokens)
+		assert.Positive(t, exceeded.PromptTokens)
+		assert.Contains(t, err.Error(), "maximum context length is 1000 tokens")
+	})
+
+	t.Run("UnknownWindowFits", func(t *testing.T) {
+		fitting, err := router.FitContextWindow(&types.Request{Model: "m", Messages: longPrompt(3000)},
+			[]types.Provider{small, unknown}, window)
+		require.NoError(t, err)
+		require.Len(t, fitting, 1)
+		assert.Equal(t, "unknown", fitting[0].GetName())
+	})
+}
+
+// TestGatewayContextWindow tests that the gateway upgrades or rejects prompts too long for the requested model
+func TestGatewayContextWindow(t *testing.T) {
+	var servedModels []string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		servedModels = append(servedModels, body.Model)
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":%q,
+			"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`, body.Model)
+	}))
+	defer upstream.Close()
+
+	newGateway := func(upgrades []types.ContextUpgrade, largeCapabilities *types.ModelCapabilities) *gateway.Gateway {
+		return gateway.New(&types.Config{
+			Logging:     types.LoggingConfig{Level: "error", Format: "text"},
+			SmartRouter: &types.SmartRouterConfig{FailoverTimeout: 10 * time.Second, ContextUpgrades: upgrades},
+			Providers: map[string]*types.ProviderConfig{
+				"local": {
+					Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+					Models: []string{"small-model", "large-model"},
+					ModelSpecs: []types.ModelSpec{
+						{Name: "small-model", ContextWindow: 64},
+						{Name: "large-model", ContextWindow: 100000, Capabilities: largeCapabilities},
+					},
+				},
+			},
+		})
+	}
+
+	sendBody := func(gw *gateway.Gateway, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+	send := func(gw *gateway.Gateway, words int) *httptest.ResponseRecorder {
+		body, err := json.Marshal(map[string]interface{}{"model": "small-model", "messages": longPrompt(words)})
+		require.NoError(t, err)
+		return sendBody(gw, string(body))
+	}
+
+	t.Run("PromptFits", func(t *testing.T) {
+		servedModels = nil
+		recorder := send(newGateway(nil, nil), 5)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, []string{"small-model"}, servedModels)
+	})
+
+	t.Run("UpgradesToLargerSibling", func(t *testing.T) {
+		servedModels = nil
+		recorder := send(newGateway([]types.ContextUpgrade{{From: "small-model", To: "large-model"}}, nil), 200)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "large-model", recorder.Header().Get("X-Gateway-Model"))
+		assert.Equal(t, []string{"large-model"}, servedModels)
+	})
+
+	t.Run("RejectsWithoutUpgrade", func(t *testing.T) {
+		servedModels = nil
+		recorder := send(newGateway(nil, nil), 200)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Empty(t, servedModels)
+
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, "context_length_exceeded", body.Error.Code)
+		assert.Contains(t, body.Error.Message, "maximum context length is 64 tokens")
+	})
+
+	t.Run("VirtualModelSkipsShortTargets", func(t *testing.T) {
+		newVirtualGateway := func(targets ...types.VirtualModelTarget) *gateway.Gateway {
+			return gateway.New(&types.Config{
+				Logging: types.LoggingConfig{Level: "error", Format: "text"},
+				SmartRouter: &types.SmartRouterConfig{
+					FailoverTimeout: 10 * time.Second,
+					VirtualModels:   map[string]*types.VirtualModelConfig{"auto": {Targets: targets}},
+				},
+				Providers: map[string]*types.ProviderConfig{
+					"local": {
+						Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+						Models: []string{"small-model", "large-model"},
+						ModelSpecs: []types.ModelSpec{
+							{Name: "small-model", ContextWindow: 64},
+							{Name: "large-model", ContextWindow: 100000},
+						},
+					},
+				},
+			})
+		}
+		body, err := json.Marshal(map[string]interface{}{"model": "auto", "messages": longPrompt(200)})
+		require.NoError(t, err)
+
+		servedModels = nil
+		recorder := sendBody(newVirtualGateway(
+			types.VirtualModelTarget{Provider: "local", Model: "small-model"},
+			types.VirtualModelTarget{Provider: "local", Model: "large-model"},
+		), string(body))
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "large-model", recorder.Header().Get("X-Gateway-Model"))
+		assert.Equal(t, []string{"large-model"}, servedModels, "the short target is never sent the prompt")
+
+		servedModels = nil
+		recorder = sendBody(newVirtualGateway(types.VirtualModelTarget{Provider: "local", Model: "small-model"}), string(body))
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), "context_length_exceeded")
+		assert.Empty(t, servedModels)
+	})
+
+	t.Run("UpgradeLackingCapabilityIsSkipped", func(t *testing.T) {
+		servedModels = nil
+		gw := newGateway([]types.ContextUpgrade{{From: "small-model", To: "large-model"}}, &types.ModelCapabilities{Streaming: true})
+		body, err := json.Marshal(map[string]interface{}{
+			"model": "small-model",
+			"messages": []map[string]interface{}{{"role": "user", "content": []map[string]interface{}{
+				{"type": "text", "text": strings.Repeat("context ", 200)},
+				{"type": "image_url", "image_url": map[string]string{"url": "https://example.com/cat.png"}},
+			}}},
+		})
+		require.NoError(t, err)
+
+		recorder := sendBody(gw, string(body))
+		require.Equal(t, http.StatusBadRequest, recorder.Code, "large-model has the room but not vision")
+		assert.Contains(t, recorder.Body.String(), "context_length_exceeded")
+		assert.Empty(t, servedModels)
+	})
+}
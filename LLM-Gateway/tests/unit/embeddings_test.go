@@ -0,0 +1,236 @@
+package unit
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestZhipuEmbed tests the Zhipu embeddings adapter and its cost accounting
+func TestZhipuEmbed(t *testing.T) {
+	var captured map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/embeddings", r.URL.Path)
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&captured))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","model":"embedding-3","data":[
+			{"object":"embedding","index":0,"embedding":[0.1,0.2,0.3]},
+			{"object":"embedding","index":1,"embedding":[0.4,0.5,0.6]}],
+			"usage":{"prompt_tokens":8,"completion_tokens":0,"total_tokens":8}}`)
+	}))
+	defer server.Close()
+
+	provider := providers.NewZhipuProvider(&types.ProviderConfig{
+		Name:    "zhipu",
+		Type:    "zhipu",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	dimensions := 3
+	req := &types.EmbeddingRequest{
+		Model:      "embedding-3",
+		Input:      []interface{}{"first", "second"},
+		Dimensions: &dimensions,
+	}
+
+	resp, err := provider.Embed(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, []interface{}{"first", "second"}, captured["input"])
+	assert.Equal(t, float64(3), captured["dimensions"])
+	assert.NotContains(t, captured, "encoding_format")
+
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, []float64{0.4, 0.5, 0.6}, resp.Data[1].Embedding)
+	assert.Equal(t, 8, resp.Usage.PromptTokens)
+
+	breakdown, err := cost.NewCostCalculator(newTestLogger()).CalculateEmbeddingCost(req, resp)
+	require.NoError(t, err)
+	assert.Equal(t, "zhipu", breakdown.Provider)
+	assert.Equal(t, 8, breakdown.InputTokens)
+	assert.Zero(t, breakdown.OutputTokens)
+}
+
+// TestEmbeddingRequestInputs tests accepted and rejected input shapes
+func TestEmbeddingRequestInputs(t *testing.T) {
+	inputs, err := (&types.EmbeddingRequest{Input: "hello"}).Inputs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hello"}, inputs)
+
+	_, err = (&types.EmbeddingRequest{Input: []interface{}{float64(15339)}}).Inputs()
+	assert.Error(t, err, "token arrays are not supported")
+
+	_, err = (&types.EmbeddingRequest{Input: []interface{}{}}).Inputs()
+	assert.Error(t, err)
+}
+
+// TestEmbeddingsEndpoint tests /v1/embeddings with float and base64 encodings
+func TestEmbeddingsEndpoint(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input      []string `json:"input"`
+			Dimensions int      `json:"dimensions"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		data := make([]map[string]interface{}, len(body.Input))
+		for i := range body.Input {
+			data[i] = map[string]interface{}{"object": "embedding", "index": i, "embedding": make([]float64, body.Dimensions)}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"object": "list", "model": "text-embedding-3-small", "data": data,
+			"usage": map[string]int{"prompt_tokens": 2, "total_tokens": 2},
+		}))
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		Providers: map[string]*types.ProviderConfig{
+			"local": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+				Models: []string{"text-embedding-3-small"},
+			},
+		},
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	type response struct {
+		Object string `json:"object"`
+		Data   []struct {
+			Index     int             `json:"index"`
+			Embedding json.RawMessage `json:"embedding"`
+		} `json:"data"`
+		Usage types.EmbeddingUsage `json:"usage"`
+	}
+
+	t.Run("Float", func(t *testing.T) {
+		recorder := post(`{"model":"text-embedding-3-small","input":["a","b"],"dimensions":8}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var resp response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.Equal(t, "list", resp.Object)
+		require.Len(t, resp.Data, 2)
+		assert.Equal(t, 1, resp.Data[1].Index)
+		assert.Positive(t, resp.Usage.PromptTokens)
+
+		var vector []float64
+		require.NoError(t, json.Unmarshal(resp.Data[0].Embedding, &vector))
+		assert.Len(t, vector, 8)
+	})
+
+	t.Run("Base64", func(t *testing.T) {
+		recorder := post(`{"model":"text-embedding-3-small","input":"a","dimensions":8,"encoding_format":"base64"}`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var resp response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		require.Len(t, resp.Data, 1)
+
+		var encoded string
+		require.NoError(t, json.Unmarshal(resp.Data[0].Embedding, &encoded))
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		require.NoError(t, err)
+		assert.Len(t, raw, 8*4, "vectors are packed as float32")
+	})
+
+	t.Run("UnknownModel", func(t *testing.T) {
+		recorder := post(`{"model":"text-embedding-3-large","input":"a"}`)
+		assert.Equal(t, http.StatusNotFound, recorder.Code, "no provider serves it, so no vectors are made up")
+		assert.Contains(t, recorder.Body.String(), "model_not_found")
+	})
+}
+
+// TestEmbeddingsRouting tests that embeddings fail over like chat and that upstream rejections are returned as-is
+func TestEmbeddingsRouting(t *testing.T) {
+	var brokenCalls int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&brokenCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"message":"overloaded","type":"server_error"}}`)
+	}))
+	defer broken.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Input []string `json:"input"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if len(body.Input) > 2 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"too many inputs","type":"invalid_request_error"}}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"object":"list","model":"text-embedding-3-small",
+			"data":[{"object":"embedding","index":0,"embedding":[0.1,0.2]}],
+			"usage":{"prompt_tokens":1,"total_tokens":1}}`)
+	}))
+	defer healthy.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverEnabled: true,
+			MaxRetries:      1,
+			FailoverTimeout: 10 * time.Second,
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"broken":  {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: broken.URL, RetryCount: 1, Models: []string{"text-embedding-3-small"}},
+			"healthy": {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: healthy.URL, RetryCount: 1, Models: []string{"text-embedding-3-small"}},
+		},
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("Failover", func(t *testing.T) {
+		// Round robin alternates the first pick, so one of two requests starts on the broken upstream
+		for i := 0; i < 2; i++ {
+			recorder := post(`{"model":"text-embedding-3-small","input":"a"}`)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			assert.Equal(t, "healthy", recorder.Header().Get("X-Gateway-Provider"))
+		}
+		assert.Positive(t, atomic.LoadInt32(&brokenCalls))
+	})
+
+	t.Run("ClientError", func(t *testing.T) {
+		// A 503 from the broken upstream fails over, so the request always reaches the healthy one
+		recorder := post(`{"model":"text-embedding-3-small","input":["a","b","c"]}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, "the upstream rejection is not turned into a 500")
+		assert.Contains(t, recorder.Body.String(), "too many inputs")
+		assert.Contains(t, recorder.Header().Get("X-Gateway-Tried-Providers"), "healthy")
+	})
+}
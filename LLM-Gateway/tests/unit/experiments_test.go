@@ -0,0 +1,286 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/internal/storage"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newExperimentRouter returns a router running the experiment
+func newExperimentRouter(t *testing.T, experiment types.ExperimentConfig) *router.SmartRouter {
+	config := router.DefaultSmartRouterConfig()
+	config.Experiments = []types.ExperimentConfig{experiment}
+
+	smartRouter, err := router.NewSmartRouter(config, newTestLogger())
+	require.NoError(t, err)
+	return smartRouter
+}
+
+// requestRows is an in-memory request log
+type requestRows struct {
+	mutex sync.Mutex
+	rows  []storage.Request
+}
+
+func (r *requestRows) Create(request *storage.Request) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.rows = append(r.rows, *request)
+	return nil
+}
+
+// snapshot returns the rows logged so far
+func (r *requestRows) snapshot() []storage.Request {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]storage.Request(nil), r.rows...)
+}
+
+// TestAssignExperiment tests sticky, weighted and scheduled assignment of requests to variants
+func TestAssignExperiment(t *testing.T) {
+	rollout := types.ExperimentConfig{
+		Name:  "rollout",
+		Model: "chat",
+		Variants: []types.ExperimentVariant{
+			{Name: "control", Model: "model-a", Weight: 90},
+			{Name: "treatment", Model: "model-b", Weight: 10},
+		},
+	}
+	now := time.Now()
+
+	t.Run("StickyPerUser", func(t *testing.T) {
+		smartRouter := newExperimentRouter(t, rollout)
+		first, ok := smartRouter.AssignExperiment("chat", router.ExperimentIdentity{UserID: "user-7", RequestID: "req-1"}, now)
+		require.True(t, ok)
+		for i := 0; i < 20; i++ {
+			assignment, ok := smartRouter.AssignExperiment("chat", router.ExperimentIdentity{UserID: "user-7", RequestID: fmt.Sprintf("req-%d", i)}, now)
+			require.True(t, ok)
+			assert.Equal(t, first, assignment)
+		}
+	})
+
+	t.Run("WeightedSplit", func(t *testing.T) {
+		smartRouter := newExperimentRouter(t, rollout)
+		counts := make(map[string]int)
+		for i := 0; i < 2000; i++ {
+			assignment, ok := smartRouter.AssignExperiment("chat", router.ExperimentIdentity{UserID: fmt.Sprintf("user-%d", i)}, now)
+			require.True(t, ok)
+			counts[assignment.Variant]++
+		}
+		assert.InDelta(t, 200, counts["treatment"], 60, "about 10%% of users get the treatment: %v", counts)
+	})
+
+	t.Run("StickyByAPIKey", func(t *testing.T) {
+		byKey := rollout
+		byKey.StickyBy = types.ExperimentStickyAPIKey
+		smartRouter := newExperimentRouter(t, byKey)
+
+		// Users sharing a key share a variant
+		variants := make(map[string]bool)
+		for i := 0; i < 20; i++ {
+			assignment, ok := smartRouter.AssignExperiment("chat", router.ExperimentIdentity{UserID: fmt.Sprintf("user-%d", i), APIKey: "key-1"}, now)
+			require.True(t, ok)
+			variants[assignment.Variant] = true
+		}
+		assert.Len(t, variants, 1)
+	})
+
+	t.Run("OtherModelsUnaffected", func(t *testing.T) {
+		smartRouter := newExperimentRouter(t, rollout)
+		_, ok := smartRouter.AssignExperiment("model-a", router.ExperimentIdentity{UserID: "user-7"}, now)
+		assert.False(t, ok)
+	})
+
+	t.Run("Schedule", func(t *testing.T) {
+		scheduled := rollout
+		scheduled.Start = "2025-01-01T00:00:00Z"
+		scheduled.End = "2025-02-01T00:00:00Z"
+		smartRouter := newExperimentRouter(t, scheduled)
+
+		identity := router.ExperimentIdentity{UserID: "user-7"}
+		_, ok := smartRouter.AssignExperiment("chat", identity, time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC))
+		assert.False(t, ok, "not started")
+		_, ok = smartRouter.AssignExperiment("chat", identity, time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC))
+		assert.True(t, ok)
+		_, ok = smartRouter.AssignExperiment("chat", identity, time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC))
+		assert.False(t, ok, "ended")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string]types.ExperimentConfig{
+			"NoVariants":   {Name: "x", Model: "chat"},
+			"ZeroWeight":   {Name: "x", Model: "chat", Variants: []types.ExperimentVariant{{Name: "a", Model: "m", Weight: 0}}},
+			"BadStart":     {Name: "x", Model: "chat", Start: "tomorrow", Variants: rollout.Variants},
+			"EndsFirst":    {Name: "x", Model: "chat", Start: "2025-02-01T00:00:00Z", End: "2025-01-01T00:00:00Z", Variants: rollout.Variants},
+			"BadStickyBy":  {Name: "x", Model: "chat", StickyBy: "ip", Variants: rollout.Variants},
+			"DupVariant":   {Name: "x", Model: "chat", Variants: []types.ExperimentVariant{{Name: "a", Model: "m", Weight: 1}, {Name: "a", Model: "n", Weight: 1}}},
+			"MissingModel": {Name: "x", Variants: rollout.Variants},
+		}
+		for name, experiment := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.Experiments = []types.ExperimentConfig{experiment}
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+	})
+}
+
+// TestGatewayExperiments tests that the gateway serves each caller's variant and reports per-variant statistics
+func TestGatewayExperiments(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model string `json:"model"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Model == "broken-model" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"bad request","type":"invalid_request_error"}}`)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":%q,
+			"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":1000,"completion_tokens":500,"total_tokens":1500}}`, body.Model)
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverTimeout: 10 * time.Second,
+			Experiments: []types.ExperimentConfig{{
+				Name:  "rollout",
+				Model: "chat-model",
+				Variants: []types.ExperimentVariant{
+					{Name: "control", Model: "chat-model", Weight: 1},
+					{Name: "treatment", Model: "new-model", Weight: 1},
+				},
+			}},
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"local": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+				Models: []string{"chat-model", "new-model", "broken-model"},
+			},
+		},
+	})
+
+	rows := &requestRows{}
+	gw.UseRequestLog(rows)
+
+	send := func(user string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"model":"chat-model","user_id":%q,"messages":[{"role":"user","content":"hi"}]}`, user)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	served := map[string]int{}
+	for i := 0; i < 40; i++ {
+		user := fmt.Sprintf("user-%d", i)
+		recorder := send(user)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "rollout", recorder.Header().Get("X-Gateway-Experiment"))
+
+		variant := recorder.Header().Get("X-Gateway-Variant")
+		var response types.Response
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		if variant == "treatment" {
+			assert.Equal(t, "new-model", response.Model)
+		} else {
+			assert.Equal(t, "control", variant)
+			assert.Equal(t, "chat-model", response.Model)
+		}
+		served[variant]++
+
+		// Each user keeps its variant
+		assert.Equal(t, variant, send(user).Header().Get("X-Gateway-Variant"))
+		served[variant]++
+	}
+	require.Len(t, served, 2, "both variants get traffic")
+
+	recorder := httptest.NewRecorder()
+	gw.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/admin/experiments", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var report struct {
+		Experiments []router.ExperimentReport `json:"experiments"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	require.Len(t, report.Experiments, 1)
+	assert.True(t, report.Experiments[0].Active)
+	for _, variant := range report.Experiments[0].Variants {
+		assert.Equal(t, int64(served[variant.Name]), variant.Requests, variant.Name)
+		assert.Zero(t, variant.Errors)
+		assert.Equal(t, int64(served[variant.Name])*1500, variant.TotalTokens)
+		assert.Positive(t, variant.TotalCost)
+	}
+
+	// Every request is logged with the variant that served it
+	require.Eventually(t, func() bool { return len(rows.snapshot()) == 80 }, 5*time.Second, 10*time.Millisecond)
+	logged := map[string]int{}
+	for _, row := range rows.snapshot() {
+		assert.Equal(t, "rollout", row.Experiment)
+		assert.Equal(t, http.StatusOK, row.StatusCode)
+		assert.Equal(t, 1500, row.TotalTokens)
+		assert.Positive(t, row.Cost)
+		if row.Variant == "treatment" {
+			assert.Equal(t, "new-model", row.ModelName)
+		} else {
+			assert.Equal(t, "chat-model", row.ModelName)
+		}
+		logged[row.Variant]++
+	}
+	assert.Equal(t, served, logged)
+
+	t.Run("FailuresCountAsErrors", func(t *testing.T) {
+		failing := gateway.New(&types.Config{
+			Logging: types.LoggingConfig{Level: "error", Format: "text"},
+			SmartRouter: &types.SmartRouterConfig{
+				FailoverTimeout: 10 * time.Second,
+				Experiments: []types.ExperimentConfig{{
+					Name: "broken", Model: "chat-model",
+					Variants: []types.ExperimentVariant{{Name: "broken", Model: "broken-model", Weight: 1}},
+				}},
+			},
+			Providers: map[string]*types.ProviderConfig{
+				"local": {
+					Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+					Models: []string{"chat-model", "broken-model"},
+				},
+			},
+		})
+
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"chat-model","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		failing.Handler().ServeHTTP(recorder, req)
+		assert.NotEqual(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "broken", recorder.Header().Get("X-Gateway-Variant"))
+
+		recorder = httptest.NewRecorder()
+		failing.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/admin/experiments", nil))
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+		variant := report.Experiments[0].Variants[0]
+		assert.Equal(t, int64(1), variant.Errors)
+		assert.Equal(t, 1.0, variant.ErrorRate)
+	})
+}
@@ -0,0 +1,69 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestProviderFactory tests building providers from the providers config section
+func TestProviderFactory(t *testing.T) {
+	logger := newTestLogger()
+
+	t.Run("BuiltinTypes", func(t *testing.T) {
+		factory := providers.NewProviderFactory(logger)
+		assert.Equal(t, []string{"anthropic", "azure_openai", "baidu", "gemini", "openai", "openai_compatible", "zhipu"}, factory.Types())
+
+		_, err := factory.Create(&types.ProviderConfig{Name: "x", Type: "unknown"})
+		assert.Error(t, err)
+	})
+
+	t.Run("BuildProviders", func(t *testing.T) {
+		t.Setenv("FACTORY_TEST_KEY", "")
+
+		registry := providers.NewDefaultRegistry(logger)
+		built, err := providers.NewProviderFactory(logger).BuildProviders(registry, map[string]*types.ProviderConfig{
+			"openai":    {Enabled: true, APIKey: "${FACTORY_TEST_KEY:-sk-default}"},
+			"anthropic": {Enabled: true, Type: "anthropic"},
+			"baidu":     {Enabled: false},
+			"local":     {Enabled: true, Type: providers.OpenAICompatibleType, BaseURL: "http://localhost:11434/v1"},
+			"broken":    {Enabled: true, Type: "no-such-type"},
+		})
+		require.Error(t, err, "unknown types are reported")
+		assert.Contains(t, err.Error(), "no-such-type")
+
+		names := make([]string, 0, len(built))
+		for _, p := range built {
+			names = append(names, p.GetName())
+		}
+		assert.Equal(t, []string{"anthropic", "local", "openai"}, names, "built in key order, disabled entries skipped")
+
+		openai, err := registry.GetProvider("openai")
+		require.NoError(t, err)
+		assert.Equal(t, "openai", openai.GetType(), "the key doubles as the type")
+		assert.Equal(t, "sk-default", openai.GetConfig().APIKey, "${VAR:-default} is expanded")
+
+		_, err = registry.GetProvider("baidu")
+		assert.Error(t, err)
+	})
+
+	t.Run("CustomConstructor", func(t *testing.T) {
+		factory := providers.NewProviderFactory(logger)
+		factory.Register("mock", func(config *types.ProviderConfig, logger *utils.Logger) (types.Provider, error) {
+			return router.NewMockProvider(config, logger), nil
+		})
+
+		registry := providers.NewDefaultRegistry(logger)
+		built, err := factory.BuildProviders(registry, map[string]*types.ProviderConfig{
+			"fake": {Enabled: true, Type: "mock", Models: []string{"fake-model"}},
+		})
+		require.NoError(t, err)
+		require.Len(t, built, 1)
+		assert.Equal(t, "fake", built[0].GetName())
+	})
+}
@@ -0,0 +1,154 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFailoverRouter creates a Smart Router with failover enabled over the named mock providers
+func newFailoverRouter(t *testing.T, maxRetries int, timeout time.Duration, names ...string) (*router.SmartRouter, []types.Provider) {
+	config := router.DefaultSmartRouterConfig()
+	config.FailoverEnabled = true
+	config.MaxRetries = maxRetries
+	config.FailoverTimeout = timeout
+	return newTestSmartRouter(t, config, names...)
+}
+
+// TestExecuteWithFailover tests re-dispatching a failed request to the next provider
+func TestExecuteWithFailover(t *testing.T) {
+	t.Run("RetryableErrorFailsOver", func(t *testing.T) {
+		smartRouter, candidates := newFailoverRouter(t, 3, time.Minute, "a", "b", "c")
+
+		var called []string
+		result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				called = append(called, p.GetName())
+				if len(called) < 3 {
+					return nil, retry.NewProviderRetryError(p.GetName(), "chat", types.ErrorServer, "upstream 503", true)
+				}
+				return nil, nil
+			})
+		require.NoError(t, err)
+
+		assert.Len(t, called, 3)
+		assert.ElementsMatch(t, []string{"a", "b", "c"}, called, "a failed provider is never retried")
+		assert.Equal(t, called[2], result.ProviderName)
+		assert.Equal(t, 3, result.Attempts)
+		assert.True(t, result.BackupUsed)
+		require.Len(t, result.Tried, 3)
+		assert.Equal(t, types.ErrorServer, result.Tried[0].Category)
+		assert.Empty(t, result.Tried[2].Error)
+	})
+
+	t.Run("NonRetryableErrorStops", func(t *testing.T) {
+		smartRouter, candidates := newFailoverRouter(t, 3, time.Minute, "a", "b")
+
+		attempts := 0
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				attempts++
+				return nil, errors.New("status code: 400 bad request")
+			})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts)
+
+		var failoverErr *router.FailoverError
+		require.ErrorAs(t, err, &failoverErr)
+		require.Len(t, failoverErr.Attempts, 1)
+		assert.False(t, failoverErr.Attempts[0].Retryable)
+	})
+
+	t.Run("MaxRetriesCapsAttempts", func(t *testing.T) {
+		smartRouter, candidates := newFailoverRouter(t, 1, time.Minute, "a", "b", "c")
+
+		attempts := 0
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				attempts++
+				return nil, fmt.Errorf("status code: 502 bad gateway")
+			})
+		require.Error(t, err)
+		assert.Equal(t, 2, attempts)
+	})
+
+	t.Run("TotalDeadline", func(t *testing.T) {
+		smartRouter, candidates := newFailoverRouter(t, 3, 50*time.Millisecond, "a", "b", "c")
+
+		attempts := 0
+		start := time.Now()
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				attempts++
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts, "no attempt starts after the deadline")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
+
+// TestGatewayFailover tests that the gateway re-sends a request to another upstream serving the model
+func TestGatewayFailover(t *testing.T) {
+	var brokenCalls int32
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&brokenCalls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprint(w, `{"error":{"message":"overloaded","type":"server_error"}}`)
+	}))
+	defer broken.Close()
+
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"shared-model",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`)
+	}))
+	defer healthy.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverEnabled: true,
+			MaxRetries:      1,
+			FailoverTimeout: 10 * time.Second,
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"broken":  {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: broken.URL, RetryCount: 1, Models: []string{"shared-model"}},
+			"healthy": {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: healthy.URL, RetryCount: 1, Models: []string{"shared-model"}},
+		},
+	})
+
+	// Round robin alternates the first pick, so one of two requests starts on the broken upstream
+	for i := 0; i < 2; i++ {
+		body := `{"model":"shared-model","messages":[{"role":"user","content":"ping"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "healthy", recorder.Header().Get("X-Gateway-Provider"))
+		if recorder.Header().Get("X-Gateway-Attempts") == "2" {
+			assert.Equal(t, "broken,healthy", recorder.Header().Get("X-Gateway-Tried-Providers"))
+		} else {
+			assert.Equal(t, "healthy", recorder.Header().Get("X-Gateway-Tried-Providers"))
+		}
+	}
+	assert.Positive(t, atomic.LoadInt32(&brokenCalls))
+}
@@ -0,0 +1,204 @@
+package unit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestChatCompletionsStream tests OpenAI-compatible SSE on /v1/chat/completions
+func TestChatCompletionsStream(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"role":"assistant"}}]}`,
+			`{"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"content":"Hello"}}]}`,
+			`{"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"content":" there"}}]}`,
+			`{"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+			`{"id":"c1","model":"gpt-4","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		Providers: map[string]*types.ProviderConfig{
+			"upstream": {
+				Type:    providers.OpenAICompatibleType,
+				Enabled: true,
+				BaseURL: upstream.URL,
+				Models:  []string{"gpt-4"},
+			},
+		},
+	})
+
+	body := `{"model":"gpt-4","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hello"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	gw.Handler().ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/event-stream")
+
+	var events []string
+	scanner := bufio.NewScanner(recorder.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "data: ") {
+			events = append(events, strings.TrimPrefix(line, "data: "))
+		}
+	}
+	require.GreaterOrEqual(t, len(events), 4)
+	assert.Equal(t, "[DONE]", events[len(events)-1])
+
+	type chunk struct {
+		Object  string `json:"object"`
+		Choices []struct {
+			Delta struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"delta"`
+			FinishReason *string `json:"finish_reason"`
+		} `json:"choices"`
+		Usage *types.Usage `json:"usage"`
+	}
+
+	var first chunk
+	require.NoError(t, json.Unmarshal([]byte(events[0]), &first))
+	assert.Equal(t, "chat.completion.chunk", first.Object)
+	require.Len(t, first.Choices, 1)
+	assert.Equal(t, "assistant", first.Choices[0].Delta.Role)
+
+	var finish chunk
+	require.NoError(t, json.Unmarshal([]byte(events[len(events)-3]), &finish))
+	require.Len(t, finish.Choices, 1)
+	require.NotNil(t, finish.Choices[0].FinishReason)
+	assert.Equal(t, "stop", *finish.Choices[0].FinishReason)
+
+	var usage chunk
+	require.NoError(t, json.Unmarshal([]byte(events[len(events)-2]), &usage))
+	assert.Empty(t, usage.Choices)
+	require.NotNil(t, usage.Usage)
+	assert.Equal(t, 5, usage.Usage.TotalTokens)
+}
+
+// TestChatCompletionsStreamTimeout tests that a stream is cut off at the serving provider's timeout
+func TestChatCompletionsStreamTimeout(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data: {"id":"c1","model":"gpt-4","choices":[{"index":0,"delta":{"role":"assistant"}}]}`+"\n\n")
+		w.(http.Flusher).Flush()
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		Providers: map[string]*types.ProviderConfig{
+			"upstream": {
+				Type:    providers.OpenAICompatibleType,
+				Enabled: true,
+				BaseURL: upstream.URL,
+				Timeout: 200 * time.Millisecond,
+				Models:  []string{"gpt-4"},
+			},
+		},
+	})
+
+	body := `{"model":"gpt-4","stream":true,"messages":[{"role":"user","content":"hello"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+
+	start := time.Now()
+	gw.Handler().ServeHTTP(recorder, req)
+
+	assert.Less(t, time.Since(start), 2*time.Second)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"role":"assistant"`)
+	assert.NotContains(t, recorder.Body.String(), `"finish_reason":"stop"`, "a stream cut off at its deadline never finishes")
+}
+
+// TestZhipuLegacyStream tests that the legacy ZhipuAI event stream is dispatched through the smart router
+func TestZhipuLegacyStream(t *testing.T) {
+	var hits int64
+	hold := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt64(&hits, 1) == 2 {
+			<-hold
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data:{"id":"1","model":"glm-4.5","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`+"\n\n")
+		fmt.Fprint(w, `data:{"id":"1","model":"glm-4.5","choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`+"\n\n")
+		fmt.Fprint(w, "data:[DONE]\n\n")
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverTimeout: 10 * time.Second,
+			Concurrency: &types.ConcurrencyConfig{
+				Limits:       []types.ConcurrencyLimit{{Provider: "zhipu", MaxInFlight: 1}},
+				QueueTimeout: 50 * time.Millisecond,
+			},
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"zhipu": {
+				Type: "zhipu", Enabled: true, BaseURL: upstream.URL, APIKey: "test-key",
+				Models: []string{"glm-4.5"},
+			},
+		},
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		body := `{"model":"glm-4.5","stream":true,"messages":[{"role":"user","content":"hello"}]}`
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/stream", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := send()
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "zhipu", recorder.Header().Get("X-Gateway-Provider"))
+	assert.Contains(t, recorder.Body.String(), "event: message\n")
+	assert.Contains(t, recorder.Body.String(), "你好")
+	assert.Contains(t, recorder.Body.String(), `"done":true`)
+	assert.True(t, strings.HasSuffix(recorder.Body.String(), "data: [DONE]\n\n"))
+
+	// A stream in flight holds the provider's only slot, so the next one is refused by admission
+	held := make(chan *httptest.ResponseRecorder)
+	go func() { held <- send() }()
+	require.Eventually(t, func() bool { return atomic.LoadInt64(&hits) == 2 }, 5*time.Second, 5*time.Millisecond)
+
+	recorder = send()
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "provider_saturated")
+	assert.Equal(t, int64(2), atomic.LoadInt64(&hits))
+
+	close(hold)
+	assert.Equal(t, http.StatusOK, (<-held).Code)
+}
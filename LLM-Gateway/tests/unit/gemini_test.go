@@ -0,0 +1,229 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/retry"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGeminiProvider(t *testing.T, baseURL string) *providers.GeminiProvider {
+	provider, err := providers.NewGeminiProvider(&types.ProviderConfig{
+		Name:    "gemini",
+		Type:    "gemini",
+		BaseURL: baseURL,
+		APIKey:  "test-key",
+		CustomConfig: map[string]string{
+			providers.GeminiSafetyThreshold: "BLOCK_ONLY_HIGH",
+			providers.GeminiSafetySettings:  "HARM_CATEGORY_HARASSMENT=BLOCK_NONE",
+		},
+	}, newTestLogger())
+	require.NoError(t, err)
+	return provider
+}
+
+// TestGeminiChatCompletion tests request mapping (roles, system instruction, tools, safety) and response mapping
+func TestGeminiChatCompletion(t *testing.T) {
+	var captured map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-1.5-flash:generateContent", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-goog-api-key"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&captured))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"candidates":[{"index":0,"finishReason":"STOP","content":{"role":"model","parts":[
+			{"text":"Checking the weather."},
+			{"functionCall":{"name":"get_weather","args":{"city":"Paris"}}}]}}],
+			"usageMetadata":{"promptTokenCount":20,"candidatesTokenCount":8,"thoughtsTokenCount":4,"totalTokenCount":32},
+			"modelVersion":"gemini-1.5-flash-002","responseId":"resp-1"}`)
+	}))
+	defer server.Close()
+
+	temperature := 0.2
+	maxTokens := 256
+	resp, err := newTestGeminiProvider(t, server.URL).Call(context.Background(), &types.ChatCompletionRequest{
+		Model:       "gemini-1.5-flash",
+		Temperature: &temperature,
+		MaxTokens:   &maxTokens,
+		Stop:        "END",
+		Tools:       []types.Tool{weatherTool()},
+		ToolChoice:  "required",
+		Messages: []types.Message{
+			{Role: "system", Content: "Be brief."},
+			{Role: "user", Content: "Weather in Paris and Rome?"},
+			{Role: "assistant", ToolCalls: []types.ToolCall{
+				{ID: "call_1", Type: "function", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"Paris"}`}},
+				{ID: "call_2", Type: "function", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+			}},
+			{Role: "tool", ToolCallID: "call_1", Content: `{"temp":18}`},
+			{Role: "tool", ToolCallID: "call_2", Content: "sunny"},
+		},
+	})
+	require.NoError(t, err)
+
+	// Request mapping
+	system := captured["systemInstruction"].(map[string]interface{})
+	assert.Equal(t, "Be brief.", system["parts"].([]interface{})[0].(map[string]interface{})["text"])
+
+	contents := captured["contents"].([]interface{})
+	require.Len(t, contents, 3, "parallel tool results are merged into one user turn")
+	assert.Equal(t, "model", contents[1].(map[string]interface{})["role"])
+
+	results := contents[2].(map[string]interface{})["parts"].([]interface{})
+	require.Len(t, results, 2)
+	first := results[0].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	second := results[1].(map[string]interface{})["functionResponse"].(map[string]interface{})
+	assert.Equal(t, "get_weather", first["name"], "tool_call_id is resolved to the function name")
+	assert.Equal(t, map[string]interface{}{"temp": float64(18)}, first["response"])
+	assert.Equal(t, map[string]interface{}{"content": "sunny"}, second["response"])
+
+	generationConfig := captured["generationConfig"].(map[string]interface{})
+	assert.Equal(t, 0.2, generationConfig["temperature"])
+	assert.Equal(t, float64(256), generationConfig["maxOutputTokens"])
+	assert.Equal(t, []interface{}{"END"}, generationConfig["stopSequences"])
+
+	toolConfig := captured["toolConfig"].(map[string]interface{})["functionCallingConfig"].(map[string]interface{})
+	assert.Equal(t, "ANY", toolConfig["mode"])
+
+	safety := captured["safetySettings"].([]interface{})
+	require.Len(t, safety, 4)
+	thresholds := make(map[string]string)
+	for _, setting := range safety {
+		s := setting.(map[string]interface{})
+		thresholds[s["category"].(string)] = s["threshold"].(string)
+	}
+	assert.Equal(t, "BLOCK_NONE", thresholds["HARM_CATEGORY_HARASSMENT"])
+	assert.Equal(t, "BLOCK_ONLY_HIGH", thresholds["HARM_CATEGORY_DANGEROUS_CONTENT"])
+
+	// Response mapping
+	assert.Equal(t, "resp-1", resp.ID)
+	assert.Equal(t, "gemini-1.5-flash-002", resp.Model)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "Checking the weather.", resp.Choices[0].Message.Content)
+	assert.Equal(t, "tool_calls", *resp.Choices[0].FinishReason)
+	require.Len(t, resp.Choices[0].Message.ToolCalls, 1)
+	assert.Equal(t, "get_weather", resp.Choices[0].Message.ToolCalls[0].Function.Name)
+	assert.JSONEq(t, `{"city":"Paris"}`, resp.Choices[0].Message.ToolCalls[0].Function.Arguments)
+
+	assert.Equal(t, 20, resp.Usage.PromptTokens)
+	assert.Equal(t, 12, resp.Usage.CompletionTokens, "thinking tokens are billed as output")
+	assert.Equal(t, 32, resp.Usage.TotalTokens)
+}
+
+// TestGeminiSafetyBlock tests finish reason mapping for blocked prompts and candidates
+func TestGeminiSafetyBlock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"promptFeedback":{"blockReason":"SAFETY"},"usageMetadata":{"promptTokenCount":5,"totalTokenCount":5}}`)
+	}))
+	defer server.Close()
+
+	resp, err := newTestGeminiProvider(t, server.URL).Call(context.Background(), &types.ChatCompletionRequest{
+		Model:    "gemini-2.0-flash",
+		Messages: []types.Message{{Role: "user", Content: "..."}},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Choices, 1)
+	assert.Equal(t, "content_filter", *resp.Choices[0].FinishReason)
+	assert.Equal(t, 5, resp.Usage.PromptTokens)
+}
+
+// TestGeminiErrorClassification tests google.rpc status mapping and RetryInfo handling
+func TestGeminiErrorClassification(t *testing.T) {
+	t.Run("InvalidArgument", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`)
+		}))
+		defer server.Close()
+
+		_, err := newTestGeminiProvider(t, server.URL).Call(context.Background(), &types.ChatCompletionRequest{
+			Model:    "gemini-1.5-pro",
+			Messages: []types.Message{{Role: "user", Content: "Hi"}},
+		})
+		require.Error(t, err)
+
+		var retryErr *retry.ProviderRetryError
+		require.True(t, errors.As(err, &retryErr))
+		assert.Equal(t, types.ErrorClient, retryErr.Category)
+		assert.False(t, retryErr.Retryable)
+		assert.Equal(t, "gemini", retryErr.Provider)
+		assert.Equal(t, 1, requests, "client errors are not retried")
+	})
+
+	t.Run("ResourceExhausted", func(t *testing.T) {
+		retryErr := retry.ClassifyGeminiError(429, "RESOURCE_EXHAUSTED", "Quota exceeded")
+		assert.Equal(t, types.ErrorRateLimit, retryErr.Category)
+		assert.True(t, retryErr.Retryable)
+
+		retryErr = retry.ClassifyGeminiError(400, "FAILED_PRECONDITION", "User location is not supported")
+		assert.Equal(t, types.ErrorQuota, retryErr.Category)
+		assert.False(t, retryErr.Retryable)
+	})
+}
+
+// TestGeminiCallStream tests translation of Gemini SSE responses into typed chunks
+func TestGeminiCallStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models/gemini-2.0-flash:streamGenerateContent", r.URL.Path)
+		assert.Equal(t, "sse", r.URL.Query().Get("alt"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":"Hello"}]}}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":1,"totalTokenCount":5},"responseId":"r1"}`,
+			`{"candidates":[{"index":0,"content":{"role":"model","parts":[{"text":" world"}]},"finishReason":"MAX_TOKENS"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2,"totalTokenCount":6},"responseId":"r1"}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	chunks, err := newTestGeminiProvider(t, server.URL).CallStream(context.Background(), &types.ChatCompletionRequest{
+		Model:    "gemini-2.0-flash",
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	collected := collectChunks(t, chunks)
+	require.Len(t, collected, 5)
+
+	assert.Equal(t, types.ChunkRole, collected[0].Type)
+	assert.Equal(t, "r1", collected[0].ID)
+	assert.Equal(t, "Hello", collected[1].Content)
+	assert.Equal(t, " world", collected[2].Content)
+	assert.Equal(t, types.ChunkFinish, collected[3].Type)
+	assert.Equal(t, "length", *collected[3].FinishReason)
+	assert.Equal(t, types.ChunkUsage, collected[4].Type)
+	assert.Equal(t, 6, collected[4].Usage.TotalTokens)
+}
+
+// TestGeminiPricing tests PricingManager entries and model detection for Gemini
+func TestGeminiPricing(t *testing.T) {
+	pm := cost.NewPricingManager()
+
+	pricing, err := pm.GetPricing("gemini", "gemini-1.5-flash-002")
+	require.NoError(t, err)
+	assert.Equal(t, "gemini-1.5-flash", pricing.Model)
+	assert.InDelta(t, 0.000075, pricing.InputPrice, 1e-12)
+
+	breakdown, err := cost.NewCostCalculator(newTestLogger()).CalculateActualCost(
+		&types.ChatCompletionRequest{Model: "gemini-2.5-pro"},
+		&types.ChatCompletionResponse{Model: "gemini-2.5-pro", Usage: types.Usage{PromptTokens: 1000, CompletionTokens: 1000}},
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "gemini", breakdown.Provider)
+	assert.InDelta(t, 0.01125, breakdown.TotalCost, 0.0001)
+}
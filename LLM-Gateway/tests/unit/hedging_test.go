@@ -0,0 +1,198 @@
+package unit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newHedgingRouter creates a Smart Router hedging model "m" over two mock providers
+func newHedgingRouter(t *testing.T, hedging *types.HedgingConfig) (*router.SmartRouter, []types.Provider) {
+	config := router.DefaultSmartRouterConfig()
+	config.Hedging = map[string]*types.HedgingConfig{"m": hedging}
+	return newTestSmartRouter(t, config, "a", "b")
+}
+
+// slowFirstCall answers the first call of every request after delay and its hedge at once
+func slowFirstCall(delay time.Duration, cancelled *int32) router.ProviderCall {
+	var seen sync.Map
+	return func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		if _, hedge := seen.LoadOrStore(req, true); !hedge {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				atomic.AddInt32(cancelled, 1)
+				return nil, ctx.Err()
+			}
+		}
+		return p.GetName(), nil
+	}
+}
+
+// TestHedgedRequests tests racing a second provider against a slow first one
+func TestHedgedRequests(t *testing.T) {
+	t.Run("HedgeWinsAndCancelsPrimary", func(t *testing.T) {
+		smartRouter, candidates := newHedgingRouter(t, &types.HedgingConfig{Delay: 10 * time.Millisecond, BudgetRatio: 1})
+
+		var cancelled int32
+		result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+			slowFirstCall(time.Second, &cancelled))
+		require.NoError(t, err)
+
+		require.Len(t, result.Tried, 2)
+		assert.False(t, result.Tried[0].Hedged)
+		assert.True(t, result.Tried[1].Hedged)
+		assert.Equal(t, result.Tried[1].ProviderName, result.ProviderName, "the hedge answered first")
+		assert.Equal(t, result.ProviderName, result.Response)
+		assert.Eventually(t, func() bool { return atomic.LoadInt32(&cancelled) == 1 },
+			time.Second, 5*time.Millisecond, "the losing attempt is cancelled")
+	})
+
+	t.Run("BudgetCapsHedges", func(t *testing.T) {
+		smartRouter, candidates := newHedgingRouter(t, &types.HedgingConfig{Delay: 5 * time.Millisecond, BudgetRatio: 0.5})
+
+		var cancelled int32
+		call := slowFirstCall(30*time.Millisecond, &cancelled)
+		hedged := 0
+		for i := 0; i < 6; i++ {
+			result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates, call)
+			require.NoError(t, err)
+			if len(result.Tried) == 2 {
+				hedged++
+			}
+		}
+		assert.Equal(t, 3, hedged, "half of the requests may be hedged")
+	})
+
+	t.Run("UnhedgedModel", func(t *testing.T) {
+		smartRouter, candidates := newHedgingRouter(t, &types.HedgingConfig{Delay: time.Millisecond, BudgetRatio: 1})
+
+		var cancelled int32
+		result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "other"}, candidates,
+			slowFirstCall(20*time.Millisecond, &cancelled))
+		require.NoError(t, err)
+		assert.Len(t, result.Tried, 1)
+	})
+
+	t.Run("ObservedP95ReplacesDelay", func(t *testing.T) {
+		smartRouter, candidates := newHedgingRouter(t, &types.HedgingConfig{Delay: time.Hour, MinSamples: 5, BudgetRatio: 1})
+
+		// Fast answers build up latency samples for both providers
+		for i := 0; i < 20; i++ {
+			_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+				func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+					return p.GetName(), nil
+				})
+			require.NoError(t, err)
+		}
+		var samples int64
+		for _, name := range []string{"a", "b"} {
+			stats, ok := smartRouter.GetLatencyStats(name)
+			require.True(t, ok)
+			require.GreaterOrEqual(t, stats.Count, int64(5))
+			samples += stats.Count
+		}
+		assert.Equal(t, int64(20), samples)
+
+		var cancelled int32
+		result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+			slowFirstCall(time.Second, &cancelled))
+		require.NoError(t, err)
+		assert.Len(t, result.Tried, 2, "the hedge fires at the observed p95, not the one hour delay")
+	})
+
+	t.Run("InvalidBudget", func(t *testing.T) {
+		config := router.DefaultSmartRouterConfig()
+		config.Hedging = map[string]*types.HedgingConfig{"m": {Delay: time.Second, BudgetRatio: 1.5}}
+		assert.Error(t, config.ValidateConfig(), "a hedge budget above 1 could more than double spend")
+	})
+}
+
+// TestLoadTrackingDrains tests that load-aware strategies count only sent calls, and every one of them finishes
+func TestLoadTrackingDrains(t *testing.T) {
+	for _, strategy := range []string{"peak_ewma", "prefix_affinity"} {
+		t.Run(strategy, func(t *testing.T) {
+			// newLoadRouter creates a router using the strategy over providers "a" and "b"
+			newLoadRouter := func(t *testing.T, hedging *types.HedgingConfig, concurrency *types.ConcurrencyConfig) (*router.SmartRouter, []types.Provider, func(string) int64) {
+				config := router.DefaultSmartRouterConfig()
+				config.Strategy = strategy
+				config.Hedging = map[string]*types.HedgingConfig{"m": hedging}
+				config.Concurrency = concurrency
+				smartRouter, candidates := newTestSmartRouter(t, config, "a", "b")
+				tracker, ok := smartRouter.GetStrategy().(interface{ GetPending(string) int64 })
+				require.True(t, ok)
+				return smartRouter, candidates, tracker.GetPending
+			}
+			drained := func(t *testing.T, pending func(string) int64) {
+				assert.Eventually(t, func() bool { return pending("a") == 0 && pending("b") == 0 },
+					time.Second, 5*time.Millisecond, "every call in flight finished")
+			}
+
+			t.Run("HedgedRace", func(t *testing.T) {
+				smartRouter, candidates, pending := newLoadRouter(t, &types.HedgingConfig{Delay: 10 * time.Millisecond, BudgetRatio: 1}, nil)
+
+				var cancelled int32
+				result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+					slowFirstCall(time.Second, &cancelled))
+				require.NoError(t, err)
+				require.Len(t, result.Tried, 2)
+				drained(t, pending)
+			})
+
+			t.Run("BudgetDeniedHedge", func(t *testing.T) {
+				smartRouter, candidates, pending := newLoadRouter(t, &types.HedgingConfig{Delay: 5 * time.Millisecond, BudgetRatio: 0.5}, nil)
+
+				var cancelled int32
+				result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates,
+					slowFirstCall(30*time.Millisecond, &cancelled))
+				require.NoError(t, err)
+				require.Len(t, result.Tried, 1, "the hedge was routed but the budget refused it")
+				drained(t, pending)
+			})
+
+			t.Run("SaturatedLegs", func(t *testing.T) {
+				smartRouter, candidates, pending := newLoadRouter(t, &types.HedgingConfig{Delay: time.Hour, BudgetRatio: 1},
+					&types.ConcurrencyConfig{
+						QueueTimeout: 10 * time.Millisecond,
+						Limits:       []types.ConcurrencyLimit{{Provider: "a", MaxInFlight: 1}, {Provider: "b", MaxInFlight: 1}},
+					})
+
+				// Hold one call on each provider so the next request finds both saturated
+				hold := make(chan struct{})
+				call := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+					if req.ID == "held" {
+						<-hold
+					}
+					return p.GetName(), nil
+				}
+				var wg sync.WaitGroup
+				for i := 0; i < 2; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m", ID: "held"}, candidates, call)
+						assert.NoError(t, err)
+					}()
+				}
+				assert.Eventually(t, func() bool { return pending("a") == 1 && pending("b") == 1 },
+					time.Second, 5*time.Millisecond, "the held calls are in flight")
+
+				_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "m"}, candidates, call)
+				require.ErrorIs(t, err, router.ErrProviderSaturated)
+				assert.Equal(t, int64(1), pending("a"), "saturated legs were never sent")
+				assert.Equal(t, int64(1), pending("b"))
+
+				close(hold)
+				wg.Wait()
+				drained(t, pending)
+			})
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package unit
+
+import (
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/llm-gateway/gateway/pkg/utils"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestLogger creates a quiet logger for tests
+func newTestLogger() *utils.Logger {
+	return utils.NewLogger(&types.LoggingConfig{
+		Level:  "error",
+		Format: "text",
+		Output: "stdout",
+	})
+}
+
+// newTestSmartRouter creates a Smart Router with config over a mock provider for each name
+func newTestSmartRouter(t *testing.T, config *router.SmartRouterConfig, names ...string) (*router.SmartRouter, []types.Provider) {
+	smartRouter, err := router.NewSmartRouter(config, newTestLogger())
+	require.NoError(t, err)
+
+	candidates := make([]types.Provider, 0, len(names))
+	for _, name := range names {
+		provider := router.NewMockProvider(&types.ProviderConfig{Name: name}, newTestLogger())
+		require.NoError(t, smartRouter.AddProvider(provider))
+		candidates = append(candidates, provider)
+	}
+	return smartRouter, candidates
+}
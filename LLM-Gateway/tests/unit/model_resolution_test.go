@@ -0,0 +1,78 @@
+package unit
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGatewayModelResolution tests registry-backed model resolution and the model_not_found error
+func TestGatewayModelResolution(t *testing.T) {
+	var hitsA, hitsB int32
+	upstream := func(hits *int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(hits, 1)
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"shared-model",
+				"choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],
+				"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`)
+		}))
+	}
+	serverA, serverB := upstream(&hitsA), upstream(&hitsB)
+	defer serverA.Close()
+	defer serverB.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		Providers: map[string]*types.ProviderConfig{
+			"upstream-a": {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: serverA.URL, Models: []string{"shared-model"}},
+			"upstream-b": {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: serverB.URL, Models: []string{"shared-model"}},
+		},
+	})
+
+	post := func(model string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"model":%q,"messages":[{"role":"user","content":"ping"}]}`, model)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	t.Run("SharedModel", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			require.Equal(t, http.StatusOK, post("shared-model").Code)
+		}
+		assert.Equal(t, int32(2), atomic.LoadInt32(&hitsA), "the Smart Router spreads a shared model across its providers")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&hitsB))
+	})
+
+	t.Run("ModelNotFound", func(t *testing.T) {
+		recorder := post("no-such-model")
+		require.Equal(t, http.StatusNotFound, recorder.Code)
+
+		var resp struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+				Param   string `json:"param"`
+				Code    string `json:"code"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+		assert.Equal(t, "model_not_found", resp.Error.Code)
+		assert.Equal(t, "invalid_request_error", resp.Error.Type)
+		assert.Equal(t, "model", resp.Error.Param)
+		assert.Contains(t, resp.Error.Message, "no-such-model")
+	})
+}
@@ -0,0 +1,132 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/cost"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const visionMessageJSON = `{"role":"user","content":[
+	{"type":"text","text":"What is in these images?"},
+	{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo="}},
+	{"type":"image_url","image_url":{"url":"https://example.com/cat.jpg","detail":"low"}}
+]}`
+
+// TestMessageContentParts tests that message content accepts both strings and content part arrays
+func TestMessageContentParts(t *testing.T) {
+	t.Run("String", func(t *testing.T) {
+		var msg types.Message
+		require.NoError(t, json.Unmarshal([]byte(`{"role":"user","content":"Hi"}`), &msg))
+		assert.Equal(t, "Hi", msg.Content)
+		assert.Empty(t, msg.Parts)
+		assert.False(t, msg.HasImages())
+	})
+
+	t.Run("Parts", func(t *testing.T) {
+		var msg types.Message
+		require.NoError(t, json.Unmarshal([]byte(visionMessageJSON), &msg))
+		assert.Equal(t, "What is in these images?", msg.Content)
+		require.Len(t, msg.Parts, 3)
+		assert.True(t, msg.HasImages())
+
+		mediaType, data, err := msg.Parts[1].ImageURL.DataURI()
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", mediaType)
+		assert.Equal(t, "iVBORw0KGgo=", data)
+
+		// Parts survive a round trip so requests can be logged or forwarded unchanged
+		encoded, err := json.Marshal(msg)
+		require.NoError(t, err)
+		var decoded types.Message
+		require.NoError(t, json.Unmarshal(encoded, &decoded))
+		assert.Equal(t, msg, decoded)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		invalid := []string{
+			`{"role":"user","content":[{"type":"audio","text":"x"}]}`,
+			`{"role":"user","content":[{"type":"image_url","image_url":{"url":"ftp://example.com/a.png"}}]}`,
+			`{"role":"user","content":[{"type":"image_url","image_url":{"url":"data:image/png,raw"}}]}`,
+			`{"role":"user","content":42}`,
+		}
+		for _, body := range invalid {
+			var msg types.Message
+			assert.Error(t, json.Unmarshal([]byte(body), &msg), body)
+		}
+	})
+}
+
+// TestClaudeImageBlocks tests translation of image parts to Claude image source blocks
+func TestClaudeImageBlocks(t *testing.T) {
+	var captured map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&captured))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_4","type":"message","role":"assistant","model":"claude-3-haiku-20240307",
+			"content":[{"type":"text","text":"A cat."}],"stop_reason":"end_turn","usage":{"input_tokens":1700,"output_tokens":3}}`)
+	}))
+	defer server.Close()
+
+	var msg types.Message
+	require.NoError(t, json.Unmarshal([]byte(visionMessageJSON), &msg))
+
+	provider := providers.NewClaudeProvider(&types.ProviderConfig{
+		Name:    "claude",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	resp, err := provider.Call(context.Background(), &types.ChatCompletionRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{msg},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "A cat.", resp.Choices[0].Message.Content)
+
+	messages := captured["messages"].([]interface{})
+	require.Len(t, messages, 1)
+	blocks := messages[0].(map[string]interface{})["content"].([]interface{})
+	require.Len(t, blocks, 3)
+
+	assert.Equal(t, "text", blocks[0].(map[string]interface{})["type"])
+	assert.Equal(t, map[string]interface{}{
+		"type":       "base64",
+		"media_type": "image/png",
+		"data":       "iVBORw0KGgo=",
+	}, blocks[1].(map[string]interface{})["source"])
+	assert.Equal(t, map[string]interface{}{
+		"type": "url",
+		"url":  "https://example.com/cat.jpg",
+	}, blocks[2].(map[string]interface{})["source"])
+}
+
+// TestTokenEstimatorImages tests that image parts add a fixed per-image token cost
+func TestTokenEstimatorImages(t *testing.T) {
+	var msg types.Message
+	require.NoError(t, json.Unmarshal([]byte(visionMessageJSON), &msg))
+
+	estimator := cost.NewTokenEstimator()
+	textOnly, err := estimator.EstimateTokens(&types.ChatCompletionRequest{
+		Messages: []types.Message{{Role: "user", Content: msg.Content}},
+	}, "openai")
+	require.NoError(t, err)
+
+	withImages, err := estimator.EstimateTokens(&types.ChatCompletionRequest{
+		Messages: []types.Message{msg},
+	}, "openai")
+	require.NoError(t, err)
+
+	// One high detail image (765) and one low detail image (85)
+	assert.Equal(t, textOnly.InputTokens+765+85, withImages.InputTokens)
+}
@@ -0,0 +1,157 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newCompatibleServer returns an OpenAI-compatible upstream that records the last request headers
+func newCompatibleServer(t *testing.T, headers *http.Header) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*headers = r.Header.Clone()
+		assert.Equal(t, "/chat/completions", r.URL.Path)
+
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":%q,
+			"choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`, body["model"])
+	}))
+}
+
+// TestOpenAICompatibleProvider tests auth styles, extra headers and configured pricing
+func TestOpenAICompatibleProvider(t *testing.T) {
+	var headers http.Header
+	server := newCompatibleServer(t, &headers)
+	defer server.Close()
+
+	req := &types.ChatCompletionRequest{
+		Model:    "deepseek-chat",
+		Messages: []types.Message{{Role: "user", Content: "ping"}},
+	}
+
+	t.Run("BearerAuth", func(t *testing.T) {
+		provider, err := providers.NewOpenAICompatibleProvider(&types.ProviderConfig{
+			Name:    "deepseek",
+			BaseURL: server.URL,
+			APIKey:  "sk-test",
+			Models:  []string{"deepseek-chat"},
+			CustomConfig: map[string]string{
+				providers.CompatExtraHeaders: "X-Client=gateway, X-Trace=on",
+				providers.CompatInputPrice:   "0.001",
+				providers.CompatOutputPrice:  "0.002",
+			},
+		}, newTestLogger())
+		require.NoError(t, err)
+
+		resp, err := provider.Call(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "pong", resp.Choices[0].Message.Content)
+		assert.Equal(t, "deepseek", resp.Provider)
+
+		assert.Equal(t, "Bearer sk-test", headers.Get("Authorization"))
+		assert.Equal(t, "gateway", headers.Get("X-Client"))
+		assert.Equal(t, "on", headers.Get("X-Trace"))
+
+		estimate, err := provider.EstimateCost(req)
+		require.NoError(t, err)
+		expected := float64(estimate.InputTokens)/1000*0.001 + float64(estimate.OutputTokens)/1000*0.002
+		assert.InDelta(t, expected, estimate.TotalCost, 0.0001)
+	})
+
+	t.Run("CustomHeader", func(t *testing.T) {
+		provider, err := providers.NewOpenAICompatibleProvider(&types.ProviderConfig{
+			Name:         "custom",
+			BaseURL:      server.URL,
+			APIKey:       "key-123",
+			CustomConfig: map[string]string{providers.CompatAuthHeader: "api-key"},
+		}, newTestLogger())
+		require.NoError(t, err)
+
+		_, err = provider.Call(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "key-123", headers.Get("api-key"))
+		assert.Empty(t, headers.Get("Authorization"))
+	})
+
+	t.Run("NoKey", func(t *testing.T) {
+		provider, err := providers.NewOpenAICompatibleProvider(&types.ProviderConfig{
+			Name:    "ollama",
+			BaseURL: server.URL,
+		}, newTestLogger())
+		require.NoError(t, err)
+
+		_, err = provider.Call(context.Background(), req)
+		require.NoError(t, err)
+		assert.Empty(t, headers.Get("Authorization"))
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		_, err := providers.NewOpenAICompatibleProvider(&types.ProviderConfig{Name: "missing-url"}, newTestLogger())
+		assert.Error(t, err)
+
+		_, err = providers.NewOpenAICompatibleProvider(&types.ProviderConfig{
+			Name:         "bad-headers",
+			BaseURL:      server.URL,
+			CustomConfig: map[string]string{providers.CompatExtraHeaders: "no-equals-sign"},
+		}, newTestLogger())
+		assert.Error(t, err)
+	})
+}
+
+// TestOpenAICompatibleGatewayRouting tests that config-declared instances serve their models
+func TestOpenAICompatibleGatewayRouting(t *testing.T) {
+	var headers http.Header
+	server := newCompatibleServer(t, &headers)
+	defer server.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		Providers: map[string]*types.ProviderConfig{
+			"local-llm": {
+				Type:    providers.OpenAICompatibleType,
+				Enabled: true,
+				BaseURL: server.URL,
+				Models:  []string{"llama3.1"},
+			},
+			"disabled-llm": {
+				Type:    providers.OpenAICompatibleType,
+				BaseURL: server.URL,
+				Models:  []string{"qwen2.5"},
+			},
+		},
+	})
+
+	post := func(model string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"model":%q,"messages":[{"role":"user","content":"ping"}]}`, model)
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := post("llama3.1")
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var resp types.Response
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, "local-llm", resp.Provider)
+	assert.Equal(t, "llama3.1", resp.Model)
+	assert.Equal(t, "pong", resp.Choices[0].Message.Content)
+
+	assert.Equal(t, http.StatusNotFound, post("qwen2.5").Code, "disabled instances are not registered")
+}
@@ -0,0 +1,218 @@
+package unit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/internal/router/strategies"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	errUpstream = errors.New("status code: 503, service unavailable")
+	errBadInput = errors.New("status code: 400, bad request")
+)
+
+// newOutlierChecker creates a health checker with outlier detection over the named providers
+func newOutlierChecker(t *testing.T, outliers *types.OutlierDetectionConfig, names ...string) router.OutlierDetector {
+	config := router.DefaultHealthCheckConfig()
+	config.OutlierDetection = outliers
+	checker, err := router.NewHealthChecker(config, newTestLogger())
+	require.NoError(t, err)
+	for _, name := range names {
+		provider := createHealthyMockProvider(name)
+		require.NoError(t, checker.AddProvider(&provider))
+	}
+	detector, ok := checker.(router.OutlierDetector)
+	require.True(t, ok)
+	return detector
+}
+
+// healthyNames returns the names of the providers a health checker reports healthy
+func healthyNames(checker router.HealthChecker) []string {
+	var names []string
+	for _, provider := range checker.GetHealthyProviders() {
+		names = append(names, (*provider).GetName())
+	}
+	return names
+}
+
+// TestOutlierDetection tests ejection of providers from the outcome of live calls
+func TestOutlierDetection(t *testing.T) {
+	outliers := &types.OutlierDetectionConfig{
+		ConsecutiveErrors: 3, BaseEjectionTime: 50 * time.Millisecond, MaxEjectionTime: 150 * time.Millisecond,
+	}
+
+	t.Run("ConsecutiveErrors", func(t *testing.T) {
+		detector := newOutlierChecker(t, outliers, "provider-0", "provider-1", "provider-2", "provider-3")
+		checker := detector.(router.HealthChecker)
+
+		detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+		detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+		detector.RecordCallOutcome("provider-0", time.Millisecond, nil)
+		detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+		detector.RecordCallOutcome("provider-0", time.Millisecond, errBadInput)
+		detector.RecordCallOutcome("provider-0", time.Millisecond, context.Canceled)
+		detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+		assert.False(t, detector.IsEjected("provider-0"), "a success resets the run; client errors and cancellations count for nothing")
+
+		detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+		require.True(t, detector.IsEjected("provider-0"))
+		assert.NotContains(t, healthyNames(checker), "provider-0")
+		assert.Len(t, healthyNames(checker), 3)
+
+		health, err := checker.GetProviderHealth("provider-0")
+		require.NoError(t, err)
+		assert.False(t, health.IsHealthy)
+		assert.Equal(t, "ejected", health.Status)
+		assert.Equal(t, 1, health.Ejections)
+		assert.WithinDuration(t, time.Now().Add(50*time.Millisecond), health.EjectedUntil, 20*time.Millisecond)
+
+		time.Sleep(60 * time.Millisecond)
+		assert.False(t, detector.IsEjected("provider-0"), "the ejection expired")
+		assert.Contains(t, healthyNames(checker), "provider-0")
+	})
+
+	t.Run("ExponentialBackoff", func(t *testing.T) {
+		detector := newOutlierChecker(t, outliers, "provider-0", "provider-1")
+		checker := detector.(router.HealthChecker)
+		eject := func() time.Duration {
+			for i := 0; i < 3; i++ {
+				detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+			}
+			require.True(t, detector.IsEjected("provider-0"))
+			health, err := checker.GetProviderHealth("provider-0")
+			require.NoError(t, err)
+			return time.Until(health.EjectedUntil)
+		}
+
+		assert.InDelta(t, 50*time.Millisecond, eject(), float64(15*time.Millisecond))
+		time.Sleep(55 * time.Millisecond)
+		assert.InDelta(t, 100*time.Millisecond, eject(), float64(15*time.Millisecond))
+		time.Sleep(105 * time.Millisecond)
+		assert.InDelta(t, 150*time.Millisecond, eject(), float64(15*time.Millisecond), "capped at the max ejection time")
+
+		// Time spent healthy earns back the doublings
+		time.Sleep(155*time.Millisecond + 2*50*time.Millisecond)
+		assert.InDelta(t, 100*time.Millisecond, eject(), float64(15*time.Millisecond))
+	})
+
+	t.Run("MaxEjectionPercent", func(t *testing.T) {
+		limited := *outliers
+		limited.MaxEjectionPercent = 10
+		detector := newOutlierChecker(t, &limited, "provider-0", "provider-1", "provider-2")
+		for _, name := range []string{"provider-0", "provider-1"} {
+			for i := 0; i < 3; i++ {
+				detector.RecordCallOutcome(name, time.Millisecond, errUpstream)
+			}
+		}
+		assert.True(t, detector.IsEjected("provider-0"), "at least one provider may be ejected")
+		assert.False(t, detector.IsEjected("provider-1"))
+
+		detector = newOutlierChecker(t, outliers, "only")
+		for i := 0; i < 10; i++ {
+			detector.RecordCallOutcome("only", time.Millisecond, errUpstream)
+		}
+		assert.False(t, detector.IsEjected("only"), "the last provider is never ejected")
+	})
+
+	t.Run("LatencySpike", func(t *testing.T) {
+		slow := *outliers
+		slow.LatencyMultiplier = 3
+		detector := newOutlierChecker(t, &slow, "provider-0", "provider-1")
+		for i := 0; i < 10; i++ {
+			detector.RecordCallOutcome("provider-0", 10*time.Millisecond, nil)
+		}
+		detector.RecordCallOutcome("provider-0", 100*time.Millisecond, nil)
+		detector.RecordCallOutcome("provider-0", 100*time.Millisecond, nil)
+		assert.False(t, detector.IsEjected("provider-0"))
+		detector.RecordCallOutcome("provider-0", 100*time.Millisecond, nil)
+		assert.True(t, detector.IsEjected("provider-0"))
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		detector := newOutlierChecker(t, nil, "provider-0", "provider-1")
+		for i := 0; i < 10; i++ {
+			detector.RecordCallOutcome("provider-0", time.Millisecond, errUpstream)
+		}
+		assert.False(t, detector.IsEjected("provider-0"))
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string]*types.OutlierDetectionConfig{
+			"NegativeErrors":    {ConsecutiveErrors: -1},
+			"MultiplierTooLow":  {LatencyMultiplier: 1},
+			"MaxBelowBase":      {BaseEjectionTime: time.Minute, MaxEjectionTime: time.Second},
+			"PercentAbove100":   {MaxEjectionPercent: 150},
+			"NegativeEjectTime": {BaseEjectionTime: -time.Second},
+		}
+		for name, outliers := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.OutlierDetection = outliers
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+	})
+}
+
+// TestOutlierDetectionRouting tests that the smart router routes around ejected providers
+func TestOutlierDetectionRouting(t *testing.T) {
+	config := router.DefaultSmartRouterConfig()
+	config.CircuitBreaker.Enabled = false
+	config.OutlierDetection = &types.OutlierDetectionConfig{ConsecutiveErrors: 3, BaseEjectionTime: time.Minute}
+	smartRouter, providerList := newTestSmartRouter(t, config, "provider-0", "provider-1", "provider-2")
+
+	calls := map[string]int{}
+	call := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		calls[p.GetName()]++
+		if p.GetName() == "provider-0" {
+			return nil, errUpstream
+		}
+		return p.GetName(), nil
+	}
+
+	for i := 0; i < 12; i++ {
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "chat"}, providerList, call)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, calls["provider-0"], "provider-0 was ejected after three errors in a row")
+
+	health := smartRouter.GetHealthStatus()
+	assert.Equal(t, "ejected", health["provider-0"].Status)
+	assert.True(t, health["provider-1"].IsHealthy)
+}
+
+// TestHealthBasedStrategyEjection tests that ejected providers score zero in the health_based strategy
+func TestHealthBasedStrategyEjection(t *testing.T) {
+	strategy := strategies.NewHealthBasedStrategy().(*strategies.HealthBasedStrategy)
+	providerList := []types.Provider{createHealthyMockProvider("fast"), createHealthyMockProvider("slow")}
+	candidates := []*types.Provider{&providerList[0], &providerList[1]}
+	strategy.UpdateProviderHealth("fast", 10*time.Millisecond, true)
+	strategy.UpdateProviderHealth("slow", 2*time.Second, true)
+
+	selected, err := strategy.SelectProvider(candidates, &types.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "fast", (*selected).GetName())
+
+	var aware strategies.OutlierAware = strategy
+	aware.SetProviderEjected("fast", true)
+	health := strategy.GetProviderHealth("fast")
+	assert.True(t, health.Ejected)
+	assert.False(t, health.IsHealthy)
+	assert.Zero(t, health.HealthScore)
+	selected, err = strategy.SelectProvider(candidates, &types.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "slow", (*selected).GetName())
+
+	aware.SetProviderEjected("fast", false)
+	assert.True(t, strategy.GetProviderHealth("fast").IsHealthy)
+	selected, err = strategy.SelectProvider(candidates, &types.Request{})
+	require.NoError(t, err)
+	assert.Equal(t, "fast", (*selected).GetName())
+}
@@ -0,0 +1,297 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/internal/storage"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newResidentProvider returns a mock provider tagged with a residency
+func newResidentProvider(name, residency string) types.Provider {
+	return router.NewMockProvider(&types.ProviderConfig{Name: name, Residency: residency}, newTestLogger())
+}
+
+// TestFilterByPolicy tests the provider and model restrictions of routing policies
+func TestFilterByPolicy(t *testing.T) {
+	candidates := []types.Provider{
+		newResidentProvider("zhipu", "cn"),
+		newResidentProvider("openai", "us"),
+		newResidentProvider("local", ""),
+	}
+	names := func(providers []types.Provider) []string {
+		result := make([]string, len(providers))
+		for i, provider := range providers {
+			result[i] = provider.GetName()
+		}
+		return result
+	}
+
+	t.Run("NoPolicy", func(t *testing.T) {
+		allowed, err := router.FilterByPolicy(nil, "chat", candidates)
+		require.NoError(t, err)
+		assert.Len(t, allowed, 3)
+	})
+
+	t.Run("Residency", func(t *testing.T) {
+		allowed, err := router.FilterByPolicy(&types.RoutingPolicyConfig{Name: "cn-only", Residency: []string{"CN"}}, "chat", candidates)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"zhipu"}, names(allowed))
+	})
+
+	t.Run("AllowedAndForbidden", func(t *testing.T) {
+		allowed, err := router.FilterByPolicy(&types.RoutingPolicyConfig{Name: "p", ForbiddenProviders: []string{"openai"}}, "chat", candidates)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"zhipu", "local"}, names(allowed))
+
+		allowed, err = router.FilterByPolicy(&types.RoutingPolicyConfig{Name: "p", AllowedProviders: []string{"local", "openai"}}, "chat", candidates)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"openai", "local"}, names(allowed))
+
+		// Provider types match as well as names
+		_, err = router.FilterByPolicy(&types.RoutingPolicyConfig{Name: "no-mocks", ForbiddenProviders: []string{"mock"}}, "chat", candidates)
+		assert.Error(t, err)
+	})
+
+	t.Run("Violations", func(t *testing.T) {
+		_, err := router.FilterByPolicy(&types.RoutingPolicyConfig{Name: "eu-only", Residency: []string{"eu"}}, "chat", candidates)
+		var policyErr *router.PolicyError
+		require.True(t, errors.As(err, &policyErr))
+		assert.Equal(t, "eu-only", policyErr.Policy)
+		assert.Contains(t, err.Error(), "eu-only")
+		assert.Contains(t, err.Error(), "openai has residency us")
+		assert.Contains(t, err.Error(), "local has no residency tag")
+
+		_, err = router.FilterByPolicy(&types.RoutingPolicyConfig{Name: "small", AllowedModels: []string{"mini"}}, "chat", candidates)
+		require.True(t, errors.As(err, &policyErr))
+		assert.Contains(t, err.Error(), "model chat is not allowed")
+	})
+}
+
+// TestRoutingPolicyFor tests how callers are bound to routing policies and that the smart router enforces them
+func TestRoutingPolicyFor(t *testing.T) {
+	config := router.DefaultSmartRouterConfig()
+	config.Policies = []types.RoutingPolicyConfig{
+		{Name: "cn-only", Users: []string{"7"}, Residency: []string{"cn"}},
+		{Name: "no-openai", APIKeys: []string{"key-1"}, ForbiddenProviders: []string{"openai"}},
+	}
+	smartRouter, err := router.NewSmartRouter(config, newTestLogger())
+	require.NoError(t, err)
+	zhipu, openai := newResidentProvider("zhipu", "cn"), newResidentProvider("openai", "us")
+	require.NoError(t, smartRouter.AddProvider(zhipu))
+	require.NoError(t, smartRouter.AddProvider(openai))
+
+	t.Run("Binding", func(t *testing.T) {
+		policy, err := smartRouter.RoutingPolicyFor(router.PolicyIdentity{UserID: "7"})
+		require.NoError(t, err)
+		assert.Equal(t, "cn-only", policy.Name)
+
+		// The API key's policy wins over the user's, and a named policy over both
+		policy, err = smartRouter.RoutingPolicyFor(router.PolicyIdentity{UserID: "7", APIKey: "key-1"})
+		require.NoError(t, err)
+		assert.Equal(t, "no-openai", policy.Name)
+		policy, err = smartRouter.RoutingPolicyFor(router.PolicyIdentity{UserID: "8", APIKey: "key-1", Policy: "cn-only"})
+		require.NoError(t, err)
+		assert.Equal(t, "cn-only", policy.Name)
+
+		policy, err = smartRouter.RoutingPolicyFor(router.PolicyIdentity{UserID: "8"})
+		require.NoError(t, err)
+		assert.Nil(t, policy)
+
+		_, err = smartRouter.RoutingPolicyFor(router.PolicyIdentity{Policy: "missing"})
+		var policyErr *router.PolicyError
+		assert.True(t, errors.As(err, &policyErr), "unknown policies fail closed")
+	})
+
+	t.Run("Enforced", func(t *testing.T) {
+		policy, err := smartRouter.RoutingPolicyFor(router.PolicyIdentity{UserID: "7"})
+		require.NoError(t, err)
+		ctx := router.WithRoutingPolicy(context.Background(), policy)
+		req := &types.Request{Model: "chat"}
+
+		for i := 0; i < 10; i++ {
+			result, err := smartRouter.RouteRequest(ctx, req)
+			require.NoError(t, err)
+			assert.Equal(t, "zhipu", result.ProviderName)
+		}
+
+		called := false
+		_, err = smartRouter.ExecuteWithFailover(ctx, req, []types.Provider{openai}, func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+			called = true
+			return nil, nil
+		})
+		var policyErr *router.PolicyError
+		require.True(t, errors.As(err, &policyErr))
+		assert.False(t, called, "forbidden providers are never called")
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		config := router.DefaultSmartRouterConfig()
+		config.Policies = []types.RoutingPolicyConfig{{Name: "anonymous", Residency: []string{"cn"}}}
+		config.DefaultPolicy = "anonymous"
+		smartRouter, err := router.NewSmartRouter(config, newTestLogger())
+		require.NoError(t, err)
+
+		policy, err := smartRouter.RoutingPolicyFor(router.PolicyIdentity{})
+		require.NoError(t, err)
+		assert.Equal(t, "anonymous", policy.Name, "unauthenticated callers obey the default policy")
+
+		policy, err = smartRouter.RoutingPolicyFor(router.PolicyIdentity{UserID: "8"})
+		require.NoError(t, err)
+		assert.Nil(t, policy, "authenticated callers without a policy are unrestricted")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string][]types.RoutingPolicyConfig{
+			"MissingName": {{Residency: []string{"cn"}}},
+			"Duplicate":   {{Name: "a"}, {Name: "a"}},
+			"UserTwice":   {{Name: "a", Users: []string{"1"}}, {Name: "b", Users: []string{"1"}}},
+			"APIKeyTwice": {{Name: "a", APIKeys: []string{"k"}}, {Name: "b", APIKeys: []string{"k"}}},
+		}
+		for name, policies := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.Policies = policies
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+
+		config := router.DefaultSmartRouterConfig()
+		config.Policies = []types.RoutingPolicyConfig{{Name: "a"}}
+		config.DefaultPolicy = "missing"
+		assert.Error(t, config.ValidateConfig(), "the default policy must be configured")
+	})
+}
+
+// authenticateAPIKeys returns an authentication middleware accepting the X-API-Key headers keys lists.
+// Like the real one, it stores the key and its owner on the request.
+func authenticateAPIKeys(keys map[string]*storage.APIKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, ok := keys[c.GetHeader("X-API-Key")]; ok {
+			c.Set("user", &storage.User{ID: key.UserID})
+			c.Set("api_key", key)
+		}
+	}
+}
+
+// TestGatewayRoutingPolicy tests that the gateway keeps each caller on the providers its policy allows
+func TestGatewayRoutingPolicy(t *testing.T) {
+	newUpstream := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"chat-model",
+				"choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}],
+				"usage":{"prompt_tokens":1,"completion_tokens":1,"total_tokens":2}}`)
+		}))
+	}
+	domestic, overseas := newUpstream(), newUpstream()
+	defer domestic.Close()
+	defer overseas.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			Strategy:        "round_robin",
+			FailoverTimeout: 10 * time.Second,
+			Policies: []types.RoutingPolicyConfig{
+				{Name: "cn-residency", APIKeys: []string{"cn-key"}, Residency: []string{"cn"}},
+				{Name: "eu-residency", APIKeys: []string{"eu-key"}, Residency: []string{"eu"}},
+				{Name: "small-models", Users: []string{"9"}, AllowedModels: []string{"mini-model"}},
+				{Name: "anonymous", AllowedModels: []string{"mini-model"}},
+			},
+			DefaultPolicy: "anonymous",
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"domestic": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: domestic.URL, RetryCount: 1,
+				Models: []string{"chat-model"}, Residency: "cn",
+			},
+			"overseas": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: overseas.URL, RetryCount: 1,
+				Models: []string{"chat-model", "text-embedding-3-small"}, Residency: "us",
+			},
+		},
+	})
+	gw.UseAuthentication(authenticateAPIKeys(map[string]*storage.APIKey{
+		"plain-key": {Key: "plain-key", UserID: 1},
+		"cn-key":    {Key: "cn-key", UserID: 2},
+		"eu-key":    {Key: "eu-key", UserID: 3},
+		"user9-key": {Key: "user9-key", UserID: 9},
+	}))
+
+	post := func(path, apiKey, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+	send := func(apiKey, user string) *httptest.ResponseRecorder {
+		body := fmt.Sprintf(`{"model":"chat-model","user_id":%q,"messages":[{"role":"user","content":"hi"}]}`, user)
+		return post("/v1/chat/completions", apiKey, body)
+	}
+	policyOf := func(recorder *httptest.ResponseRecorder) string {
+		var body struct {
+			Error struct {
+				Code   string `json:"code"`
+				Policy string `json:"policy"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		assert.Equal(t, "routing_policy_violation", body.Error.Code)
+		return body.Error.Policy
+	}
+
+	served := map[string]int{}
+	for i := 0; i < 10; i++ {
+		recorder := send("plain-key", "")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		served[recorder.Header().Get("X-Gateway-Provider")]++
+	}
+	assert.Len(t, served, 2, "unrestricted callers reach both providers")
+
+	for i := 0; i < 10; i++ {
+		recorder := send("cn-key", "")
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "domestic", recorder.Header().Get("X-Gateway-Provider"))
+	}
+
+	recorder := send("eu-key", "")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "eu-residency", policyOf(recorder))
+
+	recorder = send("user9-key", "")
+	require.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Equal(t, "small-models", policyOf(recorder))
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		// Neither an unverified key nor a user_id in the body binds a policy; the default one applies
+		for _, recorder := range []*httptest.ResponseRecorder{send("", ""), send("forged-key", "1"), send("", "9")} {
+			require.Equal(t, http.StatusForbidden, recorder.Code)
+			assert.Equal(t, "anonymous", policyOf(recorder))
+		}
+	})
+
+	t.Run("Embeddings", func(t *testing.T) {
+		recorder := post("/v1/embeddings", "eu-key", `{"model":"text-embedding-3-small","input":"hi"}`)
+		require.Equal(t, http.StatusForbidden, recorder.Code)
+		assert.Equal(t, "eu-residency", policyOf(recorder))
+	})
+}
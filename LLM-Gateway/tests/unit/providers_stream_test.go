@@ -0,0 +1,167 @@
+package unit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestClaudeCallStream tests translation of Claude streaming events into typed chunks
+func TestClaudeCallStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "test-key", r.Header.Get("x-api-key"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`event: message_start` + "\n" + `data: {"type":"message_start","message":{"id":"msg_1","model":"claude-3-haiku-20240307","role":"assistant","content":[],"usage":{"input_tokens":12,"output_tokens":1}}}`,
+			`event: content_block_start` + "\n" + `data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`event: ping` + "\n" + `data: {"type":"ping"}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}`,
+			`event: content_block_delta` + "\n" + `data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}`,
+			`event: content_block_stop` + "\n" + `data: {"type":"content_block_stop","index":0}`,
+			`event: message_delta` + "\n" + `data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}`,
+			`event: message_stop` + "\n" + `data: {"type":"message_stop"}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "%s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	provider := providers.NewClaudeProvider(&types.ProviderConfig{
+		Name:    "claude",
+		Type:    "anthropic",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	chunks, err := provider.CallStream(context.Background(), &types.ChatCompletionRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	collected := collectChunks(t, chunks)
+	require.Len(t, collected, 5)
+
+	assert.Equal(t, types.ChunkRole, collected[0].Type)
+	assert.Equal(t, "assistant", collected[0].Role)
+	assert.Equal(t, "msg_1", collected[0].ID)
+
+	assert.Equal(t, "Hello", collected[1].Content)
+	assert.Equal(t, " world", collected[2].Content)
+
+	assert.Equal(t, types.ChunkFinish, collected[3].Type)
+	require.NotNil(t, collected[3].FinishReason)
+	assert.Equal(t, "stop", *collected[3].FinishReason)
+
+	assert.Equal(t, types.ChunkUsage, collected[4].Type)
+	assert.Equal(t, 12, collected[4].Usage.PromptTokens)
+	assert.Equal(t, 5, collected[4].Usage.CompletionTokens)
+	assert.Equal(t, 17, collected[4].Usage.TotalTokens)
+}
+
+// TestClaudeCallStreamError tests that a mid-stream error event ends the stream with an error chunk
+func TestClaudeCallStreamError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	provider := providers.NewClaudeProvider(&types.ProviderConfig{
+		Name:    "claude",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	chunks, err := provider.CallStream(context.Background(), &types.ChatCompletionRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	})
+	require.NoError(t, err)
+
+	collected := collectChunks(t, chunks)
+	require.Len(t, collected, 1)
+	assert.Equal(t, types.ChunkError, collected[0].Type)
+	assert.Contains(t, collected[0].Err.Error(), "Overloaded")
+}
+
+// TestZhipuCallStream tests GLM SSE parsing, including the "data:" prefix without a space
+func TestZhipuCallStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer test-key", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, `data:{"id":"1","model":"glm-4.5","choices":[{"index":0,"delta":{"role":"assistant","content":"你好"}}]}`+"\n\n")
+		fmt.Fprint(w, `data:{"id":"1","model":"glm-4.5","choices":[{"index":0,"delta":{"content":"!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`+"\n\n")
+		fmt.Fprint(w, "data:[DONE]\n\n")
+	}))
+	defer server.Close()
+
+	provider := providers.NewZhipuProvider(&types.ProviderConfig{
+		Name:    "zhipu",
+		Type:    "zhipu",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	request := &types.ChatCompletionRequest{
+		Model:    "glm-4.5",
+		Messages: []types.Message{{Role: "user", Content: "Hi"}},
+	}
+
+	t.Run("CallStream", func(t *testing.T) {
+		chunks, err := provider.CallStream(context.Background(), request)
+		require.NoError(t, err)
+
+		collected := collectChunks(t, chunks)
+		require.Len(t, collected, 5)
+		assert.Equal(t, types.ChunkRole, collected[0].Type)
+		assert.Equal(t, "你好", collected[1].Content)
+		assert.Equal(t, "!", collected[2].Content)
+		assert.Equal(t, "stop", *collected[3].FinishReason)
+		assert.Equal(t, 5, collected[4].Usage.TotalTokens)
+	})
+
+	t.Run("ChatCompletionStream_Callback", func(t *testing.T) {
+		var content string
+		done := false
+		err := provider.ChatCompletionStream(context.Background(), request, func(chunk string, finished bool) {
+			content += chunk
+			done = done || finished
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "你好!", content)
+		assert.True(t, done)
+	})
+}
+
+// collectChunks drains a chunk channel, failing the test if it does not close in time
+func collectChunks(t *testing.T, chunks <-chan *types.StreamChunk) []*types.StreamChunk {
+	t.Helper()
+
+	var collected []*types.StreamChunk
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case chunk, ok := <-chunks:
+			if !ok {
+				return collected
+			}
+			collected = append(collected, chunk)
+		case <-timeout:
+			t.Fatal("stream did not close in time")
+			return nil
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// weatherTool returns a simple function tool used across tool calling tests
+func weatherTool() types.Tool {
+	return types.Tool{
+		Type: "function",
+		Function: types.FunctionDefinition{
+			Name:        "get_weather",
+			Description: "Get the weather for a city",
+			Parameters: map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"city": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	}
+}
+
+// TestClaudeToolCalling tests translation of tools, tool calls and tool results to Claude blocks
+func TestClaudeToolCalling(t *testing.T) {
+	var captured map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&captured))
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_2","type":"message","role":"assistant","model":"claude-3-haiku-20240307",
+			"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_2","name":"get_weather","input":{"city":"Paris"}}],
+			"stop_reason":"tool_use","usage":{"input_tokens":30,"output_tokens":10}}`)
+	}))
+	defer server.Close()
+
+	provider := providers.NewClaudeProvider(&types.ProviderConfig{
+		Name:    "claude",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	resp, err := provider.Call(context.Background(), &types.ChatCompletionRequest{
+		Model: "claude-3-haiku-20240307",
+		Messages: []types.Message{
+			{Role: "system", Content: "Be brief"},
+			{Role: "user", Content: "Weather in Berlin and Rome?"},
+			{Role: "assistant", ToolCalls: []types.ToolCall{
+				{ID: "toolu_a", Type: "function", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"Berlin"}`}},
+				{ID: "toolu_b", Type: "function", Function: types.FunctionCall{Name: "get_weather", Arguments: `{"city":"Rome"}`}},
+			}},
+			{Role: "tool", ToolCallID: "toolu_a", Content: "12C"},
+			{Role: "tool", ToolCallID: "toolu_b", Content: "20C"},
+		},
+		Tools:      []types.Tool{weatherTool()},
+		ToolChoice: "required",
+	})
+	require.NoError(t, err)
+
+	t.Run("Request", func(t *testing.T) {
+		assert.Equal(t, "Be brief", captured["system"])
+		assert.Equal(t, map[string]interface{}{"type": "any"}, captured["tool_choice"])
+
+		tools := captured["tools"].([]interface{})
+		require.Len(t, tools, 1)
+		assert.Equal(t, "get_weather", tools[0].(map[string]interface{})["name"])
+		assert.NotNil(t, tools[0].(map[string]interface{})["input_schema"])
+
+		messages := captured["messages"].([]interface{})
+		require.Len(t, messages, 3, "parallel tool results must be merged into one user turn")
+
+		assistant := messages[1].(map[string]interface{})
+		assert.Equal(t, "assistant", assistant["role"])
+		blocks := assistant["content"].([]interface{})
+		require.Len(t, blocks, 2)
+		assert.Equal(t, "tool_use", blocks[0].(map[string]interface{})["type"])
+		assert.Equal(t, map[string]interface{}{"city": "Berlin"}, blocks[0].(map[string]interface{})["input"])
+
+		results := messages[2].(map[string]interface{})
+		assert.Equal(t, "user", results["role"])
+		resultBlocks := results["content"].([]interface{})
+		require.Len(t, resultBlocks, 2)
+		assert.Equal(t, "tool_result", resultBlocks[1].(map[string]interface{})["type"])
+		assert.Equal(t, "toolu_b", resultBlocks[1].(map[string]interface{})["tool_use_id"])
+	})
+
+	t.Run("Response", func(t *testing.T) {
+		require.Len(t, resp.Choices, 1)
+		message := resp.Choices[0].Message
+		assert.Equal(t, "Checking.", message.Content)
+		require.Len(t, message.ToolCalls, 1)
+		assert.Equal(t, "toolu_2", message.ToolCalls[0].ID)
+		assert.Equal(t, "get_weather", message.ToolCalls[0].Function.Name)
+		assert.JSONEq(t, `{"city":"Paris"}`, message.ToolCalls[0].Function.Arguments)
+		assert.Equal(t, "tool_calls", *resp.Choices[0].FinishReason)
+	})
+}
+
+// TestClaudeStreamToolCallDeltas tests streamed tool_use blocks become indexed tool call deltas
+func TestClaudeStreamToolCallDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		events := []string{
+			`{"type":"message_start","message":{"id":"msg_3","model":"claude-3-haiku-20240307","role":"assistant","content":[],"usage":{"input_tokens":20}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Let me check."}}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_3","name":"get_weather","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Oslo\"}"}}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":15}}`,
+			`{"type":"message_stop"}`,
+		}
+		for _, event := range events {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}))
+	defer server.Close()
+
+	provider := providers.NewClaudeProvider(&types.ProviderConfig{
+		Name:    "claude",
+		BaseURL: server.URL,
+		APIKey:  "test-key",
+	}, newTestLogger())
+
+	chunks, err := provider.CallStream(context.Background(), &types.ChatCompletionRequest{
+		Model:    "claude-3-haiku-20240307",
+		Messages: []types.Message{{Role: "user", Content: "Weather in Oslo?"}},
+		Tools:    []types.Tool{weatherTool()},
+	})
+	require.NoError(t, err)
+
+	var toolChunks []*types.StreamChunk
+	var finishReason string
+	for _, chunk := range collectChunks(t, chunks) {
+		switch chunk.Type {
+		case types.ChunkToolCall:
+			toolChunks = append(toolChunks, chunk)
+		case types.ChunkFinish:
+			finishReason = *chunk.FinishReason
+		}
+	}
+
+	require.Len(t, toolChunks, 3)
+	assert.Equal(t, 0, toolChunks[0].ToolCallIndex, "tool calls are numbered independently of text blocks")
+	assert.Equal(t, "toolu_3", toolChunks[0].ToolCall.ID)
+	assert.Equal(t, "get_weather", toolChunks[0].ToolCall.Function.Name)
+
+	arguments := ""
+	for _, chunk := range toolChunks {
+		arguments += chunk.ToolCall.Function.Arguments
+	}
+	assert.JSONEq(t, `{"city":"Oslo"}`, arguments)
+	assert.Equal(t, "tool_calls", finishReason)
+}
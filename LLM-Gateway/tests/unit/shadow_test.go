@@ -0,0 +1,251 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTextSimilarity tests the word overlap score of shadow comparisons
+func TestTextSimilarity(t *testing.T) {
+	assert.InDelta(t, 1.0, router.TextSimilarity("The answer is 42.", "the ANSWER is 42"), 1e-9)
+	assert.InDelta(t, 1.0, router.TextSimilarity("a b c", "c b a"), 1e-9)
+	assert.Zero(t, router.TextSimilarity("yes", "no"))
+	assert.Zero(t, router.TextSimilarity("", "something"))
+	assert.Equal(t, 1.0, router.TextSimilarity("", ""))
+
+	partial := router.TextSimilarity("paris is the capital of france", "the capital of france is paris, of course")
+	assert.Greater(t, partial, 0.7)
+	assert.Less(t, partial, 1.0)
+
+	// Han characters are compared one by one
+	assert.Greater(t, router.TextSimilarity("北京是中国的首都", "中国的首都是北京"), 0.99)
+}
+
+// TestSampleShadow tests sampling, the concurrency cap and validation of shadow targets
+func TestSampleShadow(t *testing.T) {
+	newShadowRouter := func(t *testing.T, shadow types.ShadowConfig) *router.SmartRouter {
+		config := router.DefaultSmartRouterConfig()
+		config.Shadows = []types.ShadowConfig{shadow}
+		smartRouter, err := router.NewSmartRouter(config, newTestLogger())
+		require.NoError(t, err)
+		for _, name := range []string{"provider-0", "provider-1"} {
+			require.NoError(t, smartRouter.AddProvider(router.NewMockProvider(&types.ProviderConfig{Name: name}, newTestLogger())))
+		}
+		return smartRouter
+	}
+
+	t.Run("ConcurrencyCap", func(t *testing.T) {
+		smartRouter := newShadowRouter(t, types.ShadowConfig{Model: "chat", Provider: "provider-1", SampleRate: 1, MaxConcurrent: 2})
+
+		first, ok := smartRouter.SampleShadow(context.Background(), "chat")
+		require.True(t, ok)
+		assert.Equal(t, "provider-1", first.Provider.GetName())
+		assert.Equal(t, "chat", first.Model, "the model is kept without a target_model")
+		_, ok = smartRouter.SampleShadow(context.Background(), "chat")
+		require.True(t, ok)
+
+		_, ok = smartRouter.SampleShadow(context.Background(), "chat")
+		assert.False(t, ok, "over the cap")
+		assert.Equal(t, int64(1), smartRouter.ShadowReports()[0].Dropped)
+
+		first.Release()
+		_, ok = smartRouter.SampleShadow(context.Background(), "chat")
+		assert.True(t, ok)
+	})
+
+	t.Run("SampleRate", func(t *testing.T) {
+		smartRouter := newShadowRouter(t, types.ShadowConfig{Model: "chat", Provider: "provider-1", TargetModel: "candidate", SampleRate: 0.2, MaxConcurrent: 10000})
+		sampled := 0
+		for i := 0; i < 2000; i++ {
+			if shadow, ok := smartRouter.SampleShadow(context.Background(), "chat"); ok {
+				assert.Equal(t, "candidate", shadow.Model)
+				sampled++
+			}
+		}
+		assert.InDelta(t, 400, sampled, 80)
+
+		_, ok := smartRouter.SampleShadow(context.Background(), "other")
+		assert.False(t, ok, "other models are not mirrored")
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string][]types.ShadowConfig{
+			"MissingModel":    {{Provider: "p", SampleRate: 0.1}},
+			"MissingProvider": {{Model: "m", SampleRate: 0.1}},
+			"ZeroRate":        {{Model: "m", Provider: "p"}},
+			"RateAboveOne":    {{Model: "m", Provider: "p", SampleRate: 1.5}},
+			"NegativeCap":     {{Model: "m", Provider: "p", SampleRate: 0.1, MaxConcurrent: -1}},
+			"Duplicate":       {{Model: "m", Provider: "p", SampleRate: 0.1}, {Model: "m", Provider: "q", SampleRate: 0.1}},
+		}
+		for name, shadows := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.Shadows = shadows
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+	})
+}
+
+// TestGatewayShadow tests that mirrored requests leave the client response alone and are compared in the background
+func TestGatewayShadow(t *testing.T) {
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Stream bool `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for _, event := range []string{
+				`{"id":"c1","model":"chat-model","choices":[{"index":0,"delta":{"role":"assistant","content":"Paris is"}}]}`,
+				`{"id":"c1","model":"chat-model","choices":[{"index":0,"delta":{"content":" the capital of France"}}]}`,
+				`{"id":"c1","model":"chat-model","choices":[{"index":0,"delta":{},"finish_reason":"length"}]}`,
+				`{"id":"c1","model":"chat-model","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":6,"total_tokens":16}}`,
+			} {
+				fmt.Fprintf(w, "data: %s\n\n", event)
+			}
+			fmt.Fprint(w, "data: [DONE]\n\n")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":"chat-model",
+			"choices":[{"index":0,"message":{"role":"assistant","content":"Paris is the capital of France"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":6,"total_tokens":16}}`)
+	}))
+	defer primary.Close()
+
+	release := make(chan struct{})
+	shadowModels := make(chan string, 2)
+	var shadowStreams int32
+	shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Model  string `json:"model"`
+			Stream bool   `json:"stream"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Stream {
+			atomic.AddInt32(&shadowStreams, 1)
+		}
+		shadowModels <- body.Model
+		<-release
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"cmpl-2","object":"chat.completion","created":1,"model":%q,
+			"choices":[{"index":0,"message":{"role":"assistant","content":"The capital of France is Paris"},"finish_reason":"length"}],
+			"usage":{"prompt_tokens":10,"completion_tokens":7,"total_tokens":17}}`, body.Model)
+	}))
+	defer shadow.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverTimeout: 10 * time.Second,
+			Shadows: []types.ShadowConfig{{
+				Model: "chat-model", Provider: "candidate", TargetModel: "candidate-model", SampleRate: 1, MaxConcurrent: 1,
+			}},
+			Concurrency: &types.ConcurrencyConfig{
+				Limits: []types.ConcurrencyLimit{{Provider: "candidate", MaxInFlight: 1}},
+			},
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"primary": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: primary.URL, RetryCount: 1,
+				Models: []string{"chat-model"},
+			},
+			"candidate": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: shadow.URL, RetryCount: 1,
+				Models: []string{"candidate-model"},
+			},
+		},
+	})
+
+	send := func(stream bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(fmt.Sprintf(
+			`{"model":"chat-model","stream":%t,"messages":[{"role":"user","content":"capital of France?"}]}`, stream)))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+	reports := func() []router.ShadowReport {
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/admin/shadows", nil))
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var report struct {
+			Shadows []router.ShadowReport `json:"shadows"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+		require.Len(t, report.Shadows, 1)
+		return report.Shadows
+	}
+
+	// The client is answered by the primary while the shadow call is still held open
+	recorder := send(false)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var response types.Response
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Equal(t, "Paris is the capital of France", response.Choices[0].Message.Content)
+	assert.Equal(t, "primary", recorder.Header().Get("X-Gateway-Provider"))
+
+	// The only slot is taken, so the next sample is dropped
+	require.Equal(t, http.StatusOK, send(false).Code)
+	assert.Equal(t, int64(1), reports()[0].Dropped)
+	assert.Zero(t, reports()[0].Mirrored)
+
+	// The held shadow call was admitted like live traffic
+	require.Eventually(t, func() bool {
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/admin/concurrency", nil))
+		var body struct {
+			Limits []router.ConcurrencyReport `json:"limits"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return len(body.Limits) == 1 && body.Limits[0].InFlight == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	require.Eventually(t, func() bool { return reports()[0].Mirrored == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	report := reports()[0]
+	assert.Equal(t, "candidate-model", <-shadowModels)
+	assert.Zero(t, report.ShadowErrors)
+	assert.Zero(t, report.FinishReasonMatches)
+	assert.Equal(t, int64(16), report.PrimaryTokens)
+	assert.Equal(t, int64(17), report.ShadowTokens)
+	assert.InDelta(t, 1.0, report.AvgSimilarity, 1e-9)
+
+	require.Len(t, report.Recent, 1)
+	comparison := report.Recent[0]
+	assert.Equal(t, "primary", comparison.Primary.Provider)
+	assert.Equal(t, "stop", comparison.Primary.FinishReason)
+	assert.Equal(t, "candidate", comparison.Shadow.Provider)
+	assert.Equal(t, "candidate-model", comparison.Shadow.Model)
+	assert.Equal(t, "length", comparison.Shadow.FinishReason)
+
+	// A streamed answer is mirrored once the stream completes, as a regular call
+	recorder = send(true)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "data: [DONE]")
+	require.Eventually(t, func() bool { return reports()[0].Mirrored == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "candidate-model", <-shadowModels)
+	assert.Zero(t, atomic.LoadInt32(&shadowStreams))
+
+	report = reports()[0]
+	assert.Equal(t, int64(1), report.FinishReasonMatches, "both streamed and shadow answers ended on length")
+	assert.Equal(t, int64(32), report.PrimaryTokens)
+}
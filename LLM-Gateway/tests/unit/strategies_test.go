@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/llm-gateway/gateway/internal/router"
 	"github.com/llm-gateway/gateway/internal/router/strategies"
+	"github.com/llm-gateway/gateway/pkg/cost"
 	"github.com/llm-gateway/gateway/pkg/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -228,6 +230,327 @@ func TestHealthBasedStrategy(t *testing.T) {
 	})
 }
 
+// TestPeakEWMAStrategy tests the peak-EWMA latency-aware strategy
+func TestPeakEWMAStrategy(t *testing.T) {
+	providers := createMockProviders(3)
+	request := &types.Request{Model: "test-model"}
+
+	// newWarmStrategy returns a strategy that has seen one call to each provider
+	newWarmStrategy := func(decay time.Duration, latencies ...time.Duration) (strategies.LoadBalanceStrategy, *strategies.PeakEWMAStrategy) {
+		strategy := strategies.NewPeakEWMAStrategy(decay)
+		pe := strategy.(*strategies.PeakEWMAStrategy)
+		for i, latency := range latencies {
+			pe.UpdateProviderHealth(fmt.Sprintf("provider-%d", i), latency, true)
+		}
+		return strategy, pe
+	}
+
+	t.Run("SelectProvider_LowestLatency", func(t *testing.T) {
+		strategy, _ := newWarmStrategy(time.Minute, 300*time.Millisecond, 50*time.Millisecond, 200*time.Millisecond)
+
+		provider, err := strategy.SelectProvider(providers, request)
+		require.NoError(t, err)
+		assert.Equal(t, "provider-1", (*provider).GetName())
+	})
+
+	t.Run("SelectProvider_InFlightRequests", func(t *testing.T) {
+		strategy, pe := newWarmStrategy(time.Minute, 300*time.Millisecond, 50*time.Millisecond, 220*time.Millisecond)
+
+		// provider-1 is expected to finish first until four requests queue on it: 5 x 50ms > 220ms
+		var selected []string
+		for i := 0; i < 5; i++ {
+			provider, err := strategy.SelectProvider(providers, request)
+			require.NoError(t, err)
+			selected = append(selected, (*provider).GetName())
+			pe.CallStarted((*provider).GetName())
+		}
+		assert.Equal(t, []string{"provider-1", "provider-1", "provider-1", "provider-1", "provider-2"}, selected)
+		assert.Equal(t, int64(4), pe.GetPending("provider-1"))
+
+		// Finishing a request takes it out of flight; a selection that is never sent adds nothing
+		pe.CallFinished("provider-1")
+		assert.Equal(t, int64(3), pe.GetPending("provider-1"))
+		_, err := strategy.SelectProvider(providers, request)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), pe.GetPending("provider-1"))
+	})
+
+	t.Run("SelectProvider_PeakIsImmediate", func(t *testing.T) {
+		strategy, pe := newWarmStrategy(time.Minute, 50*time.Millisecond, 60*time.Millisecond)
+
+		// A single slow call moves provider-0 straight to the slower latency
+		pe.UpdateProviderHealth("provider-0", 500*time.Millisecond, true)
+		assert.Equal(t, 500*time.Millisecond, pe.GetLatency("provider-0"))
+
+		provider, err := strategy.SelectProvider(providers[:2], request)
+		require.NoError(t, err)
+		assert.Equal(t, "provider-1", (*provider).GetName())
+	})
+
+	t.Run("UpdateProviderHealth_FastSamplesDecay", func(t *testing.T) {
+		_, pe := newWarmStrategy(20*time.Millisecond, 500*time.Millisecond)
+
+		// After several decay periods a fast sample outweighs the old peak
+		time.Sleep(100 * time.Millisecond)
+		pe.UpdateProviderHealth("provider-0", 10*time.Millisecond, true)
+		assert.Less(t, pe.GetLatency("provider-0"), 50*time.Millisecond)
+	})
+
+	t.Run("UpdateProviderHealth_FailurePenalty", func(t *testing.T) {
+		_, pe := newWarmStrategy(time.Minute, 50*time.Millisecond)
+
+		// A fast failure must not make the provider look attractive
+		pe.UpdateProviderHealth("provider-0", time.Millisecond, false)
+		assert.GreaterOrEqual(t, pe.GetLatency("provider-0"), time.Second)
+	})
+
+	t.Run("SelectProvider_UnmeasuredShareTraffic", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(0)
+		selections := make(map[string]int)
+		for i := 0; i < 3; i++ {
+			provider, err := strategy.SelectProvider(providers, request)
+			require.NoError(t, err)
+			selections[(*provider).GetName()]++
+		}
+		assert.Len(t, selections, 3, "busy unmeasured providers are penalized")
+	})
+
+	t.Run("SelectProvider_EmptyProviders", func(t *testing.T) {
+		strategy := strategies.NewPeakEWMAStrategy(0)
+		provider, err := strategy.SelectProvider([]*types.Provider{}, request)
+		assert.Error(t, err)
+		assert.Nil(t, provider)
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		strategy, pe := newWarmStrategy(time.Minute, 50*time.Millisecond)
+		_, err := strategy.SelectProvider(providers, request)
+		require.NoError(t, err)
+
+		require.NoError(t, strategy.Reset())
+		assert.Zero(t, pe.GetLatency("provider-0"))
+		assert.Zero(t, strategy.GetMetrics().SelectionCount)
+		assert.Equal(t, "peak_ewma", strategy.GetStrategyName())
+	})
+}
+
+// TestCostOptimizedStrategy tests the cost-optimized load balancing strategy
+func TestCostOptimizedStrategy(t *testing.T) {
+	providers := createMockProviders(3)
+	request := &types.Request{
+		Model:    "test-model",
+		Messages: []types.Message{{Role: "user", Content: "How much does this cost?"}},
+	}
+
+	// provider-1 is the cheapest, provider-2 the most expensive
+	pricing := cost.NewPricingManager()
+	for name, price := range map[string]float64{"provider-0": 0.002, "provider-1": 0.0005, "provider-2": 0.03} {
+		pricing.UpdatePricing(name, "test-model", &types.ModelPricing{
+			Model: "test-model", Provider: name, InputPrice: price, OutputPrice: price, Currency: "USD",
+		})
+	}
+
+	t.Run("SelectProvider_Cheapest", func(t *testing.T) {
+		strategy := strategies.NewCostOptimizedStrategy(nil, pricing)
+		for i := 0; i < 3; i++ {
+			provider, err := strategy.SelectProvider(providers, request)
+			require.NoError(t, err)
+			assert.Equal(t, "provider-1", (*provider).GetName())
+		}
+		assert.Positive(t, strategy.GetMetrics().DistributionStats["provider-1_estimated_cost"])
+	})
+
+	t.Run("SelectProvider_EqualCostRotates", func(t *testing.T) {
+		strategy := strategies.NewCostOptimizedStrategy(nil, nil)
+		selections := make(map[string]int)
+		for i := 0; i < 6; i++ {
+			provider, err := strategy.SelectProvider(providers, request)
+			require.NoError(t, err)
+			selections[(*provider).GetName()]++
+		}
+		for _, provider := range providers {
+			assert.Equal(t, 2, selections[(*provider).GetName()])
+		}
+	})
+
+	t.Run("SelectProvider_LatencyTolerance", func(t *testing.T) {
+		strategy := strategies.NewCostOptimizedStrategy(&types.CostOptimizedConfig{LatencyTolerance: 0.5}, pricing)
+		updater := strategy.(strategies.HealthUpdater)
+		updater.UpdateProviderHealth("provider-0", 100*time.Millisecond, true)
+		updater.UpdateProviderHealth("provider-1", 400*time.Millisecond, true)
+
+		provider, err := strategy.SelectProvider(providers, request)
+		require.NoError(t, err)
+		assert.Equal(t, "provider-0", (*provider).GetName(), "provider-1 is cheaper but too slow")
+
+		// Failed calls don't count towards latency
+		updater.UpdateProviderHealth("provider-1", time.Millisecond, false)
+		provider, err = strategy.SelectProvider(providers[:2], request)
+		require.NoError(t, err)
+		assert.Equal(t, "provider-0", (*provider).GetName())
+	})
+
+	t.Run("SelectProvider_QualityTolerance", func(t *testing.T) {
+		strategy := strategies.NewCostOptimizedStrategy(&types.CostOptimizedConfig{
+			QualityTolerance: 0.1,
+			Quality:          map[string]float64{"provider-0": 0.9, "provider-1": 0.6, "provider-2": 0.95},
+		}, pricing)
+
+		provider, err := strategy.SelectProvider(providers, request)
+		require.NoError(t, err)
+		assert.Equal(t, "provider-0", (*provider).GetName(), "the cheapest provider within 10% of the best quality")
+	})
+
+	t.Run("SelectProvider_EmptyProviders", func(t *testing.T) {
+		strategy := strategies.NewCostOptimizedStrategy(nil, pricing)
+		provider, err := strategy.SelectProvider([]*types.Provider{}, request)
+		assert.Error(t, err)
+		assert.Nil(t, provider)
+	})
+
+	t.Run("GetStrategyName", func(t *testing.T) {
+		assert.Equal(t, "cost_optimized", strategies.NewCostOptimizedStrategy(nil, pricing).GetStrategyName())
+	})
+}
+
+func TestPrefixAffinityStrategy(t *testing.T) {
+	providers := createMockProviders(3)
+	conversation := func(system string, turns ...string) *types.Request {
+		messages := []types.Message{{Role: "system", Content: system}}
+		for i, turn := range turns {
+			role := "user"
+			if i%2 == 1 {
+				role = "assistant"
+			}
+			messages = append(messages, types.Message{Role: role, Content: turn})
+		}
+		return &types.Request{Model: "test-model", Messages: messages}
+	}
+
+	// selectDone selects a provider and completes the call, so load never spills
+	selectDone := func(t *testing.T, strategy strategies.LoadBalanceStrategy, candidates []*types.Provider, request *types.Request) string {
+		provider, err := strategy.SelectProvider(candidates, request)
+		require.NoError(t, err)
+		tracker := strategy.(strategies.LoadTracker)
+		tracker.CallStarted((*provider).GetName())
+		tracker.CallFinished((*provider).GetName())
+		return (*provider).GetName()
+	}
+
+	hashers := map[string]func() strategies.NodeHasher{
+		"Consistent": func() strategies.NodeHasher { return router.NewHashRing() },
+		"Rendezvous": func() strategies.NodeHasher { return router.NewRendezvousHash() },
+	}
+	for hashName, newHasher := range hashers {
+		t.Run(hashName, func(t *testing.T) {
+			t.Run("SelectProvider_ConversationTurnsStick", func(t *testing.T) {
+				strategy := strategies.NewPrefixAffinityStrategy(nil, newHasher())
+
+				// Later turns repeat the system prompt and first message, the cached prefix
+				first := selectDone(t, strategy, providers, conversation("You are a helpful assistant.", "Summarize this report"))
+				for turns := 2; turns <= 6; turns++ {
+					history := make([]string, turns)
+					history[0] = "Summarize this report"
+					for i := 1; i < turns; i++ {
+						history[i] = fmt.Sprintf("turn %d", i)
+					}
+					assert.Equal(t, first, selectDone(t, strategy, providers, conversation("You are a helpful assistant.", history...)))
+				}
+			})
+
+			t.Run("SelectProvider_ConversationID", func(t *testing.T) {
+				strategy := strategies.NewPrefixAffinityStrategy(nil, newHasher())
+				request := &types.Request{Model: "test-model", ConversationID: "conv-42"}
+
+				first := selectDone(t, strategy, providers, request)
+				for i := 0; i < 5; i++ {
+					request.Messages = append(request.Messages, types.Message{Role: "user", Content: fmt.Sprintf("message %d", i)})
+					assert.Equal(t, first, selectDone(t, strategy, providers, request))
+				}
+			})
+
+			t.Run("SelectProvider_PrefixesSpread", func(t *testing.T) {
+				strategy := strategies.NewPrefixAffinityStrategy(nil, newHasher())
+				selections := make(map[string]int)
+				for i := 0; i < 60; i++ {
+					selections[selectDone(t, strategy, providers, conversation(fmt.Sprintf("system prompt %d", i), "hi"))]++
+				}
+				assert.Len(t, selections, 3)
+			})
+
+			t.Run("SelectProvider_UnhealthyTargetFallsBack", func(t *testing.T) {
+				strategy := strategies.NewPrefixAffinityStrategy(nil, newHasher())
+				request := conversation("You are a code reviewer.", "Review this diff")
+				target := selectDone(t, strategy, providers, request)
+
+				var healthy []*types.Provider
+				for _, provider := range providers {
+					if (*provider).GetName() != target {
+						healthy = append(healthy, provider)
+					}
+				}
+				fallback := selectDone(t, strategy, healthy, request)
+				assert.NotEqual(t, target, fallback)
+				assert.Equal(t, fallback, selectDone(t, strategy, healthy, request), "the fallback is stable too")
+
+				// Once healthy again the target gets its conversations back
+				assert.Equal(t, target, selectDone(t, strategy, providers, request))
+			})
+		})
+	}
+
+	t.Run("SelectProvider_BoundedLoad", func(t *testing.T) {
+		strategy := strategies.NewPrefixAffinityStrategy(&types.PrefixAffinityConfig{LoadFactor: 1.5}, router.NewHashRing())
+		pa := strategy.(*strategies.PrefixAffinityStrategy)
+		request := conversation("A very popular system prompt", "hi")
+
+		// Requests for one hot prefix stay in flight; the target takes at most 1.5x the average
+		selections := make(map[string]int)
+		for i := 0; i < 12; i++ {
+			provider, err := strategy.SelectProvider(providers, request)
+			require.NoError(t, err)
+			selections[(*provider).GetName()]++
+			pa.CallStarted((*provider).GetName())
+		}
+		assert.Greater(t, len(selections), 1, "the hot prefix spills once its target is full")
+		for _, provider := range providers {
+			assert.LessOrEqual(t, pa.GetPending((*provider).GetName()), int64(6), "ceil(1.5 x 12 / 3)")
+		}
+		assert.Positive(t, strategy.GetMetrics().DistributionStats["affinity_spilled"])
+	})
+
+	t.Run("SelectProvider_NoKeyBalances", func(t *testing.T) {
+		strategy := strategies.NewPrefixAffinityStrategy(nil, router.NewHashRing())
+		selections := make(map[string]int)
+		for i := 0; i < 3; i++ {
+			provider, err := strategy.SelectProvider(providers, &types.Request{Model: "test-model"})
+			require.NoError(t, err)
+			selections[(*provider).GetName()]++
+		}
+		assert.Len(t, selections, 3)
+	})
+
+	t.Run("AffinityKey", func(t *testing.T) {
+		first := strategies.AffinityKey(conversation("system", "hello"), 1)
+		assert.NotEmpty(t, first)
+		assert.Equal(t, first, strategies.AffinityKey(conversation("system", "hello", "answer", "follow-up"), 1))
+		assert.NotEqual(t, first, strategies.AffinityKey(conversation("other system", "hello"), 1))
+		assert.NotEqual(t, first, strategies.AffinityKey(conversation("system", "hello", "answer"), 2))
+		assert.Empty(t, strategies.AffinityKey(&types.Request{Model: "test-model"}, 1))
+	})
+
+	t.Run("SelectProvider_EmptyProviders", func(t *testing.T) {
+		strategy := strategies.NewPrefixAffinityStrategy(nil, router.NewHashRing())
+		provider, err := strategy.SelectProvider([]*types.Provider{}, conversation("system", "hi"))
+		assert.Error(t, err)
+		assert.Nil(t, provider)
+	})
+
+	t.Run("GetStrategyName", func(t *testing.T) {
+		assert.Equal(t, "prefix_affinity", strategies.NewPrefixAffinityStrategy(nil, router.NewHashRing()).GetStrategyName())
+	})
+}
+
 // Benchmark tests for performance evaluation
 func BenchmarkRoundRobinStrategy(b *testing.B) {
 	strategy := strategies.NewRoundRobinStrategy()
@@ -298,6 +621,23 @@ func BenchmarkHealthBasedStrategy(b *testing.B) {
 	})
 }
 
+func BenchmarkPeakEWMAStrategy(b *testing.B) {
+	strategy := strategies.NewPeakEWMAStrategy(0)
+	providers := createMockProviders(10)
+	request := &types.Request{Model: "test-model"}
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			provider, err := strategy.SelectProvider(providers, request)
+			if err != nil {
+				b.Fatal(err)
+			}
+			strategy.(strategies.HealthUpdater).UpdateProviderHealth((*provider).GetName(), time.Millisecond, true)
+		}
+	})
+}
+
 // Helper function to create mock providers
 func createMockProviders(count int) []*types.Provider {
 	providers := make([]*types.Provider, count)
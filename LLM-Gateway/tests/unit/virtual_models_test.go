@@ -0,0 +1,158 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestVirtualModelFallthrough tests that a failed target falls through to the next with its overrides
+func TestVirtualModelFallthrough(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"message":"model overloaded","type":"invalid_request_error"}}`)
+	}))
+	defer failing.Close()
+
+	var forwarded map[string]interface{}
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&forwarded))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"cmpl-1","object":"chat.completion","created":1,"model":%q,
+			"choices":[{"index":0,"message":{"role":"assistant","content":"pong"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":4,"completion_tokens":1,"total_tokens":5}}`, forwarded["model"])
+	}))
+	defer healthy.Close()
+
+	temperature := 0.1
+	maxTokens := 64
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			VirtualModels: map[string]*types.VirtualModelConfig{
+				"smart": {Targets: []types.VirtualModelTarget{
+					{Provider: "primary", Model: "big-model"},
+					{Provider: "missing", Model: "other-model"},
+					{Provider: "backup", Model: "small-model", Temperature: &temperature, MaxTokens: &maxTokens},
+				}},
+			},
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"primary": {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: failing.URL, Models: []string{"big-model"}},
+			"backup":  {Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: healthy.URL, Models: []string{"small-model"}},
+		},
+	})
+
+	body := `{"model":"smart","temperature":0.9,"messages":[{"role":"user","content":"ping"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	gw.Handler().ServeHTTP(recorder, req)
+
+	require.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "backup", recorder.Header().Get("X-Gateway-Provider"))
+	assert.Equal(t, "small-model", recorder.Header().Get("X-Gateway-Model"))
+
+	var resp types.Response
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &resp))
+	assert.Equal(t, "small-model", resp.Model, "the response names the concrete model")
+	assert.Equal(t, "backup", resp.Provider)
+
+	assert.Equal(t, "small-model", forwarded["model"])
+	assert.Equal(t, 0.1, forwarded["temperature"], "per-target overrides replace request parameters")
+	assert.Equal(t, float64(64), forwarded["max_tokens"])
+}
+
+// TestVirtualModelRouting tests weighted ordering, exhaustion and config validation in the Smart Router
+func TestVirtualModelRouting(t *testing.T) {
+	config := router.DefaultSmartRouterConfig()
+	config.VirtualModels = map[string]*types.VirtualModelConfig{
+		"fast": {Strategy: types.VirtualModelWeighted, Targets: []types.VirtualModelTarget{
+			{Provider: "fallback", Model: "m1", Weight: 0},
+			{Provider: "preferred", Model: "m2", Weight: 1},
+		}},
+	}
+
+	smartRouter, err := router.NewSmartRouter(config, newTestLogger())
+	require.NoError(t, err)
+	for _, name := range []string{"fallback", "preferred"} {
+		require.NoError(t, smartRouter.AddProvider(router.NewMockProvider(&types.ProviderConfig{Name: name}, newTestLogger())))
+	}
+
+	assert.True(t, smartRouter.IsVirtualModel("fast"))
+	assert.False(t, smartRouter.IsVirtualModel("m1"))
+
+	t.Run("WeightedOrder", func(t *testing.T) {
+		for i := 0; i < 20; i++ {
+			var tried []string
+			result, err := smartRouter.ExecuteVirtualModel(context.Background(), &types.Request{Model: "fast"}, nil,
+				func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+					tried = append(tried, req.Model)
+					return nil, nil
+				})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"m2"}, tried, "zero-weight targets are fallback-only")
+			assert.Equal(t, "preferred", result.ProviderName)
+			assert.False(t, result.BackupUsed)
+		}
+	})
+
+	t.Run("AllTargetsFail", func(t *testing.T) {
+		attempts := 0
+		_, err := smartRouter.ExecuteVirtualModel(context.Background(), &types.Request{Model: "fast"}, nil,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				attempts++
+				return nil, errors.New("upstream down")
+			})
+		require.Error(t, err)
+		assert.Equal(t, 2, attempts)
+		assert.Contains(t, err.Error(), "upstream down")
+	})
+
+	t.Run("Deadline", func(t *testing.T) {
+		bounded := router.DefaultSmartRouterConfig()
+		bounded.FailoverTimeout = 50 * time.Millisecond
+		bounded.VirtualModels = map[string]*types.VirtualModelConfig{
+			"chain": {Targets: []types.VirtualModelTarget{{Provider: "slow", Model: "m1"}, {Provider: "next", Model: "m2"}}},
+		}
+		smartRouter, _ := newTestSmartRouter(t, bounded, "slow", "next")
+
+		attempts := 0
+		start := time.Now()
+		_, err := smartRouter.ExecuteVirtualModel(context.Background(), &types.Request{Model: "chain"}, nil,
+			func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+				attempts++
+				<-ctx.Done()
+				return nil, ctx.Err()
+			})
+		require.Error(t, err)
+		assert.Equal(t, 1, attempts, "no target is tried after the failover timeout")
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("InvalidConfig", func(t *testing.T) {
+		invalid := router.DefaultSmartRouterConfig()
+		invalid.VirtualModels = map[string]*types.VirtualModelConfig{
+			"empty": {Strategy: types.VirtualModelOrdered},
+		}
+		assert.Error(t, invalid.ValidateConfig())
+
+		invalid.VirtualModels = map[string]*types.VirtualModelConfig{
+			"bad": {Targets: []types.VirtualModelTarget{{Provider: "p"}}},
+		}
+		assert.Error(t, invalid.ValidateConfig())
+	})
+}
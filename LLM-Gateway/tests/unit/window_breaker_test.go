@@ -0,0 +1,311 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/llm-gateway/gateway/internal/gateway"
+	"github.com/llm-gateway/gateway/internal/providers"
+	"github.com/llm-gateway/gateway/internal/router"
+	"github.com/llm-gateway/gateway/pkg/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// transitionLog records the state transitions of a circuit breaker
+type transitionLog struct {
+	mutex       sync.Mutex
+	transitions []string
+}
+
+func (l *transitionLog) record(from, to router.CircuitState, reason string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.transitions = append(l.transitions, from.String()+"->"+to.String())
+}
+
+func (l *transitionLog) get() []string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return append([]string(nil), l.transitions...)
+}
+
+// TestWindowCircuitBreaker tests the rolling window, minimum volume and half-open probing of the breaker
+func TestWindowCircuitBreaker(t *testing.T) {
+	config := router.CircuitBreakerConfig{
+		Enabled: true, Threshold: 5, Timeout: 30 * time.Millisecond, MaxRequests: 2,
+		FailureRate: 0.5, MinRequests: 4,
+	}
+	call := func(breaker *router.WindowCircuitBreaker, success bool) bool {
+		ticket, ok := breaker.Allow()
+		if ok {
+			breaker.Record(ticket, success)
+		}
+		return ok
+	}
+
+	t.Run("MinimumVolume", func(t *testing.T) {
+		log := &transitionLog{}
+		breaker := router.NewWindowCircuitBreaker(config, log.record)
+
+		for i := 0; i < 3; i++ {
+			require.True(t, call(breaker, false))
+		}
+		assert.Equal(t, router.StateClosed, breaker.GetStats().State, "too few calls to judge")
+
+		require.True(t, call(breaker, true))
+		require.True(t, call(breaker, false))
+		stats := breaker.GetStats()
+		assert.Equal(t, router.StateOpen, stats.State)
+		assert.Equal(t, 5, stats.Requests)
+		assert.Equal(t, 4, stats.Failures)
+		assert.Equal(t, []string{"closed->open"}, log.get())
+
+		assert.False(t, call(breaker, true), "an open breaker refuses calls")
+		assert.False(t, breaker.Available())
+		assert.Positive(t, breaker.RetryAfter())
+	})
+
+	t.Run("ErrorRate", func(t *testing.T) {
+		breaker := router.NewWindowCircuitBreaker(config, nil)
+		for i := 0; i < 20; i++ {
+			require.True(t, call(breaker, i%4 != 0))
+		}
+		assert.Equal(t, router.StateClosed, breaker.GetStats().State, "a quarter of calls failing stays under the rate")
+	})
+
+	t.Run("RollingWindow", func(t *testing.T) {
+		timed := config
+		timed.Window = 40 * time.Millisecond
+		breaker := router.NewWindowCircuitBreaker(timed, nil)
+		for i := 0; i < 3; i++ {
+			require.True(t, call(breaker, false))
+		}
+		time.Sleep(60 * time.Millisecond)
+		require.True(t, call(breaker, true))
+		require.True(t, call(breaker, false))
+		assert.Equal(t, router.StateClosed, breaker.GetStats().State, "old failures leave the time window")
+
+		counted := config
+		counted.WindowSize = 4
+		breaker = router.NewWindowCircuitBreaker(counted, nil)
+		for i := 0; i < 10; i++ {
+			require.True(t, call(breaker, true))
+		}
+		require.True(t, call(breaker, false))
+		require.True(t, call(breaker, false))
+		assert.Equal(t, router.StateOpen, breaker.GetStats().State, "only the last window_size calls count")
+	})
+
+	t.Run("HalfOpen", func(t *testing.T) {
+		log := &transitionLog{}
+		breaker := router.NewWindowCircuitBreaker(config, log.record)
+		for i := 0; i < 4; i++ {
+			call(breaker, false)
+		}
+		require.Equal(t, router.StateOpen, breaker.GetStats().State)
+		time.Sleep(40 * time.Millisecond)
+		assert.True(t, breaker.Available())
+
+		// Probes are capped at max_requests
+		first, ok := breaker.Allow()
+		require.True(t, ok)
+		assert.Equal(t, router.StateHalfOpen, breaker.GetStats().State)
+		second, ok := breaker.Allow()
+		require.True(t, ok)
+		_, ok = breaker.Allow()
+		assert.False(t, ok, "probe cap reached")
+		assert.Equal(t, 2, breaker.GetStats().Probes)
+
+		// An abandoned probe frees its slot
+		breaker.Abandon(second)
+		second, ok = breaker.Allow()
+		require.True(t, ok)
+
+		breaker.Record(first, true)
+		assert.Equal(t, router.StateHalfOpen, breaker.GetStats().State)
+		breaker.Record(second, true)
+		assert.Equal(t, router.StateClosed, breaker.GetStats().State)
+		assert.Zero(t, breaker.GetStats().Requests, "closing starts a fresh window")
+		assert.Equal(t, []string{"closed->open", "open->half-open", "half-open->closed"}, log.get())
+
+		// A failed probe reopens it, and the other probe's late outcome is ignored
+		for i := 0; i < 4; i++ {
+			call(breaker, false)
+		}
+		time.Sleep(40 * time.Millisecond)
+		first, _ = breaker.Allow()
+		second, _ = breaker.Allow()
+		breaker.Record(first, false)
+		breaker.Record(second, true)
+		assert.Equal(t, router.StateOpen, breaker.GetStats().State)
+		assert.Equal(t, "half-open->open", log.get()[len(log.get())-1])
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		invalid := map[string]router.CircuitBreakerConfig{
+			"RateAboveOne":       {Enabled: true, Threshold: 5, Timeout: time.Second, MaxRequests: 1, FailureRate: 1.5},
+			"NegativeWindow":     {Enabled: true, Threshold: 5, Timeout: time.Second, MaxRequests: 1, Window: -time.Second},
+			"VolumeAboveWindow":  {Enabled: true, Threshold: 5, Timeout: time.Second, MaxRequests: 1, MinRequests: 20, WindowSize: 10},
+			"NegativeMinRequest": {Enabled: true, Threshold: 5, Timeout: time.Second, MaxRequests: 1, MinRequests: -1},
+		}
+		for name, breaker := range invalid {
+			t.Run(name, func(t *testing.T) {
+				config := router.DefaultSmartRouterConfig()
+				config.CircuitBreaker = breaker
+				assert.Error(t, config.ValidateConfig())
+			})
+		}
+	})
+}
+
+// TestSmartRouterCircuitBreaker tests that the smart router skips providers whose breaker for the model is open
+func TestSmartRouterCircuitBreaker(t *testing.T) {
+	config := router.DefaultSmartRouterConfig()
+	config.CircuitBreaker = router.CircuitBreakerConfig{Enabled: true, Threshold: 4, Timeout: time.Minute, MaxRequests: 1}
+	smartRouter, providerList := newTestSmartRouter(t, config, "provider-0", "provider-1")
+
+	var events []router.BreakerEvent
+	var mutex sync.Mutex
+	smartRouter.OnBreakerTransition(func(event router.BreakerEvent) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	})
+
+	var calls sync.Map
+	call := func(ctx context.Context, p types.Provider, req *types.Request) (interface{}, error) {
+		key := p.GetName() + "/" + req.Model
+		count, _ := calls.LoadOrStore(key, new(int64))
+		atomic.AddInt64(count.(*int64), 1)
+		if p.GetName() == "provider-0" && req.Model == "chat" {
+			return nil, errors.New("status code: 503, service unavailable")
+		}
+		return p.GetName(), nil
+	}
+	callsTo := func(key string) int64 {
+		count, ok := calls.Load(key)
+		if !ok {
+			return 0
+		}
+		return atomic.LoadInt64(count.(*int64))
+	}
+
+	for i := 0; i < 10; i++ {
+		result, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "chat"}, providerList, call)
+		require.NoError(t, err)
+		assert.Equal(t, "provider-1", result.ProviderName)
+	}
+	assert.Equal(t, int64(4), callsTo("provider-0/chat"), "the breaker opened after the minimum volume")
+
+	mutex.Lock()
+	require.Len(t, events, 1)
+	assert.Equal(t, "provider-0", events[0].Provider)
+	assert.Equal(t, "chat", events[0].Model)
+	assert.Equal(t, router.StateOpen, events[0].To)
+	mutex.Unlock()
+
+	// The breaker is per model, so provider-0 still serves other models
+	for i := 0; i < 4; i++ {
+		_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "other"}, providerList, call)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int64(2), callsTo("provider-0/other"))
+
+	// With every breaker for the model open the request fails fast
+	_, err := smartRouter.ExecuteWithFailover(context.Background(), &types.Request{Model: "chat"}, providerList[:1], call)
+	var circuitErr *router.CircuitOpenError
+	require.True(t, errors.As(err, &circuitErr))
+	assert.True(t, errors.Is(err, router.ErrCircuitOpen))
+	assert.Equal(t, int64(4), callsTo("provider-0/chat"))
+
+	breakers, recent := smartRouter.BreakerReports()
+	require.Len(t, breakers, 4)
+	assert.Equal(t, "provider-0", breakers[0].Provider)
+	assert.Equal(t, "chat", breakers[0].Model)
+	assert.Equal(t, router.StateOpen, breakers[0].State)
+	assert.Len(t, recent, 1)
+}
+
+// TestGatewayCircuitBreaker tests the 503 returned when every breaker for a model is open
+func TestGatewayCircuitBreaker(t *testing.T) {
+	var hits int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		http.Error(w, `{"error":{"message":"overloaded"}}`, http.StatusInternalServerError)
+	}))
+	defer upstream.Close()
+
+	gw := gateway.New(&types.Config{
+		Logging: types.LoggingConfig{Level: "error", Format: "text"},
+		SmartRouter: &types.SmartRouterConfig{
+			FailoverTimeout: 10 * time.Second,
+			CircuitBreaker: &types.CircuitBreakerConfig{
+				Enabled: true, Threshold: 2, Timeout: 30 * time.Second, MaxRequests: 1,
+			},
+		},
+		Providers: map[string]*types.ProviderConfig{
+			"flaky": {
+				Type: providers.OpenAICompatibleType, Enabled: true, BaseURL: upstream.URL, RetryCount: 1,
+				Models: []string{"chat-model"},
+			},
+		},
+	})
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions",
+			strings.NewReader(`{"model":"chat-model","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	for i := 0; i < 2; i++ {
+		require.Equal(t, http.StatusInternalServerError, send().Code)
+	}
+	sent := atomic.LoadInt64(&hits)
+
+	recorder := send()
+	require.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 30, retryAfter, 1)
+	var body struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "circuit_open", body.Error.Code)
+	assert.Equal(t, sent, atomic.LoadInt64(&hits), "the open breaker keeps requests off the upstream")
+
+	recorder = httptest.NewRecorder()
+	gw.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/admin/breakers", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var report struct {
+		Breakers []struct {
+			Provider string `json:"provider"`
+			State    string `json:"state"`
+		} `json:"breakers"`
+		Events []struct {
+			Provider string `json:"provider"`
+			To       string `json:"to"`
+		} `json:"events"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	require.Len(t, report.Breakers, 1)
+	assert.Equal(t, "flaky", report.Breakers[0].Provider)
+	assert.Equal(t, "open", report.Breakers[0].State)
+	require.Len(t, report.Events, 1)
+	assert.Equal(t, "open", report.Events[0].To)
+}